@@ -0,0 +1,280 @@
+package dyno
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// archiveBatchSize keeps a put and a delete for every item in the batch under the 25 item transaction limit
+const archiveBatchSize = 12
+
+// ArchiveDestination stores items before the Archiver deletes them
+type ArchiveDestination interface {
+	// Archive is called with a batch of items before they're deleted. Any returned writes are included in the
+	// transaction that deletes the items.
+	Archive(ctx context.Context, items []map[string]*dynamodb.AttributeValue) ([]*dynamodb.TransactWriteItem, error)
+}
+
+// TableArchive moves archived items into another table in the same transaction that deletes them
+type TableArchive struct {
+	TableName string
+}
+
+func (a *TableArchive) Archive(ctx context.Context, items []map[string]*dynamodb.AttributeValue) ([]*dynamodb.TransactWriteItem, error) {
+	writes := make([]*dynamodb.TransactWriteItem, len(items))
+	for i, item := range items {
+		writes[i] = &dynamodb.TransactWriteItem{
+			Put: &dynamodb.Put{
+				TableName: aws.String(a.TableName),
+				Item:      item,
+			},
+		}
+	}
+	return writes, nil
+}
+
+// S3Archive writes every batch of archived items to a newline delimited DynamoDB JSON object before they're deleted
+type S3Archive struct {
+	Client s3iface.S3API
+	Bucket string
+	Prefix string
+}
+
+func (a *S3Archive) Archive(ctx context.Context, items []map[string]*dynamodb.AttributeValue) ([]*dynamodb.TransactWriteItem, error) {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, item := range items {
+		if err := encoder.Encode(itemJSON(item)); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err := a.Client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(a.Bucket),
+		Key:         aws.String(fmt.Sprintf("%s%s.json", a.Prefix, NewKSUID())),
+		Body:        bytes.NewReader(body.Bytes()),
+		ContentType: aws.String("application/x-ndjson"),
+	})
+
+	return nil, err
+}
+
+// Archiver moves items matching a query out of a table
+type Archiver struct {
+	db   dynamodbiface.DynamoDBAPI
+	tn   string
+	dest ArchiveDestination
+
+	// Query selects the items to archive. TableName and ExclusiveStartKey are always overwritten.
+	Query *dynamodb.QueryInput
+
+	// AgeAttribute is a numeric attribute holding a unix timestamp. When set with OlderThan, only items older than
+	// OlderThan are archived.
+	AgeAttribute string
+	OlderThan    time.Duration
+
+	// VersionAttribute is an attribute that changes on every write. Items are only deleted if it still holds the
+	// archived value, so writes made after an item was read aren't lost. Defaults to AgeAttribute.
+	VersionAttribute string
+
+	// Checkpoints stores the query progress so an interrupted archive resumes where it left off. The checkpoint is
+	// reset once a pass completes.
+	Checkpoints *Checkpoints
+
+	// CheckpointName identifies this archiver in the checkpoints. Defaults to "archive/<table>".
+	CheckpointName string
+}
+
+func NewArchiver(db dynamodbiface.DynamoDBAPI, tableName string, dest ArchiveDestination) *Archiver {
+	return &Archiver{
+		db:   db,
+		tn:   tableName,
+		dest: dest,
+	}
+}
+
+// Run archives every matching item and returns how many were archived. Items that changed after they were read are
+// left in place.
+func (a *Archiver) Run(ctx context.Context) (int, error) {
+	table, err := a.db.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(a.tn),
+	})
+	if err != nil {
+		return 0, err
+	}
+	keySchema := table.Table.KeySchema
+
+	name := a.CheckpointName
+	if name == "" {
+		name = fmt.Sprintf("archive/%s", a.tn)
+	}
+	cp, err := a.Checkpoints.load(ctx, name, 0)
+	if err != nil {
+		return 0, err
+	}
+	if cp.done {
+		cp = &segmentCheckpoint{}
+	}
+
+	input := a.input()
+	count := 0
+
+	for {
+		input.ExclusiveStartKey = cp.lastKey
+
+		result, err := a.db.QueryWithContext(ctx, input)
+		if err != nil {
+			return count, err
+		}
+
+		for start := 0; start < len(result.Items); start += archiveBatchSize {
+			end := start + archiveBatchSize
+			if end > len(result.Items) {
+				end = len(result.Items)
+			}
+
+			archived, err := a.archive(ctx, result.Items[start:end], keySchema)
+			count += archived
+			if err != nil {
+				return count, err
+			}
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			// The pass is complete, so the next run starts from the beginning
+			if a.Checkpoints == nil {
+				return count, nil
+			}
+			return count, a.Checkpoints.Reset(ctx, name, 1)
+		}
+
+		cp.lastKey = result.LastEvaluatedKey
+		cp.count += int64(len(result.Items))
+
+		if err := a.Checkpoints.save(ctx, name, 0, cp); err != nil {
+			return count, err
+		}
+	}
+}
+
+// archive archives and deletes a batch of items, returning how many were deleted. When items changed after they were
+// read the batch is retried without them, so an S3Archive may also hold copies of items that were kept.
+func (a *Archiver) archive(ctx context.Context, items []map[string]*dynamodb.AttributeValue, keySchema []*dynamodb.KeySchemaElement) (int, error) {
+	for len(items) > 0 {
+		writes, err := a.dest.Archive(ctx, items)
+		if err != nil {
+			return 0, err
+		}
+
+		offset := len(writes)
+		for _, item := range items {
+			writes = append(writes, &dynamodb.TransactWriteItem{Delete: a.delete(item, keySchema)})
+		}
+
+		_, err = a.db.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: writes,
+		})
+		if err == nil {
+			return len(items), nil
+		}
+
+		var canceled *dynamodb.TransactionCanceledException
+		if !errors.As(err, &canceled) || len(canceled.CancellationReasons) != len(writes) {
+			return 0, err
+		}
+
+		unchanged := make([]map[string]*dynamodb.AttributeValue, 0, len(items))
+		for i, item := range items {
+			if aws.StringValue(canceled.CancellationReasons[offset+i].Code) != "ConditionalCheckFailed" {
+				unchanged = append(unchanged, item)
+			}
+		}
+		if len(unchanged) == len(items) {
+			return 0, err
+		}
+		items = unchanged
+	}
+
+	return 0, nil
+}
+
+// delete deletes an item only if its version attribute still holds the value that was read
+func (a *Archiver) delete(item map[string]*dynamodb.AttributeValue, keySchema []*dynamodb.KeySchemaElement) *dynamodb.Delete {
+	del := &dynamodb.Delete{
+		TableName: aws.String(a.tn),
+		Key:       tableKey(item, keySchema),
+	}
+
+	version := a.VersionAttribute
+	if version == "" {
+		version = a.AgeAttribute
+	}
+	if version == "" {
+		return del
+	}
+
+	del.ExpressionAttributeNames = map[string]*string{"#dyno_version": aws.String(version)}
+	if value, ok := item[version]; ok {
+		del.ConditionExpression = aws.String("#dyno_version = :dyno_version")
+		del.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{":dyno_version": value}
+	} else {
+		del.ConditionExpression = aws.String("attribute_not_exists(#dyno_version)")
+	}
+
+	return del
+}
+
+func (a *Archiver) input() *dynamodb.QueryInput {
+	input := &dynamodb.QueryInput{}
+	if a.Query != nil {
+		template := *a.Query
+		input = &template
+	}
+	input.TableName = aws.String(a.tn)
+
+	if a.AgeAttribute == "" || a.OlderThan <= 0 {
+		return input
+	}
+
+	names := map[string]*string{"#dyno_age": aws.String(a.AgeAttribute)}
+	for k, v := range input.ExpressionAttributeNames {
+		names[k] = v
+	}
+	values := map[string]*dynamodb.AttributeValue{
+		":dyno_cutoff": TimeValue(time.Now().Add(-a.OlderThan), TimeUnixSeconds),
+	}
+	for k, v := range input.ExpressionAttributeValues {
+		values[k] = v
+	}
+
+	filter := "#dyno_age < :dyno_cutoff"
+	if input.FilterExpression != nil {
+		filter = fmt.Sprintf("(%s) AND %s", aws.StringValue(input.FilterExpression), filter)
+	}
+
+	input.FilterExpression = aws.String(filter)
+	input.ExpressionAttributeNames = names
+	input.ExpressionAttributeValues = values
+
+	return input
+}
+
+// tableKey returns only the key attributes of an item
+func tableKey(item map[string]*dynamodb.AttributeValue, keySchema []*dynamodb.KeySchemaElement) map[string]*dynamodb.AttributeValue {
+	key := make(map[string]*dynamodb.AttributeValue, len(keySchema))
+	for _, element := range keySchema {
+		name := aws.StringValue(element.AttributeName)
+		key[name] = item[name]
+	}
+	return key
+}
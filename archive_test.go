@@ -0,0 +1,144 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiver(t *testing.T) {
+	src, dropSrc := createTestTable(t, "dyno-test-archive-src")
+	defer dropSrc()
+	dst, dropDst := createTestTable(t, "dyno-test-archive-dst")
+	defer dropDst()
+
+	ctx := context.Background()
+	writer := NewBatchWriter(testClient, src)
+	for i := 0; i < 30; i++ {
+		createdAt := time.Now()
+		if i%2 == 0 {
+			createdAt = createdAt.Add(-48 * time.Hour)
+		}
+
+		err := writer.Put(ctx, map[string]*dynamodb.AttributeValue{
+			"PK":        {S: aws.String("events")},
+			"SK":        {S: aws.String(fmt.Sprintf("event-%02d", i))},
+			"CreatedAt": {N: aws.String(strconv.FormatInt(createdAt.Unix(), 10))},
+		})
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Flush(ctx))
+
+	count := func(table string) int64 {
+		result, err := testClient.Scan(&dynamodb.ScanInput{TableName: aws.String(table)})
+		require.NoError(t, err)
+		return aws.Int64Value(result.Count)
+	}
+
+	archiver := NewArchiver(testClient, src, &TableArchive{TableName: dst})
+	archiver.Query = &dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("PK = :pk"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":pk": {S: aws.String("events")},
+		},
+		Limit: aws.Int64(10),
+	}
+	archiver.AgeAttribute = "CreatedAt"
+	archiver.OlderThan = 24 * time.Hour
+	archiver.Checkpoints = NewCheckpoints(testClient, tableName, "PK", "SK")
+	archiver.CheckpointName = "test-archive"
+
+	archived, err := archiver.Run(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, 15, archived)
+	assert.Equal(t, int64(15), count(src))
+	assert.Equal(t, int64(15), count(dst))
+
+	t.Run("given items added after a completed pass", func(t *testing.T) {
+		for i := 30; i < 34; i++ {
+			err := writer.Put(ctx, map[string]*dynamodb.AttributeValue{
+				"PK":        {S: aws.String("events")},
+				"SK":        {S: aws.String(fmt.Sprintf("event-%02d", i))},
+				"CreatedAt": TimeValue(time.Now().Add(-48*time.Hour), TimeUnixSeconds),
+			})
+			require.NoError(t, err)
+		}
+		require.NoError(t, writer.Flush(ctx))
+
+		archived, err := archiver.Run(ctx)
+		require.NoError(t, err)
+
+		assert.Equal(t, 4, archived)
+		assert.Equal(t, int64(15), count(src))
+		assert.Equal(t, int64(19), count(dst))
+	})
+}
+
+// touchingArchive rewrites an item while its batch is being archived
+type touchingArchive struct {
+	TableArchive
+	touch map[string]*dynamodb.AttributeValue
+}
+
+func (a *touchingArchive) Archive(ctx context.Context, items []map[string]*dynamodb.AttributeValue) ([]*dynamodb.TransactWriteItem, error) {
+	if a.touch != nil {
+		_, err := testClient.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(tableName),
+			Item:      a.touch,
+		})
+		if err != nil {
+			return nil, err
+		}
+		a.touch = nil
+	}
+	return a.TableArchive.Archive(ctx, items)
+}
+
+func TestArchiverChangedItems(t *testing.T) {
+	dst, dropDst := createTestTable(t, "dyno-test-archive-changed")
+	defer dropDst()
+
+	ctx := context.Background()
+	pk := "archive/" + NewKSUID()
+	old := TimeValue(time.Now().Add(-48*time.Hour), TimeUnixSeconds)
+	for i := 0; i < 3; i++ {
+		_, err := testClient.PutItem(&dynamodb.PutItemInput{
+			TableName: aws.String(tableName),
+			Item:      map[string]*dynamodb.AttributeValue{"PK": Str(pk), "SK": Str(strconv.Itoa(i)), "CreatedAt": old},
+		})
+		require.NoError(t, err)
+	}
+
+	dest := &touchingArchive{
+		TableArchive: TableArchive{TableName: dst},
+		touch:        map[string]*dynamodb.AttributeValue{"PK": Str(pk), "SK": Str("1"), "CreatedAt": TimeValue(time.Now(), TimeUnixSeconds)},
+	}
+	archiver := NewArchiver(testClient, tableName, dest)
+	archiver.Query = &dynamodb.QueryInput{
+		KeyConditionExpression:    aws.String("PK = :pk"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":pk": Str(pk)},
+	}
+	archiver.AgeAttribute = "CreatedAt"
+	archiver.OlderThan = 24 * time.Hour
+
+	archived, err := archiver.Run(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, archived)
+
+	result, err := testClient.Query(&dynamodb.QueryInput{
+		TableName:                 aws.String(tableName),
+		KeyConditionExpression:    aws.String("PK = :pk"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":pk": Str(pk)},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Items, 1)
+	assert.Equal(t, "1", GetString(result.Items[0], "SK", ""))
+}
@@ -0,0 +1,125 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/applicationautoscaling"
+	"github.com/aws/aws-sdk-go/service/applicationautoscaling/applicationautoscalingiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// AutoScalingOptions configures Application Auto Scaling of a PROVISIONED table's read and write capacity, and its
+// global secondary indexes'
+type AutoScalingOptions struct {
+	Client applicationautoscalingiface.ApplicationAutoScalingAPI
+
+	// MinReadCapacity and MinWriteCapacity are the least capacity auto scaling provisions. They default to the
+	// capacity the table or index has when it's registered.
+	MinReadCapacity  int64
+	MinWriteCapacity int64
+
+	// MaxReadCapacity and MaxWriteCapacity are the most capacity auto scaling provisions. They default to ten times
+	// the minimum.
+	MaxReadCapacity  int64
+	MaxWriteCapacity int64
+
+	// TargetUtilization is the percentage of provisioned capacity auto scaling aims to consume. Defaults to 70.
+	TargetUtilization float64
+}
+
+// RegisterAutoScaling registers the table and its global secondary indexes as scalable targets, with target tracking
+// policies for their read and write capacity. Registering again updates the targets and policies. Nothing is
+// registered for PAY_PER_REQUEST tables.
+func RegisterAutoScaling(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName string, opts *AutoScalingOptions) error {
+	result, err := db.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		return err
+	}
+	table := result.Table
+	if table.BillingModeSummary != nil && aws.StringValue(table.BillingModeSummary.BillingMode) == dynamodb.BillingModePayPerRequest {
+		return nil
+	}
+
+	resource := fmt.Sprintf("table/%s", tableName)
+	if err := opts.register(ctx, resource, "table", table.ProvisionedThroughput); err != nil {
+		return err
+	}
+	for _, gsi := range table.GlobalSecondaryIndexes {
+		resource := fmt.Sprintf("table/%s/index/%s", tableName, aws.StringValue(gsi.IndexName))
+		if err := opts.register(ctx, resource, "index", gsi.ProvisionedThroughput); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *AutoScalingOptions) register(ctx context.Context, resource, kind string, throughput *dynamodb.ProvisionedThroughputDescription) error {
+	if throughput == nil {
+		throughput = &dynamodb.ProvisionedThroughputDescription{}
+	}
+
+	utilization := o.TargetUtilization
+	if utilization == 0 {
+		utilization = 70
+	}
+
+	targets := []struct {
+		dimension string
+		metric    string
+		min       int64
+		max       int64
+		current   int64
+	}{
+		{"ReadCapacityUnits", applicationautoscaling.MetricTypeDynamoDbreadCapacityUtilization, o.MinReadCapacity, o.MaxReadCapacity, aws.Int64Value(throughput.ReadCapacityUnits)},
+		{"WriteCapacityUnits", applicationautoscaling.MetricTypeDynamoDbwriteCapacityUtilization, o.MinWriteCapacity, o.MaxWriteCapacity, aws.Int64Value(throughput.WriteCapacityUnits)},
+	}
+	for _, target := range targets {
+		min := target.min
+		if min == 0 {
+			min = target.current
+		}
+		if min == 0 {
+			min = 1
+		}
+		max := target.max
+		if max == 0 {
+			max = 10 * min
+		}
+		dimension := fmt.Sprintf("dynamodb:%s:%s", kind, target.dimension)
+
+		_, err := o.Client.RegisterScalableTargetWithContext(ctx, &applicationautoscaling.RegisterScalableTargetInput{
+			ServiceNamespace:  aws.String(applicationautoscaling.ServiceNamespaceDynamodb),
+			ResourceId:        aws.String(resource),
+			ScalableDimension: aws.String(dimension),
+			MinCapacity:       aws.Int64(min),
+			MaxCapacity:       aws.Int64(max),
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = o.Client.PutScalingPolicyWithContext(ctx, &applicationautoscaling.PutScalingPolicyInput{
+			PolicyName:        aws.String(fmt.Sprintf("Dyno_%s/%s", target.dimension, strings.TrimPrefix(resource, "table/"))),
+			PolicyType:        aws.String(applicationautoscaling.PolicyTypeTargetTrackingScaling),
+			ServiceNamespace:  aws.String(applicationautoscaling.ServiceNamespaceDynamodb),
+			ResourceId:        aws.String(resource),
+			ScalableDimension: aws.String(dimension),
+			TargetTrackingScalingPolicyConfiguration: &applicationautoscaling.TargetTrackingScalingPolicyConfiguration{
+				TargetValue: aws.Float64(utilization),
+				PredefinedMetricSpecification: &applicationautoscaling.PredefinedMetricSpecification{
+					PredefinedMetricType: aws.String(target.metric),
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/applicationautoscaling"
+	"github.com/aws/aws-sdk-go/service/applicationautoscaling/applicationautoscalingiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryAutoScaling struct {
+	applicationautoscalingiface.ApplicationAutoScalingAPI
+
+	targets  []*applicationautoscaling.RegisterScalableTargetInput
+	policies []*applicationautoscaling.PutScalingPolicyInput
+}
+
+func (m *memoryAutoScaling) RegisterScalableTargetWithContext(ctx aws.Context, input *applicationautoscaling.RegisterScalableTargetInput, opts ...request.Option) (*applicationautoscaling.RegisterScalableTargetOutput, error) {
+	m.targets = append(m.targets, input)
+	return &applicationautoscaling.RegisterScalableTargetOutput{}, nil
+}
+
+func (m *memoryAutoScaling) PutScalingPolicyWithContext(ctx aws.Context, input *applicationautoscaling.PutScalingPolicyInput, opts ...request.Option) (*applicationautoscaling.PutScalingPolicyOutput, error) {
+	m.policies = append(m.policies, input)
+	return &applicationautoscaling.PutScalingPolicyOutput{}, nil
+}
+
+func TestRegisterAutoScaling(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("given a provisioned table", func(t *testing.T) {
+		name := fmt.Sprintf("dyno-autoscaling-%s", ksuid.New().String())
+		defer testClient.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String(name)})
+
+		scaling := &memoryAutoScaling{}
+		opts := &TableOptions{
+			BillingMode:   dynamodb.BillingModeProvisioned,
+			ReadCapacity:  10,
+			WriteCapacity: 4,
+			AutoScaling:   &AutoScalingOptions{Client: scaling, MaxWriteCapacity: 100},
+		}
+		require.NoError(t, EnsureLockTable(ctx, testClient, name, "PK", "SK", opts))
+
+		result, err := testClient.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(name)})
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), *result.Table.ProvisionedThroughput.ReadCapacityUnits)
+
+		require.Len(t, scaling.targets, 2)
+		assert.Equal(t, "table/"+name, *scaling.targets[0].ResourceId)
+		assert.Equal(t, "dynamodb:table:ReadCapacityUnits", *scaling.targets[0].ScalableDimension)
+		assert.Equal(t, int64(10), *scaling.targets[0].MinCapacity)
+		assert.Equal(t, int64(100), *scaling.targets[0].MaxCapacity)
+		assert.Equal(t, int64(4), *scaling.targets[1].MinCapacity)
+		assert.Equal(t, int64(100), *scaling.targets[1].MaxCapacity)
+
+		require.Len(t, scaling.policies, 2)
+		assert.Equal(t, 70.0, *scaling.policies[0].TargetTrackingScalingPolicyConfiguration.TargetValue)
+		assert.Equal(t, applicationautoscaling.MetricTypeDynamoDbwriteCapacityUtilization, *scaling.policies[1].TargetTrackingScalingPolicyConfiguration.PredefinedMetricSpecification.PredefinedMetricType)
+	})
+
+	t.Run("given a pay per request table", func(t *testing.T) {
+		name := fmt.Sprintf("dyno-autoscaling-%s", ksuid.New().String())
+		defer testClient.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String(name)})
+
+		scaling := &memoryAutoScaling{}
+		require.NoError(t, EnsureLockTable(ctx, testClient, name, "PK", "SK", &TableOptions{
+			AutoScaling: &AutoScalingOptions{Client: scaling},
+		}))
+
+		assert.Empty(t, scaling.targets)
+	})
+}
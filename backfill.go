@@ -0,0 +1,94 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// BackfillFunc is called by Backfill for every scanned item
+type BackfillFunc func(ctx context.Context, item map[string]*dynamodb.AttributeValue) error
+
+// BackfillOptions configures Backfill
+type BackfillOptions struct {
+	// Segments is the number of parallel scan segments. Defaults to 4.
+	Segments int
+
+	// ItemsPerSecond limits how many items are handled per second across all segments. Zero is unlimited.
+	ItemsPerSecond int
+
+	// Scan is used as the template for the scan requests, e.g. to set a FilterExpression or ProjectionExpression.
+	// TableName, Segment, TotalSegments, and ExclusiveStartKey are always overwritten.
+	Scan *dynamodb.ScanInput
+
+	// Checkpoints stores the scan progress so a crashed or redeployed backfill resumes where it left off
+	Checkpoints *Checkpoints
+
+	// CheckpointName identifies this backfill in the checkpoints. Defaults to "backfill/<table>".
+	CheckpointName string
+}
+
+// Backfill scans the table and calls fn for every item. A page is only checkpointed once fn has succeeded for every
+// item in it, so fn may be called again for some items after a resume and should be idempotent.
+func Backfill(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName string, fn BackfillFunc, opts *BackfillOptions) error {
+	if opts == nil {
+		opts = &BackfillOptions{}
+	}
+
+	segments := opts.Segments
+	if segments <= 0 {
+		segments = 4
+	}
+	name := opts.CheckpointName
+	if name == "" {
+		name = fmt.Sprintf("backfill/%s", tableName)
+	}
+	input := &dynamodb.ScanInput{}
+	if opts.Scan != nil {
+		template := *opts.Scan
+		input = &template
+	}
+	input.TableName = aws.String(tableName)
+	limiter := newRateLimiter(opts.ItemsPerSecond)
+
+	scan := &segmentScan{
+		db:          db,
+		input:       input,
+		segments:    segments,
+		checkpoints: opts.Checkpoints,
+		name:        name,
+	}
+
+	return scan.run(ctx, func(ctx context.Context, items []map[string]*dynamodb.AttributeValue) error {
+		for _, item := range items {
+			if err := limiter.wait(ctx, 1); err != nil {
+				return err
+			}
+			if err := fn(ctx, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// PutMapped returns a BackfillFunc that writes the mapped item back to the table. Items the mapper returns nil for are
+// left untouched.
+func PutMapped(db dynamodbiface.DynamoDBAPI, tableName string, mapper ItemMapper) BackfillFunc {
+	return func(ctx context.Context, item map[string]*dynamodb.AttributeValue) error {
+		mapped, err := mapper(item)
+		if err != nil || mapped == nil {
+			return err
+		}
+
+		_, err = db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(tableName),
+			Item:      mapped,
+		})
+
+		return err
+	}
+}
@@ -0,0 +1,69 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackfill(t *testing.T) {
+	table, drop := createTestTable(t, "dyno-test-backfill")
+	defer drop()
+
+	ctx := context.Background()
+	writer := NewBatchWriter(testClient, table)
+	for i := 0; i < 30; i++ {
+		err := writer.Put(ctx, map[string]*dynamodb.AttributeValue{
+			"PK": {S: aws.String(fmt.Sprintf("item-%d", i))},
+			"SK": {S: aws.String("value")},
+		})
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Flush(ctx))
+
+	t.Run("given a failing item", func(t *testing.T) {
+		err := Backfill(ctx, testClient, table, func(context.Context, map[string]*dynamodb.AttributeValue) error {
+			return errors.New("boom")
+		}, &BackfillOptions{
+			Segments:       2,
+			Checkpoints:    NewCheckpoints(testClient, tableName, "PK", "SK"),
+			CheckpointName: "test-backfill",
+		})
+		assert.EqualError(t, err, "boom")
+	})
+
+	t.Run("updates every item", func(t *testing.T) {
+		var mu sync.Mutex
+		count := 0
+		mapper := PutMapped(testClient, table, func(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+			mu.Lock()
+			count++
+			mu.Unlock()
+
+			item["Version"] = &dynamodb.AttributeValue{N: aws.String("2")}
+			return item, nil
+		})
+
+		err := Backfill(ctx, testClient, table, mapper, &BackfillOptions{
+			Segments:       2,
+			ItemsPerSecond: 1000,
+			Checkpoints:    NewCheckpoints(testClient, tableName, "PK", "SK"),
+			CheckpointName: "test-backfill",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 30, count)
+
+		result, err := testClient.Scan(&dynamodb.ScanInput{TableName: aws.String(table)})
+		require.NoError(t, err)
+		for _, item := range result.Items {
+			assert.Equal(t, "2", aws.StringValue(item["Version"].N))
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+var ErrBackupDeleted = errors.New("backup was deleted before it became available")
+
+// backupTimeFormat is a timestamp that only uses characters allowed in backup names
+const backupTimeFormat = "20060102T150405Z"
+
+// BackupName returns the conventional name for a backup of the table: "<table>-<label>-<timestamp>". The label is
+// optional.
+func BackupName(tableName, label string, at time.Time) string {
+	parts := []string{tableName}
+	if label != "" {
+		parts = append(parts, label)
+	}
+	parts = append(parts, at.UTC().Format(backupTimeFormat))
+
+	return strings.Join(parts, "-")
+}
+
+// CreateBackup starts an on-demand backup of the table named with BackupName
+func CreateBackup(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName, label string) (*dynamodb.BackupDetails, error) {
+	result, err := db.CreateBackupWithContext(ctx, &dynamodb.CreateBackupInput{
+		TableName:  aws.String(tableName),
+		BackupName: aws.String(BackupName(tableName, label, time.Now())),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.BackupDetails, nil
+}
+
+// WaitForBackup blocks until the backup is AVAILABLE
+func WaitForBackup(ctx context.Context, db dynamodbiface.DynamoDBAPI, backupArn string) (*dynamodb.BackupDescription, error) {
+	for {
+		result, err := db.DescribeBackupWithContext(ctx, &dynamodb.DescribeBackupInput{
+			BackupArn: aws.String(backupArn),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		switch aws.StringValue(result.BackupDescription.BackupDetails.BackupStatus) {
+		case dynamodb.BackupStatusAvailable:
+			return result.BackupDescription, nil
+		case dynamodb.BackupStatusDeleted:
+			return nil, ErrBackupDeleted
+		}
+
+		if err := sleepContext(ctx, tablePollInterval); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// RestoreToNewTable restores the backup into a new table and blocks until the table is ACTIVE
+func RestoreToNewTable(ctx context.Context, db dynamodbiface.DynamoDBAPI, backupArn, tableName string) (*dynamodb.TableDescription, error) {
+	result, err := db.RestoreTableFromBackupWithContext(ctx, &dynamodb.RestoreTableFromBackupInput{
+		BackupArn:       aws.String(backupArn),
+		TargetTableName: aws.String(tableName),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := WaitUntilTableActive(ctx, db, tableName, nil); err != nil {
+		return nil, err
+	}
+
+	return result.TableDescription, nil
+}
+
+// ListBackups returns the backups of the table that were created with the label, newest first
+func ListBackups(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName, label string) ([]*dynamodb.BackupSummary, error) {
+	prefix := fmt.Sprintf("%s-", tableName)
+	if label != "" {
+		prefix = fmt.Sprintf("%s-%s-", tableName, label)
+	}
+
+	backups := []*dynamodb.BackupSummary{}
+	input := &dynamodb.ListBackupsInput{
+		TableName:  aws.String(tableName),
+		BackupType: aws.String(dynamodb.BackupTypeFilterUser),
+	}
+
+	for {
+		result, err := db.ListBackupsWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, backup := range result.BackupSummaries {
+			if strings.HasPrefix(aws.StringValue(backup.BackupName), prefix) {
+				backups = append(backups, backup)
+			}
+		}
+
+		if result.LastEvaluatedBackupArn == nil {
+			break
+		}
+		input.ExclusiveStartBackupArn = result.LastEvaluatedBackupArn
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return aws.TimeValue(backups[i].BackupCreationDateTime).After(aws.TimeValue(backups[j].BackupCreationDateTime))
+	})
+
+	return backups, nil
+}
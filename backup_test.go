@@ -0,0 +1,20 @@
+package dyno
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackupName(t *testing.T) {
+	at := time.Date(2019, 10, 1, 12, 30, 45, 0, time.FixedZone("PDT", -7*60*60))
+
+	t.Run("given a label", func(t *testing.T) {
+		assert.Equal(t, "orders-nightly-20191001T193045Z", BackupName("orders", "nightly", at))
+	})
+
+	t.Run("given no label", func(t *testing.T) {
+		assert.Equal(t, "orders-20191001T193045Z", BackupName("orders", "", at))
+	})
+}
@@ -0,0 +1,130 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// maxBatchWriteItems is the largest number of requests DynamoDB accepts in a single BatchWriteItem call
+const maxBatchWriteItems = 25
+
+var ErrUnprocessedItems = errors.New("batch write left unprocessed items after retrying")
+
+// BatchWriter buffers puts and deletes for a single table and writes them with BatchWriteItem, retrying any
+// unprocessed items, and requests that fail with a retryable error, with exponential backoff.
+//
+// A BatchWriter is not safe for concurrent use.
+type BatchWriter struct {
+	db      dynamodbiface.DynamoDBAPI
+	tn      string
+	pending []*dynamodb.WriteRequest
+
+	// MaxAttempts is the number of times unprocessed items are retried before Flush gives up. Defaults to 10.
+	MaxAttempts int
+
+	// Tracer records a span for each batch written, annotated with the table, item count, and number of retries
+	Tracer Tracer
+
+	// Metrics receives the capacity consumed by the writes as the "BatchWriter" operation
+	Metrics Metrics
+}
+
+func NewBatchWriter(db dynamodbiface.DynamoDBAPI, tableName string) *BatchWriter {
+	return &BatchWriter{
+		db:          db,
+		tn:          tableName,
+		MaxAttempts: 10,
+	}
+}
+
+// Put queues the item to be written, flushing if a full batch is pending
+func (w *BatchWriter) Put(ctx context.Context, item map[string]*dynamodb.AttributeValue) error {
+	return w.add(ctx, &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: item}})
+}
+
+// Delete queues the key to be deleted, flushing if a full batch is pending
+func (w *BatchWriter) Delete(ctx context.Context, key map[string]*dynamodb.AttributeValue) error {
+	return w.add(ctx, &dynamodb.WriteRequest{DeleteRequest: &dynamodb.DeleteRequest{Key: key}})
+}
+
+// Pending returns the number of queued requests that haven't been written yet
+func (w *BatchWriter) Pending() int {
+	return len(w.pending)
+}
+
+func (w *BatchWriter) add(ctx context.Context, req *dynamodb.WriteRequest) error {
+	w.pending = append(w.pending, req)
+	if len(w.pending) < maxBatchWriteItems {
+		return nil
+	}
+	return w.Flush(ctx)
+}
+
+// Flush writes all pending requests
+func (w *BatchWriter) Flush(ctx context.Context) error {
+	for len(w.pending) > 0 {
+		n := len(w.pending)
+		if n > maxBatchWriteItems {
+			n = maxBatchWriteItems
+		}
+
+		if _, err := w.write(ctx, w.pending[:n]); err != nil {
+			return err
+		}
+
+		w.pending = w.pending[n:]
+	}
+	w.pending = nil
+
+	return nil
+}
+
+// write writes a batch, returning the requests that weren't written if it fails
+func (w *BatchWriter) write(ctx context.Context, requests []*dynamodb.WriteRequest) (unwritten []*dynamodb.WriteRequest, err error) {
+	ctx, span := startSpan(ctx, w.Tracer, "dyno.BatchWriter.Write")
+	span.Annotate("dyno_table", w.tn)
+	span.Annotate("dyno_items", len(requests))
+	attempt := 0
+	defer func() {
+		span.Annotate("dyno_retries", attempt)
+		span.End(err)
+	}()
+
+	backoff := 50 * time.Millisecond
+
+	for ; ; attempt++ {
+		result, err := w.db.BatchWriteItemWithContext(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems:           map[string][]*dynamodb.WriteRequest{w.tn: requests},
+			ReturnConsumedCapacity: returnConsumedCapacity(w.Metrics),
+		})
+		if err != nil && !IsRetryable(err) {
+			return requests, err
+		}
+		if err == nil {
+			RecordCapacity(w.Metrics, "BatchWriter", true, result.ConsumedCapacity...)
+
+			requests = result.UnprocessedItems[w.tn]
+			if len(requests) == 0 {
+				return nil, nil
+			}
+		}
+
+		if attempt+1 >= w.MaxAttempts {
+			if err != nil {
+				return requests, err
+			}
+			return requests, ErrUnprocessedItems
+		}
+
+		if err := sleepContext(ctx, backoff); err != nil {
+			return requests, err
+		}
+		if backoff < 5*time.Second {
+			backoff *= 2
+		}
+	}
+}
@@ -0,0 +1,369 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+var ErrBreakerOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of a CircuitBreaker
+type BreakerState string
+
+const (
+	// BreakerClosed lets every call through
+	BreakerClosed BreakerState = "closed"
+
+	// BreakerOpen rejects every call with ErrBreakerOpen
+	BreakerOpen BreakerState = "open"
+
+	// BreakerHalfOpen lets a single probe call through. Its result closes or re-opens the breaker.
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// CircuitBreaker is a circuit breaker whose state is shared through a DynamoDB item, so every instance of a service
+// opens and closes it together. Failures from all instances count towards tripping it, and a single instance probes
+// the dependency when it's half-open.
+type CircuitBreaker struct {
+	db   dynamodbiface.DynamoDBAPI
+	tn   string
+	pk   string
+	sk   string
+	name string
+
+	mu       sync.Mutex
+	cached   breakerItem
+	cachedAt time.Time
+
+	// FailureThreshold is how many failures within the window trip the breaker. Defaults to 5.
+	FailureThreshold int
+
+	// Window is how long failures are counted for. Defaults to one minute.
+	Window time.Duration
+
+	// OpenDuration is how long the breaker stays open before letting a probe through. Defaults to 30 seconds.
+	OpenDuration time.Duration
+
+	// CacheTTL is how long the breaker's state is cached before it's read again. Defaults to one second.
+	CacheTTL time.Duration
+
+	// ConsistentRead reads the breaker's state with strongly consistent reads. Without it, an instance may keep
+	// calling for a moment after the breaker trips. Defaults to true.
+	ConsistentRead bool
+
+	// IsFailure decides which errors count as failures. Defaults to every error except a canceled context.
+	IsFailure func(error) bool
+
+	// OnStateChange is called when this instance changes the breaker's state
+	OnStateChange func(from, to BreakerState)
+}
+
+func NewCircuitBreaker(db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey, name string) *CircuitBreaker {
+	return &CircuitBreaker{
+		db:               db,
+		tn:               tableName,
+		pk:               primaryKey,
+		sk:               sortKey,
+		name:             name,
+		FailureThreshold: 5,
+		Window:           time.Minute,
+		OpenDuration:     30 * time.Second,
+		CacheTTL:         time.Second,
+		ConsistentRead:   true,
+		IsFailure: func(err error) bool {
+			return Classify(err) != ErrorClassCanceled
+		},
+	}
+}
+
+// breakerItem is the breaker's stored state. Times are unix milliseconds.
+type breakerItem struct {
+	state       BreakerState
+	failures    int
+	windowStart int64
+	openedAt    int64
+	probeAt     int64
+}
+
+// Do calls fn unless the breaker is open, and records its result. ErrBreakerOpen is returned without calling fn while
+// the breaker is open, or half-open with another instance probing.
+func (b *CircuitBreaker) Do(ctx context.Context, fn func(context.Context) error) error {
+	current, err := b.load(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	probe := false
+	switch current.state {
+	case BreakerOpen, BreakerHalfOpen:
+		probe, err = b.claimProbe(ctx, current)
+		if err != nil {
+			return err
+		}
+		if !probe {
+			return ErrBreakerOpen
+		}
+	}
+
+	callErr := fn(ctx)
+	failed := callErr != nil && b.IsFailure(callErr)
+
+	switch {
+	case probe && failed:
+		err = b.transition(ctx, BreakerHalfOpen, BreakerOpen)
+	case probe:
+		err = b.transition(ctx, BreakerHalfOpen, BreakerClosed)
+	case failed:
+		err = b.recordFailure(ctx)
+	}
+	if err != nil && callErr == nil {
+		return err
+	}
+
+	return callErr
+}
+
+// State returns the breaker's state, which may be cached for up to CacheTTL
+func (b *CircuitBreaker) State(ctx context.Context) (BreakerState, error) {
+	current, err := b.load(ctx, false)
+	return current.state, err
+}
+
+// Reset closes the breaker and clears its failures
+func (b *CircuitBreaker) Reset(ctx context.Context) error {
+	_, err := b.db.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(b.tn),
+		Key:       b.key(),
+	})
+	if err != nil {
+		return err
+	}
+
+	b.cache(breakerItem{state: BreakerClosed})
+	return nil
+}
+
+func (b *CircuitBreaker) key() map[string]*dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{}
+	item[b.pk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_Breaker/%s", b.name))}
+
+	if b.sk != "" {
+		item[b.sk] = &dynamodb.AttributeValue{S: aws.String("Dyno_BreakerSortKeyValue")}
+	}
+
+	return item
+}
+
+func (b *CircuitBreaker) load(ctx context.Context, fresh bool) (breakerItem, error) {
+	b.mu.Lock()
+	if !fresh && time.Since(b.cachedAt) < b.CacheTTL {
+		defer b.mu.Unlock()
+		return b.cached, nil
+	}
+	b.mu.Unlock()
+
+	result, err := b.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(b.tn),
+		Key:            b.key(),
+		ConsistentRead: aws.Bool(b.ConsistentRead),
+	})
+	if err != nil {
+		return breakerItem{}, err
+	}
+
+	current := parseBreakerItem(result.Item)
+	b.cache(current)
+
+	return current, nil
+}
+
+func (b *CircuitBreaker) cache(current breakerItem) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.cached = current
+	b.cachedAt = time.Now()
+}
+
+func parseBreakerItem(item map[string]*dynamodb.AttributeValue) breakerItem {
+	current := breakerItem{state: BreakerClosed}
+	if v, ok := item["Dyno_State"]; ok {
+		current.state = BreakerState(aws.StringValue(v.S))
+	}
+
+	number := func(name string) int64 {
+		if v, ok := item[name]; ok {
+			n, _ := strconv.ParseInt(aws.StringValue(v.N), 10, 64)
+			return n
+		}
+		return 0
+	}
+	current.failures = int(number("Dyno_Failures"))
+	current.windowStart = number("Dyno_WindowStart")
+	current.openedAt = number("Dyno_OpenedAt")
+	current.probeAt = number("Dyno_ProbeAt")
+
+	return current
+}
+
+// claimProbe moves an open breaker whose open duration has passed to half-open, or takes over a probe that has been
+// running for longer than the open duration. Only one instance can win the claim.
+func (b *CircuitBreaker) claimProbe(ctx context.Context, current breakerItem) (bool, error) {
+	now := unixMilli(time.Now())
+	wait := int64(b.OpenDuration / time.Millisecond)
+
+	if current.state == BreakerOpen && current.openedAt+wait > now {
+		return false, nil
+	}
+	if current.state == BreakerHalfOpen && current.probeAt+wait > now {
+		return false, nil
+	}
+
+	_, err := b.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(b.tn),
+		Key:                 b.key(),
+		UpdateExpression:    aws.String("SET #st = :half, #pa = :now"),
+		ConditionExpression: aws.String("(#st = :open AND #oa = :oa) OR (#st = :half AND #pa = :pa)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#st": aws.String("Dyno_State"),
+			"#oa": aws.String("Dyno_OpenedAt"),
+			"#pa": aws.String("Dyno_ProbeAt"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":open": {S: aws.String(string(BreakerOpen))},
+			":half": {S: aws.String(string(BreakerHalfOpen))},
+			":now":  {N: aws.String(strconv.FormatInt(now, 10))},
+			":oa":   {N: aws.String(strconv.FormatInt(current.openedAt, 10))},
+			":pa":   {N: aws.String(strconv.FormatInt(current.probeAt, 10))},
+		},
+	})
+	if Classify(err) == ErrorClassConditionalCheckFailed { // Another instance is probing
+		_, err = b.load(ctx, true)
+		return false, err
+	}
+	if err != nil {
+		return false, err
+	}
+
+	b.changed(current.state, BreakerHalfOpen)
+	return true, nil
+}
+
+// recordFailure counts a failure in the current window, and trips the breaker if it reaches the threshold
+func (b *CircuitBreaker) recordFailure(ctx context.Context) error {
+	now := unixMilli(time.Now())
+	cutoff := now - int64(b.Window/time.Millisecond)
+
+	result, err := b.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(b.tn),
+		Key:                 b.key(),
+		UpdateExpression:    aws.String("ADD #f :one"),
+		ConditionExpression: aws.String("#st = :closed AND #ws >= :cutoff"),
+		ExpressionAttributeNames: map[string]*string{
+			"#st": aws.String("Dyno_State"),
+			"#f":  aws.String("Dyno_Failures"),
+			"#ws": aws.String("Dyno_WindowStart"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":closed": {S: aws.String(string(BreakerClosed))},
+			":one":    {N: aws.String("1")},
+			":cutoff": {N: aws.String(strconv.FormatInt(cutoff, 10))},
+		},
+		ReturnValues: aws.String(dynamodb.ReturnValueAllNew),
+	})
+	if Classify(err) == ErrorClassConditionalCheckFailed { // The window expired, or the breaker isn't closed
+		result, err = b.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+			TableName:           aws.String(b.tn),
+			Key:                 b.key(),
+			UpdateExpression:    aws.String("SET #st = :closed, #f = :one, #ws = :now"),
+			ConditionExpression: aws.String("attribute_not_exists(#st) OR (#st = :closed AND #ws < :cutoff)"),
+			ExpressionAttributeNames: map[string]*string{
+				"#st": aws.String("Dyno_State"),
+				"#f":  aws.String("Dyno_Failures"),
+				"#ws": aws.String("Dyno_WindowStart"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":closed": {S: aws.String(string(BreakerClosed))},
+				":one":    {N: aws.String("1")},
+				":now":    {N: aws.String(strconv.FormatInt(now, 10))},
+				":cutoff": {N: aws.String(strconv.FormatInt(cutoff, 10))},
+			},
+			ReturnValues: aws.String(dynamodb.ReturnValueAllNew),
+		})
+	}
+	if Classify(err) == ErrorClassConditionalCheckFailed { // The breaker is already open
+		_, err = b.load(ctx, true)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	current := parseBreakerItem(result.Attributes)
+	b.cache(current)
+	if current.failures < b.FailureThreshold {
+		return nil
+	}
+
+	return b.transition(ctx, BreakerClosed, BreakerOpen)
+}
+
+// transition changes the breaker's state if it's still in the expected state
+func (b *CircuitBreaker) transition(ctx context.Context, from, to BreakerState) error {
+	now := strconv.FormatInt(unixMilli(time.Now()), 10)
+
+	input := &dynamodb.UpdateItemInput{
+		TableName:           aws.String(b.tn),
+		Key:                 b.key(),
+		ConditionExpression: aws.String("#st = :from"),
+		ExpressionAttributeNames: map[string]*string{
+			"#st": aws.String("Dyno_State"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":from": {S: aws.String(string(from))},
+			":to":   {S: aws.String(string(to))},
+			":now":  {N: aws.String(now)},
+		},
+		ReturnValues: aws.String(dynamodb.ReturnValueAllNew),
+	}
+	switch to {
+	case BreakerOpen:
+		input.UpdateExpression = aws.String("SET #st = :to, #oa = :now REMOVE #pa")
+		input.ExpressionAttributeNames["#oa"] = aws.String("Dyno_OpenedAt")
+		input.ExpressionAttributeNames["#pa"] = aws.String("Dyno_ProbeAt")
+	case BreakerClosed:
+		input.UpdateExpression = aws.String("SET #st = :to, #f = :zero, #ws = :now REMOVE #oa, #pa")
+		input.ExpressionAttributeNames["#f"] = aws.String("Dyno_Failures")
+		input.ExpressionAttributeNames["#ws"] = aws.String("Dyno_WindowStart")
+		input.ExpressionAttributeNames["#oa"] = aws.String("Dyno_OpenedAt")
+		input.ExpressionAttributeNames["#pa"] = aws.String("Dyno_ProbeAt")
+		input.ExpressionAttributeValues[":zero"] = &dynamodb.AttributeValue{N: aws.String("0")}
+	}
+
+	result, err := b.db.UpdateItemWithContext(ctx, input)
+	if Classify(err) == ErrorClassConditionalCheckFailed { // Another instance got there first
+		_, err = b.load(ctx, true)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	b.cache(parseBreakerItem(result.Attributes))
+	b.changed(from, to)
+	return nil
+}
+
+func (b *CircuitBreaker) changed(from, to BreakerState) {
+	if b.OnStateChange != nil {
+		b.OnStateChange(from, to)
+	}
+}
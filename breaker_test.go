@@ -0,0 +1,80 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	errDown := errors.New("down")
+	fail := func(context.Context) error { return errDown }
+	succeed := func(context.Context) error { return nil }
+
+	breaker := func() *CircuitBreaker {
+		b := NewCircuitBreaker(testClient, tableName, "PK", "SK", "testing-breaker")
+		b.FailureThreshold = 2
+		b.OpenDuration = 50 * time.Millisecond
+		b.CacheTTL = 0
+		return b
+	}
+	instance1, instance2 := breaker(), breaker()
+	require.NoError(t, instance1.Reset(ctx))
+
+	changes := []BreakerState{}
+	instance1.OnStateChange = func(from, to BreakerState) { changes = append(changes, to) }
+
+	t.Run("given failures from several instances", func(t *testing.T) {
+		assert.Equal(t, errDown, instance1.Do(ctx, fail))
+		state, err := instance2.State(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, BreakerClosed, state)
+
+		assert.Equal(t, errDown, instance2.Do(ctx, fail))
+		state, err = instance1.State(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, BreakerOpen, state)
+
+		called := false
+		err = instance1.Do(ctx, func(context.Context) error { called = true; return nil })
+		assert.Equal(t, ErrBreakerOpen, err)
+		assert.False(t, called)
+	})
+
+	t.Run("given a failed probe", func(t *testing.T) {
+		time.Sleep(60 * time.Millisecond)
+
+		assert.Equal(t, errDown, instance1.Do(ctx, fail))
+		assert.Equal(t, ErrBreakerOpen, instance2.Do(ctx, succeed))
+		assert.Equal(t, []BreakerState{BreakerHalfOpen, BreakerOpen}, changes)
+	})
+
+	t.Run("given a successful probe", func(t *testing.T) {
+		time.Sleep(60 * time.Millisecond)
+
+		assert.NoError(t, instance1.Do(ctx, succeed))
+		assert.NoError(t, instance2.Do(ctx, succeed))
+		state, err := instance2.State(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, BreakerClosed, state)
+		assert.Equal(t, []BreakerState{BreakerHalfOpen, BreakerOpen, BreakerHalfOpen, BreakerClosed}, changes)
+	})
+
+	t.Run("given failures spread over more than the window", func(t *testing.T) {
+		b := breaker()
+		b.Window = 20 * time.Millisecond
+
+		assert.Equal(t, errDown, b.Do(ctx, fail))
+		time.Sleep(30 * time.Millisecond)
+		assert.Equal(t, errDown, b.Do(ctx, fail))
+
+		state, err := b.State(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, BreakerClosed, state)
+	})
+}
@@ -0,0 +1,90 @@
+package dyno
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// lockHold tracks the lock's current hold, for its hold budget and Context
+type lockHold struct {
+	mu     sync.Mutex
+	budget time.Duration
+	cancel bool
+
+	lockID   string
+	ctx      context.Context
+	cancelFn context.CancelFunc
+	timer    *time.Timer
+}
+
+// HoldBudget sets how long the lock is expected to be held. If it's still held when the budget runs out, a
+// LockHoldExceeded event is sent and the "LockHoldBudgetExceeded" metric counted, and if cancel is true the lock's
+// Context is canceled. Zero disables the budget.
+func (l *Lock) HoldBudget(budget time.Duration, cancel bool) {
+	l.hold.mu.Lock()
+	defer l.hold.mu.Unlock()
+
+	l.hold.budget = budget
+	l.hold.cancel = cancel
+}
+
+// Context returns a context for the current hold, canceled when the lock is released or lost, or when it runs past
+// its hold budget if the budget cancels it. If the lock isn't held it's already canceled.
+func (l *Lock) Context() context.Context {
+	l.hold.mu.Lock()
+	defer l.hold.mu.Unlock()
+
+	if l.hold.ctx == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		return ctx
+	}
+	return l.hold.ctx
+}
+
+func (h *lockHold) observe(l *Lock, e LockEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch e.Type {
+	case LockAcquired, LockStolen:
+		h.end()
+		h.lockID = e.LockID
+		h.ctx, h.cancelFn = context.WithCancel(context.Background())
+		if h.budget > 0 {
+			lockID := e.LockID
+			h.timer = time.AfterFunc(h.budget, func() { l.exceeded(lockID) })
+		}
+	case LockReleased, LockLost:
+		h.end()
+	}
+}
+
+// end stops the budget and cancels the context of the current hold, if there is one
+func (h *lockHold) end() {
+	if h.timer != nil {
+		h.timer.Stop()
+		h.timer = nil
+	}
+	if h.cancelFn != nil {
+		h.cancelFn()
+	}
+	h.lockID = ""
+}
+
+// exceeded is called when a hold runs past its budget, unless the lock was released in the meantime
+func (l *Lock) exceeded(lockID string) {
+	l.hold.mu.Lock()
+	if l.hold.lockID != lockID {
+		l.hold.mu.Unlock()
+		return
+	}
+	if l.hold.cancel {
+		l.hold.cancelFn()
+	}
+	l.hold.mu.Unlock()
+
+	countMetric(l.metrics, "LockHoldBudgetExceeded", 1, l.dimensions())
+	l.event(LockEvent{Type: LockHoldExceeded, LockID: lockID})
+}
@@ -0,0 +1,82 @@
+package dyno
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockHoldBudget(t *testing.T) {
+	exceeded := func(lock *Lock) func() int {
+		var mu sync.Mutex
+		count := 0
+		lock.OnEvent(func(e LockEvent) {
+			if e.Type == LockHoldExceeded {
+				mu.Lock()
+				count++
+				mu.Unlock()
+			}
+		})
+		return func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return count
+		}
+	}
+
+	t.Run("given a lock released within its budget", func(t *testing.T) {
+		lock := NewLock(testClient, tableName, "PK", "SK", "testing-hold-budget")
+		lock.HoldBudget(50*time.Millisecond, true)
+		count := exceeded(lock)
+
+		require.NoError(t, lock.Acquire(30*time.Second))
+		ctx := lock.Context()
+		assert.NoError(t, ctx.Err())
+		require.NoError(t, lock.Release())
+
+		assert.Error(t, ctx.Err(), "releasing ends the hold")
+		time.Sleep(100 * time.Millisecond)
+		assert.Equal(t, 0, count())
+	})
+
+	t.Run("given a lock held past its budget", func(t *testing.T) {
+		counter := &countingMetrics{}
+		lock := NewLock(testClient, tableName, "PK", "SK", "testing-hold-budget")
+		lock.Metrics(counter)
+		lock.HoldBudget(10*time.Millisecond, false)
+		count := exceeded(lock)
+
+		require.NoError(t, lock.Acquire(30*time.Second))
+		defer lock.Release()
+
+		assert.Eventually(t, func() bool { return count() == 1 }, time.Second, 5*time.Millisecond)
+		assert.NoError(t, lock.Context().Err())
+
+		counter.mu.Lock()
+		defer counter.mu.Unlock()
+		assert.Equal(t, 1.0, counter.counts["LockHoldBudgetExceeded"])
+	})
+
+	t.Run("given a budget that cancels", func(t *testing.T) {
+		lock := NewLock(testClient, tableName, "PK", "SK", "testing-hold-budget")
+		lock.HoldBudget(10*time.Millisecond, true)
+
+		require.NoError(t, lock.Acquire(30*time.Second))
+		defer lock.Release()
+
+		select {
+		case <-lock.Context().Done():
+		case <-time.After(time.Second):
+			t.Fatal("the context wasn't canceled")
+		}
+	})
+
+	t.Run("given a lock that isn't held", func(t *testing.T) {
+		lock := NewLock(testClient, tableName, "PK", "SK", "testing-hold-budget")
+
+		assert.Error(t, lock.Context().Err())
+	})
+}
@@ -0,0 +1,280 @@
+package dyno
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+var ErrBufferFull = errors.New("write buffer is full")
+
+// BufferedWrite is a put or an update queued in a WriteBuffer. Only one of Item and Update is set.
+type BufferedWrite struct {
+	Item   map[string]*dynamodb.AttributeValue
+	Update *dynamodb.UpdateItemInput
+}
+
+// WriteBuffer accumulates puts and updates in memory and writes them in the background, in batches, when FlushSize
+// writes are pending or FlushInterval passes. Writes that fail are reported to OnError rather than returned, so it's
+// meant for high-rate writes that can tolerate loss, like telemetry.
+//
+// Writes to the same item are applied in the order they were made. A put replaces any earlier writes to its item that
+// are still pending, so they're never written.
+//
+// A WriteBuffer is safe for concurrent use. Writes are only made while Run is running.
+type WriteBuffer struct {
+	db        dynamodbiface.DynamoDBAPI
+	tn        string
+	keySchema []*dynamodb.KeySchemaElement
+
+	mu      sync.Mutex
+	pending []BufferedWrite
+	size    int
+	flush   chan struct{}
+	flushMu sync.Mutex
+
+	// FlushInterval is how often pending writes are flushed. Defaults to one second.
+	FlushInterval time.Duration
+
+	// FlushSize is the number of pending writes that triggers a flush. Defaults to 100.
+	FlushSize int
+
+	// MaxBufferSize is the total size, in bytes, of the writes that can be pending. Put and Update return
+	// ErrBufferFull beyond it. Defaults to 4MB.
+	MaxBufferSize int
+
+	// Concurrency is the number of updates made at once while flushing. Updates to the same item are made one at a
+	// time. Defaults to 4.
+	Concurrency int
+
+	// ShutdownTimeout is how long Run spends flushing once its context is done. Defaults to 10 seconds.
+	ShutdownTimeout time.Duration
+
+	// OnError is called with each write that failed. It may be called concurrently.
+	OnError func(write BufferedWrite, err error)
+
+	// Metrics receives the capacity consumed by puts as the "BatchWriter" operation, and by updates as the
+	// "WriteBuffer" operation
+	Metrics Metrics
+}
+
+func NewWriteBuffer(db dynamodbiface.DynamoDBAPI, tableName string) *WriteBuffer {
+	return &WriteBuffer{
+		db:              db,
+		tn:              tableName,
+		flush:           make(chan struct{}, 1),
+		FlushInterval:   time.Second,
+		FlushSize:       100,
+		MaxBufferSize:   4 * 1024 * 1024,
+		Concurrency:     4,
+		ShutdownTimeout: 10 * time.Second,
+	}
+}
+
+// Put queues the item to be written
+func (b *WriteBuffer) Put(item map[string]*dynamodb.AttributeValue) error {
+	return b.add(BufferedWrite{Item: item}, SizeOf(item))
+}
+
+// Update queues an update. Its table name is set to the buffer's table.
+func (b *WriteBuffer) Update(input *dynamodb.UpdateItemInput) error {
+	input.TableName = aws.String(b.tn)
+
+	size := SizeOf(input.Key) + SizeOf(input.ExpressionAttributeValues) + len(aws.StringValue(input.UpdateExpression))
+	return b.add(BufferedWrite{Update: input}, size)
+}
+
+// Pending returns the number of queued writes
+func (b *WriteBuffer) Pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.pending)
+}
+
+func (b *WriteBuffer) add(write BufferedWrite, size int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.size+size > b.MaxBufferSize {
+		return ErrBufferFull
+	}
+	b.pending = append(b.pending, write)
+	b.size += size
+
+	if len(b.pending) >= b.FlushSize {
+		select {
+		case b.flush <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Run flushes pending writes until the context is done, then flushes what's left
+func (b *WriteBuffer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(b.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			shutdown, cancel := context.WithTimeout(context.Background(), b.ShutdownTimeout)
+			defer cancel()
+
+			b.Flush(shutdown)
+			return ctx.Err()
+		case <-ticker.C:
+		case <-b.flush:
+		}
+
+		b.Flush(ctx)
+	}
+}
+
+// Flush writes everything that's pending. Writes that fail are reported to OnError.
+func (b *WriteBuffer) Flush(ctx context.Context) {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = nil
+	b.size = 0
+	b.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	keySchema, err := b.describeKeys(ctx)
+	if err != nil {
+		for _, write := range pending {
+			b.failed(write, err)
+		}
+		return
+	}
+
+	ids := make([]string, len(pending))
+	lastPut := map[string]int{}
+	for i, write := range pending {
+		if write.Update != nil {
+			ids[i] = bufferKey(write.Update.Key)
+		} else {
+			ids[i] = bufferKey(tableKey(write.Item, keySchema))
+			lastPut[ids[i]] = i
+		}
+	}
+
+	// Only the writes after an item's last put are kept, which leaves at most one put for each item followed by its
+	// updates. Writing every put before the updates keeps the order of the writes to each item.
+	puts := []*dynamodb.WriteRequest{}
+	updates := []*dynamodb.UpdateItemInput{}
+	updateIDs := []string{}
+	for i, write := range pending {
+		if last, ok := lastPut[ids[i]]; ok && i < last {
+			continue
+		}
+		if write.Update != nil {
+			updates = append(updates, write.Update)
+			updateIDs = append(updateIDs, ids[i])
+		} else {
+			puts = append(puts, &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: write.Item}})
+		}
+	}
+
+	writer := NewBatchWriter(b.db, b.tn)
+	writer.Metrics = b.Metrics
+	for len(puts) > 0 {
+		n := len(puts)
+		if n > maxBatchWriteItems {
+			n = maxBatchWriteItems
+		}
+
+		unwritten, err := writer.write(ctx, puts[:n])
+		for _, req := range unwritten {
+			b.failed(BufferedWrite{Item: req.PutRequest.Item}, err)
+		}
+		puts = puts[n:]
+	}
+
+	b.update(ctx, updates, updateIDs)
+}
+
+// describeKeys returns the table's key schema, which is read once
+func (b *WriteBuffer) describeKeys(ctx context.Context) ([]*dynamodb.KeySchemaElement, error) {
+	if b.keySchema != nil {
+		return b.keySchema, nil
+	}
+
+	table, err := b.db.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(b.tn),
+	})
+	if err != nil {
+		return nil, err
+	}
+	b.keySchema = table.Table.KeySchema
+
+	return b.keySchema, nil
+}
+
+// update makes the updates concurrently, sending every update to the same item to the same worker so they're made in
+// order
+func (b *WriteBuffer) update(ctx context.Context, updates []*dynamodb.UpdateItemInput, ids []string) {
+	concurrency := b.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	workers := make([]chan *dynamodb.UpdateItemInput, concurrency)
+	var wg sync.WaitGroup
+
+	for i := range workers {
+		inputs := make(chan *dynamodb.UpdateItemInput)
+		workers[i] = inputs
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for input := range inputs {
+				input.ReturnConsumedCapacity = returnConsumedCapacity(b.Metrics)
+				result, err := b.db.UpdateItemWithContext(ctx, input)
+				if err != nil {
+					b.failed(BufferedWrite{Update: input}, err)
+					continue
+				}
+				RecordCapacity(b.Metrics, "WriteBuffer", true, result.ConsumedCapacity)
+			}
+		}()
+	}
+
+	for i, input := range updates {
+		hash := fnv.New32a()
+		hash.Write([]byte(ids[i]))
+		workers[hash.Sum32()%uint32(len(workers))] <- input
+	}
+	for _, inputs := range workers {
+		close(inputs)
+	}
+	wg.Wait()
+}
+
+// bufferKey identifies the item a key belongs to
+func bufferKey(key map[string]*dynamodb.AttributeValue) string {
+	data, _ := json.Marshal(itemJSON(key))
+	return string(data)
+}
+
+func (b *WriteBuffer) failed(write BufferedWrite, err error) {
+	if b.OnError != nil {
+		b.OnError(write, err)
+	}
+}
@@ -0,0 +1,138 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteBuffer(t *testing.T) {
+	table, drop := createTestTable(t, "dyno-test-write-buffer")
+	defer drop()
+
+	count := func() int64 {
+		result, err := testClient.Scan(&dynamodb.ScanInput{TableName: aws.String(table)})
+		require.NoError(t, err)
+		return aws.Int64Value(result.Count)
+	}
+	item := func(i int) map[string]*dynamodb.AttributeValue {
+		return map[string]*dynamodb.AttributeValue{
+			"PK": {S: aws.String("metrics")},
+			"SK": {S: aws.String(fmt.Sprintf("metric-%03d", i))},
+		}
+	}
+
+	t.Run("given the flush size is reached", func(t *testing.T) {
+		buffer := NewWriteBuffer(testClient, table)
+		buffer.FlushInterval = time.Hour
+		buffer.FlushSize = 30
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- buffer.Run(ctx) }()
+
+		for i := 0; i < 30; i++ {
+			require.NoError(t, buffer.Put(item(i)))
+		}
+		require.Eventually(t, func() bool { return count() == 30 }, time.Second, 10*time.Millisecond)
+
+		t.Run("given shutdown with writes pending", func(t *testing.T) {
+			require.NoError(t, buffer.Update(&dynamodb.UpdateItemInput{
+				Key:              item(0),
+				UpdateExpression: aws.String("SET Hits = :one"),
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":one": {N: aws.String("1")},
+				},
+			}))
+			require.NoError(t, buffer.Put(item(30)))
+
+			cancel()
+			assert.Equal(t, context.Canceled, <-done)
+
+			assert.Equal(t, int64(31), count())
+			result, err := testClient.GetItem(&dynamodb.GetItemInput{TableName: aws.String(table), Key: item(0)})
+			require.NoError(t, err)
+			assert.Equal(t, "1", aws.StringValue(result.Item["Hits"].N))
+		})
+	})
+
+	t.Run("given a full buffer", func(t *testing.T) {
+		buffer := NewWriteBuffer(testClient, table)
+		buffer.MaxBufferSize = SizeOf(item(0))
+
+		require.NoError(t, buffer.Put(item(0)))
+		assert.Equal(t, ErrBufferFull, buffer.Put(item(1)))
+		assert.Equal(t, 1, buffer.Pending())
+	})
+
+	t.Run("given failed writes", func(t *testing.T) {
+		buffer := NewWriteBuffer(testClient, table)
+		var mu sync.Mutex
+		failed := []BufferedWrite{}
+		buffer.OnError = func(write BufferedWrite, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Error(t, err)
+			failed = append(failed, write)
+		}
+
+		require.NoError(t, buffer.Put(map[string]*dynamodb.AttributeValue{"PK": {S: aws.String("missing-sort-key")}}))
+		require.NoError(t, buffer.Update(&dynamodb.UpdateItemInput{
+			Key:                 item(0),
+			UpdateExpression:    aws.String("SET Hits = Hits + :one"),
+			ConditionExpression: aws.String("attribute_not_exists(PK)"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":one": {N: aws.String("1")},
+			},
+		}))
+		buffer.Flush(context.Background())
+
+		require.Len(t, failed, 2)
+		assert.Equal(t, 0, buffer.Pending())
+	})
+
+	t.Run("given several writes to the same item", func(t *testing.T) {
+		buffer := NewWriteBuffer(testClient, table)
+		buffer.OnError = func(write BufferedWrite, err error) {
+			assert.NoError(t, err)
+		}
+		set := func(i int, value string) *dynamodb.UpdateItemInput {
+			return &dynamodb.UpdateItemInput{
+				Key:                       item(i),
+				UpdateExpression:          aws.String("SET Version = :v"),
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":v": {S: aws.String(value)}},
+			}
+		}
+		put := func(i int, value string) map[string]*dynamodb.AttributeValue {
+			written := item(i)
+			written["Version"] = &dynamodb.AttributeValue{S: aws.String(value)}
+			return written
+		}
+
+		require.NoError(t, buffer.Put(put(100, "first")))
+		require.NoError(t, buffer.Put(put(100, "second")))
+		require.NoError(t, buffer.Update(set(101, "updated")))
+		require.NoError(t, buffer.Put(put(101, "put")))
+		require.NoError(t, buffer.Put(put(102, "put")))
+		for i := 0; i < 20; i++ {
+			require.NoError(t, buffer.Update(set(102, fmt.Sprintf("update-%02d", i))))
+		}
+		buffer.Flush(context.Background())
+
+		version := func(i int) string {
+			result, err := testClient.GetItem(&dynamodb.GetItemInput{TableName: aws.String(table), Key: item(i)})
+			require.NoError(t, err)
+			return aws.StringValue(result.Item["Version"].S)
+		}
+		assert.Equal(t, "second", version(100))
+		assert.Equal(t, "put", version(101))
+		assert.Equal(t, "update-19", version(102))
+	})
+}
@@ -0,0 +1,409 @@
+package dyno
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+)
+
+// Cache is a read-through cache with two tiers: a process-local LRU in front of items stored in DynamoDB. Entries
+// expire from both tiers at the TTL they were stored with. Enable TTL on Dyno_ExpiresAt to have DynamoDB delete them.
+type Cache struct {
+	db dynamodbiface.DynamoDBAPI
+	tn string
+	pk string
+	sk string
+
+	local *lru
+	calls singleflight
+
+	// LocalTTL is the longest an entry is served from the local tier before DynamoDB is read again, which bounds how
+	// stale it can be after another process changes it. Defaults to 5 seconds.
+	LocalTTL time.Duration
+
+	// LoadTimeout is how long GetOrLoad waits for another process that's loading the same key, and the lease of the
+	// lock held while loading, which is renewed until the load finishes. Defaults to 10 seconds.
+	LoadTimeout time.Duration
+
+	// ConsistentRead reads entries from DynamoDB with strongly consistent reads, at twice the read capacity. By default
+	// an entry that was just stored may not be seen.
+	ConsistentRead bool
+
+	// Compressor compresses large values before they're stored in DynamoDB. Compressed entries are decompressed when
+	// they're read, so it can be enabled on a table that already has entries.
+	Compressor *Compressor
+
+	// Metrics receives a CacheHit or CacheMiss count for each tier a lookup reads, with the tier, Local or DynamoDB,
+	// as the Tier dimension
+	Metrics Metrics
+}
+
+// NewCache returns a cache whose local tier holds up to size entries
+func NewCache(db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey string, size int) *Cache {
+	return &Cache{
+		db:          db,
+		tn:          tableName,
+		pk:          primaryKey,
+		sk:          sortKey,
+		local:       newLRU(size),
+		LocalTTL:    5 * time.Second,
+		LoadTimeout: 10 * time.Second,
+	}
+}
+
+// LoadFunc loads a value that isn't cached
+type LoadFunc func(ctx context.Context) ([]byte, error)
+
+// Get returns the cached value and true, or false if the key isn't cached
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if value, ok := c.cached(key); ok {
+		return value, true, nil
+	}
+
+	return c.read(ctx, key)
+}
+
+// cached returns the value in the local tier, counting the hit or miss
+func (c *Cache) cached(key string) ([]byte, bool) {
+	value, ok := c.local.get(key, time.Now())
+	c.count("Local", ok)
+	return value, ok
+}
+
+// read returns the value stored in DynamoDB, counting the hit or miss
+func (c *Cache) read(ctx context.Context, key string) ([]byte, bool, error) {
+	value, ok, err := c.get(ctx, key, c.ConsistentRead)
+	if err == nil {
+		c.count("DynamoDB", ok)
+	}
+	return value, ok, err
+}
+
+func (c *Cache) count(tier string, hit bool) {
+	name := "CacheMiss"
+	if hit {
+		name = "CacheHit"
+	}
+	countMetric(c.Metrics, name, 1, map[string]string{"Tier": tier})
+}
+
+func (c *Cache) get(ctx context.Context, key string, consistent bool) ([]byte, bool, error) {
+	result, err := c.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(c.tn),
+		Key:            c.key(key),
+		ConsistentRead: aws.Bool(consistent),
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	item, err := c.compressor().Decompress(result.Item)
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, expiresAt, ok := cacheEntry(item)
+	if !ok || !expiresAt.After(time.Now()) {
+		return nil, false, nil
+	}
+
+	c.store(key, value, expiresAt)
+	return value, true, nil
+}
+
+// Set stores a value in both tiers for the TTL
+func (c *Cache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	expiresAt := time.Now().Add(ttl)
+
+	item := c.key(key)
+	item["Dyno_Value"] = &dynamodb.AttributeValue{B: value}
+	item["Dyno_ExpiresAt"] = TimeValue(expiresAt, TimeUnixSeconds)
+	item["Dyno_ExpiresAtMs"] = TimeValue(expiresAt, TimeUnixMillis)
+
+	if c.Compressor != nil {
+		compressed, err := c.Compressor.Compress(item)
+		if err != nil {
+			return err
+		}
+		item = compressed
+	}
+
+	_, err := c.db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(c.tn),
+		Item:      item,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.store(key, value, expiresAt)
+	return nil
+}
+
+// Delete removes a value from both tiers. Other processes keep serving it from their local tier for up to LocalTTL,
+// unless they invalidate it from the table's stream.
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	c.local.remove(key)
+
+	_, err := c.db.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(c.tn),
+		Key:       c.key(key),
+	})
+	return err
+}
+
+// Invalidate removes a value from the local tier
+func (c *Cache) Invalidate(key string) {
+	c.local.remove(key)
+}
+
+// GetOrLoad returns the cached value, or loads and caches it for the TTL. Only one caller loads a key at a time: other
+// goroutines share the result, and other processes wait on a lock for the value to be stored.
+//
+// The load isn't cancelled when the context of the caller that started it is done, since other callers may be waiting
+// on it. Each caller stops waiting when its own context is done.
+func (c *Cache) GetOrLoad(ctx context.Context, key string, ttl time.Duration, load LoadFunc) ([]byte, error) {
+	if value, ok := c.cached(key); ok {
+		return value, nil
+	}
+
+	loadCtx := detachedContext{ctx}
+	return c.calls.do(ctx, key, func() ([]byte, error) {
+		return c.load(loadCtx, key, ttl, load)
+	})
+}
+
+func (c *Cache) load(ctx context.Context, key string, ttl time.Duration, load LoadFunc) ([]byte, error) {
+	value, ok, err := c.read(ctx, key)
+	if err != nil || ok {
+		return value, err
+	}
+
+	// The lock item is deleted on release, since a key that's left behind for every cache key TTL can't remove
+	lock := NewLock(c.db, c.tn, c.pk, c.sk, "Dyno_Cache/"+key)
+	lock.deleteOnRelease = true
+
+	err = lock.Do(ctx, c.LoadTimeout, c.LoadTimeout, func(ctx context.Context) error {
+		// Another process may have loaded it while we waited for the lock
+		loaded, ok, err := c.get(ctx, key, true)
+		if err != nil || ok {
+			value = loaded
+			return err
+		}
+
+		loaded, err = load(ctx)
+		if err != nil {
+			return err
+		}
+		value = loaded
+		return c.Set(ctx, key, value, ttl)
+	})
+	if err != ErrLockAcquireTimeout {
+		return value, err
+	}
+
+	// Another process held the lock the whole time, and has most likely stored the value by now
+	value, ok, rerr := c.get(ctx, key, true)
+	if rerr != nil || ok {
+		return value, rerr
+	}
+	return nil, err
+}
+
+// StreamHandler returns a handler for a StreamPoller on the cache's table that invalidates the local tier when another
+// process changes or deletes an entry
+func (c *Cache) StreamHandler() StreamHandler {
+	return func(ctx context.Context, shardID string, records []*dynamodbstreams.Record) error {
+		for _, record := range records {
+			if record.Dynamodb == nil {
+				continue
+			}
+			if v, ok := record.Dynamodb.Keys[c.pk]; ok && strings.HasPrefix(aws.StringValue(v.S), "Dyno_Cache/") {
+				c.local.remove(strings.TrimPrefix(aws.StringValue(v.S), "Dyno_Cache/"))
+			}
+		}
+		return nil
+	}
+}
+
+func (c *Cache) key(key string) map[string]*dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{}
+	item[c.pk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_Cache/%s", key))}
+
+	if c.sk != "" {
+		item[c.sk] = &dynamodb.AttributeValue{S: aws.String("Dyno_CacheSortKeyValue")}
+	}
+
+	return item
+}
+
+// compressor returns the Compressor, or one that reads entries compressed with the default codec
+func (c *Cache) compressor() *Compressor {
+	if c.Compressor == nil {
+		return &Compressor{}
+	}
+	return c.Compressor
+}
+
+// store adds a value to the local tier until it expires, or LocalTTL passes
+func (c *Cache) store(key string, value []byte, expiresAt time.Time) {
+	if local := time.Now().Add(c.LocalTTL); local.Before(expiresAt) {
+		expiresAt = local
+	}
+	c.local.add(key, value, expiresAt)
+}
+
+func cacheEntry(item map[string]*dynamodb.AttributeValue) ([]byte, time.Time, bool) {
+	value := item["Dyno_Value"]
+	if value == nil {
+		return nil, time.Time{}, false
+	}
+
+	expiresAt, err := ParseTime(item["Dyno_ExpiresAtMs"], TimeUnixMillis)
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+
+	return value.B, expiresAt, true
+}
+
+// lru is a fixed size cache of values that expire
+type lru struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func newLRU(size int) *lru {
+	return &lru{
+		size:    size,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+func (l *lru) get(key string, now time.Time) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*lruEntry)
+	if !entry.expiresAt.After(now) {
+		l.order.Remove(e)
+		delete(l.entries, key)
+		return nil, false
+	}
+
+	l.order.MoveToFront(e)
+	return entry.value, true
+}
+
+func (l *lru) add(key string, value []byte, expiresAt time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.size <= 0 {
+		return
+	}
+
+	if e, ok := l.entries[key]; ok {
+		e.Value = &lruEntry{key: key, value: value, expiresAt: expiresAt}
+		l.order.MoveToFront(e)
+		return
+	}
+
+	l.entries[key] = l.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	for l.order.Len() > l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+func (l *lru) remove(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.entries[key]; ok {
+		l.order.Remove(e)
+		delete(l.entries, key)
+	}
+}
+
+// singleflight shares the result of a call between the goroutines making it at the same time
+type singleflight struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+type flight struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// do runs fn in the background unless a call for the key is already running, and waits for its result or for the
+// context to be done
+func (s *singleflight) do(ctx context.Context, key string, fn func() ([]byte, error)) ([]byte, error) {
+	s.mu.Lock()
+	if s.calls == nil {
+		s.calls = map[string]*flight{}
+	}
+	f, ok := s.calls[key]
+	if !ok {
+		f = &flight{done: make(chan struct{})}
+		s.calls[key] = f
+
+		go func() {
+			f.value, f.err = fn()
+
+			s.mu.Lock()
+			delete(s.calls, key)
+			s.mu.Unlock()
+			close(f.done)
+		}()
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// detachedContext keeps a context's values but is never done
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
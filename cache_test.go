@@ -0,0 +1,271 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tieredMetrics counts the cache's metrics by name and tier
+type tieredMetrics struct {
+	NopMetrics
+
+	mu     sync.Mutex
+	counts map[string]float64
+}
+
+func (m *tieredMetrics) Count(name string, value float64, dimensions map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.counts == nil {
+		m.counts = map[string]float64{}
+	}
+	m.counts[name+"/"+dimensions["Tier"]] += value
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("GetOrLoad", func(t *testing.T) {
+		t.Run("given concurrent loads from several processes", func(t *testing.T) {
+			var loads int32
+			load := func(context.Context) ([]byte, error) {
+				atomic.AddInt32(&loads, 1)
+				time.Sleep(20 * time.Millisecond)
+				return []byte("loaded"), nil
+			}
+
+			var wg sync.WaitGroup
+			for _, cache := range []*Cache{
+				NewCache(testClient, tableName, "PK", "SK", 10),
+				NewCache(testClient, tableName, "PK", "SK", 10),
+			} {
+				for i := 0; i < 5; i++ {
+					wg.Add(1)
+					go func(cache *Cache) {
+						defer wg.Done()
+						value, err := cache.GetOrLoad(ctx, "testing-stampede", time.Minute, load)
+						assert.NoError(t, err)
+						assert.Equal(t, []byte("loaded"), value)
+					}(cache)
+				}
+			}
+			wg.Wait()
+
+			assert.Equal(t, int32(1), loads)
+		})
+
+		t.Run("given a failed load", func(t *testing.T) {
+			cache := NewCache(testClient, tableName, "PK", "SK", 10)
+			errLoad := errors.New("load failed")
+
+			_, err := cache.GetOrLoad(ctx, "testing-failed-load", time.Minute, func(context.Context) ([]byte, error) {
+				return nil, errLoad
+			})
+			assert.Equal(t, errLoad, err)
+
+			_, ok, err := cache.Get(ctx, "testing-failed-load")
+			require.NoError(t, err)
+			assert.False(t, ok)
+		})
+
+		t.Run("given the caller that started the load gives up", func(t *testing.T) {
+			cache := NewCache(testClient, tableName, "PK", "SK", 10)
+			started := make(chan struct{})
+			load := func(ctx context.Context) ([]byte, error) {
+				close(started)
+				time.Sleep(50 * time.Millisecond)
+				return []byte("loaded"), ctx.Err()
+			}
+
+			first, cancel := context.WithCancel(ctx)
+			returned := make(chan error, 1)
+			go func() {
+				_, err := cache.GetOrLoad(first, "testing-abandoned-load", time.Minute, load)
+				returned <- err
+			}()
+			<-started
+			cancel()
+			assert.Equal(t, context.Canceled, <-returned)
+
+			value, err := cache.GetOrLoad(ctx, "testing-abandoned-load", time.Minute, load)
+			require.NoError(t, err)
+			assert.Equal(t, []byte("loaded"), value)
+		})
+
+		t.Run("given another process loading for longer than the timeout", func(t *testing.T) {
+			holder := NewLock(testClient, tableName, "PK", "SK", "Dyno_Cache/testing-slow-load")
+			require.NoError(t, holder.Acquire(time.Minute))
+			defer holder.Release()
+
+			other := NewCache(testClient, tableName, "PK", "SK", 10)
+			go func() {
+				time.Sleep(30 * time.Millisecond)
+				assert.NoError(t, other.Set(ctx, "testing-slow-load", []byte("loaded elsewhere"), time.Minute))
+			}()
+
+			cache := NewCache(testClient, tableName, "PK", "SK", 10)
+			cache.LoadTimeout = 100 * time.Millisecond
+			value, err := cache.GetOrLoad(ctx, "testing-slow-load", time.Minute, func(context.Context) ([]byte, error) {
+				return nil, errors.New("loaded twice")
+			})
+			require.NoError(t, err)
+			assert.Equal(t, []byte("loaded elsewhere"), value)
+		})
+		t.Run("given a load that outlasts the lock's lease", func(t *testing.T) {
+			var loads int32
+			load := func(context.Context) ([]byte, error) {
+				atomic.AddInt32(&loads, 1)
+				time.Sleep(1600 * time.Millisecond)
+				return []byte("loaded"), nil
+			}
+
+			slow := NewCache(testClient, tableName, "PK", "SK", 10)
+			slow.LoadTimeout = time.Second
+			loaded := make(chan error, 1)
+			go func() {
+				_, err := slow.GetOrLoad(ctx, "testing-renewed-load", time.Minute, load)
+				loaded <- err
+			}()
+			time.Sleep(10 * time.Millisecond)
+
+			other := NewCache(testClient, tableName, "PK", "SK", 10)
+			other.LoadTimeout = 1300 * time.Millisecond
+			value, err := other.GetOrLoad(ctx, "testing-renewed-load", time.Minute, load)
+			if err != ErrLockAcquireTimeout {
+				require.NoError(t, err)
+				assert.Equal(t, []byte("loaded"), value, "the value loaded by the lock holder")
+			}
+
+			require.NoError(t, <-loaded)
+			assert.Equal(t, int32(1), loads)
+
+			result, err := testClient.GetItem(&dynamodb.GetItemInput{
+				TableName: aws.String(tableName),
+				Key:       lockKey("PK", "SK", "Dyno_Cache/testing-renewed-load"),
+			})
+			require.NoError(t, err)
+			assert.Empty(t, result.Item, "the lock item is deleted on release")
+		})
+	})
+
+	t.Run("given metrics", func(t *testing.T) {
+		metrics := &tieredMetrics{}
+		cache := NewCache(testClient, tableName, "PK", "SK", 10)
+		cache.Metrics = metrics
+		load := func(context.Context) ([]byte, error) { return []byte("loaded"), nil }
+
+		_, err := cache.GetOrLoad(ctx, "testing-metrics-"+NewKSUID(), time.Minute, load)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]float64{"CacheMiss/Local": 1, "CacheMiss/DynamoDB": 1}, metrics.counts)
+
+		require.NoError(t, cache.Set(ctx, "testing-metrics", []byte("stored"), time.Minute))
+		_, ok, err := cache.Get(ctx, "testing-metrics")
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, 1.0, metrics.counts["CacheHit/Local"])
+
+		other := NewCache(testClient, tableName, "PK", "SK", 10)
+		other.Metrics = metrics
+		other.ConsistentRead = true
+		_, ok, err = other.Get(ctx, "testing-metrics")
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, 2.0, metrics.counts["CacheMiss/Local"])
+		assert.Equal(t, 1.0, metrics.counts["CacheHit/DynamoDB"])
+	})
+
+	t.Run("given an entry changed by another process", func(t *testing.T) {
+		cache1 := NewCache(testClient, tableName, "PK", "SK", 10)
+		cache2 := NewCache(testClient, tableName, "PK", "SK", 10)
+		cache2.LocalTTL = 20 * time.Millisecond
+
+		require.NoError(t, cache1.Set(ctx, "testing-coherence", []byte("v1"), time.Minute))
+		value, _, err := cache2.Get(ctx, "testing-coherence")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("v1"), value)
+
+		require.NoError(t, cache1.Set(ctx, "testing-coherence", []byte("v2"), time.Minute))
+		value, _, _ = cache2.Get(ctx, "testing-coherence")
+		assert.Equal(t, []byte("v1"), value, "served from the local tier")
+
+		time.Sleep(30 * time.Millisecond)
+		value, _, _ = cache2.Get(ctx, "testing-coherence")
+		assert.Equal(t, []byte("v2"), value)
+	})
+
+	t.Run("given an expired entry", func(t *testing.T) {
+		cache := NewCache(testClient, tableName, "PK", "SK", 10)
+
+		require.NoError(t, cache.Set(ctx, "testing-expired", []byte("v"), 20*time.Millisecond))
+		time.Sleep(30 * time.Millisecond)
+
+		_, ok, err := cache.Get(ctx, "testing-expired")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("given a compressed entry", func(t *testing.T) {
+		cache := NewCache(testClient, tableName, "PK", "SK", 10)
+		cache.Compressor = &Compressor{}
+		large := []byte(strings.Repeat("dyno ", 2000))
+
+		require.NoError(t, cache.Set(ctx, "testing-compressed", large, time.Minute))
+
+		result, err := testClient.GetItem(&dynamodb.GetItemInput{
+			TableName: aws.String(tableName),
+			Key:       cache.key("testing-compressed"),
+		})
+		require.NoError(t, err)
+		assert.True(t, len(result.Item["Dyno_Value"].B) < len(large))
+		assert.Contains(t, result.Item, compressionAttribute)
+
+		reader := NewCache(testClient, tableName, "PK", "SK", 10)
+		value, ok, err := reader.Get(ctx, "testing-compressed")
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, large, value)
+	})
+
+	t.Run("StreamHandler", func(t *testing.T) {
+		cache := NewCache(testClient, tableName, "PK", "SK", 10)
+		cache.local.add("testing-stream", []byte("v"), time.Now().Add(time.Minute))
+
+		err := cache.StreamHandler()(ctx, "shard", []*dynamodbstreams.Record{{
+			Dynamodb: &dynamodbstreams.StreamRecord{
+				Keys: map[string]*dynamodb.AttributeValue{"PK": {S: aws.String("Dyno_Cache/testing-stream")}},
+			},
+		}})
+		require.NoError(t, err)
+
+		_, ok := cache.local.get("testing-stream", time.Now())
+		assert.False(t, ok)
+	})
+}
+
+func TestLRU(t *testing.T) {
+	now := time.Now()
+	l := newLRU(2)
+	l.add("a", []byte("a"), now.Add(time.Minute))
+	l.add("b", []byte("b"), now.Add(time.Minute))
+	l.get("a", now)
+	l.add("c", []byte("c"), now.Add(time.Minute))
+
+	_, ok := l.get("b", now)
+	assert.False(t, ok, "the least recently used entry is evicted")
+	_, ok = l.get("a", now)
+	assert.True(t, ok)
+	_, ok = l.get("c", now.Add(2*time.Minute))
+	assert.False(t, ok, "expired entries aren't returned")
+}
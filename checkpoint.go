@@ -0,0 +1,224 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Checkpoints stores the progress of segmented scans in a DynamoDB table so that long running jobs like Copy can
+// resume where they left off.
+type Checkpoints struct {
+	db dynamodbiface.DynamoDBAPI
+	tn string
+	pk string
+	sk string
+}
+
+func NewCheckpoints(db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey string) *Checkpoints {
+	return &Checkpoints{
+		db: db,
+		tn: tableName,
+		pk: primaryKey,
+		sk: sortKey,
+	}
+}
+
+type segmentCheckpoint struct {
+	lastKey map[string]*dynamodb.AttributeValue
+	count   int64
+	done    bool
+}
+
+// Reset removes the stored progress for every segment of the named job
+func (c *Checkpoints) Reset(ctx context.Context, name string, segments int) error {
+	for segment := 0; segment < segments; segment++ {
+		_, err := c.db.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(c.tn),
+			Key:       c.key(name, segment),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Checkpoints) key(name string, segment int) map[string]*dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{}
+
+	if c.sk == "" {
+		item[c.pk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_Checkpoint/%s/%d", name, segment))}
+	} else {
+		item[c.pk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_Checkpoint/%s", name))}
+		item[c.sk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_Segment/%d", segment))}
+	}
+
+	return item
+}
+
+func (c *Checkpoints) load(ctx context.Context, name string, segment int) (*segmentCheckpoint, error) {
+	if c == nil {
+		return &segmentCheckpoint{}, nil
+	}
+
+	result, err := c.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(c.tn),
+		Key:            c.key(name, segment),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &segmentCheckpoint{}
+	if len(result.Item) == 0 {
+		return cp, nil
+	}
+
+	if v, ok := result.Item["Dyno_LastKey"]; ok {
+		cp.lastKey = v.M
+	}
+	if v, ok := result.Item["Dyno_Count"]; ok {
+		cp.count, _ = strconv.ParseInt(aws.StringValue(v.N), 10, 64)
+	}
+	if v, ok := result.Item["Dyno_Done"]; ok {
+		cp.done = aws.BoolValue(v.BOOL)
+	}
+
+	return cp, nil
+}
+
+func (c *Checkpoints) save(ctx context.Context, name string, segment int, cp *segmentCheckpoint) error {
+	if c == nil {
+		return nil
+	}
+
+	item := c.key(name, segment)
+	item["Dyno_Count"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(cp.count, 10))}
+	item["Dyno_Done"] = &dynamodb.AttributeValue{BOOL: aws.Bool(cp.done)}
+	item["Dyno_UpdatedAt"] = TimeValue(time.Now(), TimeUnixSeconds)
+	if len(cp.lastKey) > 0 {
+		item["Dyno_LastKey"] = &dynamodb.AttributeValue{M: cp.lastKey}
+	}
+
+	_, err := c.db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(c.tn),
+		Item:      item,
+	})
+
+	return err
+}
+
+// segmentScan runs a parallel scan, checkpointing after every page has been handled
+type segmentScan struct {
+	db          dynamodbiface.DynamoDBAPI
+	input       *dynamodb.ScanInput
+	segments    int
+	checkpoints *Checkpoints
+	name        string
+}
+
+type pageHandler func(ctx context.Context, items []map[string]*dynamodb.AttributeValue) error
+
+func (s *segmentScan) run(ctx context.Context, handle pageHandler) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	segments := s.segments
+	if segments <= 0 {
+		segments = 1
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, segments)
+
+	for i := 0; i < segments; i++ {
+		wg.Add(1)
+		go func(segment int) {
+			defer wg.Done()
+
+			if err := s.scan(ctx, segment, segments, handle); err != nil {
+				errs <- err
+				cancel()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	// The first error is the one that canceled the other segments
+	return <-errs
+}
+
+func (s *segmentScan) scan(ctx context.Context, segment, total int, handle pageHandler) error {
+	cp, err := s.checkpoints.load(ctx, s.name, segment)
+	if err != nil {
+		return err
+	}
+
+	for !cp.done {
+		input := *s.input
+		input.Segment = aws.Int64(int64(segment))
+		input.TotalSegments = aws.Int64(int64(total))
+		input.ExclusiveStartKey = cp.lastKey
+
+		result, err := s.db.ScanWithContext(ctx, &input)
+		if err != nil {
+			return err
+		}
+
+		if err := handle(ctx, result.Items); err != nil {
+			return err
+		}
+
+		cp.lastKey = result.LastEvaluatedKey
+		cp.count += int64(len(result.Items))
+		cp.done = len(result.LastEvaluatedKey) == 0
+
+		if err := s.checkpoints.save(ctx, s.name, segment, cp); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rateLimiter spaces out work to a fixed number of units per second across goroutines.
+// A nil rateLimiter doesn't limit anything.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(perSecond int) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Second / time.Duration(perSecond)}
+}
+
+func (r *rateLimiter) wait(ctx context.Context, n int) error {
+	if r == nil || n <= 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	now := time.Now()
+	if r.next.Before(now) {
+		r.next = now
+	}
+	at := r.next
+	r.next = r.next.Add(time.Duration(n) * r.interval)
+	r.mu.Unlock()
+
+	return sleepContext(ctx, time.Until(at))
+}
@@ -0,0 +1,183 @@
+package dyno
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+)
+
+// maxMetricData is the largest number of metrics CloudWatch accepts in a single PutMetricData call
+const maxMetricData = 20
+
+// CloudWatchMetrics is a Metrics that publishes to CloudWatch. Measurements are aggregated into statistic sets in
+// memory and sent by Flush, or periodically by Run.
+//
+// Consumed capacity is published as ConsumedReadCapacityUnits and ConsumedWriteCapacityUnits with Operation and
+// TableName dimensions. Counts and timings use their own names and dimensions, timings in milliseconds.
+type CloudWatchMetrics struct {
+	client    cloudwatchiface.CloudWatchAPI
+	namespace string
+
+	// Dimensions are added to every metric, e.g. the service and environment
+	Dimensions map[string]string
+
+	// OnError is called when Run fails to flush. The measurements are kept and sent with the next flush.
+	OnError func(err error)
+
+	mu      sync.Mutex
+	pending map[string]*cloudwatch.MetricDatum
+}
+
+func NewCloudWatchMetrics(client cloudwatchiface.CloudWatchAPI, namespace string) *CloudWatchMetrics {
+	return &CloudWatchMetrics{
+		client:    client,
+		namespace: namespace,
+		pending:   map[string]*cloudwatch.MetricDatum{},
+	}
+}
+
+func (c *CloudWatchMetrics) ConsumedCapacity(operation string, capacity Capacity) {
+	dimensions := map[string]string{"Operation": operation, "TableName": capacity.Table}
+
+	if capacity.ReadUnits > 0 {
+		c.add("ConsumedReadCapacityUnits", capacity.ReadUnits, cloudwatch.StandardUnitCount, dimensions)
+	}
+	if capacity.WriteUnits > 0 {
+		c.add("ConsumedWriteCapacityUnits", capacity.WriteUnits, cloudwatch.StandardUnitCount, dimensions)
+	}
+}
+
+func (c *CloudWatchMetrics) Count(name string, value float64, dimensions map[string]string) {
+	c.add(name, value, cloudwatch.StandardUnitCount, dimensions)
+}
+
+func (c *CloudWatchMetrics) Timing(name string, d time.Duration, dimensions map[string]string) {
+	c.add(name, float64(d)/float64(time.Millisecond), cloudwatch.StandardUnitMilliseconds, dimensions)
+}
+
+func (c *CloudWatchMetrics) add(name string, value float64, unit string, dimensions map[string]string) {
+	merged := make(map[string]string, len(c.Dimensions)+len(dimensions))
+	for k, v := range c.Dimensions {
+		merged[k] = v
+	}
+	for k, v := range dimensions {
+		if v != "" {
+			merged[k] = v
+		}
+	}
+
+	names := make([]string, 0, len(merged))
+	for k := range merged {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	key := []string{name, unit}
+	dims := make([]*cloudwatch.Dimension, len(names))
+	for index, k := range names {
+		key = append(key, k, merged[k])
+		dims[index] = &cloudwatch.Dimension{Name: aws.String(k), Value: aws.String(merged[k])}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.merge(strings.Join(key, "\x00"), &cloudwatch.MetricDatum{
+		MetricName: aws.String(name),
+		Unit:       aws.String(unit),
+		Dimensions: dims,
+		StatisticValues: &cloudwatch.StatisticSet{
+			Maximum:     aws.Float64(value),
+			Minimum:     aws.Float64(value),
+			SampleCount: aws.Float64(1),
+			Sum:         aws.Float64(value),
+		},
+	})
+}
+
+// merge adds the datum's statistics to the pending datum with the same id. The caller must hold mu.
+func (c *CloudWatchMetrics) merge(id string, datum *cloudwatch.MetricDatum) {
+	current, ok := c.pending[id]
+	if !ok {
+		c.pending[id] = datum
+		return
+	}
+
+	stats, other := current.StatisticValues, datum.StatisticValues
+	if *other.Maximum > *stats.Maximum {
+		stats.Maximum = other.Maximum
+	}
+	if *other.Minimum < *stats.Minimum {
+		stats.Minimum = other.Minimum
+	}
+	stats.SampleCount = aws.Float64(*stats.SampleCount + *other.SampleCount)
+	stats.Sum = aws.Float64(*stats.Sum + *other.Sum)
+}
+
+// Flush publishes the measurements aggregated since the last flush. Measurements that couldn't be published are kept
+// for the next flush.
+func (c *CloudWatchMetrics) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = map[string]*cloudwatch.MetricDatum{}
+	c.mu.Unlock()
+
+	now := time.Now()
+	ids := make([]string, 0, len(pending))
+	for id, datum := range pending {
+		datum.Timestamp = aws.Time(now)
+		ids = append(ids, id)
+	}
+
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > maxMetricData {
+			n = maxMetricData
+		}
+
+		data := make([]*cloudwatch.MetricDatum, n)
+		for i, id := range ids[:n] {
+			data[i] = pending[id]
+		}
+
+		_, err := c.client.PutMetricDataWithContext(ctx, &cloudwatch.PutMetricDataInput{
+			Namespace:  aws.String(c.namespace),
+			MetricData: data,
+		})
+		if err != nil {
+			c.mu.Lock()
+			for _, id := range ids {
+				c.merge(id, pending[id])
+			}
+			c.mu.Unlock()
+			return err
+		}
+		ids = ids[n:]
+	}
+
+	return nil
+}
+
+// Run flushes on the interval until the context is done, then flushes one last time and returns its error. Errors
+// from earlier flushes are reported to OnError, and their measurements are retried on the next interval.
+func (c *CloudWatchMetrics) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return c.Flush(context.Background())
+		case <-ticker.C:
+			if err := c.Flush(ctx); err != nil && c.OnError != nil {
+				c.OnError(err)
+			}
+		}
+	}
+}
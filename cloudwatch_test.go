@@ -0,0 +1,161 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryCloudWatch struct {
+	cloudwatchiface.CloudWatchAPI
+
+	mu     sync.Mutex
+	err    error
+	inputs []*cloudwatch.PutMetricDataInput
+}
+
+func (m *memoryCloudWatch) PutMetricDataWithContext(ctx aws.Context, input *cloudwatch.PutMetricDataInput, opts ...request.Option) (*cloudwatch.PutMetricDataOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.inputs = append(m.inputs, input)
+	return &cloudwatch.PutMetricDataOutput{}, nil
+}
+
+func (m *memoryCloudWatch) datum(name string) *cloudwatch.MetricDatum {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, input := range m.inputs {
+		for _, datum := range input.MetricData {
+			if *datum.MetricName == name {
+				return datum
+			}
+		}
+	}
+	return nil
+}
+
+func TestCloudWatchMetrics(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("given measurements", func(t *testing.T) {
+		client := &memoryCloudWatch{}
+		metrics := NewCloudWatchMetrics(client, "Dyno/Test")
+		metrics.Dimensions = map[string]string{"Service": "test"}
+
+		metrics.Count("LockContention", 1, map[string]string{"Lock": "a"})
+		metrics.Count("LockContention", 3, map[string]string{"Lock": "a"})
+		metrics.Timing("LockWait", 250*time.Millisecond, map[string]string{"Lock": "a"})
+		metrics.ConsumedCapacity("Lock", Capacity{Table: "t", ReadUnits: 0.5, WriteUnits: 1})
+
+		require.NoError(t, metrics.Flush(ctx))
+
+		require.Len(t, client.inputs, 1)
+		assert.Equal(t, "Dyno/Test", *client.inputs[0].Namespace)
+		assert.Len(t, client.inputs[0].MetricData, 4)
+
+		contention := client.datum("LockContention")
+		require.NotNil(t, contention)
+		assert.Equal(t, 4.0, *contention.StatisticValues.Sum)
+		assert.Equal(t, 2.0, *contention.StatisticValues.SampleCount)
+		assert.Equal(t, 3.0, *contention.StatisticValues.Maximum)
+		assert.Equal(t, []*cloudwatch.Dimension{
+			{Name: aws.String("Lock"), Value: aws.String("a")},
+			{Name: aws.String("Service"), Value: aws.String("test")},
+		}, contention.Dimensions)
+
+		assert.Equal(t, 250.0, *client.datum("LockWait").StatisticValues.Sum)
+		assert.Equal(t, cloudwatch.StandardUnitMilliseconds, *client.datum("LockWait").Unit)
+		assert.Equal(t, 1.0, *client.datum("ConsumedWriteCapacityUnits").StatisticValues.Sum)
+	})
+
+	t.Run("given more metrics than fit in a request", func(t *testing.T) {
+		client := &memoryCloudWatch{}
+		metrics := NewCloudWatchMetrics(client, "Dyno/Test")
+
+		for i := 0; i < 25; i++ {
+			metrics.Count(fmt.Sprintf("Metric%d", i), 1, nil)
+		}
+		require.NoError(t, metrics.Flush(ctx))
+		require.NoError(t, metrics.Flush(ctx))
+
+		require.Len(t, client.inputs, 2)
+		assert.Len(t, client.inputs[0].MetricData, 20)
+		assert.Len(t, client.inputs[1].MetricData, 5)
+	})
+
+	t.Run("given a failed flush", func(t *testing.T) {
+		client := &memoryCloudWatch{err: errors.New("throttled")}
+		metrics := NewCloudWatchMetrics(client, "Dyno/Test")
+
+		metrics.Count("LockContention", 1, nil)
+		assert.Error(t, metrics.Flush(ctx))
+
+		metrics.Count("LockContention", 2, nil)
+		client.err = nil
+		require.NoError(t, metrics.Flush(ctx))
+
+		contention := client.datum("LockContention")
+		require.NotNil(t, contention)
+		assert.Equal(t, 3.0, *contention.StatisticValues.Sum)
+		assert.Equal(t, 2.0, *contention.StatisticValues.SampleCount)
+		assert.Equal(t, 1.0, *contention.StatisticValues.Minimum)
+	})
+
+	t.Run("given Run fails to flush", func(t *testing.T) {
+		client := &memoryCloudWatch{err: errors.New("throttled")}
+		metrics := NewCloudWatchMetrics(client, "Dyno/Test")
+		failures := make(chan error, 10)
+		metrics.OnError = func(err error) {
+			select {
+			case failures <- err:
+			default:
+			}
+		}
+		metrics.Count("LockContention", 1, nil)
+
+		ctx, cancel := context.WithCancel(ctx)
+		returned := make(chan error, 1)
+		go func() { returned <- metrics.Run(ctx, 10*time.Millisecond) }()
+
+		assert.EqualError(t, <-failures, "throttled")
+		client.mu.Lock()
+		client.err = nil
+		client.mu.Unlock()
+		cancel()
+
+		require.NoError(t, <-returned)
+		require.NotNil(t, client.datum("LockContention"))
+	})
+
+	t.Run("given lock contention", func(t *testing.T) {
+		client := &memoryCloudWatch{}
+		metrics := NewCloudWatchMetrics(client, "Dyno/Test")
+		lock := NewLock(testClient, tableName, "PK", "SK", "test-cloudwatch-lock")
+		lock.Metrics(metrics)
+		other := NewLock(testClient, tableName, "PK", "SK", "test-cloudwatch-lock")
+
+		require.NoError(t, other.Acquire(time.Minute))
+		assert.Equal(t, ErrLockAcquireTimeout, lock.AcquireWithTimeout(time.Minute, 60*time.Millisecond))
+		require.NoError(t, other.Release())
+		require.NoError(t, lock.Acquire(time.Minute))
+		require.NoError(t, lock.Release())
+		require.NoError(t, metrics.Flush(ctx))
+
+		require.NotNil(t, client.datum("LockContention"))
+		assert.True(t, *client.datum("LockContention").StatisticValues.Sum >= 1)
+		assert.Equal(t, 1.0, *client.datum("LockWait").StatisticValues.SampleCount)
+	})
+}
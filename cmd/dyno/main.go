@@ -0,0 +1,284 @@
+// Command dyno inspects and manages the locks dyno stores in a DynamoDB table.
+//
+//	dyno -table <name> locks               list held locks
+//	dyno -table <name> show <lock>         show a lock's holder and lease
+//	dyno -table <name> waiters <lock>      list the contenders waiting for a lock in fairness mode
+//	dyno -table <name> release [-id] <lock> force-release a lock
+//	dyno -table <name> tail                print lock acquires and releases from the table's stream
+//	dyno -table <name> janitor [-delete]   report or delete stale locks
+//
+// The AWS region and credentials are read from the environment. Set -endpoint to use DynamoDB Local.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsiface"
+	"github.com/maddiesch/dyno"
+)
+
+var errUsage = errors.New("usage: dyno [-table name] [-pk PK] [-sk SK] [-endpoint url] [-region region] locks|show|waiters|release|tail|janitor")
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	if err := run(ctx, os.Args[1:], os.Stdout, connect); err != nil && err != context.Canceled {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// config is the table and key schema the locks are stored in
+type config struct {
+	db      dynamodbiface.DynamoDBAPI
+	streams dynamodbstreamsiface.DynamoDBStreamsAPI
+	table   string
+	pk      string
+	sk      string
+}
+
+type connectFunc func(endpoint, region string) (dynamodbiface.DynamoDBAPI, dynamodbstreamsiface.DynamoDBStreamsAPI, error)
+
+func connect(endpoint, region string) (dynamodbiface.DynamoDBAPI, dynamodbstreamsiface.DynamoDBStreamsAPI, error) {
+	sess, err := dyno.Config{Endpoint: endpoint, Region: region}.Session()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return dynamodb.New(sess), dynamodbstreams.New(sess), nil
+}
+
+func run(ctx context.Context, args []string, out io.Writer, connect connectFunc) error {
+	flags := flag.NewFlagSet("dyno", flag.ContinueOnError)
+	flags.SetOutput(out)
+	env := dyno.ConfigFromEnv()
+	table := flags.String("table", env.TableName, "the table the locks are stored in, defaults to $DYNO_TABLE")
+	pk := flags.String("pk", env.PrimaryKey, "the table's partition key, defaults to $DYNO_PRIMARY_KEY or PK")
+	sk := flags.String("sk", env.SortKey, "the table's sort key, empty if it doesn't have one, defaults to $DYNO_SORT_KEY or SK")
+	endpoint := flags.String("endpoint", env.Endpoint, "the DynamoDB endpoint, defaults to $DYNO_ENDPOINT")
+	region := flags.String("region", env.Region, "the AWS region, defaults to $DYNO_REGION")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *table == "" || flags.NArg() == 0 {
+		return errUsage
+	}
+
+	db, streams, err := connect(*endpoint, *region)
+	if err != nil {
+		return err
+	}
+	c := &config{db: db, streams: streams, table: *table, pk: *pk, sk: *sk}
+
+	command, args := flags.Arg(0), flags.Args()[1:]
+	switch command {
+	case "locks":
+		return c.locks(ctx, out)
+	case "show":
+		return c.show(ctx, args, out)
+	case "waiters":
+		return c.waiters(ctx, args, out)
+	case "release":
+		return c.release(ctx, args, out)
+	case "tail":
+		return c.tail(ctx, out)
+	case "janitor":
+		return c.janitor(ctx, args, out)
+	default:
+		return fmt.Errorf("unknown command %q\n%w", command, errUsage)
+	}
+}
+
+func (c *config) locks(ctx context.Context, out io.Writer) error {
+	locks, err := dyno.ListLocks(ctx, c.db, c.table, c.pk)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tLOCK ID\tLEASE\tACQUIRED\tEXPIRES")
+	for _, lock := range locks {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", lock.Name, lock.LockID, lock.Lease, formatTime(lock.AcquiredAt), formatTime(lock.ExpiredAt()))
+	}
+	return w.Flush()
+}
+
+func (c *config) show(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("usage: dyno show <lock>")
+	}
+
+	lock, err := dyno.DescribeLock(ctx, c.db, c.table, c.pk, c.sk, args[0])
+	if err != nil {
+		return err
+	}
+	if lock == nil {
+		fmt.Fprintf(out, "%s is not held\n", args[0])
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", lock.Name)
+	fmt.Fprintf(w, "Lock ID:\t%s\n", lock.LockID)
+	fmt.Fprintf(w, "Lease:\t%s\n", lock.Lease)
+	fmt.Fprintf(w, "Acquired:\t%s\n", formatTime(lock.AcquiredAt))
+	fmt.Fprintf(w, "Expires:\t%s\n", formatTime(lock.ExpiredAt()))
+	if lock.Region != "" {
+		fmt.Fprintf(w, "Region:\t%s\n", lock.Region)
+		fmt.Fprintf(w, "Epoch:\t%d\n", lock.Epoch)
+	}
+	if expires := lock.ExpiredAt(); !expires.IsZero() && expires.Before(time.Now()) {
+		fmt.Fprintf(w, "Expired:\t%s ago\n", time.Since(expires).Round(time.Second))
+	}
+	return w.Flush()
+}
+
+func (c *config) waiters(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("usage: dyno waiters <lock>")
+	}
+
+	waiters, err := dyno.ListLockWaiters(ctx, c.db, c.table, c.pk, c.sk, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%d waiting for %s\n", len(waiters), args[0])
+	if len(waiters) == 0 {
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "LOCK ID\tPRIORITY\tWAITING SINCE\tWAITED")
+	for _, waiter := range waiters {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", waiter.ID, waiter.Priority, formatTime(waiter.Since), time.Since(waiter.Since).Round(time.Second))
+	}
+	return w.Flush()
+}
+
+func (c *config) release(ctx context.Context, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("release", flag.ContinueOnError)
+	flags.SetOutput(out)
+	id := flags.String("id", "", "only release the lock if it's held by this lock ID")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: dyno release [-id lock-id] <lock>")
+	}
+
+	name := flags.Arg(0)
+	if err := dyno.ForceRelease(ctx, c.db, c.table, c.pk, c.sk, name, *id); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	fmt.Fprintf(out, "released %s\n", name)
+	return nil
+}
+
+// tail follows the table's stream, which must include old and new images, and prints every lock acquire and release
+func (c *config) tail(ctx context.Context, out io.Writer) error {
+	arn, err := dyno.LatestStreamArn(ctx, c.db, c.table)
+	if err != nil {
+		return err
+	}
+
+	poller := dyno.NewStreamPoller(c.streams, arn)
+	return poller.Run(ctx, func(ctx context.Context, _ string, records []*dynamodbstreams.Record) error {
+		for _, record := range records {
+			if line, ok := lockEvent(record, c.pk); ok {
+				fmt.Fprintln(out, line)
+			}
+		}
+		return nil
+	})
+}
+
+// lockEvent describes a stream record that changed a lock's holder
+func lockEvent(record *dynamodbstreams.Record, primaryKey string) (string, bool) {
+	if record.Dynamodb == nil {
+		return "", false
+	}
+	key := record.Dynamodb.Keys[primaryKey]
+	if key == nil || !strings.HasPrefix(aws.StringValue(key.S), "Dyno_Lock/") {
+		return "", false
+	}
+
+	name := strings.TrimPrefix(aws.StringValue(key.S), "Dyno_Lock/")
+	at := aws.TimeValue(record.Dynamodb.ApproximateCreationDateTime).Format(time.RFC3339)
+	before := lockID(record.Dynamodb.OldImage)
+	after := lockID(record.Dynamodb.NewImage)
+
+	switch {
+	case before == after:
+		return "", false
+	case before == "":
+		return fmt.Sprintf("%s acquired %s by %s lease=%ss", at, name, after, aws.StringValue(record.Dynamodb.NewImage["Dyno_Lease"].N)), true
+	case after == "":
+		return fmt.Sprintf("%s released %s by %s", at, name, before), true
+	default:
+		return fmt.Sprintf("%s expired %s from %s to %s", at, name, before, after), true
+	}
+}
+
+func lockID(image map[string]*dynamodb.AttributeValue) string {
+	if id := image["Dyno_LockID"]; id != nil {
+		return aws.StringValue(id.S)
+	}
+	return ""
+}
+
+func (c *config) janitor(ctx context.Context, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("janitor", flag.ContinueOnError)
+	flags.SetOutput(out)
+	del := flags.Bool("delete", false, "delete stale locks instead of only reporting them")
+	grace := flags.Duration("grace", time.Minute, "how long after its lease expires a lock is stale")
+	interval := flags.Duration("interval", time.Minute, "how often to sweep")
+	once := flags.Bool("once", false, "sweep once and exit")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	janitor := dyno.NewJanitor(c.db, c.table, c.pk, c.sk)
+	janitor.Delete = *del
+	janitor.GracePeriod = *grace
+	janitor.Interval = *interval
+	janitor.Report = func(lock dyno.LockInfo) {
+		action := "stale"
+		if *del {
+			action = "deleting"
+		}
+		fmt.Fprintf(out, "%s %s held by %s expired %s\n", action, lock.Name, lock.LockID, formatTime(lock.ExpiredAt()))
+	}
+
+	if *once {
+		_, err := janitor.Sweep(ctx)
+		return err
+	}
+	return janitor.Run(ctx)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsiface"
+	"github.com/maddiesch/dyno"
+	"github.com/maddiesch/dyno/dynotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	db := dynotest.New()
+	_, err := db.CreateTable(&dynamodb.CreateTableInput{
+		TableName: aws.String("Locks"),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("PK"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("SK"), AttributeType: aws.String("S")},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("PK"), KeyType: aws.String("HASH")},
+			{AttributeName: aws.String("SK"), KeyType: aws.String("RANGE")},
+		},
+	})
+	require.NoError(t, err)
+
+	lock := dyno.NewLock(db, "Locks", "PK", "SK", "jobs")
+	require.NoError(t, lock.Acquire(time.Minute))
+
+	connect := func(string, string) (dynamodbiface.DynamoDBAPI, dynamodbstreamsiface.DynamoDBStreamsAPI, error) {
+		return db, nil, nil
+	}
+	dynoCLI := func(args ...string) (string, error) {
+		out := &bytes.Buffer{}
+		err := run(ctx, append([]string{"-table", "Locks"}, args...), out, connect)
+		return out.String(), err
+	}
+
+	t.Run("given no command", func(t *testing.T) {
+		_, err := dynoCLI()
+
+		assert.Equal(t, errUsage, err)
+	})
+
+	t.Run("locks", func(t *testing.T) {
+		out, err := dynoCLI("locks")
+
+		require.NoError(t, err)
+		assert.Contains(t, out, "jobs")
+		assert.Contains(t, out, "1m0s")
+	})
+
+	t.Run("show", func(t *testing.T) {
+		out, err := dynoCLI("show", "jobs")
+		require.NoError(t, err)
+		assert.Contains(t, out, "Lease:     1m0s")
+
+		out, err = dynoCLI("show", "missing")
+		require.NoError(t, err)
+		assert.Equal(t, "missing is not held\n", out)
+	})
+
+	t.Run("waiters", func(t *testing.T) {
+		out, err := dynoCLI("waiters", "jobs")
+		require.NoError(t, err)
+		assert.Equal(t, "0 waiting for jobs\n", out)
+
+		waiter := dyno.NewLockWithOptions(db, "Locks", "jobs", dyno.WithFairness(3), dyno.WithBackoff(10*time.Millisecond, 10*time.Millisecond))
+
+		waiting := make(chan error)
+		go func() { waiting <- waiter.AcquireWithTimeout(time.Minute, 200*time.Millisecond) }()
+		time.Sleep(50 * time.Millisecond)
+
+		out, err = dynoCLI("waiters", "jobs")
+		require.NoError(t, err)
+		assert.Contains(t, out, "1 waiting for jobs\n")
+		assert.Contains(t, out, "PRIORITY")
+		assert.Equal(t, dyno.ErrLockAcquireTimeout, <-waiting)
+	})
+
+	t.Run("janitor", func(t *testing.T) {
+		out, err := dynoCLI("janitor", "-once")
+
+		require.NoError(t, err)
+		assert.Empty(t, out)
+	})
+
+	t.Run("release", func(t *testing.T) {
+		_, err := dynoCLI("release", "-id", "someone-else", "jobs")
+		assert.True(t, errors.Is(err, dyno.ErrLockNotHeld))
+
+		out, err := dynoCLI("release", "jobs")
+		require.NoError(t, err)
+		assert.Equal(t, "released jobs\n", out)
+	})
+}
+
+func TestLockEvent(t *testing.T) {
+	record := func(old, new string) *dynamodbstreams.Record {
+		image := func(id string) map[string]*dynamodb.AttributeValue {
+			if id == "" {
+				return map[string]*dynamodb.AttributeValue{}
+			}
+			return map[string]*dynamodb.AttributeValue{
+				"Dyno_LockID": {S: aws.String(id)},
+				"Dyno_Lease":  {N: aws.String("30")},
+			}
+		}
+		return &dynamodbstreams.Record{Dynamodb: &dynamodbstreams.StreamRecord{
+			ApproximateCreationDateTime: aws.Time(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)),
+			Keys:                        map[string]*dynamodb.AttributeValue{"PK": {S: aws.String("Dyno_Lock/jobs")}},
+			OldImage:                    image(old),
+			NewImage:                    image(new),
+		}}
+	}
+
+	tests := map[string]struct {
+		record *dynamodbstreams.Record
+		line   string
+	}{
+		"acquired": {record("", "a"), "2020-01-02T03:04:05Z acquired jobs by a lease=30s"},
+		"released": {record("a", ""), "2020-01-02T03:04:05Z released jobs by a"},
+		"expired":  {record("a", "b"), "2020-01-02T03:04:05Z expired jobs from a to b"},
+		"other":    {&dynamodbstreams.Record{Dynamodb: &dynamodbstreams.StreamRecord{Keys: map[string]*dynamodb.AttributeValue{"PK": {S: aws.String("user")}}}}, ""},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			line, ok := lockEvent(test.record, "PK")
+
+			assert.Equal(t, test.line != "", ok)
+			assert.Equal(t, test.line, line)
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package dyno
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// compressionAttribute records which attributes of an item were compressed, their codec, and their original type
+const compressionAttribute = "Dyno_Encoding"
+
+// Codec compresses attribute values
+type Codec interface {
+	Name() string
+	Encode([]byte) ([]byte, error)
+	Decode([]byte) ([]byte, error)
+}
+
+// GzipCodec compresses with gzip at the default compression level
+var GzipCodec Codec = gzipCodec{}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string {
+	return "gzip"
+}
+
+func (gzipCodec) Encode(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decode(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+// Compressor compresses large string and binary attributes so items stay under the 400KB item size limit.
+//
+// Compressed attributes are stored as binary and listed in a Dyno_Encoding attribute so Decompress can restore them.
+// Set it as a Cache's Compressor to compress cached values transparently.
+type Compressor struct {
+	// Codec defaults to GzipCodec. Other algorithms like zstd can be used by implementing Codec, and every codec
+	// used to write items must be registered in Codecs to read them back.
+	Codec Codec
+
+	// Codecs are additional codecs Decompress can read
+	Codecs []Codec
+
+	// Threshold is the size in bytes above which an attribute is compressed. Defaults to 4KB, which is larger than
+	// any key attribute can be, so keys are never compressed.
+	Threshold int
+
+	// Attributes limits compression to the named attributes. When empty, any string or binary attribute is eligible.
+	Attributes []string
+}
+
+func (c *Compressor) codec() Codec {
+	if c.Codec == nil {
+		return GzipCodec
+	}
+	return c.Codec
+}
+
+func (c *Compressor) threshold() int {
+	if c.Threshold <= 0 {
+		return 4 * 1024
+	}
+	return c.Threshold
+}
+
+func (c *Compressor) eligible(name string) bool {
+	if len(c.Attributes) == 0 {
+		return true
+	}
+	for _, attr := range c.Attributes {
+		if attr == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Compress returns a copy of the item with every eligible attribute above the threshold compressed
+func (c *Compressor) Compress(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	codec := c.codec()
+	out := make(map[string]*dynamodb.AttributeValue, len(item)+1)
+	encodings := map[string]*dynamodb.AttributeValue{}
+
+	for name, av := range item {
+		out[name] = av
+
+		if !c.eligible(name) {
+			continue
+		}
+
+		var data []byte
+		var kind string
+		switch {
+		case av.S != nil:
+			data, kind = []byte(*av.S), "S"
+		case av.B != nil:
+			data, kind = av.B, "B"
+		default:
+			continue
+		}
+		if len(data) <= c.threshold() {
+			continue
+		}
+
+		compressed, err := codec.Encode(data)
+		if err != nil {
+			return nil, err
+		}
+
+		out[name] = &dynamodb.AttributeValue{B: compressed}
+		encodings[name] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("%s:%s", codec.Name(), kind))}
+	}
+
+	if len(encodings) > 0 {
+		out[compressionAttribute] = &dynamodb.AttributeValue{M: encodings}
+	}
+
+	return out, nil
+}
+
+// Decompress returns a copy of the item with every compressed attribute restored
+func (c *Compressor) Decompress(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	encodings, ok := item[compressionAttribute]
+	if !ok {
+		return item, nil
+	}
+
+	out := make(map[string]*dynamodb.AttributeValue, len(item))
+	for name, av := range item {
+		if name != compressionAttribute {
+			out[name] = av
+		}
+	}
+
+	for name, encoding := range encodings.M {
+		av, ok := out[name]
+		if !ok {
+			continue
+		}
+
+		parts := strings.SplitN(aws.StringValue(encoding.S), ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid encoding for attribute %s", name)
+		}
+
+		codec, err := c.lookup(parts[0])
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := codec.Decode(av.B)
+		if err != nil {
+			return nil, err
+		}
+
+		if parts[1] == "S" {
+			out[name] = &dynamodb.AttributeValue{S: aws.String(string(data))}
+		} else {
+			out[name] = &dynamodb.AttributeValue{B: data}
+		}
+	}
+
+	return out, nil
+}
+
+func (c *Compressor) lookup(name string) (Codec, error) {
+	for _, codec := range append([]Codec{c.codec(), GzipCodec}, c.Codecs...) {
+		if codec.Name() == name {
+			return codec, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown codec %s", name)
+}
@@ -0,0 +1,57 @@
+package dyno
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressor(t *testing.T) {
+	large := strings.Repeat("dyno ", 2000)
+	item := map[string]*dynamodb.AttributeValue{
+		"PK":    {S: aws.String("item")},
+		"Body":  {S: aws.String(large)},
+		"Blob":  {B: []byte(large)},
+		"Small": {S: aws.String("small")},
+	}
+
+	c := &Compressor{}
+
+	compressed, err := c.Compress(item)
+	require.NoError(t, err)
+
+	t.Run("Compress", func(t *testing.T) {
+		assert.Nil(t, compressed["Body"].S)
+		assert.True(t, len(compressed["Body"].B) < len(large))
+		assert.Equal(t, "gzip:S", aws.StringValue(compressed[compressionAttribute].M["Body"].S))
+		assert.Equal(t, "gzip:B", aws.StringValue(compressed[compressionAttribute].M["Blob"].S))
+		assert.Equal(t, "small", aws.StringValue(compressed["Small"].S))
+		assert.Equal(t, "item", aws.StringValue(compressed["PK"].S))
+		assert.Equal(t, large, aws.StringValue(item["Body"].S), "the original item is unchanged")
+	})
+
+	t.Run("Decompress", func(t *testing.T) {
+		decompressed, err := c.Decompress(compressed)
+		require.NoError(t, err)
+		assert.Equal(t, item, decompressed)
+	})
+
+	t.Run("given limited attributes", func(t *testing.T) {
+		c := &Compressor{Attributes: []string{"Blob"}}
+
+		compressed, err := c.Compress(item)
+		require.NoError(t, err)
+		assert.Equal(t, large, aws.StringValue(compressed["Body"].S))
+		assert.Contains(t, compressed[compressionAttribute].M, "Blob")
+	})
+
+	t.Run("given an uncompressed item", func(t *testing.T) {
+		decompressed, err := c.Decompress(item)
+		require.NoError(t, err)
+		assert.Equal(t, item, decompressed)
+	})
+}
@@ -0,0 +1,128 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+var (
+	ErrItemExists        = errors.New("item already exists")
+	ErrAttributeMismatch = errors.New("attribute does not have the expected value")
+	ErrVersionMismatch   = errors.New("item is not at the expected version")
+)
+
+// PutIfNotExists writes the item unless there's already an item with its key. ErrItemExists is returned if there is.
+func PutIfNotExists(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName, primaryKey string, item map[string]*dynamodb.AttributeValue) error {
+	return conditionalPut(ctx, db, &dynamodb.PutItemInput{
+		TableName:                aws.String(tableName),
+		Item:                     item,
+		ConditionExpression:      aws.String("attribute_not_exists(#pk)"),
+		ExpressionAttributeNames: map[string]*string{"#pk": aws.String(primaryKey)},
+	}, ErrItemExists)
+}
+
+// PutIfExists replaces the item with the same key. ErrItemNotFound is returned if there isn't one.
+func PutIfExists(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName, primaryKey string, item map[string]*dynamodb.AttributeValue) error {
+	return conditionalPut(ctx, db, &dynamodb.PutItemInput{
+		TableName:                aws.String(tableName),
+		Item:                     item,
+		ConditionExpression:      aws.String("attribute_exists(#pk)"),
+		ExpressionAttributeNames: map[string]*string{"#pk": aws.String(primaryKey)},
+	}, ErrItemNotFound)
+}
+
+// PutIfAttributeEquals replaces the item with the same key if its named attribute has the value. ErrAttributeMismatch
+// is returned if it doesn't, or there isn't an item.
+func PutIfAttributeEquals(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName string, item map[string]*dynamodb.AttributeValue, name string, value *dynamodb.AttributeValue) error {
+	return conditionalPut(ctx, db, &dynamodb.PutItemInput{
+		TableName:                 aws.String(tableName),
+		Item:                      item,
+		ConditionExpression:       aws.String("#a = :v"),
+		ExpressionAttributeNames:  map[string]*string{"#a": aws.String(name)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":v": value},
+	}, ErrAttributeMismatch)
+}
+
+// PutIfVersion writes the item if the existing item's numeric version attribute is version, setting it to version+1
+// on the written item. A version of 0 is for an item that doesn't exist yet, or doesn't have a version.
+// ErrVersionMismatch is returned if the item is at a different version.
+func PutIfVersion(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName string, item map[string]*dynamodb.AttributeValue, versionName string, version int64) error {
+	written := make(map[string]*dynamodb.AttributeValue, len(item)+1)
+	for k, v := range item {
+		written[k] = v
+	}
+	written[versionName] = Int(version + 1)
+
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String(tableName),
+		Item:      written,
+	}
+	input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues = versionCondition(versionName, version)
+
+	return conditionalPut(ctx, db, input, ErrVersionMismatch)
+}
+
+// DeleteIfExists deletes the item with the key. ErrItemNotFound is returned if there isn't one.
+func DeleteIfExists(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName, primaryKey string, key map[string]*dynamodb.AttributeValue) error {
+	return conditionalDelete(ctx, db, &dynamodb.DeleteItemInput{
+		TableName:                aws.String(tableName),
+		Key:                      key,
+		ConditionExpression:      aws.String("attribute_exists(#pk)"),
+		ExpressionAttributeNames: map[string]*string{"#pk": aws.String(primaryKey)},
+	}, ErrItemNotFound)
+}
+
+// DeleteIfAttributeEquals deletes the item with the key if its named attribute has the value. ErrAttributeMismatch is
+// returned if it doesn't, or there isn't an item.
+func DeleteIfAttributeEquals(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName string, key map[string]*dynamodb.AttributeValue, name string, value *dynamodb.AttributeValue) error {
+	return conditionalDelete(ctx, db, &dynamodb.DeleteItemInput{
+		TableName:                 aws.String(tableName),
+		Key:                       key,
+		ConditionExpression:       aws.String("#a = :v"),
+		ExpressionAttributeNames:  map[string]*string{"#a": aws.String(name)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":v": value},
+	}, ErrAttributeMismatch)
+}
+
+// DeleteIfVersion deletes the item with the key if its numeric version attribute is version. ErrVersionMismatch is
+// returned if it isn't.
+func DeleteIfVersion(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName string, key map[string]*dynamodb.AttributeValue, versionName string, version int64) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(tableName),
+		Key:       key,
+	}
+	input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues = versionCondition(versionName, version)
+
+	return conditionalDelete(ctx, db, input, ErrVersionMismatch)
+}
+
+// versionCondition is the condition that an item's version attribute is version, where 0 is an item without one
+func versionCondition(name string, version int64) (*string, map[string]*string, map[string]*dynamodb.AttributeValue) {
+	names := map[string]*string{"#ver": aws.String(name)}
+	if version == 0 {
+		return aws.String("attribute_not_exists(#ver)"), names, nil
+	}
+	return aws.String("#ver = :ver"), names, map[string]*dynamodb.AttributeValue{
+		":ver": Int(version),
+	}
+}
+
+func conditionalPut(ctx context.Context, db dynamodbiface.DynamoDBAPI, input *dynamodb.PutItemInput, failed error) error {
+	_, err := db.PutItemWithContext(ctx, input)
+	if Classify(err) == ErrorClassConditionalCheckFailed {
+		return failed
+	}
+	return err
+}
+
+func conditionalDelete(ctx context.Context, db dynamodbiface.DynamoDBAPI, input *dynamodb.DeleteItemInput, failed error) error {
+	_, err := db.DeleteItemWithContext(ctx, input)
+	if Classify(err) == ErrorClassConditionalCheckFailed {
+		return failed
+	}
+	return err
+}
@@ -0,0 +1,69 @@
+package dyno
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditionalWrites(t *testing.T) {
+	ctx := context.Background()
+	table, drop := createTestTable(t, "dyno-test-conditional")
+	defer drop()
+
+	key := func() map[string]*dynamodb.AttributeValue {
+		return map[string]*dynamodb.AttributeValue{
+			"PK": {S: aws.String("users")},
+			"SK": {S: aws.String("user-1")},
+		}
+	}
+	item := func(status string) map[string]*dynamodb.AttributeValue {
+		i := key()
+		i["Status"] = &dynamodb.AttributeValue{S: aws.String(status)}
+		return i
+	}
+	get := func() map[string]*dynamodb.AttributeValue {
+		result, err := testClient.GetItem(&dynamodb.GetItemInput{TableName: aws.String(table), Key: key(), ConsistentRead: aws.Bool(true)})
+		require.NoError(t, err)
+		return result.Item
+	}
+	status := func(s string) *dynamodb.AttributeValue {
+		return &dynamodb.AttributeValue{S: aws.String(s)}
+	}
+
+	t.Run("given a missing item", func(t *testing.T) {
+		assert.Equal(t, ErrItemNotFound, PutIfExists(ctx, testClient, table, "PK", item("active")))
+		assert.Equal(t, ErrItemNotFound, DeleteIfExists(ctx, testClient, table, "PK", key()))
+		assert.Equal(t, ErrAttributeMismatch, PutIfAttributeEquals(ctx, testClient, table, item("active"), "Status", status("new")))
+
+		require.NoError(t, PutIfNotExists(ctx, testClient, table, "PK", item("new")))
+		assert.Equal(t, ErrItemExists, PutIfNotExists(ctx, testClient, table, "PK", item("new")))
+	})
+
+	t.Run("given an attribute", func(t *testing.T) {
+		require.NoError(t, PutIfAttributeEquals(ctx, testClient, table, item("active"), "Status", status("new")))
+		assert.Equal(t, "active", aws.StringValue(get()["Status"].S))
+
+		assert.Equal(t, ErrAttributeMismatch, DeleteIfAttributeEquals(ctx, testClient, table, key(), "Status", status("new")))
+		require.NoError(t, PutIfExists(ctx, testClient, table, "PK", item("closed")))
+		require.NoError(t, DeleteIfAttributeEquals(ctx, testClient, table, key(), "Status", status("closed")))
+		assert.Nil(t, get())
+	})
+
+	t.Run("given versions", func(t *testing.T) {
+		require.NoError(t, PutIfVersion(ctx, testClient, table, item("new"), "Version", 0))
+		assert.Equal(t, "1", aws.StringValue(get()["Version"].N))
+		assert.Equal(t, ErrVersionMismatch, PutIfVersion(ctx, testClient, table, item("new"), "Version", 0))
+
+		require.NoError(t, PutIfVersion(ctx, testClient, table, item("active"), "Version", 1))
+		assert.Equal(t, "2", aws.StringValue(get()["Version"].N))
+
+		assert.Equal(t, ErrVersionMismatch, DeleteIfVersion(ctx, testClient, table, key(), "Version", 1))
+		require.NoError(t, DeleteIfVersion(ctx, testClient, table, key(), "Version", 2))
+		assert.Nil(t, get())
+	})
+}
@@ -0,0 +1,103 @@
+package dyno
+
+import (
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Config is the table, and the key schema, that dyno's primitives are stored in, so that an app can configure them
+// all in one place and load them from the environment
+type Config struct {
+	TableName string
+
+	// Region and Endpoint configure the session returned by Session. Either can be empty, for the AWS SDK's default.
+	Region   string
+	Endpoint string
+
+	// PrimaryKey and SortKey are the names of the table's keys. SortKey is empty for a table without one.
+	PrimaryKey string
+	SortKey    string
+
+	// Namespace, if set, prefixes the names of locks and circuit breakers, so that apps or environments sharing a
+	// table don't share them
+	Namespace string
+}
+
+// ConfigFromEnv loads a config from DYNO_TABLE, DYNO_REGION, DYNO_ENDPOINT, DYNO_PRIMARY_KEY, DYNO_SORT_KEY, and
+// DYNO_NAMESPACE. The keys default to "PK" and "SK"; set DYNO_SORT_KEY to an empty value for a table without a sort
+// key.
+func ConfigFromEnv() Config {
+	c := Config{
+		TableName:  os.Getenv("DYNO_TABLE"),
+		Region:     os.Getenv("DYNO_REGION"),
+		Endpoint:   os.Getenv("DYNO_ENDPOINT"),
+		PrimaryKey: "PK",
+		SortKey:    "SK",
+		Namespace:  os.Getenv("DYNO_NAMESPACE"),
+	}
+	if pk := os.Getenv("DYNO_PRIMARY_KEY"); pk != "" {
+		c.PrimaryKey = pk
+	}
+	if sk, ok := os.LookupEnv("DYNO_SORT_KEY"); ok {
+		c.SortKey = sk
+	}
+	return c
+}
+
+// Session returns an AWS session for the config's region and endpoint, with credentials and anything else left unset
+// read from the environment and shared config
+func (c Config) Session() (*session.Session, error) {
+	cfg := aws.NewConfig()
+	if c.Endpoint != "" {
+		cfg = cfg.WithEndpoint(c.Endpoint)
+	}
+	if c.Region != "" {
+		cfg = cfg.WithRegion(c.Region)
+	}
+
+	return session.NewSessionWithOptions(session.Options{
+		Config:            *cfg,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+}
+
+func (c Config) name(name string) string {
+	if c.Namespace == "" {
+		return name
+	}
+	return c.Namespace + "/" + name
+}
+
+// NewLock returns a lock in the config's table
+func (c Config) NewLock(db dynamodbiface.DynamoDBAPI, name string, options ...LockOption) *Lock {
+	options = append([]LockOption{WithPrimaryKey(c.PrimaryKey), WithSortKey(c.SortKey)}, options...)
+	return NewLockWithOptions(db, c.TableName, c.name(name), options...)
+}
+
+// NewGlobalLock returns a global lock in the config's table, stamped with the config's region
+func (c Config) NewGlobalLock(db dynamodbiface.DynamoDBAPI, name string) *GlobalLock {
+	return NewGlobalLock(db, c.TableName, c.PrimaryKey, c.SortKey, c.name(name), c.Region)
+}
+
+// NewCircuitBreaker returns a circuit breaker in the config's table
+func (c Config) NewCircuitBreaker(db dynamodbiface.DynamoDBAPI, name string) *CircuitBreaker {
+	return NewCircuitBreaker(db, c.TableName, c.PrimaryKey, c.SortKey, c.name(name))
+}
+
+// NewCache returns a cache in the config's table
+func (c Config) NewCache(db dynamodbiface.DynamoDBAPI, size int) *Cache {
+	return NewCache(db, c.TableName, c.PrimaryKey, c.SortKey, size)
+}
+
+// NewCheckpoints returns stream checkpoints in the config's table
+func (c Config) NewCheckpoints(db dynamodbiface.DynamoDBAPI) *Checkpoints {
+	return NewCheckpoints(db, c.TableName, c.PrimaryKey, c.SortKey)
+}
+
+// NewJanitor returns a janitor for the locks in the config's table
+func (c Config) NewJanitor(db dynamodbiface.DynamoDBAPI) *Janitor {
+	return NewJanitor(db, c.TableName, c.PrimaryKey, c.SortKey)
+}
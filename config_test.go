@@ -0,0 +1,66 @@
+package dyno
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	// setenv sets the variables, and returns a func that restores them
+	setenv := func(env map[string]string) func() {
+		restore := []func(){}
+		for key, value := range env {
+			key := key
+			if previous, ok := os.LookupEnv(key); ok {
+				restore = append(restore, func() { os.Setenv(key, previous) })
+			} else {
+				restore = append(restore, func() { os.Unsetenv(key) })
+			}
+			os.Setenv(key, value)
+		}
+		return func() {
+			for _, fn := range restore {
+				fn()
+			}
+		}
+	}
+
+	t.Run("given only a table", func(t *testing.T) {
+		defer setenv(map[string]string{"DYNO_TABLE": "locks"})()
+		os.Unsetenv("DYNO_SORT_KEY")
+
+		c := ConfigFromEnv()
+
+		assert.Equal(t, Config{TableName: "locks", PrimaryKey: "PK", SortKey: "SK"}, c)
+	})
+
+	t.Run("given every variable", func(t *testing.T) {
+		defer setenv(map[string]string{
+			"DYNO_TABLE":       "locks",
+			"DYNO_REGION":      "us-west-2",
+			"DYNO_ENDPOINT":    "http://localhost:8000",
+			"DYNO_PRIMARY_KEY": "ID",
+			"DYNO_SORT_KEY":    "",
+			"DYNO_NAMESPACE":   "staging",
+		})()
+
+		c := ConfigFromEnv()
+
+		assert.Equal(t, Config{
+			TableName:  "locks",
+			Region:     "us-west-2",
+			Endpoint:   "http://localhost:8000",
+			PrimaryKey: "ID",
+			SortKey:    "",
+			Namespace:  "staging",
+		}, c)
+
+		lock := c.NewLock(nil, "jobs")
+		assert.Equal(t, "staging/jobs", lock.name)
+		assert.Equal(t, "ID", lock.pk)
+		assert.Equal(t, "", lock.sk)
+		assert.Equal(t, "us-west-2", c.NewGlobalLock(nil, "jobs").region)
+	})
+}
@@ -0,0 +1,49 @@
+package dyno
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// ConsumeFunc processes a message and returns its outcome
+type ConsumeFunc func(ctx context.Context, messageID, body string) ([]byte, error)
+
+// IdempotentConsumer processes each message once, for queues like SQS that deliver messages at least once. The first
+// delivery of a message runs the handler and stores its outcome, and redeliveries return the stored outcome without
+// running it.
+//
+// A delivery that arrives while the message is being processed fails with ErrIdempotencyInProgress, so it's retried
+// later. If the handler crashes, its lease runs out and a redelivery processes the message. The store's lease should be
+// longer than the queue's visibility timeout, so a slow handler isn't repeated while it's still running.
+type IdempotentConsumer struct {
+	store *IdempotencyStore
+	name  string
+	fn    ConsumeFunc
+}
+
+// NewIdempotentConsumer returns a consumer of the named queue. The name scopes message IDs in the store, so consumers of
+// different queues can share one.
+func NewIdempotentConsumer(store *IdempotencyStore, name string, fn ConsumeFunc) *IdempotentConsumer {
+	return &IdempotentConsumer{store: store, name: name, fn: fn}
+}
+
+// Consume processes the message unless it's already been processed, and returns its outcome
+func (c *IdempotentConsumer) Consume(ctx context.Context, messageID, body string) ([]byte, error) {
+	return c.store.Do(ctx, c.name+"/"+messageID, func(ctx context.Context) ([]byte, error) {
+		return c.fn(ctx, messageID, body)
+	})
+}
+
+// ConsumeSQS processes the messages in order, and returns the IDs of the ones that failed. A Lambda function can return
+// them as its batch item failures, so only they're redelivered.
+func (c *IdempotentConsumer) ConsumeSQS(ctx context.Context, messages []*sqs.Message) []string {
+	failed := []string{}
+	for _, m := range messages {
+		if _, err := c.Consume(ctx, aws.StringValue(m.MessageId), aws.StringValue(m.Body)); err != nil {
+			failed = append(failed, aws.StringValue(m.MessageId))
+		}
+	}
+	return failed
+}
@@ -0,0 +1,49 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotentConsumer(t *testing.T) {
+	ctx := context.Background()
+	processed := map[string]int{}
+	consumer := NewIdempotentConsumer(NewIdempotencyStore(testClient, tableName, "PK", "SK"), "testing-queue", func(ctx context.Context, id, body string) ([]byte, error) {
+		processed[id]++
+		if body == "poison" {
+			return nil, errors.New("can't process")
+		}
+		return []byte("processed " + body), nil
+	})
+
+	t.Run("given a redelivered message", func(t *testing.T) {
+		outcome, err := consumer.Consume(ctx, "message-1", "hello")
+		require.NoError(t, err)
+		assert.Equal(t, "processed hello", string(outcome))
+
+		outcome, err = consumer.Consume(ctx, "message-1", "hello")
+		require.NoError(t, err)
+		assert.Equal(t, "processed hello", string(outcome))
+		assert.Equal(t, 1, processed["message-1"])
+	})
+
+	t.Run("given a batch of SQS messages", func(t *testing.T) {
+		failed := consumer.ConsumeSQS(ctx, []*sqs.Message{
+			{MessageId: aws.String("message-1"), Body: aws.String("hello")},
+			{MessageId: aws.String("message-2"), Body: aws.String("poison")},
+			{MessageId: aws.String("message-3"), Body: aws.String("world")},
+		})
+
+		assert.Equal(t, []string{"message-2"}, failed)
+		assert.Equal(t, map[string]int{"message-1": 1, "message-2": 1, "message-3": 1}, processed)
+
+		consumer.ConsumeSQS(ctx, []*sqs.Message{{MessageId: aws.String("message-2"), Body: aws.String("poison")}})
+		assert.Equal(t, 2, processed["message-2"])
+	})
+}
@@ -0,0 +1,403 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+var (
+	ErrParticipantNotRegistered = errors.New("transaction participant is not registered")
+	ErrTransactionTooManyWrites = errors.New("transaction has more than 24 writes")
+	ErrTransactionAborted       = errors.New("transaction aborted")
+	ErrTransactionUnconfirmed   = errors.New("transaction committed, but not every participant has confirmed")
+)
+
+// Statuses of a transaction's intent record
+const (
+	TransactionPreparing  = "PREPARING"
+	TransactionCommitting = "COMMITTING"
+	TransactionAborting   = "ABORTING"
+)
+
+// Transaction is an operation that spans DynamoDB and other systems
+type Transaction struct {
+	// ID identifies the transaction. Execute sets it if it's empty.
+	ID string
+
+	// Participants are the names of the registered participants, in the order they're prepared
+	Participants []string
+
+	// Payload describes the operation to the participants. It's kept in the intent record, so recovery can pass it to
+	// them after a crash.
+	Payload []byte
+
+	// Writes are applied to DynamoDB when the transaction commits. They aren't kept, so recovery never applies them.
+	Writes []*dynamodb.TransactWriteItem
+
+	Status    string
+	StartedAt time.Time
+
+	// Pending are the participants that haven't confirmed the commit or abort
+	Pending []string
+}
+
+// Participant is a system that takes part in transactions. Prepare must make the participant able to commit, e.g. by
+// staging the change or reserving what it needs, and returns an error to vote to abort. Commit and Abort are called at
+// least once after a successful Prepare, possibly from another process during recovery, so they must be idempotent.
+// Abort can also be called for a transaction that was never prepared.
+type Participant interface {
+	Prepare(ctx context.Context, tx *Transaction) error
+	Commit(ctx context.Context, tx *Transaction) error
+	Abort(ctx context.Context, tx *Transaction) error
+}
+
+// Coordinator runs two-phase commits between DynamoDB and external participants. A transaction's intent record is
+// written before any participant is prepared. Once every participant has prepared, the record is moved to
+// COMMITTING in the same DynamoDB transaction as the transaction's writes, which is the commit point. Participants
+// then commit, and the record is removed once they've all confirmed.
+//
+// If a coordinator crashes, its transactions are left in doubt. Recover, or RunRecovery, resolves the ones older than
+// the timeout, holding a Lock so only one process recovers at a time. Transactions that hadn't reached the commit point
+// are aborted, and the rest are committed.
+//
+// Intent records are kept in the coordinator's partition, so the table must have a sort key.
+type Coordinator struct {
+	db   dynamodbiface.DynamoDBAPI
+	tn   string
+	pk   string
+	sk   string
+	name string
+
+	mu           sync.RWMutex
+	participants map[string]Participant
+
+	// Timeout is how long a transaction can take before recovery considers it in doubt. Defaults to one minute.
+	Timeout time.Duration
+
+	// Lease is the lease of the recovery lock. Defaults to one minute.
+	Lease time.Duration
+
+	// Interval is how often RunRecovery recovers. Defaults to one minute.
+	Interval time.Duration
+
+	// Metrics receives "TransactionsCommitted" and "TransactionsAborted" counts of the transactions each recovery
+	// resolved
+	Metrics Metrics
+}
+
+func NewCoordinator(db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey, name string) *Coordinator {
+	return &Coordinator{
+		db:           db,
+		tn:           tableName,
+		pk:           primaryKey,
+		sk:           sortKey,
+		name:         name,
+		participants: map[string]Participant{},
+		Timeout:      time.Minute,
+		Lease:        time.Minute,
+		Interval:     time.Minute,
+	}
+}
+
+// Register adds the participant, replacing any registered with the same name. Every process that recovers transactions
+// must register the same participants.
+func (c *Coordinator) Register(name string, p Participant) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.participants[name] = p
+}
+
+func (c *Coordinator) participant(name string) (Participant, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	p, ok := c.participants[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrParticipantNotRegistered, name)
+	}
+	return p, nil
+}
+
+func (c *Coordinator) partition() *dynamodb.AttributeValue {
+	return Str(fmt.Sprintf("Dyno_Transactions/%s", c.name))
+}
+
+func (c *Coordinator) key(id string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		c.pk: c.partition(),
+		c.sk: Str("Dyno_Transaction/" + id),
+	}
+}
+
+// Execute runs the transaction. If a participant fails to prepare, or a write's condition fails, the transaction is
+// aborted and ErrTransactionAborted is returned. If the transaction commits but a participant fails to, the commit is
+// left to recovery and ErrTransactionUnconfirmed is returned. Other errors leave the transaction in doubt, for recovery
+// to resolve.
+func (c *Coordinator) Execute(ctx context.Context, tx *Transaction) error {
+	if len(tx.Writes) >= maxTransactItems {
+		return ErrTransactionTooManyWrites
+	}
+	for _, name := range tx.Participants {
+		if _, err := c.participant(name); err != nil {
+			return err
+		}
+	}
+	if tx.ID == "" {
+		tx.ID = NewKSUID()
+	}
+	tx.Status = TransactionPreparing
+	tx.StartedAt = time.Now()
+	tx.Pending = tx.Participants
+
+	names := make([]*dynamodb.AttributeValue, len(tx.Participants))
+	for i, name := range tx.Participants {
+		names[i] = Str(name)
+	}
+	item := c.key(tx.ID)
+	item["Dyno_Status"] = Str(tx.Status)
+	item["Dyno_StartedAt"] = TimeValue(tx.StartedAt, TimeUnixMillis)
+	item["Dyno_Participants"] = List(names...)
+	if len(tx.Payload) > 0 {
+		item["Dyno_Payload"] = Bytes(tx.Payload)
+	}
+	if len(tx.Participants) > 0 {
+		item["Dyno_Pending"] = StringSet(tx.Participants...)
+	}
+
+	err := conditionalPut(ctx, c.db, &dynamodb.PutItemInput{
+		TableName:                aws.String(c.tn),
+		Item:                     item,
+		ConditionExpression:      aws.String("attribute_not_exists(#pk)"),
+		ExpressionAttributeNames: map[string]*string{"#pk": aws.String(c.pk)},
+	}, ErrItemExists)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range tx.Participants {
+		p, _ := c.participant(name)
+		if err := p.Prepare(ctx, tx); err != nil {
+			return c.abort(ctx, tx, fmt.Errorf("participant %s: %v", name, err))
+		}
+	}
+
+	items := append([]*dynamodb.TransactWriteItem{{
+		Update: &dynamodb.Update{
+			TableName:                 aws.String(c.tn),
+			Key:                       c.key(tx.ID),
+			UpdateExpression:          aws.String("SET #s = :committing"),
+			ConditionExpression:       aws.String("#s = :preparing"),
+			ExpressionAttributeNames:  map[string]*string{"#s": aws.String("Dyno_Status")},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":committing": Str(TransactionCommitting), ":preparing": Str(TransactionPreparing)},
+		},
+	}}, tx.Writes...)
+
+	_, err = c.db.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if err != nil {
+		// Either a write's condition failed, or recovery already aborted the transaction
+		var canceled *dynamodb.TransactionCanceledException
+		if errors.As(err, &canceled) {
+			for _, reason := range canceled.CancellationReasons {
+				if aws.StringValue(reason.Code) == "ConditionalCheckFailed" {
+					return c.abort(ctx, tx, err)
+				}
+			}
+		}
+		return err
+	}
+	tx.Status = TransactionCommitting
+
+	return c.finish(ctx, tx)
+}
+
+// abort aborts the transaction and its participants. If a participant fails to abort, recovery finishes aborting it.
+func (c *Coordinator) abort(ctx context.Context, tx *Transaction, cause error) error {
+	if _, err := c.markAborting(ctx, tx); err != nil {
+		return err
+	}
+	if err := c.finish(ctx, tx); err != nil {
+		return fmt.Errorf("%w: %v, then %v", ErrTransactionAborted, cause, err)
+	}
+	return fmt.Errorf("%w: %v", ErrTransactionAborted, cause)
+}
+
+// markAborting moves the transaction to ABORTING, and returns false if it reached the commit point first
+func (c *Coordinator) markAborting(ctx context.Context, tx *Transaction) (bool, error) {
+	_, err := c.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(c.tn),
+		Key:                       c.key(tx.ID),
+		UpdateExpression:          aws.String("SET #s = :aborting"),
+		ConditionExpression:       aws.String("#s IN (:preparing, :aborting)"),
+		ExpressionAttributeNames:  map[string]*string{"#s": aws.String("Dyno_Status")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":aborting": Str(TransactionAborting), ":preparing": Str(TransactionPreparing)},
+	})
+	if Classify(err) == ErrorClassConditionalCheckFailed {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	tx.Status = TransactionAborting
+	return true, nil
+}
+
+// finish commits or aborts the pending participants, by the transaction's status, removing each from the record once
+// it confirms, and then removes the record
+func (c *Coordinator) finish(ctx context.Context, tx *Transaction) error {
+	for _, name := range tx.Pending {
+		p, err := c.participant(name)
+		if err != nil {
+			return err
+		}
+		if tx.Status == TransactionCommitting {
+			err = p.Commit(ctx, tx)
+		} else {
+			err = p.Abort(ctx, tx)
+		}
+		if err != nil {
+			if tx.Status == TransactionCommitting {
+				return fmt.Errorf("%w: participant %s: %v", ErrTransactionUnconfirmed, name, err)
+			}
+			return fmt.Errorf("participant %s: %w", name, err)
+		}
+
+		_, err = c.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+			TableName:                 aws.String(c.tn),
+			Key:                       c.key(tx.ID),
+			UpdateExpression:          aws.String("DELETE #p :p"),
+			ConditionExpression:       aws.String("attribute_exists(#pk)"),
+			ExpressionAttributeNames:  map[string]*string{"#p": aws.String("Dyno_Pending"), "#pk": aws.String(c.pk)},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":p": StringSet(name)},
+		})
+		if Classify(err) == ErrorClassConditionalCheckFailed { // Recovery finished the transaction
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := c.db.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(c.tn),
+		Key:       c.key(tx.ID),
+	})
+	return err
+}
+
+// Transactions returns the transactions that haven't finished, oldest first
+func (c *Coordinator) Transactions(ctx context.Context) ([]*Transaction, error) {
+	txs := []*Transaction{}
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(c.tn),
+		KeyConditionExpression:    aws.String("#pk = :pk AND begins_with(#sk, :prefix)"),
+		ExpressionAttributeNames:  map[string]*string{"#pk": aws.String(c.pk), "#sk": aws.String(c.sk)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":pk": c.partition(), ":prefix": Str("Dyno_Transaction/")},
+		ConsistentRead:            aws.Bool(true),
+	}
+
+	for {
+		result, err := c.db.QueryWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Items {
+			tx := &Transaction{
+				ID:      strings.TrimPrefix(GetString(item, c.sk, ""), "Dyno_Transaction/"),
+				Payload: GetBytes(item, "Dyno_Payload", nil),
+				Status:  GetString(item, "Dyno_Status", ""),
+				Pending: GetStringSet(item, "Dyno_Pending"),
+			}
+			tx.StartedAt, _ = ParseTime(item["Dyno_StartedAt"], TimeUnixMillis)
+			for _, name := range GetList(item, "Dyno_Participants") {
+				tx.Participants = append(tx.Participants, aws.StringValue(name.S))
+			}
+			txs = append(txs, tx)
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	// IDs set by the caller don't sort by time like KSUIDs
+	sort.SliceStable(txs, func(i, j int) bool { return txs[i].StartedAt.Before(txs[j].StartedAt) })
+	return txs, nil
+}
+
+// Recover resolves the transactions that have been in doubt for longer than the timeout, and returns how many it
+// resolved. Transactions still preparing are aborted, and the rest are finished. If another process is recovering,
+// Recover returns without doing anything.
+func (c *Coordinator) Recover(ctx context.Context) (int, error) {
+	lock := NewLock(c.db, c.tn, c.pk, c.sk, fmt.Sprintf("Dyno_CoordinatorRecovery/%s", c.name))
+	err := lock.AcquireContext(ctx, c.Lease, 0)
+	if err == ErrLockAcquireTimeout {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer lock.Release()
+
+	txs, err := c.Transactions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	resolved := 0
+	cutoff := time.Now().Add(-c.Timeout)
+	for _, tx := range txs {
+		if tx.StartedAt.After(cutoff) {
+			continue
+		}
+
+		if tx.Status == TransactionPreparing {
+			aborting, err := c.markAborting(ctx, tx)
+			if err != nil {
+				return resolved, err
+			}
+			if !aborting { // It reached the commit point since it was read
+				continue
+			}
+		}
+		if err := c.finish(ctx, tx); err != nil {
+			return resolved, err
+		}
+
+		if tx.Status == TransactionCommitting {
+			countMetric(c.Metrics, "TransactionsCommitted", 1, map[string]string{"Coordinator": c.name})
+		} else {
+			countMetric(c.Metrics, "TransactionsAborted", 1, map[string]string{"Coordinator": c.name})
+		}
+		resolved++
+	}
+	return resolved, nil
+}
+
+// RunRecovery recovers on the interval until the context is done
+func (c *Coordinator) RunRecovery(ctx context.Context) error {
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := c.Recover(ctx); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryParticipant records the transactions it prepared, committed and aborted
+type memoryParticipant struct {
+	failPrepare bool
+	failCommit  bool
+	onPrepare   func()
+
+	prepared  []string
+	committed []string
+	aborted   []string
+}
+
+func (p *memoryParticipant) Prepare(ctx context.Context, tx *Transaction) error {
+	if p.onPrepare != nil {
+		p.onPrepare()
+	}
+	if p.failPrepare {
+		return errors.New("can't prepare")
+	}
+	p.prepared = append(p.prepared, tx.ID)
+	return nil
+}
+
+func (p *memoryParticipant) Commit(ctx context.Context, tx *Transaction) error {
+	if p.failCommit {
+		return errors.New("can't commit")
+	}
+	p.committed = append(p.committed, tx.ID+"/"+string(tx.Payload))
+	return nil
+}
+
+func (p *memoryParticipant) Abort(ctx context.Context, tx *Transaction) error {
+	p.aborted = append(p.aborted, tx.ID)
+	return nil
+}
+
+func TestCoordinator(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func() (*Coordinator, *memoryParticipant, *memoryParticipant) {
+		c := NewCoordinator(testClient, tableName, "PK", "SK", ksuid.New().String())
+		payments, email := &memoryParticipant{}, &memoryParticipant{}
+		c.Register("payments", payments)
+		c.Register("email", email)
+		return c, payments, email
+	}
+	orderWrite := func(id string, condition bool) *dynamodb.TransactWriteItem {
+		put := &dynamodb.Put{
+			TableName: aws.String(tableName),
+			Item:      map[string]*dynamodb.AttributeValue{"PK": Str("order/" + id), "SK": Str("order")},
+		}
+		if condition {
+			put.ConditionExpression = aws.String("attribute_exists(PK)")
+		}
+		return &dynamodb.TransactWriteItem{Put: put}
+	}
+	orderExists := func(id string) bool {
+		result, err := testClient.GetItem(&dynamodb.GetItemInput{
+			TableName: aws.String(tableName),
+			Key:       map[string]*dynamodb.AttributeValue{"PK": Str("order/" + id), "SK": Str("order")},
+		})
+		require.NoError(t, err)
+		return result.Item != nil
+	}
+
+	t.Run("given participants that prepare", func(t *testing.T) {
+		c, payments, email := setup()
+		tx := &Transaction{
+			Participants: []string{"payments", "email"},
+			Payload:      []byte("order"),
+			Writes:       []*dynamodb.TransactWriteItem{orderWrite("committed", false)},
+		}
+
+		require.NoError(t, c.Execute(ctx, tx))
+		assert.True(t, orderExists("committed"))
+		assert.Equal(t, []string{tx.ID + "/order"}, payments.committed)
+		assert.Equal(t, []string{tx.ID + "/order"}, email.committed)
+		assert.Empty(t, payments.aborted)
+
+		txs, err := c.Transactions(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, txs)
+	})
+
+	t.Run("given a participant that fails to prepare", func(t *testing.T) {
+		c, payments, email := setup()
+		email.failPrepare = true
+		tx := &Transaction{
+			Participants: []string{"payments", "email"},
+			Writes:       []*dynamodb.TransactWriteItem{orderWrite("unprepared", false)},
+		}
+
+		err := c.Execute(ctx, tx)
+		assert.True(t, errors.Is(err, ErrTransactionAborted))
+		assert.False(t, orderExists("unprepared"))
+		assert.Equal(t, []string{tx.ID}, payments.aborted)
+		assert.Equal(t, []string{tx.ID}, email.aborted)
+		assert.Empty(t, payments.committed)
+
+		txs, err := c.Transactions(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, txs)
+	})
+
+	t.Run("given a write whose condition fails", func(t *testing.T) {
+		c, payments, _ := setup()
+		tx := &Transaction{
+			Participants: []string{"payments"},
+			Writes:       []*dynamodb.TransactWriteItem{orderWrite("missing", true)},
+		}
+
+		err := c.Execute(ctx, tx)
+		assert.True(t, errors.Is(err, ErrTransactionAborted))
+		assert.Equal(t, []string{tx.ID}, payments.prepared)
+		assert.Equal(t, []string{tx.ID}, payments.aborted)
+	})
+
+	t.Run("given an unregistered participant", func(t *testing.T) {
+		c, _, _ := setup()
+
+		err := c.Execute(ctx, &Transaction{Participants: []string{"shipping"}})
+		assert.True(t, errors.Is(err, ErrParticipantNotRegistered))
+	})
+
+	t.Run("given a participant that fails to commit", func(t *testing.T) {
+		c, payments, email := setup()
+		email.failCommit = true
+		tx := &Transaction{Participants: []string{"payments", "email"}, Payload: []byte("order")}
+
+		err := c.Execute(ctx, tx)
+		assert.True(t, errors.Is(err, ErrTransactionUnconfirmed))
+		assert.Equal(t, []string{tx.ID + "/order"}, payments.committed)
+
+		txs, err := c.Transactions(ctx)
+		require.NoError(t, err)
+		require.Len(t, txs, 1)
+		assert.Equal(t, TransactionCommitting, txs[0].Status)
+		assert.Equal(t, []string{"email"}, txs[0].Pending)
+		assert.Equal(t, []string{"payments", "email"}, txs[0].Participants)
+
+		email.failCommit = false
+		c.Timeout = 0
+		resolved, err := c.Recover(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, resolved)
+		assert.Equal(t, []string{tx.ID + "/order"}, email.committed)
+		assert.Len(t, payments.committed, 1)
+
+		txs, err = c.Transactions(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, txs)
+	})
+
+	t.Run("given a coordinator that crashed while preparing", func(t *testing.T) {
+		c, payments, email := setup()
+		email.onPrepare = func() { panic("crashed") }
+		tx := &Transaction{
+			Participants: []string{"payments", "email"},
+			Writes:       []*dynamodb.TransactWriteItem{orderWrite("crashed", false)},
+		}
+
+		assert.Panics(t, func() { c.Execute(ctx, tx) })
+		email.onPrepare = nil
+
+		resolved, err := c.Recover(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, resolved, "the transaction isn't in doubt until it times out")
+
+		c.Timeout = 0
+		resolved, err = c.Recover(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, resolved)
+		assert.False(t, orderExists("crashed"))
+		assert.Equal(t, []string{tx.ID}, payments.aborted)
+		assert.Equal(t, []string{tx.ID}, email.aborted)
+	})
+
+	t.Run("given another process is recovering", func(t *testing.T) {
+		c, _, email := setup()
+		email.failCommit = true
+		require.Error(t, c.Execute(ctx, &Transaction{Participants: []string{"email"}}))
+
+		lock := NewLock(testClient, tableName, "PK", "SK", "Dyno_CoordinatorRecovery/"+c.name)
+		require.NoError(t, lock.Acquire(10*time.Second))
+		defer lock.Release()
+
+		email.failCommit = false
+		c.Timeout = 0
+		resolved, err := c.Recover(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, resolved)
+		assert.Empty(t, email.committed)
+	})
+}
@@ -0,0 +1,91 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// ItemMapper transforms an item. Returning a nil item skips it.
+type ItemMapper func(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error)
+
+// CopyOptions configures Copy
+type CopyOptions struct {
+	// Segments is the number of parallel scan segments. Defaults to 4.
+	Segments int
+
+	// Mapper is applied to every item before it is written to the destination
+	Mapper ItemMapper
+
+	// ItemsPerSecond limits how fast items are written to the destination. Zero is unlimited.
+	ItemsPerSecond int
+
+	// Checkpoints stores the scan progress so an interrupted copy can resume. The checkpoints are reset once the copy
+	// completes, so the next copy with the same name starts from the beginning. Without it, Copy starts from the
+	// beginning every time.
+	Checkpoints *Checkpoints
+
+	// CheckpointName identifies this copy in the checkpoints. Defaults to "copy/<src>/<dst>".
+	CheckpointName string
+}
+
+// Copy scans every item in the source table and batch writes it to the destination table
+func Copy(ctx context.Context, db dynamodbiface.DynamoDBAPI, srcTable, dstTable string, opts *CopyOptions) error {
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+
+	segments := opts.Segments
+	if segments <= 0 {
+		segments = 4
+	}
+	name := opts.CheckpointName
+	if name == "" {
+		name = fmt.Sprintf("copy/%s/%s", srcTable, dstTable)
+	}
+	limiter := newRateLimiter(opts.ItemsPerSecond)
+
+	scan := &segmentScan{
+		db:          db,
+		input:       &dynamodb.ScanInput{TableName: aws.String(srcTable), ConsistentRead: aws.Bool(true)},
+		segments:    segments,
+		checkpoints: opts.Checkpoints,
+		name:        name,
+	}
+
+	err := scan.run(ctx, func(ctx context.Context, items []map[string]*dynamodb.AttributeValue) error {
+		writer := NewBatchWriter(db, dstTable)
+
+		for _, item := range items {
+			if opts.Mapper != nil {
+				mapped, err := opts.Mapper(item)
+				if err != nil {
+					return err
+				}
+				if mapped == nil {
+					continue
+				}
+				item = mapped
+			}
+
+			if err := limiter.wait(ctx, 1); err != nil {
+				return err
+			}
+			if err := writer.Put(ctx, item); err != nil {
+				return err
+			}
+		}
+
+		// Everything in the page has to be written before the checkpoint moves past it
+		return writer.Flush(ctx)
+	})
+	if err != nil || opts.Checkpoints == nil {
+		return err
+	}
+
+	// The copy is complete, so the next one starts from the beginning
+	return opts.Checkpoints.Reset(ctx, name, segments)
+}
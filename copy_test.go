@@ -0,0 +1,102 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopy(t *testing.T) {
+	src, dropSrc := createTestTable(t, "dyno-test-copy-src")
+	defer dropSrc()
+	dst, dropDst := createTestTable(t, "dyno-test-copy-dst")
+	defer dropDst()
+
+	ctx := context.Background()
+	writer := NewBatchWriter(testClient, src)
+	for i := 0; i < 60; i++ {
+		err := writer.Put(ctx, map[string]*dynamodb.AttributeValue{
+			"PK": {S: aws.String(fmt.Sprintf("item-%d", i))},
+			"SK": {S: aws.String("value")},
+		})
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Flush(ctx))
+
+	t.Run("copies mapped items and resets checkpoints", func(t *testing.T) {
+		checkpoints := NewCheckpoints(testClient, tableName, "PK", "SK")
+
+		err := Copy(ctx, testClient, src, dst, &CopyOptions{
+			Segments:       3,
+			Checkpoints:    checkpoints,
+			CheckpointName: "test-copy",
+			Mapper: func(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+				if aws.StringValue(item["PK"].S) == "item-0" {
+					return nil, nil
+				}
+				item["Copied"] = &dynamodb.AttributeValue{BOOL: aws.Bool(true)}
+				return item, nil
+			},
+		})
+		require.NoError(t, err)
+
+		result, err := testClient.Scan(&dynamodb.ScanInput{TableName: aws.String(dst)})
+		require.NoError(t, err)
+		assert.Equal(t, int64(59), aws.Int64Value(result.Count))
+		for _, item := range result.Items {
+			assert.True(t, aws.BoolValue(item["Copied"].BOOL))
+		}
+
+		for segment := 0; segment < 3; segment++ {
+			cp, err := checkpoints.load(ctx, "test-copy", segment)
+			require.NoError(t, err)
+			assert.False(t, cp.done)
+			assert.Empty(t, cp.lastKey)
+		}
+	})
+
+	t.Run("given a copy that's run again", func(t *testing.T) {
+		var copied int32
+		err := Copy(ctx, testClient, src, dst, &CopyOptions{
+			Segments:       3,
+			Checkpoints:    NewCheckpoints(testClient, tableName, "PK", "SK"),
+			CheckpointName: "test-copy",
+			Mapper: func(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+				atomic.AddInt32(&copied, 1)
+				return item, nil
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int32(60), copied)
+	})
+
+	t.Run("given a copy interrupted before its checkpoints were reset", func(t *testing.T) {
+		checkpoints := NewCheckpoints(testClient, tableName, "PK", "SK")
+		for segment := 0; segment < 3; segment++ {
+			require.NoError(t, checkpoints.save(ctx, "test-copy", segment, &segmentCheckpoint{done: true}))
+		}
+
+		called := false
+		err := Copy(ctx, testClient, src, dst, &CopyOptions{
+			Segments:       3,
+			Checkpoints:    checkpoints,
+			CheckpointName: "test-copy",
+			Mapper: func(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+				called = true
+				return item, nil
+			},
+		})
+		require.NoError(t, err)
+		assert.False(t, called)
+
+		cp, err := checkpoints.load(ctx, "test-copy", 0)
+		require.NoError(t, err)
+		assert.False(t, cp.done)
+	})
+}
@@ -0,0 +1,150 @@
+package dyno
+
+import (
+	"math"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// hoursPerMonth is the number of hours AWS bills in a month
+const hoursPerMonth = 730
+
+// Pricing is the price in dollars of DynamoDB capacity and storage in a region
+type Pricing struct {
+	// ReadRequestUnit and WriteRequestUnit are the on-demand prices of a single request unit
+	ReadRequestUnit  float64
+	WriteRequestUnit float64
+
+	// ReadCapacityUnitHour and WriteCapacityUnitHour are the provisioned prices of a capacity unit for an hour
+	ReadCapacityUnitHour  float64
+	WriteCapacityUnitHour float64
+
+	// StorageGBMonth is the price of storing a GB for a month
+	StorageGBMonth float64
+}
+
+// USEast1Pricing is the standard table class pricing in us-east-1
+var USEast1Pricing = Pricing{
+	ReadRequestUnit:       0.25 / 1e6,
+	WriteRequestUnit:      1.25 / 1e6,
+	ReadCapacityUnitHour:  0.00013,
+	WriteCapacityUnitHour: 0.00065,
+	StorageGBMonth:        0.25,
+}
+
+// Usage is the capacity a table used over a period of time
+type Usage struct {
+	Period     time.Duration
+	ReadUnits  float64
+	WriteUnits float64
+
+	// PeakReadUnitsPerSecond and PeakWriteUnitsPerSecond size the provisioned capacity. They default to the average
+	// rate over the period.
+	PeakReadUnitsPerSecond  float64
+	PeakWriteUnitsPerSecond float64
+
+	StorageBytes int64
+}
+
+// UsageFromCapacity returns the usage for capacity consumed over the period, e.g. from a CapacityCounter
+func UsageFromCapacity(c Capacity, period time.Duration) Usage {
+	return Usage{
+		Period:     period,
+		ReadUnits:  c.ReadUnits,
+		WriteUnits: c.WriteUnits,
+	}
+}
+
+// AddReads adds count reads of items of the size to the usage
+func (u *Usage) AddReads(count int64, size int, consistent bool) {
+	u.ReadUnits += float64(count) * ReadUnits(size, consistent)
+}
+
+// AddWrites adds count writes of items of the size to the usage
+func (u *Usage) AddWrites(count int64, size int) {
+	u.WriteUnits += float64(count) * WriteUnits(size)
+}
+
+// CostOptions configures EstimateCost
+type CostOptions struct {
+	// Pricing defaults to USEast1Pricing
+	Pricing *Pricing
+
+	// TargetUtilization is the fraction of provisioned capacity the peak should use, as with auto scaling. Defaults
+	// to 0.7.
+	TargetUtilization float64
+}
+
+// CostEstimate is the monthly cost of a table in dollars
+type CostEstimate struct {
+	OnDemand    float64
+	Provisioned float64
+	Storage     float64
+
+	// ProvisionedReadUnits and ProvisionedWriteUnits are the capacity the provisioned estimate is for
+	ProvisionedReadUnits  int64
+	ProvisionedWriteUnits int64
+}
+
+// BillingMode returns the cheaper billing mode
+func (e CostEstimate) BillingMode() string {
+	if e.Provisioned < e.OnDemand {
+		return dynamodb.BillingModeProvisioned
+	}
+	return dynamodb.BillingModePayPerRequest
+}
+
+// EstimateCost extrapolates the usage to a month and prices it with on-demand and provisioned capacity. Both
+// estimates include storage.
+func EstimateCost(u Usage, opts *CostOptions) CostEstimate {
+	if opts == nil {
+		opts = &CostOptions{}
+	}
+	pricing := opts.Pricing
+	if pricing == nil {
+		pricing = &USEast1Pricing
+	}
+	target := opts.TargetUtilization
+	if target <= 0 || target > 1 {
+		target = 0.7
+	}
+
+	seconds := u.Period.Seconds()
+	if seconds <= 0 {
+		return CostEstimate{}
+	}
+	scale := hoursPerMonth * 3600 / seconds
+
+	peakReads := u.PeakReadUnitsPerSecond
+	if peakReads == 0 {
+		peakReads = u.ReadUnits / seconds
+	}
+	peakWrites := u.PeakWriteUnitsPerSecond
+	if peakWrites == 0 {
+		peakWrites = u.WriteUnits / seconds
+	}
+
+	estimate := CostEstimate{
+		Storage:               float64(u.StorageBytes) / (1 << 30) * pricing.StorageGBMonth,
+		ProvisionedReadUnits:  provisionedUnits(peakReads, target),
+		ProvisionedWriteUnits: provisionedUnits(peakWrites, target),
+	}
+	estimate.OnDemand = estimate.Storage +
+		u.ReadUnits*scale*pricing.ReadRequestUnit +
+		u.WriteUnits*scale*pricing.WriteRequestUnit
+	estimate.Provisioned = estimate.Storage +
+		float64(estimate.ProvisionedReadUnits)*hoursPerMonth*pricing.ReadCapacityUnitHour +
+		float64(estimate.ProvisionedWriteUnits)*hoursPerMonth*pricing.WriteCapacityUnitHour
+
+	return estimate
+}
+
+// provisionedUnits is the capacity that keeps the peak at the target utilization, never less than one unit
+func provisionedUnits(peak, target float64) int64 {
+	units := int64(math.Ceil(peak / target))
+	if units < 1 {
+		units = 1
+	}
+	return units
+}
@@ -0,0 +1,43 @@
+package dyno
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateCost(t *testing.T) {
+	t.Run("given steady traffic", func(t *testing.T) {
+		// 10 writes and 50 reads a second for an hour
+		usage := Usage{Period: time.Hour}
+		usage.AddWrites(10*3600, 500)
+		usage.AddReads(50*3600, 500, true)
+
+		estimate := EstimateCost(usage, nil)
+
+		assert.Equal(t, int64(15), estimate.ProvisionedWriteUnits)
+		assert.Equal(t, int64(72), estimate.ProvisionedReadUnits)
+		assert.InDelta(t, 10*3600*730*1.25/1e6+50*3600*730*0.25/1e6, estimate.OnDemand, 0.01)
+		assert.InDelta(t, 15*730*0.00065+72*730*0.00013, estimate.Provisioned, 0.01)
+		assert.Equal(t, dynamodb.BillingModeProvisioned, estimate.BillingMode())
+	})
+
+	t.Run("given spiky traffic", func(t *testing.T) {
+		usage := UsageFromCapacity(Capacity{ReadUnits: 1000, WriteUnits: 1000}, 24*time.Hour)
+		usage.PeakWriteUnitsPerSecond = 500
+		usage.StorageBytes = 2 << 30
+
+		estimate := EstimateCost(usage, &CostOptions{TargetUtilization: 0.5})
+
+		assert.Equal(t, int64(1000), estimate.ProvisionedWriteUnits)
+		assert.Equal(t, int64(1), estimate.ProvisionedReadUnits)
+		assert.InDelta(t, 0.5, estimate.Storage, 0.0001)
+		assert.Equal(t, dynamodb.BillingModePayPerRequest, estimate.BillingMode())
+	})
+
+	t.Run("given no period", func(t *testing.T) {
+		assert.Equal(t, CostEstimate{}, EstimateCost(Usage{ReadUnits: 1}, nil))
+	})
+}
@@ -0,0 +1,147 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// CounterBucket is the count of events in one of a RateCounter's buckets
+type CounterBucket struct {
+	Start time.Time
+	Count int64
+}
+
+// RateCounter counts events in time buckets shared between processes, for rates like the requests in the last 15
+// minutes across a fleet. Each bucket is an item in the counter's partition that increments are added to atomically,
+// and expires with the table's TTL, if it's enabled on Dyno_ExpiresAt.
+//
+// Every increment in a bucket writes the same item, so a counter takes up to the 1,000 writes a second DynamoDB allows
+// an item. The table must have a sort key.
+type RateCounter struct {
+	db   dynamodbiface.DynamoDBAPI
+	tn   string
+	pk   string
+	sk   string
+	name string
+
+	// Bucket is how long each bucket counts events for, e.g. a minute or an hour. Buckets start at multiples of it
+	// since the zero time, so it can't be changed once the counter has counts. Defaults to one minute.
+	Bucket time.Duration
+
+	// Retention is how long buckets are kept after they end. Defaults to 24 hours.
+	Retention time.Duration
+
+	// ConsistentRead reads buckets with strongly consistent reads. Defaults to true.
+	ConsistentRead bool
+}
+
+func NewRateCounter(db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey, name string) *RateCounter {
+	return &RateCounter{
+		db:             db,
+		tn:             tableName,
+		pk:             primaryKey,
+		sk:             sortKey,
+		name:           name,
+		Bucket:         time.Minute,
+		Retention:      24 * time.Hour,
+		ConsistentRead: true,
+	}
+}
+
+func (c *RateCounter) partition() *dynamodb.AttributeValue {
+	return Str(fmt.Sprintf("Dyno_Counter/%s", c.name))
+}
+
+func (c *RateCounter) bucketKey(start time.Time) *dynamodb.AttributeValue {
+	return Str(TimeSortKeyBound("Dyno_CounterBucket", start))
+}
+
+// Add counts n events now
+func (c *RateCounter) Add(ctx context.Context, n int64) error {
+	return c.AddAt(ctx, time.Now(), n)
+}
+
+// AddAt counts n events in the bucket for the time
+func (c *RateCounter) AddAt(ctx context.Context, at time.Time, n int64) error {
+	start := at.Truncate(c.Bucket)
+
+	_, err := c.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(c.tn),
+		Key: map[string]*dynamodb.AttributeValue{
+			c.pk: c.partition(),
+			c.sk: c.bucketKey(start),
+		},
+		UpdateExpression:         aws.String("ADD #c :n SET #x = :x"),
+		ExpressionAttributeNames: map[string]*string{"#c": aws.String("Dyno_Count"), "#x": aws.String("Dyno_ExpiresAt")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":n": Int(n),
+			":x": TimeValue(start.Add(c.Bucket+c.Retention), TimeUnixSeconds),
+		},
+	})
+	return err
+}
+
+// Buckets returns the buckets that overlap the time range, oldest first. Buckets without counts are left out.
+func (c *RateCounter) Buckets(ctx context.Context, from, to time.Time) ([]CounterBucket, error) {
+	buckets := []CounterBucket{}
+	if !to.After(from) {
+		return buckets, nil
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:                aws.String(c.tn),
+		KeyConditionExpression:   aws.String("#pk = :pk AND #sk BETWEEN :from AND :to"),
+		ExpressionAttributeNames: map[string]*string{"#pk": aws.String(c.pk), "#sk": aws.String(c.sk)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":pk":   c.partition(),
+			":from": c.bucketKey(from.Truncate(c.Bucket)),
+			":to":   c.bucketKey(to.Add(-time.Nanosecond)),
+		},
+		ConsistentRead: aws.Bool(c.ConsistentRead),
+	}
+
+	for {
+		result, err := c.db.QueryWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Items {
+			start, err := ParseTimeSortKey("Dyno_CounterBucket", GetString(item, c.sk, ""))
+			if err != nil {
+				return nil, err
+			}
+			buckets = append(buckets, CounterBucket{Start: start, Count: GetInt(item, "Dyno_Count", 0)})
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return buckets, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// Sum returns the number of events in the buckets that overlap the time range. The bucket from is in is counted in
+// full, so the sum can include up to a bucket's worth of events from before it.
+func (c *RateCounter) Sum(ctx context.Context, from, to time.Time) (int64, error) {
+	buckets, err := c.Buckets(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	var sum int64
+	for _, b := range buckets {
+		sum += b.Count
+	}
+	return sum, nil
+}
+
+// Last returns the number of events in the duration up to now, e.g. Last(ctx, 15*time.Minute)
+func (c *RateCounter) Last(ctx context.Context, d time.Duration) (int64, error) {
+	now := time.Now()
+	return c.Sum(ctx, now.Add(-d), now.Add(time.Nanosecond))
+}
@@ -0,0 +1,51 @@
+package dyno
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateCounter(t *testing.T) {
+	ctx := context.Background()
+	counter := NewRateCounter(testClient, tableName, "PK", "SK", "testing-counter")
+	start := time.Date(2020, 11, 15, 8, 0, 0, 0, time.UTC)
+
+	require.NoError(t, counter.AddAt(ctx, start, 1))
+	require.NoError(t, counter.AddAt(ctx, start.Add(30*time.Second), 2))
+	require.NoError(t, counter.AddAt(ctx, start.Add(5*time.Minute), 4))
+	require.NoError(t, counter.AddAt(ctx, start.Add(20*time.Minute), 8))
+
+	t.Run("given a range of buckets", func(t *testing.T) {
+		buckets, err := counter.Buckets(ctx, start, start.Add(10*time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, []CounterBucket{
+			{Start: start, Count: 3},
+			{Start: start.Add(5 * time.Minute), Count: 4},
+		}, buckets)
+	})
+
+	t.Run("given a range that starts partway through a bucket", func(t *testing.T) {
+		sum, err := counter.Sum(ctx, start.Add(45*time.Second), start.Add(20*time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), sum)
+
+		sum, err = counter.Sum(ctx, start.Add(time.Minute), start.Add(21*time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, int64(12), sum)
+	})
+
+	t.Run("given recent events", func(t *testing.T) {
+		recent := NewRateCounter(testClient, tableName, "PK", "SK", "testing-recent-counter")
+		recent.Bucket = time.Hour
+		require.NoError(t, recent.Add(ctx, 5))
+		require.NoError(t, recent.AddAt(ctx, time.Now().Add(-3*time.Hour), 10))
+
+		sum, err := recent.Last(ctx, 15*time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), sum)
+	})
+}
@@ -0,0 +1,97 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// PanicError is returned in place of a panic recovered while holding a lock, or while renewing one
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("recovered panic: %v", e.Value)
+}
+
+func recovered(v interface{}) *PanicError {
+	return &PanicError{Value: v, Stack: debug.Stack()}
+}
+
+// Do acquires the lock, calls fn while holding it, and releases it. The lease is renewed every third of the lease
+// until fn returns. The context passed to fn is canceled if the lock is lost, or runs past a hold budget that cancels.
+//
+// If fn panics, renewal stops and the lock is released before Do returns a *PanicError, so a failed critical section
+// can't keep a lock it will never release.
+func (l *Lock) Do(ctx context.Context, lease, duration time.Duration, fn func(context.Context) error) (err error) {
+	if err := l.AcquireContext(ctx, lease, duration); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	hold := l.Context()
+
+	var wg sync.WaitGroup
+	var renewPanic *PanicError
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer func() {
+			if v := recover(); v != nil {
+				renewPanic = recovered(v)
+				cancel()
+			}
+		}()
+
+		l.keepAlive(ctx, hold, lease, cancel)
+	}()
+
+	defer func() {
+		v := recover()
+
+		cancel()
+		wg.Wait()
+		releaseErr := l.Release()
+
+		switch {
+		case v != nil:
+			err = recovered(v)
+		case renewPanic != nil:
+			err = renewPanic
+		case err == nil:
+			err = releaseErr
+		}
+	}()
+
+	return fn(ctx)
+}
+
+// keepAlive renews the lease until the context is done, canceling it if the hold ends first
+func (l *Lock) keepAlive(ctx, hold context.Context, lease time.Duration, cancel context.CancelFunc) {
+	var renew <-chan time.Time
+	if lease > 0 {
+		ticker := time.NewTicker(lease / 3)
+		defer ticker.Stop()
+		renew = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hold.Done():
+			cancel()
+			return
+		case <-renew:
+		}
+
+		if err := l.Renew(ctx); err == ErrLockNotOwned {
+			cancel()
+			return
+		}
+	}
+}
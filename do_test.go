@@ -0,0 +1,92 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockDo(t *testing.T) {
+	ctx := context.Background()
+	held := func() bool {
+		info, err := DescribeLock(ctx, testClient, tableName, "PK", "SK", "testing-lock-do")
+		require.NoError(t, err)
+		return info != nil
+	}
+
+	t.Run("given a function that returns", func(t *testing.T) {
+		lock := NewLock(testClient, tableName, "PK", "SK", "testing-lock-do")
+		failure := errors.New("failed")
+
+		err := lock.Do(ctx, 30*time.Second, 0, func(ctx context.Context) error {
+			assert.True(t, held())
+			assert.NoError(t, ctx.Err())
+			return failure
+		})
+
+		assert.Equal(t, failure, err)
+		assert.False(t, held())
+	})
+
+	t.Run("given a function that panics", func(t *testing.T) {
+		lock := NewLock(testClient, tableName, "PK", "SK", "testing-lock-do")
+
+		err := lock.Do(ctx, 30*time.Second, 0, func(ctx context.Context) error {
+			panic("boom")
+		})
+
+		var panicked *PanicError
+		require.True(t, errors.As(err, &panicked))
+		assert.Equal(t, "boom", panicked.Value)
+		assert.Contains(t, string(panicked.Stack), "TestLockDo")
+		assert.False(t, held(), "the lock was released")
+	})
+
+	t.Run("given a panic while renewing", func(t *testing.T) {
+		lock := NewLock(testClient, tableName, "PK", "SK", "testing-lock-do")
+		lock.OnEvent(func(e LockEvent) {
+			if e.Type == LockRenewed {
+				panic("renewing")
+			}
+		})
+
+		err := lock.Do(ctx, 3*time.Second, 0, func(ctx context.Context) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+				return errors.New("the context wasn't canceled")
+			}
+		})
+
+		var panicked *PanicError
+		require.True(t, errors.As(err, &panicked))
+		assert.Equal(t, "renewing", panicked.Value)
+		assert.False(t, held())
+	})
+}
+
+func TestHeartbeatPanic(t *testing.T) {
+	lock := NewLock(testClient, tableName, "PK", "SK", "testing-heartbeat-panic")
+	require.NoError(t, lock.Acquire(30*time.Second))
+	defer lock.Release()
+	lock.OnEvent(func(e LockEvent) {
+		if e.Type == LockRenewed {
+			panic("renewing")
+		}
+	})
+
+	heartbeat := NewHeartbeat(testClient)
+	heartbeat.Interval = 10 * time.Millisecond
+	heartbeat.Add(lock)
+
+	err := heartbeat.Run(context.Background())
+
+	var panicked *PanicError
+	require.True(t, errors.As(err, &panicked))
+	assert.Equal(t, "renewing", panicked.Value)
+}
@@ -0,0 +1,307 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Document is a version of a document in a DocumentStore
+type Document struct {
+	ID        string
+	Version   int64
+	UpdatedAt time.Time
+
+	// Data is the document's attributes
+	Data map[string]*dynamodb.AttributeValue
+}
+
+// Unmarshal unmarshals the document's data into out
+func (d *Document) Unmarshal(out interface{}) error {
+	return dynamodbattribute.UnmarshalMap(d.Data, out)
+}
+
+// DocumentDiff is the difference between two versions of a document's top level attributes
+type DocumentDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// DocumentStore keeps every version of its documents. Each write adds an item for the new version, with the version
+// zero padded in its sort key, and moves the document's latest item to it in the same transaction. Writes are
+// conditional on the version they replace, so concurrent writers can't lose each other's changes.
+//
+// Old versions are pruned with the table's TTL, if it's enabled on Dyno_ExpiresAt. The table must have a sort key.
+type DocumentStore struct {
+	db   dynamodbiface.DynamoDBAPI
+	tn   string
+	pk   string
+	sk   string
+	name string
+
+	// Retention is how long a version is kept after it's replaced. Zero keeps versions until they're pruned.
+	Retention time.Duration
+}
+
+func NewDocumentStore(db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey, name string) *DocumentStore {
+	return &DocumentStore{
+		db:   db,
+		tn:   tableName,
+		pk:   primaryKey,
+		sk:   sortKey,
+		name: name,
+	}
+}
+
+func (s *DocumentStore) partition(id string) *dynamodb.AttributeValue {
+	return Str(fmt.Sprintf("Dyno_Document/%s/%s", s.name, id))
+}
+
+func (s *DocumentStore) latestKey(id string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		s.pk: s.partition(id),
+		s.sk: Str("Dyno_DocumentLatest"),
+	}
+}
+
+func (s *DocumentStore) versionKey(id string, version int64) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		s.pk: s.partition(id),
+		s.sk: Str(fmt.Sprintf("Dyno_DocumentVersion/%020d", version)),
+	}
+}
+
+// Put writes a new version of the document, with v marshaled by dynamodbattribute, and returns it. version is the
+// version being replaced, or 0 for a new document. ErrVersionMismatch is returned if the document is at a different
+// version.
+func (s *DocumentStore) Put(ctx context.Context, id string, v interface{}, version int64) (*Document, error) {
+	data, err := dynamodbattribute.MarshalMap(v)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{ID: id, Version: version + 1, UpdatedAt: time.Now(), Data: data}
+	item := func(key map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+		key["Dyno_Data"] = Map(data)
+		key["Dyno_Version"] = Int(doc.Version)
+		key["Dyno_UpdatedAt"] = TimeValue(doc.UpdatedAt, TimeUnixMillis)
+		return key
+	}
+
+	latest := &dynamodb.Put{
+		TableName:                aws.String(s.tn),
+		Item:                     item(s.latestKey(id)),
+		ConditionExpression:      aws.String("attribute_not_exists(#v)"),
+		ExpressionAttributeNames: map[string]*string{"#v": aws.String("Dyno_Version")},
+	}
+	if version > 0 {
+		latest.ConditionExpression = aws.String("#v = :v")
+		latest.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{":v": Int(version)}
+	}
+
+	items := []*dynamodb.TransactWriteItem{
+		{Put: latest},
+		{Put: &dynamodb.Put{TableName: aws.String(s.tn), Item: item(s.versionKey(id, doc.Version))}},
+	}
+	if version > 0 && s.Retention > 0 {
+		items = append(items, &dynamodb.TransactWriteItem{Update: &dynamodb.Update{
+			TableName:                 aws.String(s.tn),
+			Key:                       s.versionKey(id, version),
+			UpdateExpression:          aws.String("SET #x = :x"),
+			ConditionExpression:       aws.String("attribute_exists(#pk)"),
+			ExpressionAttributeNames:  map[string]*string{"#x": aws.String("Dyno_ExpiresAt"), "#pk": aws.String(s.pk)},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":x": TimeValue(doc.UpdatedAt.Add(s.Retention), TimeUnixSeconds)},
+		}})
+	}
+
+	_, err = s.db.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if len(items) == 3 && canceledOnlyBy(err, 2) {
+		// The replaced version was already pruned, so there's nothing to expire
+		_, err = s.db.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items[:2]})
+	}
+	if Classify(err) == ErrorClassConditionalCheckFailed {
+		return nil, ErrVersionMismatch
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// canceledOnlyBy returns true if a transaction was canceled only because the condition of the write at index failed
+func canceledOnlyBy(err error, index int) bool {
+	var canceled *dynamodb.TransactionCanceledException
+	if !errors.As(err, &canceled) || index >= len(canceled.CancellationReasons) {
+		return false
+	}
+	for i, reason := range canceled.CancellationReasons {
+		code := aws.StringValue(reason.Code)
+		if i == index && code != "ConditionalCheckFailed" {
+			return false
+		}
+		if i != index && code != "" && code != "None" {
+			return false
+		}
+	}
+	return true
+}
+
+// Get returns the latest version of the document. ErrItemNotFound is returned if it doesn't exist.
+func (s *DocumentStore) Get(ctx context.Context, id string) (*Document, error) {
+	return s.get(ctx, id, s.latestKey(id))
+}
+
+// GetVersion returns the version of the document. ErrItemNotFound is returned if the version doesn't exist, or it
+// was pruned.
+func (s *DocumentStore) GetVersion(ctx context.Context, id string, version int64) (*Document, error) {
+	return s.get(ctx, id, s.versionKey(id, version))
+}
+
+func (s *DocumentStore) get(ctx context.Context, id string, key map[string]*dynamodb.AttributeValue) (*Document, error) {
+	result, err := s.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.tn),
+		Key:            key,
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	doc, ok := s.document(id, result.Item)
+	if !ok {
+		return nil, ErrItemNotFound
+	}
+	return doc, nil
+}
+
+// document returns the document in the item, and false if there isn't one or it was pruned. TTL deletes expired items
+// some time after they expire, so they're checked here.
+func (s *DocumentStore) document(id string, item map[string]*dynamodb.AttributeValue) (*Document, bool) {
+	if item["Dyno_Version"] == nil {
+		return nil, false
+	}
+	if expiresAt, err := ParseTime(item["Dyno_ExpiresAt"], TimeUnixSeconds); err == nil && expiresAt.Before(time.Now()) {
+		return nil, false
+	}
+
+	doc := &Document{
+		ID:      id,
+		Version: GetInt(item, "Dyno_Version", 0),
+		Data:    GetMap(item, "Dyno_Data"),
+	}
+	doc.UpdatedAt, _ = ParseTime(item["Dyno_UpdatedAt"], TimeUnixMillis)
+	return doc, true
+}
+
+// History returns up to limit of the document's versions that haven't been pruned, newest first. A limit of 0 returns
+// every version.
+func (s *DocumentStore) History(ctx context.Context, id string, limit int) ([]*Document, error) {
+	docs := []*Document{}
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(s.tn),
+		KeyConditionExpression:    aws.String("#pk = :pk AND begins_with(#sk, :prefix)"),
+		ExpressionAttributeNames:  map[string]*string{"#pk": aws.String(s.pk), "#sk": aws.String(s.sk)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":pk": s.partition(id), ":prefix": Str("Dyno_DocumentVersion/")},
+		ScanIndexForward:          aws.Bool(false),
+		ConsistentRead:            aws.Bool(true),
+	}
+
+	for {
+		result, err := s.db.QueryWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Items {
+			if doc, ok := s.document(id, item); ok {
+				docs = append(docs, doc)
+				if len(docs) == limit {
+					return docs, nil
+				}
+			}
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return docs, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// Diff returns the top level attributes that differ between two versions of the document
+func (s *DocumentStore) Diff(ctx context.Context, id string, from, to int64) (*DocumentDiff, error) {
+	before, err := s.GetVersion(ctx, id, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.GetVersion(ctx, id, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &DocumentDiff{Added: []string{}, Removed: []string{}, Changed: []string{}}
+	for name, av := range after.Data {
+		old, ok := before.Data[name]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, name)
+		case !reflect.DeepEqual(old, av):
+			diff.Changed = append(diff.Changed, name)
+		}
+	}
+	for name := range before.Data {
+		if _, ok := after.Data[name]; !ok {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+
+	return diff, nil
+}
+
+// Prune expires every version of the document but the newest keep, and returns how many it expired. They can't be
+// read once they've expired, and TTL deletes them. Versions TTL deleted since they were listed aren't counted.
+func (s *DocumentStore) Prune(ctx context.Context, id string, keep int) (int, error) {
+	docs, err := s.History(ctx, id, 0)
+	if err != nil {
+		return 0, err
+	}
+	if keep < 1 {
+		keep = 1
+	}
+
+	if len(docs) <= keep {
+		return 0, nil
+	}
+
+	pruned := 0
+	now := time.Now()
+	for _, doc := range docs[keep:] {
+		_, err := s.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+			TableName:                 aws.String(s.tn),
+			Key:                       s.versionKey(id, doc.Version),
+			UpdateExpression:          aws.String("SET #x = :x"),
+			ConditionExpression:       aws.String("attribute_exists(#pk)"),
+			ExpressionAttributeNames:  map[string]*string{"#x": aws.String("Dyno_ExpiresAt"), "#pk": aws.String(s.pk)},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":x": TimeValue(now, TimeUnixSeconds)},
+		})
+		if Classify(err) == ErrorClassConditionalCheckFailed { // It's already gone
+			continue
+		}
+		if err != nil {
+			return pruned, err
+		}
+		pruned++
+	}
+	return pruned, nil
+}
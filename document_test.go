@@ -0,0 +1,171 @@
+package dyno
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// vanishingVersions deletes document versions just before they're expired, as though TTL deleted them first
+type vanishingVersions struct {
+	dynamodbiface.DynamoDBAPI
+}
+
+func (v vanishingVersions) UpdateItemWithContext(ctx aws.Context, input *dynamodb.UpdateItemInput, opts ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	if _, err := v.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{TableName: input.TableName, Key: input.Key}); err != nil {
+		return nil, err
+	}
+	return v.DynamoDBAPI.UpdateItemWithContext(ctx, input, opts...)
+}
+
+func (v vanishingVersions) TransactWriteItemsWithContext(ctx aws.Context, input *dynamodb.TransactWriteItemsInput, opts ...request.Option) (*dynamodb.TransactWriteItemsOutput, error) {
+	for _, item := range input.TransactItems {
+		if item.Update != nil {
+			if _, err := v.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{TableName: item.Update.TableName, Key: item.Update.Key}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v.DynamoDBAPI.TransactWriteItemsWithContext(ctx, input, opts...)
+}
+
+func TestDocumentStore(t *testing.T) {
+	ctx := context.Background()
+
+	type profile struct {
+		Name  string
+		Email string `dynamodbav:",omitempty"`
+		Plan  string `dynamodbav:",omitempty"`
+	}
+	s := NewDocumentStore(testClient, tableName, "PK", "SK", "testing-documents")
+	id := ksuid.New().String()
+
+	t.Run("given versions that were put", func(t *testing.T) {
+		_, err := s.Get(ctx, id)
+		assert.Equal(t, ErrItemNotFound, err)
+
+		doc, err := s.Put(ctx, id, profile{Name: "Maddie", Email: "m@example.com"}, 0)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), doc.Version)
+
+		_, err = s.Put(ctx, id, profile{Name: "Other"}, 0)
+		assert.Equal(t, ErrVersionMismatch, err)
+
+		doc, err = s.Put(ctx, id, profile{Name: "Maddie", Plan: "pro"}, 1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), doc.Version)
+
+		_, err = s.Put(ctx, id, profile{Name: "Stale"}, 1)
+		assert.Equal(t, ErrVersionMismatch, err)
+
+		latest, err := s.Get(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), latest.Version)
+		var p profile
+		require.NoError(t, latest.Unmarshal(&p))
+		assert.Equal(t, profile{Name: "Maddie", Plan: "pro"}, p)
+
+		first, err := s.GetVersion(ctx, id, 1)
+		require.NoError(t, err)
+		var old profile
+		require.NoError(t, first.Unmarshal(&old))
+		assert.Equal(t, profile{Name: "Maddie", Email: "m@example.com"}, old)
+
+		_, err = s.GetVersion(ctx, id, 3)
+		assert.Equal(t, ErrItemNotFound, err)
+	})
+
+	t.Run("given a diff between versions", func(t *testing.T) {
+		diff, err := s.Diff(ctx, id, 1, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Plan"}, diff.Added)
+		assert.Equal(t, []string{"Email"}, diff.Removed)
+		assert.Empty(t, diff.Changed)
+
+		_, err = s.Put(ctx, id, profile{Name: "Madison", Plan: "pro"}, 2)
+		require.NoError(t, err)
+		diff, err = s.Diff(ctx, id, 2, 3)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Name"}, diff.Changed)
+	})
+
+	t.Run("given the history is pruned", func(t *testing.T) {
+		history, err := s.History(ctx, id, 0)
+		require.NoError(t, err)
+		require.Len(t, history, 3)
+		assert.Equal(t, int64(3), history[0].Version)
+		assert.Equal(t, int64(1), history[2].Version)
+
+		history, err = s.History(ctx, id, 2)
+		require.NoError(t, err)
+		assert.Len(t, history, 2)
+
+		pruned, err := s.Prune(ctx, id, 2)
+		require.NoError(t, err)
+		assert.Equal(t, 1, pruned)
+
+		_, err = s.GetVersion(ctx, id, 1)
+		assert.Equal(t, ErrItemNotFound, err)
+		history, err = s.History(ctx, id, 0)
+		require.NoError(t, err)
+		assert.Len(t, history, 2)
+
+		latest, err := s.Get(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), latest.Version)
+	})
+
+	t.Run("given a retention", func(t *testing.T) {
+		r := NewDocumentStore(testClient, tableName, "PK", "SK", "testing-documents")
+		r.Retention = time.Second
+		id := ksuid.New().String()
+
+		_, err := r.Put(ctx, id, profile{Name: "First"}, 0)
+		require.NoError(t, err)
+		_, err = r.Put(ctx, id, profile{Name: "Second"}, 1)
+		require.NoError(t, err)
+
+		_, err = r.GetVersion(ctx, id, 1)
+		require.NoError(t, err)
+
+		time.Sleep(2 * time.Second)
+		_, err = r.GetVersion(ctx, id, 1)
+		assert.Equal(t, ErrItemNotFound, err)
+		_, err = r.GetVersion(ctx, id, 2)
+		assert.NoError(t, err)
+	})
+
+	t.Run("given versions that are deleted before they're expired", func(t *testing.T) {
+		v := NewDocumentStore(vanishingVersions{testClient}, tableName, "PK", "SK", "testing-documents")
+		v.Retention = time.Hour
+		id := ksuid.New().String()
+		exists := func(version int64) bool {
+			result, err := testClient.GetItem(&dynamodb.GetItemInput{TableName: aws.String(tableName), Key: v.versionKey(id, version)})
+			require.NoError(t, err)
+			return result.Item != nil
+		}
+
+		for version := int64(0); version < 3; version++ {
+			_, err := v.Put(ctx, id, profile{Name: "Maddie"}, version)
+			require.NoError(t, err)
+		}
+		assert.False(t, exists(1), "expiring a deleted version doesn't put it back")
+		assert.False(t, exists(2))
+
+		_, err := s.Put(ctx, id, profile{Name: "Maddie"}, 3)
+		require.NoError(t, err)
+
+		pruned, err := v.Prune(ctx, id, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 0, pruned)
+		assert.False(t, exists(3))
+	})
+}
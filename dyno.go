@@ -1,10 +1,30 @@
 package dyno
 
-import "github.com/aws/aws-sdk-go/aws/awserr"
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
 
 func isAwsErrorCode(err error, code string) bool {
-	if err, ok := err.(awserr.Error); ok {
-		return err.Code() == code
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		return aerr.Code() == code
 	}
 	return false
 }
+
+// sleepContext pauses for the duration or until the context is done, whichever happens first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
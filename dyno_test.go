@@ -1,19 +1,22 @@
 package dyno
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/maddiesch/dyno/dynotest"
 	"github.com/segmentio/ksuid"
 )
 
 var (
-	tableName  = fmt.Sprintf("dyno-test-table-%s", ksuid.New().String())
-	testClient = dynamodb.New(session.New(&aws.Config{Region: aws.String("us-east-1")}), aws.NewConfig().WithEndpoint("http://localhost:8000/"))
+	tableName   = fmt.Sprintf("dyno-test-table-%s", ksuid.New().String())
+	testClient  *dynamodb.DynamoDB
+	testStreams *dynamodbstreams.DynamoDBStreams
 )
 
 func TestMain(m *testing.M) {
@@ -38,17 +41,42 @@ func testRunner(m *testing.M) int {
 		},
 	}
 
-	_, err := testClient.CreateTable(create)
+	// Set DYNAMODB_ENDPOINT to use a running DynamoDB Local instead of starting a container
+	local, err := dynotest.StartLocal(context.Background(), &dynotest.LocalOptions{
+		Tables: []*dynamodb.CreateTableInput{create},
+	})
 	if err != nil {
 		panic(err)
 	}
+	defer local.Close()
 
-	defer func() {
-		delete := &dynamodb.DeleteTableInput{
-			TableName: aws.String(tableName),
-		}
-		testClient.DeleteTable(delete)
-	}()
+	testClient = local.Client()
+	testStreams = local.Streams()
 
 	return m.Run()
 }
+
+// createTestTable creates a new table with the same key schema as the shared test table. The returned func deletes it.
+func createTestTable(t *testing.T, prefix string) (string, func()) {
+	name := fmt.Sprintf("%s-%s", prefix, ksuid.New().String())
+
+	_, err := testClient.CreateTable(&dynamodb.CreateTableInput{
+		TableName:   aws.String(name),
+		BillingMode: aws.String("PAY_PER_REQUEST"),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("PK"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("SK"), AttributeType: aws.String("S")},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("PK"), KeyType: aws.String("HASH")},
+			{AttributeName: aws.String("SK"), KeyType: aws.String("RANGE")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return name, func() {
+		testClient.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String(name)})
+	}
+}
@@ -0,0 +1,304 @@
+package dynotest
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// ChaosPolicy decides which faults Chaos injects. Rates are probabilities between 0 and 1.
+type ChaosPolicy struct {
+	// Seed makes the injected faults repeatable
+	Seed int64
+
+	// Operations limits faults to the named operations, e.g. "PutItem". Empty means every operation.
+	Operations []string
+
+	// ThrottleRate fails requests with a ProvisionedThroughputExceededException before they are sent
+	ThrottleRate float64
+
+	// ConditionFailureRate fails conditional PutItem, UpdateItem, and DeleteItem requests with a
+	// ConditionalCheckFailedException before they are sent
+	ConditionFailureRate float64
+
+	// UnprocessedRate returns batch items as unprocessed instead of sending them
+	UnprocessedRate float64
+
+	// Latency is added to every request, plus a random duration up to LatencyJitter
+	Latency       time.Duration
+	LatencyJitter time.Duration
+}
+
+// Chaos wraps a DynamoDB client and injects faults according to its policy. Operations without faults are passed
+// through to the wrapped client.
+type Chaos struct {
+	dynamodbiface.DynamoDBAPI
+
+	policy   ChaosPolicy
+	mu       sync.Mutex
+	rand     *rand.Rand
+	injected int
+}
+
+func NewChaos(db dynamodbiface.DynamoDBAPI, policy ChaosPolicy) *Chaos {
+	return &Chaos{
+		DynamoDBAPI: db,
+		policy:      policy,
+		rand:        rand.New(rand.NewSource(policy.Seed)),
+	}
+}
+
+// Injected returns the number of faults injected so far
+func (c *Chaos) Injected() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.injected
+}
+
+func (c *Chaos) applies(operation string) bool {
+	if len(c.policy.Operations) == 0 {
+		return true
+	}
+	for _, name := range c.policy.Operations {
+		if name == operation {
+			return true
+		}
+	}
+	return false
+}
+
+// roll returns true with the probability rate, counting it as an injected fault
+func (c *Chaos) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.rand.Float64() >= rate {
+		return false
+	}
+	c.injected++
+	return true
+}
+
+// before adds latency and decides if the request fails before it is sent
+func (c *Chaos) before(ctx context.Context, operation string, conditional bool) error {
+	if !c.applies(operation) {
+		return nil
+	}
+
+	latency := c.policy.Latency
+	if c.policy.LatencyJitter > 0 {
+		c.mu.Lock()
+		latency += time.Duration(c.rand.Int63n(int64(c.policy.LatencyJitter)))
+		c.mu.Unlock()
+	}
+	if latency > 0 {
+		if err := sleepContext(ctx, latency); err != nil {
+			return err
+		}
+	}
+
+	if c.roll(c.policy.ThrottleRate) {
+		return &dynamodb.ProvisionedThroughputExceededException{
+			Message_: aws.String("The level of configured provisioned throughput for the table was exceeded"),
+		}
+	}
+	if conditional && c.roll(c.policy.ConditionFailureRate) {
+		return conditionalCheckFailed()
+	}
+
+	return nil
+}
+
+func (c *Chaos) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	return c.GetItemWithContext(context.Background(), input)
+}
+
+func (c *Chaos) GetItemWithContext(ctx aws.Context, input *dynamodb.GetItemInput, opts ...request.Option) (*dynamodb.GetItemOutput, error) {
+	if err := c.before(ctx, "GetItem", false); err != nil {
+		return nil, err
+	}
+	return c.DynamoDBAPI.GetItemWithContext(ctx, input, opts...)
+}
+
+func (c *Chaos) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	return c.PutItemWithContext(context.Background(), input)
+}
+
+func (c *Chaos) PutItemWithContext(ctx aws.Context, input *dynamodb.PutItemInput, opts ...request.Option) (*dynamodb.PutItemOutput, error) {
+	if err := c.before(ctx, "PutItem", input.ConditionExpression != nil); err != nil {
+		return nil, err
+	}
+	return c.DynamoDBAPI.PutItemWithContext(ctx, input, opts...)
+}
+
+func (c *Chaos) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	return c.UpdateItemWithContext(context.Background(), input)
+}
+
+func (c *Chaos) UpdateItemWithContext(ctx aws.Context, input *dynamodb.UpdateItemInput, opts ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	if err := c.before(ctx, "UpdateItem", input.ConditionExpression != nil); err != nil {
+		return nil, err
+	}
+	return c.DynamoDBAPI.UpdateItemWithContext(ctx, input, opts...)
+}
+
+func (c *Chaos) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	return c.DeleteItemWithContext(context.Background(), input)
+}
+
+func (c *Chaos) DeleteItemWithContext(ctx aws.Context, input *dynamodb.DeleteItemInput, opts ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	if err := c.before(ctx, "DeleteItem", input.ConditionExpression != nil); err != nil {
+		return nil, err
+	}
+	return c.DynamoDBAPI.DeleteItemWithContext(ctx, input, opts...)
+}
+
+func (c *Chaos) Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	return c.QueryWithContext(context.Background(), input)
+}
+
+func (c *Chaos) QueryWithContext(ctx aws.Context, input *dynamodb.QueryInput, opts ...request.Option) (*dynamodb.QueryOutput, error) {
+	if err := c.before(ctx, "Query", false); err != nil {
+		return nil, err
+	}
+	return c.DynamoDBAPI.QueryWithContext(ctx, input, opts...)
+}
+
+func (c *Chaos) Scan(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	return c.ScanWithContext(context.Background(), input)
+}
+
+func (c *Chaos) ScanWithContext(ctx aws.Context, input *dynamodb.ScanInput, opts ...request.Option) (*dynamodb.ScanOutput, error) {
+	if err := c.before(ctx, "Scan", false); err != nil {
+		return nil, err
+	}
+	return c.DynamoDBAPI.ScanWithContext(ctx, input, opts...)
+}
+
+func (c *Chaos) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	return c.TransactWriteItemsWithContext(context.Background(), input)
+}
+
+func (c *Chaos) TransactWriteItemsWithContext(ctx aws.Context, input *dynamodb.TransactWriteItemsInput, opts ...request.Option) (*dynamodb.TransactWriteItemsOutput, error) {
+	if err := c.before(ctx, "TransactWriteItems", false); err != nil {
+		return nil, err
+	}
+	return c.DynamoDBAPI.TransactWriteItemsWithContext(ctx, input, opts...)
+}
+
+func (c *Chaos) TransactGetItems(input *dynamodb.TransactGetItemsInput) (*dynamodb.TransactGetItemsOutput, error) {
+	return c.TransactGetItemsWithContext(context.Background(), input)
+}
+
+func (c *Chaos) TransactGetItemsWithContext(ctx aws.Context, input *dynamodb.TransactGetItemsInput, opts ...request.Option) (*dynamodb.TransactGetItemsOutput, error) {
+	if err := c.before(ctx, "TransactGetItems", false); err != nil {
+		return nil, err
+	}
+	return c.DynamoDBAPI.TransactGetItemsWithContext(ctx, input, opts...)
+}
+
+func (c *Chaos) BatchWriteItem(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+	return c.BatchWriteItemWithContext(context.Background(), input)
+}
+
+// BatchWriteItemWithContext holds back requests as unprocessed according to the policy and sends the rest
+func (c *Chaos) BatchWriteItemWithContext(ctx aws.Context, input *dynamodb.BatchWriteItemInput, opts ...request.Option) (*dynamodb.BatchWriteItemOutput, error) {
+	if err := c.before(ctx, "BatchWriteItem", false); err != nil {
+		return nil, err
+	}
+
+	send := map[string][]*dynamodb.WriteRequest{}
+	unprocessed := map[string][]*dynamodb.WriteRequest{}
+	for table, requests := range input.RequestItems {
+		for _, req := range requests {
+			if c.applies("BatchWriteItem") && c.roll(c.policy.UnprocessedRate) {
+				unprocessed[table] = append(unprocessed[table], req)
+			} else {
+				send[table] = append(send[table], req)
+			}
+		}
+	}
+
+	output := &dynamodb.BatchWriteItemOutput{UnprocessedItems: unprocessed}
+	if len(send) == 0 {
+		return output, nil
+	}
+
+	sent := *input
+	sent.RequestItems = send
+	result, err := c.DynamoDBAPI.BatchWriteItemWithContext(ctx, &sent, opts...)
+	if err != nil {
+		return nil, err
+	}
+	for table, requests := range result.UnprocessedItems {
+		unprocessed[table] = append(unprocessed[table], requests...)
+	}
+	output.ConsumedCapacity = result.ConsumedCapacity
+	output.ItemCollectionMetrics = result.ItemCollectionMetrics
+
+	return output, nil
+}
+
+func (c *Chaos) BatchGetItem(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	return c.BatchGetItemWithContext(context.Background(), input)
+}
+
+// BatchGetItemWithContext holds back keys as unprocessed according to the policy and reads the rest
+func (c *Chaos) BatchGetItemWithContext(ctx aws.Context, input *dynamodb.BatchGetItemInput, opts ...request.Option) (*dynamodb.BatchGetItemOutput, error) {
+	if err := c.before(ctx, "BatchGetItem", false); err != nil {
+		return nil, err
+	}
+
+	send := map[string]*dynamodb.KeysAndAttributes{}
+	unprocessed := map[string]*dynamodb.KeysAndAttributes{}
+	for table, keys := range input.RequestItems {
+		for _, key := range keys.Keys {
+			target := send
+			if c.applies("BatchGetItem") && c.roll(c.policy.UnprocessedRate) {
+				target = unprocessed
+			}
+			if target[table] == nil {
+				copied := *keys
+				copied.Keys = nil
+				target[table] = &copied
+			}
+			target[table].Keys = append(target[table].Keys, key)
+		}
+	}
+
+	output := &dynamodb.BatchGetItemOutput{
+		Responses:       map[string][]map[string]*dynamodb.AttributeValue{},
+		UnprocessedKeys: unprocessed,
+	}
+	if len(send) == 0 {
+		return output, nil
+	}
+
+	sent := *input
+	sent.RequestItems = send
+	result, err := c.DynamoDBAPI.BatchGetItemWithContext(ctx, &sent, opts...)
+	if err != nil {
+		return nil, err
+	}
+	for table, keys := range result.UnprocessedKeys {
+		if unprocessed[table] == nil {
+			unprocessed[table] = keys
+		} else {
+			unprocessed[table].Keys = append(unprocessed[table].Keys, keys.Keys...)
+		}
+	}
+	output.Responses = result.Responses
+	output.ConsumedCapacity = result.ConsumedCapacity
+
+	return output, nil
+}
@@ -0,0 +1,101 @@
+package dynotest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/maddiesch/dyno"
+	"github.com/maddiesch/dyno/dynotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChaos(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("given a throttle rate", func(t *testing.T) {
+		chaos := dynotest.NewChaos(newTable(t), dynotest.ChaosPolicy{ThrottleRate: 1})
+
+		_, err := chaos.PutItem(&dynamodb.PutItemInput{TableName: aws.String("Test"), Item: key("a", "1")})
+
+		assert.IsType(t, &dynamodb.ProvisionedThroughputExceededException{}, err)
+		assert.Equal(t, 1, chaos.Injected())
+	})
+
+	t.Run("given operations", func(t *testing.T) {
+		chaos := dynotest.NewChaos(newTable(t), dynotest.ChaosPolicy{ThrottleRate: 1, Operations: []string{"GetItem"}})
+
+		_, err := chaos.PutItem(&dynamodb.PutItemInput{TableName: aws.String("Test"), Item: key("a", "1")})
+		require.NoError(t, err)
+
+		_, err = chaos.GetItem(&dynamodb.GetItemInput{TableName: aws.String("Test"), Key: key("a", "1")})
+		assert.Error(t, err)
+	})
+
+	t.Run("given the same seed", func(t *testing.T) {
+		run := func() []bool {
+			chaos := dynotest.NewChaos(newTable(t), dynotest.ChaosPolicy{Seed: 42, ThrottleRate: 0.5})
+			results := []bool{}
+			for i := 0; i < 20; i++ {
+				_, err := chaos.GetItem(&dynamodb.GetItemInput{TableName: aws.String("Test"), Key: key("a", "1")})
+				results = append(results, err == nil)
+			}
+			return results
+		}
+
+		assert.Equal(t, run(), run())
+	})
+
+	t.Run("given unprocessed batch items the batch writer retries them", func(t *testing.T) {
+		db := newTable(t)
+		chaos := dynotest.NewChaos(db, dynotest.ChaosPolicy{Seed: 1, UnprocessedRate: 0.25})
+		writer := dyno.NewBatchWriter(chaos, "Test")
+
+		for i := 0; i < 10; i++ {
+			require.NoError(t, writer.Put(ctx, key("batch", fmt.Sprintf("%02d", i))))
+		}
+		require.NoError(t, writer.Flush(ctx))
+
+		out, err := db.Scan(&dynamodb.ScanInput{TableName: aws.String("Test")})
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), *out.Count)
+		assert.True(t, chaos.Injected() > 0)
+	})
+
+	t.Run("given throttling the batch writer retries the request", func(t *testing.T) {
+		db := newTable(t)
+		chaos := dynotest.NewChaos(db, dynotest.ChaosPolicy{Seed: 2, ThrottleRate: 0.5})
+		writer := dyno.NewBatchWriter(chaos, "Test")
+
+		for i := 0; i < 10; i++ {
+			require.NoError(t, writer.Put(ctx, key("throttled", fmt.Sprintf("%02d", i))))
+		}
+		require.NoError(t, writer.Flush(ctx))
+
+		out, err := db.Scan(&dynamodb.ScanInput{TableName: aws.String("Test")})
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), *out.Count)
+		assert.True(t, chaos.Injected() > 0)
+	})
+
+	t.Run("given failing conditions a free lock can not be acquired", func(t *testing.T) {
+		chaos := dynotest.NewChaos(newTable(t), dynotest.ChaosPolicy{ConditionFailureRate: 1})
+		lock := dyno.NewLock(chaos, "Test", "PK", "SK", "chaos-lock")
+
+		assert.Equal(t, dyno.ErrLockAcquireTimeout, lock.AcquireWithTimeout(time.Minute, 50*time.Millisecond))
+	})
+
+	t.Run("given latency", func(t *testing.T) {
+		chaos := dynotest.NewChaos(newTable(t), dynotest.ChaosPolicy{Latency: 20 * time.Millisecond})
+
+		start := time.Now()
+		_, err := chaos.GetItem(&dynamodb.GetItemInput{TableName: aws.String("Test"), Key: key("a", "1")})
+
+		require.NoError(t, err)
+		assert.True(t, time.Since(start) >= 20*time.Millisecond)
+	})
+}
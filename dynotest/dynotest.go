@@ -0,0 +1,504 @@
+// Package dynotest provides an in-memory implementation of the DynamoDB client for unit tests.
+//
+// DB implements the parts of dynamodbiface.DynamoDBAPI that dyno uses: table management, conditional item writes,
+// updates, queries, scans, batches, and transactions, including condition, update, key condition, filter, and
+// projection expressions. Calling any other method panics.
+package dynotest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Clock returns the current time. It is used to decide which items have expired.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// ManualClock is a Clock that only moves when it is told to
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to the time
+func (c *ManualClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// DB is an in-memory DynamoDB
+type DB struct {
+	dynamodbiface.DynamoDBAPI
+
+	// Clock decides when items with a TTL attribute have expired. Defaults to the system clock.
+	Clock Clock
+
+	// AutoExpire deletes expired items before every request. Without it, expired items stay readable until Expire is
+	// called, like they do in DynamoDB until the TTL process gets to them.
+	AutoExpire bool
+
+	mu     sync.Mutex
+	tables map[string]*table
+}
+
+func New() *DB {
+	return &DB{
+		Clock:  systemClock{},
+		tables: map[string]*table{},
+	}
+}
+
+type table struct {
+	desc     *dynamodb.TableDescription
+	hashKey  string
+	rangeKey string
+	types    map[string]string
+	indexes  map[string]*index
+	ttl      string
+	tags     map[string]string
+	items    map[string]item
+}
+
+type index struct {
+	name       string
+	hashKey    string
+	rangeKey   string
+	projection *dynamodb.Projection
+}
+
+func validationError(format string, args ...interface{}) error {
+	return awserr.New("ValidationException", fmt.Sprintf(format, args...), nil)
+}
+
+func resourceNotFound() error {
+	return &dynamodb.ResourceNotFoundException{Message_: aws.String("Requested resource not found")}
+}
+
+func conditionalCheckFailed() error {
+	return &dynamodb.ConditionalCheckFailedException{Message_: aws.String("The conditional request failed")}
+}
+
+func keySchema(elements []*dynamodb.KeySchemaElement) (string, string) {
+	var hash, rng string
+	for _, e := range elements {
+		if aws.StringValue(e.KeyType) == dynamodb.KeyTypeHash {
+			hash = aws.StringValue(e.AttributeName)
+		} else {
+			rng = aws.StringValue(e.AttributeName)
+		}
+	}
+	return hash, rng
+}
+
+func (db *DB) table(name *string) (*table, error) {
+	if db.AutoExpire {
+		db.expire()
+	}
+
+	t, ok := db.tables[aws.StringValue(name)]
+	if !ok {
+		return nil, resourceNotFound()
+	}
+	return t, nil
+}
+
+// Expire deletes every item whose TTL attribute is in the past, and returns how many were deleted
+func (db *DB) Expire() int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.expire()
+}
+
+func (db *DB) expire() int {
+	now := db.Clock.Now().Unix()
+	count := 0
+
+	for _, t := range db.tables {
+		if t.ttl == "" {
+			continue
+		}
+		for key, i := range t.items {
+			v, ok := i[t.ttl]
+			if !ok || v.N == nil {
+				continue
+			}
+			if compareNumbers(*v.N, fmt.Sprintf("%d", now)) < 0 {
+				delete(t.items, key)
+				count++
+			}
+		}
+	}
+
+	return count
+}
+
+func (db *DB) CreateTable(input *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+	return db.CreateTableWithContext(context.Background(), input)
+}
+
+func (db *DB) CreateTableWithContext(ctx aws.Context, input *dynamodb.CreateTableInput, opts ...request.Option) (*dynamodb.CreateTableOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	name := aws.StringValue(input.TableName)
+	if _, ok := db.tables[name]; ok {
+		return nil, &dynamodb.ResourceInUseException{Message_: aws.String(fmt.Sprintf("Table already exists: %s", name))}
+	}
+
+	t := &table{
+		types:   map[string]string{},
+		indexes: map[string]*index{},
+		tags:    map[string]string{},
+		items:   map[string]item{},
+	}
+	for _, tag := range input.Tags {
+		t.tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	t.hashKey, t.rangeKey = keySchema(input.KeySchema)
+	if t.hashKey == "" {
+		return nil, validationError("the key schema must have a HASH key")
+	}
+	for _, def := range input.AttributeDefinitions {
+		t.types[aws.StringValue(def.AttributeName)] = aws.StringValue(def.AttributeType)
+	}
+
+	billing := aws.StringValue(input.BillingMode)
+	if billing == "" {
+		billing = dynamodb.BillingModeProvisioned
+	}
+
+	t.desc = &dynamodb.TableDescription{
+		TableName:            aws.String(name),
+		TableArn:             aws.String(fmt.Sprintf("arn:aws:dynamodb:us-east-1:000000000000:table/%s", name)),
+		TableId:              aws.String(fmt.Sprintf("%x", len(db.tables)+1)),
+		TableStatus:          aws.String(dynamodb.TableStatusActive),
+		KeySchema:            input.KeySchema,
+		AttributeDefinitions: input.AttributeDefinitions,
+		CreationDateTime:     aws.Time(db.Clock.Now()),
+		BillingModeSummary:   &dynamodb.BillingModeSummary{BillingMode: aws.String(billing)},
+		StreamSpecification:  input.StreamSpecification,
+	}
+	if input.ProvisionedThroughput != nil {
+		t.desc.ProvisionedThroughput = &dynamodb.ProvisionedThroughputDescription{
+			ReadCapacityUnits:  input.ProvisionedThroughput.ReadCapacityUnits,
+			WriteCapacityUnits: input.ProvisionedThroughput.WriteCapacityUnits,
+		}
+	}
+
+	for _, gsi := range input.GlobalSecondaryIndexes {
+		t.addIndex(aws.StringValue(gsi.IndexName), gsi.KeySchema, gsi.Projection)
+	}
+	for _, lsi := range input.LocalSecondaryIndexes {
+		t.addIndex(aws.StringValue(lsi.IndexName), lsi.KeySchema, lsi.Projection)
+	}
+
+	db.tables[name] = t
+
+	return &dynamodb.CreateTableOutput{TableDescription: t.describe()}, nil
+}
+
+func (t *table) addIndex(name string, schema []*dynamodb.KeySchemaElement, projection *dynamodb.Projection) {
+	i := &index{name: name, projection: projection}
+	i.hashKey, i.rangeKey = keySchema(schema)
+	t.indexes[name] = i
+
+	if i.hashKey == t.hashKey {
+		t.desc.LocalSecondaryIndexes = append(t.desc.LocalSecondaryIndexes, &dynamodb.LocalSecondaryIndexDescription{
+			IndexName:  aws.String(name),
+			KeySchema:  schema,
+			Projection: projection,
+		})
+		return
+	}
+
+	t.desc.GlobalSecondaryIndexes = append(t.desc.GlobalSecondaryIndexes, &dynamodb.GlobalSecondaryIndexDescription{
+		IndexName:   aws.String(name),
+		IndexStatus: aws.String(dynamodb.IndexStatusActive),
+		Backfilling: aws.Bool(false),
+		KeySchema:   schema,
+		Projection:  projection,
+	})
+}
+
+func (t *table) describe() *dynamodb.TableDescription {
+	desc := *t.desc
+	desc.ItemCount = aws.Int64(int64(len(t.items)))
+
+	size := 0
+	for _, i := range t.items {
+		size += itemSize(i)
+	}
+	desc.TableSizeBytes = aws.Int64(int64(size))
+
+	return &desc
+}
+
+func (db *DB) DeleteTable(input *dynamodb.DeleteTableInput) (*dynamodb.DeleteTableOutput, error) {
+	return db.DeleteTableWithContext(context.Background(), input)
+}
+
+func (db *DB) DeleteTableWithContext(ctx aws.Context, input *dynamodb.DeleteTableInput, opts ...request.Option) (*dynamodb.DeleteTableOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, err := db.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	delete(db.tables, aws.StringValue(input.TableName))
+
+	desc := t.describe()
+	desc.TableStatus = aws.String(dynamodb.TableStatusDeleting)
+
+	return &dynamodb.DeleteTableOutput{TableDescription: desc}, nil
+}
+
+func (db *DB) DescribeTable(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+	return db.DescribeTableWithContext(context.Background(), input)
+}
+
+func (db *DB) DescribeTableWithContext(ctx aws.Context, input *dynamodb.DescribeTableInput, opts ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, err := db.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.DescribeTableOutput{Table: t.describe()}, nil
+}
+
+func (db *DB) ListTables(input *dynamodb.ListTablesInput) (*dynamodb.ListTablesOutput, error) {
+	return db.ListTablesWithContext(context.Background(), input)
+}
+
+func (db *DB) ListTablesWithContext(ctx aws.Context, input *dynamodb.ListTablesInput, opts ...request.Option) (*dynamodb.ListTablesOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	names := []string{}
+	for name := range db.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return &dynamodb.ListTablesOutput{TableNames: aws.StringSlice(names)}, nil
+}
+
+func (db *DB) UpdateTable(input *dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+	return db.UpdateTableWithContext(context.Background(), input)
+}
+
+// UpdateTableWithContext supports changing the billing mode and throughput and creating or deleting global secondary
+// indexes. New indexes are ACTIVE immediately.
+func (db *DB) UpdateTableWithContext(ctx aws.Context, input *dynamodb.UpdateTableInput, opts ...request.Option) (*dynamodb.UpdateTableOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, err := db.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, def := range input.AttributeDefinitions {
+		t.types[aws.StringValue(def.AttributeName)] = aws.StringValue(def.AttributeType)
+		t.desc.AttributeDefinitions = append(t.desc.AttributeDefinitions, def)
+	}
+	if input.BillingMode != nil {
+		t.desc.BillingModeSummary = &dynamodb.BillingModeSummary{BillingMode: input.BillingMode}
+	}
+	if input.ProvisionedThroughput != nil {
+		t.desc.ProvisionedThroughput = &dynamodb.ProvisionedThroughputDescription{
+			ReadCapacityUnits:  input.ProvisionedThroughput.ReadCapacityUnits,
+			WriteCapacityUnits: input.ProvisionedThroughput.WriteCapacityUnits,
+		}
+	}
+	if input.StreamSpecification != nil {
+		t.desc.StreamSpecification = input.StreamSpecification
+	}
+
+	for _, update := range input.GlobalSecondaryIndexUpdates {
+		switch {
+		case update.Create != nil:
+			name := aws.StringValue(update.Create.IndexName)
+			if _, ok := t.indexes[name]; ok {
+				return nil, validationError("attempting to create an index which already exists")
+			}
+			t.addIndex(name, update.Create.KeySchema, update.Create.Projection)
+		case update.Delete != nil:
+			name := aws.StringValue(update.Delete.IndexName)
+			if _, ok := t.indexes[name]; !ok {
+				return nil, resourceNotFound()
+			}
+			delete(t.indexes, name)
+
+			indexes := []*dynamodb.GlobalSecondaryIndexDescription{}
+			for _, gsi := range t.desc.GlobalSecondaryIndexes {
+				if aws.StringValue(gsi.IndexName) != name {
+					indexes = append(indexes, gsi)
+				}
+			}
+			t.desc.GlobalSecondaryIndexes = indexes
+		}
+	}
+
+	return &dynamodb.UpdateTableOutput{TableDescription: t.describe()}, nil
+}
+
+func (db *DB) UpdateTimeToLive(input *dynamodb.UpdateTimeToLiveInput) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	return db.UpdateTimeToLiveWithContext(context.Background(), input)
+}
+
+func (db *DB) UpdateTimeToLiveWithContext(ctx aws.Context, input *dynamodb.UpdateTimeToLiveInput, opts ...request.Option) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, err := db.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	if aws.BoolValue(input.TimeToLiveSpecification.Enabled) {
+		t.ttl = aws.StringValue(input.TimeToLiveSpecification.AttributeName)
+	} else {
+		t.ttl = ""
+	}
+
+	return &dynamodb.UpdateTimeToLiveOutput{TimeToLiveSpecification: input.TimeToLiveSpecification}, nil
+}
+
+func (db *DB) DescribeTimeToLive(input *dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	return db.DescribeTimeToLiveWithContext(context.Background(), input)
+}
+
+func (db *DB) DescribeTimeToLiveWithContext(ctx aws.Context, input *dynamodb.DescribeTimeToLiveInput, opts ...request.Option) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, err := db.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	desc := &dynamodb.TimeToLiveDescription{TimeToLiveStatus: aws.String(dynamodb.TimeToLiveStatusDisabled)}
+	if t.ttl != "" {
+		desc.TimeToLiveStatus = aws.String(dynamodb.TimeToLiveStatusEnabled)
+		desc.AttributeName = aws.String(t.ttl)
+	}
+
+	return &dynamodb.DescribeTimeToLiveOutput{TimeToLiveDescription: desc}, nil
+}
+
+// tableByArn returns the table with the ARN, for the tagging operations
+func (db *DB) tableByArn(arn *string) (*table, error) {
+	for _, t := range db.tables {
+		if aws.StringValue(t.desc.TableArn) == aws.StringValue(arn) {
+			return t, nil
+		}
+	}
+	return nil, resourceNotFound()
+}
+
+func (db *DB) TagResource(input *dynamodb.TagResourceInput) (*dynamodb.TagResourceOutput, error) {
+	return db.TagResourceWithContext(context.Background(), input)
+}
+
+func (db *DB) TagResourceWithContext(ctx aws.Context, input *dynamodb.TagResourceInput, opts ...request.Option) (*dynamodb.TagResourceOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, err := db.tableByArn(input.ResourceArn)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range input.Tags {
+		t.tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	return &dynamodb.TagResourceOutput{}, nil
+}
+
+func (db *DB) UntagResource(input *dynamodb.UntagResourceInput) (*dynamodb.UntagResourceOutput, error) {
+	return db.UntagResourceWithContext(context.Background(), input)
+}
+
+func (db *DB) UntagResourceWithContext(ctx aws.Context, input *dynamodb.UntagResourceInput, opts ...request.Option) (*dynamodb.UntagResourceOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, err := db.tableByArn(input.ResourceArn)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range input.TagKeys {
+		delete(t.tags, aws.StringValue(key))
+	}
+
+	return &dynamodb.UntagResourceOutput{}, nil
+}
+
+func (db *DB) ListTagsOfResource(input *dynamodb.ListTagsOfResourceInput) (*dynamodb.ListTagsOfResourceOutput, error) {
+	return db.ListTagsOfResourceWithContext(context.Background(), input)
+}
+
+// ListTagsOfResourceWithContext returns every tag in one page, sorted by key
+func (db *DB) ListTagsOfResourceWithContext(ctx aws.Context, input *dynamodb.ListTagsOfResourceInput, opts ...request.Option) (*dynamodb.ListTagsOfResourceOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, err := db.tableByArn(input.ResourceArn)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	for key := range t.tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tags := []*dynamodb.Tag{}
+	for _, key := range keys {
+		tags = append(tags, &dynamodb.Tag{Key: aws.String(key), Value: aws.String(t.tags[key])})
+	}
+
+	return &dynamodb.ListTagsOfResourceOutput{Tags: tags}, nil
+}
@@ -0,0 +1,367 @@
+package dynotest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/maddiesch/dyno"
+	"github.com/maddiesch/dyno/dynotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTable(t *testing.T) *dynotest.DB {
+	db := dynotest.New()
+
+	_, err := db.CreateTable(&dynamodb.CreateTableInput{
+		TableName: aws.String("Test"),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("PK"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("SK"), AttributeType: aws.String("S")},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("PK"), KeyType: aws.String("HASH")},
+			{AttributeName: aws.String("SK"), KeyType: aws.String("RANGE")},
+		},
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+	})
+	require.NoError(t, err)
+
+	return db
+}
+
+func key(pk, sk string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"PK": {S: aws.String(pk)},
+		"SK": {S: aws.String(sk)},
+	}
+}
+
+func TestPutItem(t *testing.T) {
+	db := newTable(t)
+
+	input := &dynamodb.PutItemInput{
+		TableName:                aws.String("Test"),
+		Item:                     key("a", "1"),
+		ConditionExpression:      aws.String("attribute_not_exists(#pk)"),
+		ExpressionAttributeNames: map[string]*string{"#pk": aws.String("PK")},
+	}
+
+	t.Run("given a new item", func(t *testing.T) {
+		_, err := db.PutItem(input)
+
+		require.NoError(t, err)
+	})
+
+	t.Run("given an existing item", func(t *testing.T) {
+		_, err := db.PutItem(input)
+
+		require.Error(t, err)
+		assert.IsType(t, &dynamodb.ConditionalCheckFailedException{}, err)
+	})
+
+	t.Run("given a missing key", func(t *testing.T) {
+		_, err := db.PutItem(&dynamodb.PutItemInput{
+			TableName: aws.String("Test"),
+			Item:      map[string]*dynamodb.AttributeValue{"PK": {S: aws.String("a")}},
+		})
+
+		require.Error(t, err)
+	})
+
+	t.Run("given a missing table", func(t *testing.T) {
+		_, err := db.PutItem(&dynamodb.PutItemInput{TableName: aws.String("Missing"), Item: key("a", "1")})
+
+		require.Error(t, err)
+		assert.IsType(t, &dynamodb.ResourceNotFoundException{}, err)
+	})
+}
+
+func TestUpdateItem(t *testing.T) {
+	db := newTable(t)
+
+	update := func(expression string, values map[string]*dynamodb.AttributeValue) (*dynamodb.UpdateItemOutput, error) {
+		return db.UpdateItem(&dynamodb.UpdateItemInput{
+			TableName:                 aws.String("Test"),
+			Key:                       key("a", "1"),
+			UpdateExpression:          aws.String(expression),
+			ExpressionAttributeValues: values,
+			ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+		})
+	}
+
+	t.Run("given a missing item it creates the item", func(t *testing.T) {
+		out, err := update("SET Tags = :tags, Total = if_not_exists(Total, :zero) + :one", map[string]*dynamodb.AttributeValue{
+			":tags": {L: []*dynamodb.AttributeValue{{S: aws.String("x")}}},
+			":zero": {N: aws.String("0")},
+			":one":  {N: aws.String("1")},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "1", *out.Attributes["Total"].N)
+		assert.Equal(t, "a", *out.Attributes["PK"].S)
+	})
+
+	t.Run("given list_append and ADD", func(t *testing.T) {
+		out, err := update("SET Tags = list_append(Tags, :more) ADD Total :two, Colors :colors", map[string]*dynamodb.AttributeValue{
+			":more":   {L: []*dynamodb.AttributeValue{{S: aws.String("y")}}},
+			":two":    {N: aws.String("2")},
+			":colors": {SS: aws.StringSlice([]string{"red", "blue"})},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "3", *out.Attributes["Total"].N)
+		assert.Len(t, out.Attributes["Tags"].L, 2)
+		assert.Len(t, out.Attributes["Colors"].SS, 2)
+	})
+
+	t.Run("given REMOVE and DELETE", func(t *testing.T) {
+		out, err := update("REMOVE Tags[0] DELETE Colors :red", map[string]*dynamodb.AttributeValue{
+			":red": {SS: aws.StringSlice([]string{"red"})},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "y", *out.Attributes["Tags"].L[0].S)
+		assert.Equal(t, []string{"blue"}, aws.StringValueSlice(out.Attributes["Colors"].SS))
+	})
+
+	t.Run("given a failing condition", func(t *testing.T) {
+		_, err := db.UpdateItem(&dynamodb.UpdateItemInput{
+			TableName:                 aws.String("Test"),
+			Key:                       key("a", "1"),
+			UpdateExpression:          aws.String("SET Total = :one"),
+			ConditionExpression:       aws.String("Total < :one"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":one": {N: aws.String("1")}},
+		})
+
+		assert.IsType(t, &dynamodb.ConditionalCheckFailedException{}, err)
+	})
+
+	t.Run("given an update to the key", func(t *testing.T) {
+		_, err := update("SET PK = :pk", map[string]*dynamodb.AttributeValue{":pk": {S: aws.String("b")}})
+
+		require.Error(t, err)
+	})
+}
+
+func TestQuery(t *testing.T) {
+	db := newTable(t)
+
+	for _, sk := range []string{"1", "2", "3", "4"} {
+		item := key("a", sk)
+		item["Even"] = &dynamodb.AttributeValue{BOOL: aws.Bool(sk == "2" || sk == "4")}
+		_, err := db.PutItem(&dynamodb.PutItemInput{TableName: aws.String("Test"), Item: item})
+		require.NoError(t, err)
+	}
+	_, err := db.PutItem(&dynamodb.PutItemInput{TableName: aws.String("Test"), Item: key("b", "1")})
+	require.NoError(t, err)
+
+	query := func(input *dynamodb.QueryInput) *dynamodb.QueryOutput {
+		input.TableName = aws.String("Test")
+		input.KeyConditionExpression = aws.String("PK = :pk AND SK > :sk")
+		input.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+			":pk": {S: aws.String("a")},
+			":sk": {S: aws.String("1")},
+		}
+		if input.FilterExpression != nil {
+			input.ExpressionAttributeValues[":true"] = &dynamodb.AttributeValue{BOOL: aws.Bool(true)}
+		}
+		out, err := db.Query(input)
+		require.NoError(t, err)
+		return out
+	}
+
+	t.Run("given a key condition", func(t *testing.T) {
+		out := query(&dynamodb.QueryInput{})
+
+		require.Len(t, out.Items, 3)
+		assert.Equal(t, "2", *out.Items[0]["SK"].S)
+	})
+
+	t.Run("given a reverse query with a limit", func(t *testing.T) {
+		out := query(&dynamodb.QueryInput{ScanIndexForward: aws.Bool(false), Limit: aws.Int64(2)})
+
+		require.Len(t, out.Items, 2)
+		assert.Equal(t, "4", *out.Items[0]["SK"].S)
+		require.NotNil(t, out.LastEvaluatedKey)
+
+		out = query(&dynamodb.QueryInput{ScanIndexForward: aws.Bool(false), ExclusiveStartKey: out.LastEvaluatedKey})
+
+		require.Len(t, out.Items, 1)
+		assert.Equal(t, "2", *out.Items[0]["SK"].S)
+		assert.Nil(t, out.LastEvaluatedKey)
+	})
+
+	t.Run("given a filter", func(t *testing.T) {
+		out := query(&dynamodb.QueryInput{FilterExpression: aws.String("Even = :true"), Select: aws.String(dynamodb.SelectCount)})
+
+		assert.Equal(t, int64(2), *out.Count)
+		assert.Equal(t, int64(3), *out.ScannedCount)
+		assert.Empty(t, out.Items)
+	})
+}
+
+func TestScan(t *testing.T) {
+	db := newTable(t)
+
+	for _, pk := range []string{"a", "b", "c", "d", "e", "f"} {
+		_, err := db.PutItem(&dynamodb.PutItemInput{TableName: aws.String("Test"), Item: key(pk, "1")})
+		require.NoError(t, err)
+	}
+
+	t.Run("given segments every item is returned once", func(t *testing.T) {
+		seen := map[string]int{}
+		for segment := int64(0); segment < 3; segment++ {
+			out, err := db.Scan(&dynamodb.ScanInput{
+				TableName:     aws.String("Test"),
+				Segment:       aws.Int64(segment),
+				TotalSegments: aws.Int64(3),
+			})
+			require.NoError(t, err)
+			for _, item := range out.Items {
+				seen[*item["PK"].S]++
+			}
+		}
+
+		assert.Len(t, seen, 6)
+		for _, count := range seen {
+			assert.Equal(t, 1, count)
+		}
+	})
+}
+
+func TestTransactWriteItems(t *testing.T) {
+	db := newTable(t)
+
+	_, err := db.PutItem(&dynamodb.PutItemInput{TableName: aws.String("Test"), Item: key("a", "1")})
+	require.NoError(t, err)
+
+	t.Run("given a failing condition nothing is written", func(t *testing.T) {
+		_, err := db.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+			TransactItems: []*dynamodb.TransactWriteItem{
+				{Put: &dynamodb.Put{TableName: aws.String("Test"), Item: key("b", "1")}},
+				{Put: &dynamodb.Put{
+					TableName:           aws.String("Test"),
+					Item:                key("a", "1"),
+					ConditionExpression: aws.String("attribute_not_exists(PK)"),
+				}},
+			},
+		})
+
+		require.Error(t, err)
+		canceled, ok := err.(*dynamodb.TransactionCanceledException)
+		require.True(t, ok)
+		assert.Equal(t, "None", *canceled.CancellationReasons[0].Code)
+		assert.Equal(t, "ConditionalCheckFailed", *canceled.CancellationReasons[1].Code)
+
+		out, err := db.GetItem(&dynamodb.GetItemInput{TableName: aws.String("Test"), Key: key("b", "1")})
+		require.NoError(t, err)
+		assert.Nil(t, out.Item)
+	})
+}
+
+func TestTimeToLive(t *testing.T) {
+	db := newTable(t)
+	clock := dynotest.NewManualClock(time.Unix(1000, 0))
+	db.Clock = clock
+
+	_, err := db.UpdateTimeToLive(&dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String("Test"),
+		TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+			AttributeName: aws.String("ExpiresAt"),
+			Enabled:       aws.Bool(true),
+		},
+	})
+	require.NoError(t, err)
+
+	item := key("a", "1")
+	item["ExpiresAt"] = &dynamodb.AttributeValue{N: aws.String("1010")}
+	_, err = db.PutItem(&dynamodb.PutItemInput{TableName: aws.String("Test"), Item: item})
+	require.NoError(t, err)
+
+	t.Run("given an item that has not expired", func(t *testing.T) {
+		assert.Equal(t, 0, db.Expire())
+	})
+
+	t.Run("given an item that has expired", func(t *testing.T) {
+		clock.Advance(time.Minute)
+
+		assert.Equal(t, 1, db.Expire())
+
+		out, err := db.GetItem(&dynamodb.GetItemInput{TableName: aws.String("Test"), Key: key("a", "1")})
+		require.NoError(t, err)
+		assert.Nil(t, out.Item)
+	})
+}
+
+func TestLock(t *testing.T) {
+	db := newTable(t)
+
+	t.Run("given a held lock", func(t *testing.T) {
+		first := dyno.NewLock(db, "Test", "PK", "SK", "test-lock")
+		second := dyno.NewLock(db, "Test", "PK", "SK", "test-lock")
+
+		require.NoError(t, first.Acquire(time.Minute))
+
+		assert.Equal(t, dyno.ErrLockAcquireTimeout, second.AcquireWithTimeout(time.Minute, 50*time.Millisecond))
+
+		require.NoError(t, first.Release())
+		require.NoError(t, second.Acquire(time.Minute))
+		require.NoError(t, second.Release())
+	})
+
+	t.Run("given a lock that expired by TTL", func(t *testing.T) {
+		clock := dynotest.NewManualClock(time.Now())
+		db.Clock = clock
+		db.AutoExpire = true
+		_, err := db.UpdateTimeToLive(&dynamodb.UpdateTimeToLiveInput{
+			TableName: aws.String("Test"),
+			TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+				AttributeName: aws.String("ExpiresAt"),
+				Enabled:       aws.Bool(true),
+			},
+		})
+		require.NoError(t, err)
+
+		first := dyno.NewLock(db, "Test", "PK", "SK", "expiring-lock")
+		first.Expiration("ExpiresAt", clock.Now().Add(time.Minute))
+		second := dyno.NewLock(db, "Test", "PK", "SK", "expiring-lock")
+
+		require.NoError(t, first.Acquire(time.Hour))
+		assert.Equal(t, dyno.ErrLockAcquireTimeout, second.Acquire(time.Hour))
+
+		clock.Advance(2 * time.Minute)
+
+		require.NoError(t, second.Acquire(time.Hour))
+	})
+}
+
+func TestTagResource(t *testing.T) {
+	db := newTable(t)
+
+	desc, err := db.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String("Test")})
+	require.NoError(t, err)
+	arn := desc.Table.TableArn
+
+	_, err = db.TagResource(&dynamodb.TagResourceInput{
+		ResourceArn: arn,
+		Tags: []*dynamodb.Tag{
+			{Key: aws.String("team"), Value: aws.String("platform")},
+			{Key: aws.String("environment"), Value: aws.String("test")},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = db.UntagResource(&dynamodb.UntagResourceInput{ResourceArn: arn, TagKeys: aws.StringSlice([]string{"team"})})
+	require.NoError(t, err)
+
+	out, err := db.ListTagsOfResource(&dynamodb.ListTagsOfResourceInput{ResourceArn: arn})
+	require.NoError(t, err)
+	require.Len(t, out.Tags, 1)
+	assert.Equal(t, "environment", *out.Tags[0].Key)
+
+	_, err = db.ListTagsOfResource(&dynamodb.ListTagsOfResourceInput{ResourceArn: aws.String("arn:aws:dynamodb:us-east-1:000000000000:table/Missing")})
+	assert.Equal(t, dyno.ErrorClassNotFound, dyno.Classify(err))
+}
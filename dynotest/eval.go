@@ -0,0 +1,733 @@
+package dynotest
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+type item = map[string]*dynamodb.AttributeValue
+
+// env is everything an expression is evaluated against
+type env struct {
+	item   item
+	values map[string]*dynamodb.AttributeValue
+}
+
+func (e env) resolve(o operand) (*dynamodb.AttributeValue, error) {
+	switch o := o.(type) {
+	case pathOperand:
+		return lookup(e.item, o.path), nil
+	case valueOperand:
+		v, ok := e.values[o.name]
+		if !ok {
+			return nil, fmt.Errorf("expression attribute value %s is not defined", o.name)
+		}
+		return v, nil
+	case functionOperand:
+		return e.function(o)
+	case arithmeticOperand:
+		left, err := e.resolve(o.left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := e.resolve(o.right)
+		if err != nil {
+			return nil, err
+		}
+		if left == nil || right == nil || left.N == nil || right.N == nil {
+			return nil, fmt.Errorf("an operand in the update expression has an incorrect data type")
+		}
+		if o.op == "+" {
+			return &dynamodb.AttributeValue{N: aws.String(addNumbers(*left.N, *right.N, 1))}, nil
+		}
+		return &dynamodb.AttributeValue{N: aws.String(addNumbers(*left.N, *right.N, -1))}, nil
+	default:
+		return nil, fmt.Errorf("unsupported operand %T", o)
+	}
+}
+
+func (e env) function(fn functionOperand) (*dynamodb.AttributeValue, error) {
+	switch fn.name {
+	case "size":
+		if len(fn.args) != 1 {
+			return nil, fmt.Errorf("size takes one argument")
+		}
+		v, err := e.resolve(fn.args[0])
+		if err != nil || v == nil {
+			return nil, err
+		}
+		return &dynamodb.AttributeValue{N: aws.String(fmt.Sprintf("%d", sizeOf(v)))}, nil
+	case "if_not_exists":
+		if len(fn.args) != 2 {
+			return nil, fmt.Errorf("if_not_exists takes two arguments")
+		}
+		v, err := e.resolve(fn.args[0])
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			return v, nil
+		}
+		return e.resolve(fn.args[1])
+	case "list_append":
+		if len(fn.args) != 2 {
+			return nil, fmt.Errorf("list_append takes two arguments")
+		}
+		a, err := e.resolve(fn.args[0])
+		if err != nil {
+			return nil, err
+		}
+		b, err := e.resolve(fn.args[1])
+		if err != nil {
+			return nil, err
+		}
+		if a == nil || b == nil || a.L == nil || b.L == nil {
+			return nil, fmt.Errorf("list_append requires two lists")
+		}
+		return &dynamodb.AttributeValue{L: append(append([]*dynamodb.AttributeValue{}, a.L...), b.L...)}, nil
+	default:
+		return nil, fmt.Errorf("invalid function name %s", fn.name)
+	}
+}
+
+func (e env) test(c condition) (bool, error) {
+	switch c := c.(type) {
+	case andCondition:
+		left, err := e.test(c.left)
+		if err != nil || !left {
+			return false, err
+		}
+		return e.test(c.right)
+	case orCondition:
+		left, err := e.test(c.left)
+		if err != nil || left {
+			return left, err
+		}
+		return e.test(c.right)
+	case notCondition:
+		v, err := e.test(c.condition)
+		return !v, err
+	case compareCondition:
+		left, err := e.resolve(c.left)
+		if err != nil {
+			return false, err
+		}
+		right, err := e.resolve(c.right)
+		if err != nil {
+			return false, err
+		}
+		return compareOp(c.op, left, right), nil
+	case betweenCondition:
+		v, err := e.resolve(c.value)
+		if err != nil {
+			return false, err
+		}
+		low, err := e.resolve(c.low)
+		if err != nil {
+			return false, err
+		}
+		high, err := e.resolve(c.high)
+		if err != nil {
+			return false, err
+		}
+		return compareOp(">=", v, low) && compareOp("<=", v, high), nil
+	case inCondition:
+		v, err := e.resolve(c.value)
+		if err != nil {
+			return false, err
+		}
+		for _, o := range c.list {
+			candidate, err := e.resolve(o)
+			if err != nil {
+				return false, err
+			}
+			if compareOp("=", v, candidate) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case functionCondition:
+		return e.predicate(c)
+	default:
+		return false, fmt.Errorf("unsupported condition %T", c)
+	}
+}
+
+func (e env) predicate(c functionCondition) (bool, error) {
+	args := make([]*dynamodb.AttributeValue, len(c.args))
+	for i, arg := range c.args {
+		v, err := e.resolve(arg)
+		if err != nil {
+			return false, err
+		}
+		args[i] = v
+	}
+
+	switch c.name {
+	case "attribute_exists":
+		return args[0] != nil, nil
+	case "attribute_not_exists":
+		return args[0] == nil, nil
+	case "attribute_type":
+		return args[0] != nil && args[1] != nil && typeOf(args[0]) == aws.StringValue(args[1].S), nil
+	case "begins_with":
+		if len(args) != 2 || args[0] == nil || args[1] == nil {
+			return false, nil
+		}
+		switch {
+		case args[0].S != nil && args[1].S != nil:
+			return strings.HasPrefix(*args[0].S, *args[1].S), nil
+		case args[0].B != nil && args[1].B != nil:
+			return bytes.HasPrefix(args[0].B, args[1].B), nil
+		}
+		return false, nil
+	case "contains":
+		if len(args) != 2 || args[0] == nil || args[1] == nil {
+			return false, nil
+		}
+		return contains(args[0], args[1]), nil
+	default:
+		return false, fmt.Errorf("invalid function name %s", c.name)
+	}
+}
+
+func contains(haystack, needle *dynamodb.AttributeValue) bool {
+	switch {
+	case haystack.S != nil && needle.S != nil:
+		return strings.Contains(*haystack.S, *needle.S)
+	case haystack.B != nil && needle.B != nil:
+		return bytes.Contains(haystack.B, needle.B)
+	case haystack.SS != nil && needle.S != nil:
+		for _, s := range haystack.SS {
+			if *s == *needle.S {
+				return true
+			}
+		}
+	case haystack.NS != nil && needle.N != nil:
+		for _, n := range haystack.NS {
+			if compareNumbers(*n, *needle.N) == 0 {
+				return true
+			}
+		}
+	case haystack.BS != nil && needle.B != nil:
+		for _, b := range haystack.BS {
+			if bytes.Equal(b, needle.B) {
+				return true
+			}
+		}
+	case haystack.L != nil:
+		for _, v := range haystack.L {
+			if equal(v, needle) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func compareOp(op string, left, right *dynamodb.AttributeValue) bool {
+	if op == "=" {
+		return left != nil && right != nil && equal(left, right)
+	}
+	if op == "<>" {
+		return left == nil || right == nil || !equal(left, right)
+	}
+
+	if left == nil || right == nil {
+		return false
+	}
+	cmp, ok := compare(left, right)
+	if !ok {
+		return false
+	}
+
+	switch op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// compare orders two scalar values of the same type
+func compare(a, b *dynamodb.AttributeValue) (int, bool) {
+	switch {
+	case a.S != nil && b.S != nil:
+		return strings.Compare(*a.S, *b.S), true
+	case a.N != nil && b.N != nil:
+		return compareNumbers(*a.N, *b.N), true
+	case a.B != nil && b.B != nil:
+		return bytes.Compare(a.B, b.B), true
+	}
+	return 0, false
+}
+
+func equal(a, b *dynamodb.AttributeValue) bool {
+	if typeOf(a) != typeOf(b) {
+		return false
+	}
+
+	switch typeOf(a) {
+	case "S", "N", "B":
+		cmp, _ := compare(a, b)
+		return cmp == 0
+	case "BOOL":
+		return *a.BOOL == *b.BOOL
+	case "NULL":
+		return true
+	case "SS":
+		return sameStrings(aws.StringValueSlice(a.SS), aws.StringValueSlice(b.SS))
+	case "NS":
+		return sameStrings(normalizeNumbers(a.NS), normalizeNumbers(b.NS))
+	case "BS":
+		as := make([]string, len(a.BS))
+		for i, v := range a.BS {
+			as[i] = string(v)
+		}
+		bs := make([]string, len(b.BS))
+		for i, v := range b.BS {
+			bs[i] = string(v)
+		}
+		return sameStrings(as, bs)
+	case "L":
+		if len(a.L) != len(b.L) {
+			return false
+		}
+		for i := range a.L {
+			if !equal(a.L[i], b.L[i]) {
+				return false
+			}
+		}
+		return true
+	case "M":
+		if len(a.M) != len(b.M) {
+			return false
+		}
+		for k, v := range a.M {
+			other, ok := b.M[k]
+			if !ok || !equal(v, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeNumbers(ns []*string) []string {
+	out := make([]string, len(ns))
+	for i, n := range ns {
+		r, ok := new(big.Rat).SetString(*n)
+		if !ok {
+			out[i] = *n
+			continue
+		}
+		out[i] = r.RatString()
+	}
+	return out
+}
+
+func typeOf(av *dynamodb.AttributeValue) string {
+	switch {
+	case av == nil:
+		return ""
+	case av.S != nil:
+		return "S"
+	case av.N != nil:
+		return "N"
+	case av.B != nil:
+		return "B"
+	case av.BOOL != nil:
+		return "BOOL"
+	case av.NULL != nil:
+		return "NULL"
+	case av.SS != nil:
+		return "SS"
+	case av.NS != nil:
+		return "NS"
+	case av.BS != nil:
+		return "BS"
+	case av.L != nil:
+		return "L"
+	case av.M != nil:
+		return "M"
+	}
+	return ""
+}
+
+func sizeOf(av *dynamodb.AttributeValue) int {
+	switch {
+	case av.S != nil:
+		return len(*av.S)
+	case av.B != nil:
+		return len(av.B)
+	case av.SS != nil:
+		return len(av.SS)
+	case av.NS != nil:
+		return len(av.NS)
+	case av.BS != nil:
+		return len(av.BS)
+	case av.L != nil:
+		return len(av.L)
+	case av.M != nil:
+		return len(av.M)
+	}
+	return 0
+}
+
+func compareNumbers(a, b string) int {
+	x, okA := new(big.Rat).SetString(a)
+	y, okB := new(big.Rat).SetString(b)
+	if !okA || !okB {
+		return strings.Compare(a, b)
+	}
+	return x.Cmp(y)
+}
+
+// addNumbers returns a + sign*b
+func addNumbers(a, b string, sign int) string {
+	x, _ := new(big.Rat).SetString(a)
+	y, _ := new(big.Rat).SetString(b)
+	if x == nil || y == nil {
+		return "0"
+	}
+	if sign < 0 {
+		y.Neg(y)
+	}
+	return formatNumber(x.Add(x, y))
+}
+
+func formatNumber(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	s := strings.TrimRight(r.FloatString(38), "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// lookup returns the value at the path, or nil if it doesn't exist
+func lookup(i item, p path) *dynamodb.AttributeValue {
+	current, ok := i[p[0].name]
+	if !ok {
+		return nil
+	}
+
+	for _, e := range p[1:] {
+		if current == nil {
+			return nil
+		}
+		if e.list {
+			if current.L == nil || e.index >= len(current.L) {
+				return nil
+			}
+			current = current.L[e.index]
+		} else {
+			if current.M == nil {
+				return nil
+			}
+			if current, ok = current.M[e.name]; !ok {
+				return nil
+			}
+		}
+	}
+
+	return current
+}
+
+// assign sets the value at the path. The parent of the path must already exist.
+func assign(i item, p path, v *dynamodb.AttributeValue) error {
+	if len(p) == 1 {
+		i[p[0].name] = v
+		return nil
+	}
+
+	parent := lookup(i, p[:len(p)-1])
+	last := p[len(p)-1]
+
+	switch {
+	case parent != nil && last.list && parent.L != nil:
+		if last.index >= len(parent.L) {
+			parent.L = append(parent.L, v)
+		} else {
+			parent.L[last.index] = v
+		}
+		return nil
+	case parent != nil && !last.list && parent.M != nil:
+		parent.M[last.name] = v
+		return nil
+	}
+
+	return fmt.Errorf("the document path provided in the update expression is invalid for update")
+}
+
+func remove(i item, p path) {
+	if len(p) == 1 {
+		delete(i, p[0].name)
+		return
+	}
+
+	parent := lookup(i, p[:len(p)-1])
+	last := p[len(p)-1]
+
+	switch {
+	case parent == nil:
+	case last.list && parent.L != nil && last.index < len(parent.L):
+		parent.L = append(parent.L[:last.index], parent.L[last.index+1:]...)
+	case !last.list && parent.M != nil:
+		delete(parent.M, last.name)
+	}
+}
+
+// applyUpdate applies the actions to a copy of the item. Every value is resolved against the original item.
+func applyUpdate(original item, actions []updateAction, values map[string]*dynamodb.AttributeValue) (item, error) {
+	updated := copyItem(original)
+	e := env{item: original, values: values}
+
+	for _, action := range actions {
+		switch action.action {
+		case "SET":
+			v, err := e.resolve(action.value)
+			if err != nil {
+				return nil, err
+			}
+			if v == nil {
+				return nil, fmt.Errorf("the provided expression refers to an attribute that does not exist in the item")
+			}
+			if err := assign(updated, action.path, copyValue(v)); err != nil {
+				return nil, err
+			}
+		case "REMOVE":
+			remove(updated, action.path)
+		case "ADD":
+			v, err := e.resolve(action.value)
+			if err != nil {
+				return nil, err
+			}
+			current := lookup(updated, action.path)
+			next, err := add(current, v)
+			if err != nil {
+				return nil, err
+			}
+			if err := assign(updated, action.path, next); err != nil {
+				return nil, err
+			}
+		case "DELETE":
+			v, err := e.resolve(action.value)
+			if err != nil {
+				return nil, err
+			}
+			current := lookup(updated, action.path)
+			if current == nil {
+				continue
+			}
+			next := subtract(current, v)
+			if next == nil {
+				remove(updated, action.path)
+			} else if err := assign(updated, action.path, next); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return updated, nil
+}
+
+func add(current, v *dynamodb.AttributeValue) (*dynamodb.AttributeValue, error) {
+	switch {
+	case v.N != nil:
+		if current == nil {
+			return copyValue(v), nil
+		}
+		if current.N == nil {
+			return nil, fmt.Errorf("an operand in the update expression has an incorrect data type")
+		}
+		return &dynamodb.AttributeValue{N: aws.String(addNumbers(*current.N, *v.N, 1))}, nil
+	case v.SS != nil:
+		if current == nil {
+			return copyValue(v), nil
+		}
+		return &dynamodb.AttributeValue{SS: union(current.SS, v.SS, func(a, b *string) bool { return *a == *b })}, nil
+	case v.NS != nil:
+		if current == nil {
+			return copyValue(v), nil
+		}
+		return &dynamodb.AttributeValue{NS: union(current.NS, v.NS, func(a, b *string) bool { return compareNumbers(*a, *b) == 0 })}, nil
+	case v.BS != nil:
+		if current == nil {
+			return copyValue(v), nil
+		}
+		out := append([][]byte{}, current.BS...)
+		for _, b := range v.BS {
+			found := false
+			for _, existing := range out {
+				if bytes.Equal(existing, b) {
+					found = true
+				}
+			}
+			if !found {
+				out = append(out, b)
+			}
+		}
+		return &dynamodb.AttributeValue{BS: out}, nil
+	}
+	return nil, fmt.Errorf("ADD only supports numbers and sets")
+}
+
+func union(a, b []*string, same func(a, b *string) bool) []*string {
+	out := append([]*string{}, a...)
+	for _, v := range b {
+		found := false
+		for _, existing := range out {
+			if same(existing, v) {
+				found = true
+			}
+		}
+		if !found {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// subtract removes the elements of v from the set, returning nil if the set is empty
+func subtract(current, v *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	out := &dynamodb.AttributeValue{}
+	switch {
+	case current.SS != nil:
+		for _, s := range current.SS {
+			if !contains(v, &dynamodb.AttributeValue{S: s}) {
+				out.SS = append(out.SS, s)
+			}
+		}
+		if len(out.SS) == 0 {
+			return nil
+		}
+	case current.NS != nil:
+		for _, n := range current.NS {
+			if !contains(v, &dynamodb.AttributeValue{N: n}) {
+				out.NS = append(out.NS, n)
+			}
+		}
+		if len(out.NS) == 0 {
+			return nil
+		}
+	case current.BS != nil:
+		for _, b := range current.BS {
+			if !contains(v, &dynamodb.AttributeValue{B: b}) {
+				out.BS = append(out.BS, b)
+			}
+		}
+		if len(out.BS) == 0 {
+			return nil
+		}
+	default:
+		return current
+	}
+	return out
+}
+
+// project returns only the attributes at the paths. Paths through lists project the whole top level attribute.
+func project(i item, paths []path) item {
+	out := item{}
+	for _, p := range paths {
+		v := lookup(i, p)
+		if v == nil {
+			continue
+		}
+
+		whole := len(p) == 1
+		for _, e := range p {
+			whole = whole || e.list
+		}
+		if whole {
+			out[p[0].name] = copyValue(i[p[0].name])
+			continue
+		}
+
+		// Nested map paths keep their parent documents, but only with the projected elements
+		target := out
+		for _, e := range p[:len(p)-1] {
+			existing, ok := target[e.name]
+			if !ok || existing.M == nil {
+				existing = &dynamodb.AttributeValue{M: item{}}
+				target[e.name] = existing
+			}
+			target = existing.M
+		}
+		target[p[len(p)-1].name] = copyValue(v)
+	}
+	return out
+}
+
+func copyItem(i item) item {
+	if i == nil {
+		return nil
+	}
+	out := make(item, len(i))
+	for k, v := range i {
+		out[k] = copyValue(v)
+	}
+	return out
+}
+
+func copyValue(v *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if v == nil {
+		return nil
+	}
+	out := &dynamodb.AttributeValue{}
+	switch {
+	case v.S != nil:
+		out.S = aws.String(*v.S)
+	case v.N != nil:
+		out.N = aws.String(*v.N)
+	case v.B != nil:
+		out.B = append([]byte{}, v.B...)
+	case v.BOOL != nil:
+		out.BOOL = aws.Bool(*v.BOOL)
+	case v.NULL != nil:
+		out.NULL = aws.Bool(*v.NULL)
+	case v.SS != nil:
+		out.SS = aws.StringSlice(aws.StringValueSlice(v.SS))
+	case v.NS != nil:
+		out.NS = aws.StringSlice(aws.StringValueSlice(v.NS))
+	case v.BS != nil:
+		for _, b := range v.BS {
+			out.BS = append(out.BS, append([]byte{}, b...))
+		}
+	case v.L != nil:
+		out.L = make([]*dynamodb.AttributeValue, len(v.L))
+		for i, e := range v.L {
+			out.L[i] = copyValue(e)
+		}
+	case v.M != nil:
+		out.M = copyItem(v.M)
+	}
+	return out
+}
@@ -0,0 +1,496 @@
+package dynotest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// token is a lexical element of a DynamoDB expression
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenName
+	tokenValue
+	tokenNumber
+	tokenSymbol
+)
+
+func tokenize(expression string) ([]token, error) {
+	tokens := []token{}
+	runes := []rune(expression)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '#' || r == ':' || unicode.IsLetter(r) || r == '_':
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+
+			kind := tokenIdent
+			if r == '#' {
+				kind = tokenName
+			} else if r == ':' {
+				kind = tokenValue
+			}
+			tokens = append(tokens, token{kind: kind, value: string(runes[start:i])})
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && unicode.IsDigit(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, value: string(runes[start:i])})
+		case strings.ContainsRune("(),.[]=+-", r):
+			tokens = append(tokens, token{kind: tokenSymbol, value: string(r)})
+			i++
+		case r == '<' || r == '>':
+			if i+1 < len(runes) && (runes[i+1] == '=' || (r == '<' && runes[i+1] == '>')) {
+				tokens = append(tokens, token{kind: tokenSymbol, value: string(runes[i : i+2])})
+				i += 2
+			} else {
+				tokens = append(tokens, token{kind: tokenSymbol, value: string(r)})
+				i++
+			}
+		default:
+			return nil, fmt.Errorf("invalid character %q in expression", r)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+// pathElement is a map key or a list index in a document path
+type pathElement struct {
+	name  string
+	index int
+	list  bool
+}
+
+type path []pathElement
+
+func (p path) String() string {
+	var b strings.Builder
+	for i, e := range p {
+		if e.list {
+			fmt.Fprintf(&b, "[%d]", e.index)
+			continue
+		}
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(e.name)
+	}
+	return b.String()
+}
+
+// operand is a value in an expression
+type operand interface{}
+
+type pathOperand struct{ path path }
+type valueOperand struct{ name string }
+type functionOperand struct {
+	name string
+	args []operand
+}
+type arithmeticOperand struct {
+	op          string
+	left, right operand
+}
+
+// condition is a boolean expression
+type condition interface{}
+
+type compareCondition struct {
+	op          string
+	left, right operand
+}
+type betweenCondition struct{ value, low, high operand }
+type inCondition struct {
+	value operand
+	list  []operand
+}
+type andCondition struct{ left, right condition }
+type orCondition struct{ left, right condition }
+type notCondition struct{ condition condition }
+type functionCondition struct {
+	name string
+	args []operand
+}
+
+// updateAction is a single clause of an update expression
+type updateAction struct {
+	action string
+	path   path
+	value  operand
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	names  map[string]*string
+}
+
+func newParser(expression string, names map[string]*string) (*parser, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+	return &parser{tokens: tokens, names: names}, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) keyword(word string) bool {
+	t := p.peek()
+	if t.kind == tokenIdent && strings.EqualFold(t.value, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) symbol(s string) bool {
+	t := p.peek()
+	if t.kind == tokenSymbol && t.value == s {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if !p.symbol(s) {
+		return fmt.Errorf("expected %q but found %q", s, p.peek().value)
+	}
+	return nil
+}
+
+func (p *parser) done() error {
+	if t := p.peek(); t.kind != tokenEOF {
+		return fmt.Errorf("unexpected %q in expression", t.value)
+	}
+	return nil
+}
+
+func (p *parser) name(t token) (string, error) {
+	if t.kind == tokenName {
+		name, ok := p.names[t.value]
+		if !ok || name == nil {
+			return "", fmt.Errorf("expression attribute name %s is not defined", t.value)
+		}
+		return *name, nil
+	}
+	if t.kind == tokenIdent {
+		return t.value, nil
+	}
+	return "", fmt.Errorf("expected an attribute name but found %q", t.value)
+}
+
+func (p *parser) parsePath() (path, error) {
+	first, err := p.name(p.next())
+	if err != nil {
+		return nil, err
+	}
+	result := path{{name: first}}
+
+	for {
+		switch {
+		case p.symbol("."):
+			name, err := p.name(p.next())
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, pathElement{name: name})
+		case p.symbol("["):
+			t := p.next()
+			if t.kind != tokenNumber {
+				return nil, fmt.Errorf("expected a list index but found %q", t.value)
+			}
+			index, _ := strconv.Atoi(t.value)
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			result = append(result, pathElement{index: index, list: true})
+		default:
+			return result, nil
+		}
+	}
+}
+
+func (p *parser) parseOperand() (operand, error) {
+	t := p.peek()
+
+	switch t.kind {
+	case tokenValue:
+		p.next()
+		return valueOperand{name: t.value}, nil
+	case tokenIdent:
+		if p.tokens[p.pos+1].kind == tokenSymbol && p.tokens[p.pos+1].value == "(" {
+			return p.parseFunction()
+		}
+		fallthrough
+	case tokenName:
+		path, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		return pathOperand{path: path}, nil
+	default:
+		return nil, fmt.Errorf("expected an operand but found %q", t.value)
+	}
+}
+
+func (p *parser) parseFunction() (functionOperand, error) {
+	name := strings.ToLower(p.next().value)
+	if err := p.expect("("); err != nil {
+		return functionOperand{}, err
+	}
+
+	args := []operand{}
+	for {
+		arg, err := p.parseOperand()
+		if err != nil {
+			return functionOperand{}, err
+		}
+		args = append(args, arg)
+
+		if p.symbol(")") {
+			return functionOperand{name: name, args: args}, nil
+		}
+		if err := p.expect(","); err != nil {
+			return functionOperand{}, err
+		}
+	}
+}
+
+func parseCondition(expression string, names map[string]*string) (condition, error) {
+	p, err := newParser(expression, names)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, p.done()
+}
+
+func (p *parser) parseOr() (condition, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orCondition{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (condition, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andCondition{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (condition, error) {
+	if p.keyword("NOT") {
+		c, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notCondition{condition: c}, nil
+	}
+	return p.parsePredicate()
+}
+
+func (p *parser) parsePredicate() (condition, error) {
+	if p.symbol("(") {
+		c, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return c, p.expect(")")
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	if fn, ok := left.(functionOperand); ok && fn.name != "size" {
+		return functionCondition{name: fn.name, args: fn.args}, nil
+	}
+
+	t := p.peek()
+	switch {
+	case t.kind == tokenSymbol && (t.value == "=" || t.value == "<>" || t.value == "<" || t.value == "<=" || t.value == ">" || t.value == ">="):
+		p.next()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return compareCondition{op: t.value, left: left, right: right}, nil
+	case p.keyword("BETWEEN"):
+		low, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword("AND") {
+			return nil, fmt.Errorf("expected AND in BETWEEN")
+		}
+		high, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return betweenCondition{value: left, low: low, high: high}, nil
+	case p.keyword("IN"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		list := []operand{}
+		for {
+			o, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, o)
+			if p.symbol(")") {
+				return inCondition{value: left, list: list}, nil
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("expected a comparison but found %q", t.value)
+	}
+}
+
+func parseUpdate(expression string, names map[string]*string) ([]updateAction, error) {
+	p, err := newParser(expression, names)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := []updateAction{}
+	for p.peek().kind != tokenEOF {
+		clause := strings.ToUpper(p.next().value)
+		if clause != "SET" && clause != "REMOVE" && clause != "ADD" && clause != "DELETE" {
+			return nil, fmt.Errorf("unknown update clause %q", clause)
+		}
+
+		for {
+			path, err := p.parsePath()
+			if err != nil {
+				return nil, err
+			}
+			action := updateAction{action: clause, path: path}
+
+			switch clause {
+			case "SET":
+				if err := p.expect("="); err != nil {
+					return nil, err
+				}
+				if action.value, err = p.parseSetValue(); err != nil {
+					return nil, err
+				}
+			case "ADD", "DELETE":
+				if action.value, err = p.parseOperand(); err != nil {
+					return nil, err
+				}
+			}
+			actions = append(actions, action)
+
+			if !p.symbol(",") {
+				break
+			}
+		}
+	}
+
+	return actions, nil
+}
+
+func (p *parser) parseSetValue() (operand, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch {
+		case p.symbol("+"):
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			left = arithmeticOperand{op: "+", left: left, right: right}
+		case p.symbol("-"):
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			left = arithmeticOperand{op: "-", left: left, right: right}
+		default:
+			return left, nil
+		}
+	}
+}
+
+func parseProjection(expression string, names map[string]*string) ([]path, error) {
+	p, err := newParser(expression, names)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []path{}
+	for {
+		path, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+
+		if !p.symbol(",") {
+			return paths, p.done()
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package dynotest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/segmentio/ksuid"
+	"gopkg.in/yaml.v2"
+)
+
+// Fixtures loads items into a table before a test and deletes them afterward.
+//
+// String values, and the contents of fixture files, are text/template templates. The template data is Data, and the
+// following functions are available:
+//
+//	now               the current time from Now
+//	duration "1h"     parses a time.Duration, e.g. {{(now.Add (duration "-1h")).Unix}}
+//	unix TIME         seconds since the epoch
+//	rfc3339 TIME      the time formatted as RFC 3339
+//	ksuid             a new KSUID
+type Fixtures struct {
+	// Data is passed to the templates
+	Data interface{}
+
+	// Now returns the time used by the templates. Defaults to time.Now.
+	Now func() time.Time
+
+	db     dynamodbiface.DynamoDBAPI
+	tn     string
+	keys   []string
+	loaded []map[string]*dynamodb.AttributeValue
+}
+
+func NewFixtures(db dynamodbiface.DynamoDBAPI, tableName string) *Fixtures {
+	return &Fixtures{
+		Now: time.Now,
+		db:  db,
+		tn:  tableName,
+	}
+}
+
+// Load puts the items into the table. Items are structs or maps that dynamodbattribute can marshal, or
+// map[string]*dynamodb.AttributeValue. Templates in string values are expanded first.
+func (f *Fixtures) Load(ctx context.Context, items ...interface{}) error {
+	for _, i := range items {
+		av, ok := i.(map[string]*dynamodb.AttributeValue)
+		if !ok {
+			var err error
+			if av, err = dynamodbattribute.MarshalMap(i); err != nil {
+				return err
+			}
+		}
+
+		expanded, err := f.expandItem(av)
+		if err != nil {
+			return err
+		}
+		if err := f.put(ctx, expanded); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadFile puts the items in a JSON (.json) or YAML (.yaml, .yml) file into the table. The file contains a list of
+// items. The whole file is expanded as a template before it is parsed.
+func (f *Fixtures) LoadFile(ctx context.Context, path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	expanded, err := f.expand(string(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	var items []interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		decoder := json.NewDecoder(strings.NewReader(expanded))
+		decoder.UseNumber()
+		err = decoder.Decode(&items)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal([]byte(expanded), &items)
+	default:
+		return fmt.Errorf("%s: unsupported fixture file type", path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, i := range items {
+		av, err := dynamodbattribute.MarshalMap(fixtureValue(i))
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if err := f.put(ctx, av); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Truncate deletes every item loaded by the fixtures
+func (f *Fixtures) Truncate(ctx context.Context) error {
+	for len(f.loaded) > 0 {
+		key := f.loaded[len(f.loaded)-1]
+
+		_, err := f.db.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(f.tn),
+			Key:       key,
+		})
+		if err != nil {
+			return err
+		}
+
+		f.loaded = f.loaded[:len(f.loaded)-1]
+	}
+
+	return nil
+}
+
+func (f *Fixtures) put(ctx context.Context, i map[string]*dynamodb.AttributeValue) error {
+	if f.keys == nil {
+		out, err := f.db.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(f.tn)})
+		if err != nil {
+			return err
+		}
+		for _, e := range out.Table.KeySchema {
+			f.keys = append(f.keys, aws.StringValue(e.AttributeName))
+		}
+	}
+
+	key := map[string]*dynamodb.AttributeValue{}
+	for _, name := range f.keys {
+		v, ok := i[name]
+		if !ok {
+			return fmt.Errorf("fixture is missing key attribute %s", name)
+		}
+		key[name] = v
+	}
+
+	_, err := f.db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(f.tn),
+		Item:      i,
+	})
+	if err != nil {
+		return err
+	}
+
+	f.loaded = append(f.loaded, key)
+
+	return nil
+}
+
+func (f *Fixtures) expand(text string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	now := f.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	tmpl, err := template.New("fixture").Funcs(template.FuncMap{
+		"now":      now,
+		"duration": time.ParseDuration,
+		"unix":     func(t time.Time) int64 { return t.Unix() },
+		"rfc3339":  func(t time.Time) string { return t.Format(time.RFC3339) },
+		"ksuid":    func() string { return ksuid.New().String() },
+	}).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, f.Data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func (f *Fixtures) expandItem(i map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	out := make(map[string]*dynamodb.AttributeValue, len(i))
+	for name, v := range i {
+		expanded, err := f.expandValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		out[name] = expanded
+	}
+	return out, nil
+}
+
+func (f *Fixtures) expandValue(v *dynamodb.AttributeValue) (*dynamodb.AttributeValue, error) {
+	switch {
+	case v.S != nil:
+		s, err := f.expand(*v.S)
+		return &dynamodb.AttributeValue{S: aws.String(s)}, err
+	case v.SS != nil:
+		ss := make([]*string, len(v.SS))
+		for index, s := range v.SS {
+			expanded, err := f.expand(*s)
+			if err != nil {
+				return nil, err
+			}
+			ss[index] = aws.String(expanded)
+		}
+		return &dynamodb.AttributeValue{SS: ss}, nil
+	case v.L != nil:
+		l := make([]*dynamodb.AttributeValue, len(v.L))
+		for index, e := range v.L {
+			expanded, err := f.expandValue(e)
+			if err != nil {
+				return nil, err
+			}
+			l[index] = expanded
+		}
+		return &dynamodb.AttributeValue{L: l}, nil
+	case v.M != nil:
+		m, err := f.expandItem(v.M)
+		return &dynamodb.AttributeValue{M: m}, err
+	}
+	return v, nil
+}
+
+// fixtureValue converts decoded JSON and YAML into values dynamodbattribute marshals as DynamoDB types
+func fixtureValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		return dynamodbattribute.Number(v)
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = fixtureValue(value)
+		}
+		return m
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = fixtureValue(value)
+		}
+	case []interface{}:
+		for index, value := range v {
+			v[index] = fixtureValue(value)
+		}
+	}
+	return v
+}
@@ -0,0 +1,95 @@
+package dynotest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/maddiesch/dyno/dynotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixtures(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	count := func(t *testing.T, db *dynotest.DB) int64 {
+		out, err := db.Scan(&dynamodb.ScanInput{TableName: aws.String("Test")})
+		require.NoError(t, err)
+		return *out.Count
+	}
+
+	t.Run("given go literals", func(t *testing.T) {
+		db := newTable(t)
+		fixtures := dynotest.NewFixtures(db, "Test")
+		fixtures.Data = map[string]string{"ID": "1"}
+
+		err := fixtures.Load(ctx,
+			map[string]interface{}{"PK": "User/{{.ID}}", "SK": "profile", "Age": 36},
+			struct{ PK, SK string }{"User/{{.ID}}", "settings"},
+		)
+		require.NoError(t, err)
+
+		out, err := db.GetItem(&dynamodb.GetItemInput{TableName: aws.String("Test"), Key: key("User/1", "profile")})
+		require.NoError(t, err)
+		assert.Equal(t, "36", *out.Item["Age"].N)
+		assert.Equal(t, int64(2), count(t, db))
+
+		require.NoError(t, fixtures.Truncate(ctx))
+		assert.Equal(t, int64(0), count(t, db))
+	})
+
+	t.Run("given a yaml file", func(t *testing.T) {
+		db := newTable(t)
+		fixtures := dynotest.NewFixtures(db, "Test")
+		fixtures.Data = map[string]string{"ID": "2"}
+		fixtures.Now = func() time.Time { return now }
+
+		require.NoError(t, fixtures.LoadFile(ctx, "testdata/users.yaml"))
+
+		out, err := db.GetItem(&dynamodb.GetItemInput{TableName: aws.String("Test"), Key: key("User/2", "profile")})
+		require.NoError(t, err)
+		assert.Equal(t, "Ada", *out.Item["Name"].S)
+		assert.Equal(t, "1577934245", *out.Item["CreatedAt"].N)
+
+		sessions, err := db.Query(&dynamodb.QueryInput{
+			TableName:              aws.String("Test"),
+			KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :prefix)"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":pk":     {S: aws.String("User/2")},
+				":prefix": {S: aws.String("Session/")},
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, sessions.Items, 1)
+		assert.Equal(t, "2020-01-02T04:04:05Z", *sessions.Items[0]["ExpiresAt"].S)
+
+		require.NoError(t, fixtures.Truncate(ctx))
+		assert.Equal(t, int64(0), count(t, db))
+	})
+
+	t.Run("given a json file", func(t *testing.T) {
+		db := newTable(t)
+		fixtures := dynotest.NewFixtures(db, "Test")
+		fixtures.Data = map[string]string{"ID": "3"}
+
+		require.NoError(t, fixtures.LoadFile(ctx, "testdata/users.json"))
+
+		out, err := db.GetItem(&dynamodb.GetItemInput{TableName: aws.String("Test"), Key: key("User/3", "profile")})
+		require.NoError(t, err)
+		assert.Equal(t, "85", *out.Item["Age"].N)
+		assert.Len(t, out.Item["Tags"].L, 2)
+	})
+
+	t.Run("given an item without a key", func(t *testing.T) {
+		db := newTable(t)
+		fixtures := dynotest.NewFixtures(db, "Test")
+
+		err := fixtures.Load(ctx, map[string]interface{}{"PK": "a"})
+
+		require.Error(t, err)
+	})
+}
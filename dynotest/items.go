@@ -0,0 +1,930 @@
+package dynotest
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/big"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// encodeKey returns a string that uniquely identifies the item's key, validating the key attributes
+func (t *table) encodeKey(i item) (string, error) {
+	encoded := ""
+	for _, name := range []string{t.hashKey, t.rangeKey} {
+		if name == "" {
+			continue
+		}
+
+		v, ok := i[name]
+		if !ok || v == nil {
+			return "", validationError("One of the required keys was not given a value")
+		}
+
+		kind := typeOf(v)
+		if want, ok := t.types[name]; ok && want != kind {
+			return "", validationError("Type mismatch for key %s expected: %s actual: %s", name, want, kind)
+		}
+
+		switch kind {
+		case "S":
+			encoded += "S:" + *v.S
+		case "N":
+			r, ok := new(big.Rat).SetString(*v.N)
+			if !ok {
+				return "", validationError("invalid number %s", *v.N)
+			}
+			encoded += "N:" + r.RatString()
+		case "B":
+			encoded += "B:" + string(v.B)
+		default:
+			return "", validationError("key attribute %s must be a scalar", name)
+		}
+		encoded += "\x00"
+	}
+	return encoded, nil
+}
+
+func (t *table) validateKey(key item) (string, error) {
+	expected := 1
+	if t.rangeKey != "" {
+		expected = 2
+	}
+	if len(key) != expected {
+		return "", validationError("The provided key element does not match the schema")
+	}
+	return t.encodeKey(key)
+}
+
+func (t *table) keyAttributes(i item) item {
+	key := item{t.hashKey: copyValue(i[t.hashKey])}
+	if t.rangeKey != "" {
+		key[t.rangeKey] = copyValue(i[t.rangeKey])
+	}
+	return key
+}
+
+func checkCondition(expression *string, names map[string]*string, values map[string]*dynamodb.AttributeValue, current item) error {
+	if expression == nil {
+		return nil
+	}
+
+	c, err := parseCondition(*expression, names)
+	if err != nil {
+		return validationError("Invalid ConditionExpression: %s", err)
+	}
+
+	if current == nil {
+		current = item{}
+	}
+	ok, err := env{item: current, values: values}.test(c)
+	if err != nil {
+		return validationError("Invalid ConditionExpression: %s", err)
+	}
+	if !ok {
+		return conditionalCheckFailed()
+	}
+
+	return nil
+}
+
+func projectExpression(i item, expression *string, names map[string]*string) (item, error) {
+	if expression == nil || i == nil {
+		return copyItem(i), nil
+	}
+
+	paths, err := parseProjection(*expression, names)
+	if err != nil {
+		return nil, validationError("Invalid ProjectionExpression: %s", err)
+	}
+
+	return project(i, paths), nil
+}
+
+func (db *DB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	return db.GetItemWithContext(context.Background(), input)
+}
+
+func (db *DB) GetItemWithContext(ctx aws.Context, input *dynamodb.GetItemInput, opts ...request.Option) (*dynamodb.GetItemOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, err := db.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := t.validateKey(input.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	projected, err := projectExpression(t.items[key], input.ProjectionExpression, input.ExpressionAttributeNames)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &dynamodb.GetItemOutput{Item: projected}
+	output.ConsumedCapacity = t.consumed(input.ReturnConsumedCapacity, readUnits(t.items[key], aws.BoolValue(input.ConsistentRead)), false)
+
+	return output, nil
+}
+
+func (db *DB) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	return db.PutItemWithContext(context.Background(), input)
+}
+
+func (db *DB) PutItemWithContext(ctx aws.Context, input *dynamodb.PutItemInput, opts ...request.Option) (*dynamodb.PutItemOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, err := db.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	key, old, err := t.preparePut(input.Item, input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	t.items[key] = copyItem(input.Item)
+
+	output := &dynamodb.PutItemOutput{}
+	output.ConsumedCapacity = t.consumed(input.ReturnConsumedCapacity, writeUnits(old, input.Item), true)
+	if aws.StringValue(input.ReturnValues) == dynamodb.ReturnValueAllOld {
+		output.Attributes = old
+	}
+
+	return output, nil
+}
+
+func (t *table) preparePut(i item, expression *string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (string, item, error) {
+	key, err := t.encodeKey(i)
+	if err != nil {
+		return "", nil, err
+	}
+	if size := itemSize(i); size > 400*1024 {
+		return "", nil, validationError("Item size has exceeded the maximum allowed size")
+	}
+
+	old := t.items[key]
+	if err := checkCondition(expression, names, values, old); err != nil {
+		return "", nil, err
+	}
+
+	return key, copyItem(old), nil
+}
+
+func (db *DB) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	return db.DeleteItemWithContext(context.Background(), input)
+}
+
+func (db *DB) DeleteItemWithContext(ctx aws.Context, input *dynamodb.DeleteItemInput, opts ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, err := db.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := t.validateKey(input.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	old := t.items[key]
+	if err := checkCondition(input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues, old); err != nil {
+		return nil, err
+	}
+	delete(t.items, key)
+
+	output := &dynamodb.DeleteItemOutput{}
+	output.ConsumedCapacity = t.consumed(input.ReturnConsumedCapacity, writeUnits(old, nil), true)
+	if aws.StringValue(input.ReturnValues) == dynamodb.ReturnValueAllOld {
+		output.Attributes = old
+	}
+
+	return output, nil
+}
+
+func (db *DB) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	return db.UpdateItemWithContext(context.Background(), input)
+}
+
+func (db *DB) UpdateItemWithContext(ctx aws.Context, input *dynamodb.UpdateItemInput, opts ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, err := db.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	key, old, updated, actions, err := t.prepareUpdate(input.Key, input.UpdateExpression, input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	t.items[key] = updated
+
+	output := &dynamodb.UpdateItemOutput{}
+	output.ConsumedCapacity = t.consumed(input.ReturnConsumedCapacity, writeUnits(old, updated), true)
+	switch aws.StringValue(input.ReturnValues) {
+	case dynamodb.ReturnValueAllOld:
+		output.Attributes = copyItem(old)
+	case dynamodb.ReturnValueAllNew:
+		output.Attributes = copyItem(updated)
+	case dynamodb.ReturnValueUpdatedOld:
+		output.Attributes = touched(old, actions)
+	case dynamodb.ReturnValueUpdatedNew:
+		output.Attributes = touched(updated, actions)
+	}
+
+	return output, nil
+}
+
+func (t *table) prepareUpdate(keyItem item, update, expression *string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (string, item, item, []updateAction, error) {
+	key, err := t.validateKey(keyItem)
+	if err != nil {
+		return "", nil, nil, nil, err
+	}
+
+	old := t.items[key]
+	if err := checkCondition(expression, names, values, old); err != nil {
+		return "", nil, nil, nil, err
+	}
+
+	base := old
+	if base == nil {
+		base = copyItem(keyItem)
+	}
+
+	updated := copyItem(base)
+	actions := []updateAction{}
+	if update != nil {
+		if actions, err = parseUpdate(*update, names); err != nil {
+			return "", nil, nil, nil, validationError("Invalid UpdateExpression: %s", err)
+		}
+		if updated, err = applyUpdate(base, actions, values); err != nil {
+			return "", nil, nil, nil, validationError("Invalid UpdateExpression: %s", err)
+		}
+	}
+
+	if updatedKey, err := t.encodeKey(updated); err != nil || updatedKey != key {
+		return "", nil, nil, nil, validationError("Cannot update attribute %s. This attribute is part of the key", t.hashKey)
+	}
+	if itemSize(updated) > 400*1024 {
+		return "", nil, nil, nil, validationError("Item size to update has exceeded the maximum allowed size")
+	}
+
+	return key, old, updated, actions, nil
+}
+
+// touched returns the top level attributes the update actions modified
+func touched(i item, actions []updateAction) item {
+	out := item{}
+	for _, action := range actions {
+		name := action.path[0].name
+		if v, ok := i[name]; ok {
+			out[name] = copyValue(v)
+		}
+	}
+	return out
+}
+
+func (db *DB) BatchWriteItem(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+	return db.BatchWriteItemWithContext(context.Background(), input)
+}
+
+func (db *DB) BatchWriteItemWithContext(ctx aws.Context, input *dynamodb.BatchWriteItemInput, opts ...request.Option) (*dynamodb.BatchWriteItemOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	count := 0
+	for _, requests := range input.RequestItems {
+		count += len(requests)
+	}
+	if count == 0 || count > 25 {
+		return nil, validationError("Too many items requested for the BatchWriteItem call")
+	}
+
+	output := &dynamodb.BatchWriteItemOutput{UnprocessedItems: map[string][]*dynamodb.WriteRequest{}}
+	for name, requests := range input.RequestItems {
+		t, err := db.table(aws.String(name))
+		if err != nil {
+			return nil, err
+		}
+
+		units := 0.0
+		for _, req := range requests {
+			switch {
+			case req.PutRequest != nil:
+				key, old, err := t.preparePut(req.PutRequest.Item, nil, nil, nil)
+				if err != nil {
+					return nil, err
+				}
+				t.items[key] = copyItem(req.PutRequest.Item)
+				units += writeUnits(old, req.PutRequest.Item)
+			case req.DeleteRequest != nil:
+				key, err := t.validateKey(req.DeleteRequest.Key)
+				if err != nil {
+					return nil, err
+				}
+				units += writeUnits(t.items[key], nil)
+				delete(t.items, key)
+			}
+		}
+
+		if consumed := t.consumed(input.ReturnConsumedCapacity, units, true); consumed != nil {
+			output.ConsumedCapacity = append(output.ConsumedCapacity, consumed)
+		}
+	}
+
+	return output, nil
+}
+
+func (db *DB) BatchGetItem(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	return db.BatchGetItemWithContext(context.Background(), input)
+}
+
+func (db *DB) BatchGetItemWithContext(ctx aws.Context, input *dynamodb.BatchGetItemInput, opts ...request.Option) (*dynamodb.BatchGetItemOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	output := &dynamodb.BatchGetItemOutput{
+		Responses:       map[string][]map[string]*dynamodb.AttributeValue{},
+		UnprocessedKeys: map[string]*dynamodb.KeysAndAttributes{},
+	}
+
+	for name, keys := range input.RequestItems {
+		t, err := db.table(aws.String(name))
+		if err != nil {
+			return nil, err
+		}
+
+		results := []map[string]*dynamodb.AttributeValue{}
+		for _, k := range keys.Keys {
+			key, err := t.validateKey(k)
+			if err != nil {
+				return nil, err
+			}
+			if i, ok := t.items[key]; ok {
+				projected, err := projectExpression(i, keys.ProjectionExpression, keys.ExpressionAttributeNames)
+				if err != nil {
+					return nil, err
+				}
+				results = append(results, projected)
+			}
+		}
+		output.Responses[name] = results
+	}
+
+	return output, nil
+}
+
+func (db *DB) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	return db.TransactWriteItemsWithContext(context.Background(), input)
+}
+
+// TransactWriteItemsWithContext checks every condition before applying any of the writes. If a condition fails, a
+// TransactionCanceledException is returned with a cancellation reason for every item.
+func (db *DB) TransactWriteItemsWithContext(ctx aws.Context, input *dynamodb.TransactWriteItemsInput, opts ...request.Option) (*dynamodb.TransactWriteItemsOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if len(input.TransactItems) == 0 || len(input.TransactItems) > 25 {
+		return nil, validationError("Member must have length less than or equal to 25")
+	}
+
+	type write struct {
+		table   *table
+		key     string
+		updated item
+		remove  bool
+		check   bool
+	}
+
+	writes := make([]write, len(input.TransactItems))
+	reasons := make([]*dynamodb.CancellationReason, len(input.TransactItems))
+	seen := map[string]bool{}
+	failed := false
+
+	for index, ti := range input.TransactItems {
+		var w write
+		var err error
+
+		switch {
+		case ti.Put != nil:
+			if w.table, err = db.table(ti.Put.TableName); err != nil {
+				return nil, err
+			}
+			w.key, _, err = w.table.preparePut(ti.Put.Item, ti.Put.ConditionExpression, ti.Put.ExpressionAttributeNames, ti.Put.ExpressionAttributeValues)
+			w.updated = copyItem(ti.Put.Item)
+		case ti.Update != nil:
+			if w.table, err = db.table(ti.Update.TableName); err != nil {
+				return nil, err
+			}
+			w.key, _, w.updated, _, err = w.table.prepareUpdate(ti.Update.Key, ti.Update.UpdateExpression, ti.Update.ConditionExpression, ti.Update.ExpressionAttributeNames, ti.Update.ExpressionAttributeValues)
+		case ti.Delete != nil:
+			if w.table, err = db.table(ti.Delete.TableName); err != nil {
+				return nil, err
+			}
+			if w.key, err = w.table.validateKey(ti.Delete.Key); err == nil {
+				err = checkCondition(ti.Delete.ConditionExpression, ti.Delete.ExpressionAttributeNames, ti.Delete.ExpressionAttributeValues, w.table.items[w.key])
+			}
+			w.remove = true
+		case ti.ConditionCheck != nil:
+			if w.table, err = db.table(ti.ConditionCheck.TableName); err != nil {
+				return nil, err
+			}
+			if w.key, err = w.table.validateKey(ti.ConditionCheck.Key); err == nil {
+				err = checkCondition(ti.ConditionCheck.ConditionExpression, ti.ConditionCheck.ExpressionAttributeNames, ti.ConditionCheck.ExpressionAttributeValues, w.table.items[w.key])
+			}
+			w.check = true
+		default:
+			return nil, validationError("TransactItems can only contain one of Check, Put, Update or Delete")
+		}
+
+		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			failed = true
+			reasons[index] = &dynamodb.CancellationReason{
+				Code:    aws.String("ConditionalCheckFailed"),
+				Message: aws.String("The conditional request failed"),
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		id := aws.StringValue(w.table.desc.TableName) + "\x00" + w.key
+		if seen[id] {
+			return nil, validationError("Transaction request cannot include multiple operations on one item")
+		}
+		seen[id] = true
+
+		writes[index] = w
+		reasons[index] = &dynamodb.CancellationReason{Code: aws.String("None")}
+	}
+
+	if failed {
+		return nil, &dynamodb.TransactionCanceledException{
+			Message_:            aws.String("Transaction cancelled, please refer cancellation reasons for specific reasons"),
+			CancellationReasons: reasons,
+		}
+	}
+
+	for _, w := range writes {
+		switch {
+		case w.check:
+		case w.remove:
+			delete(w.table.items, w.key)
+		default:
+			w.table.items[w.key] = w.updated
+		}
+	}
+
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+func (db *DB) TransactGetItems(input *dynamodb.TransactGetItemsInput) (*dynamodb.TransactGetItemsOutput, error) {
+	return db.TransactGetItemsWithContext(context.Background(), input)
+}
+
+func (db *DB) TransactGetItemsWithContext(ctx aws.Context, input *dynamodb.TransactGetItemsInput, opts ...request.Option) (*dynamodb.TransactGetItemsOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	output := &dynamodb.TransactGetItemsOutput{}
+	for _, ti := range input.TransactItems {
+		t, err := db.table(ti.Get.TableName)
+		if err != nil {
+			return nil, err
+		}
+		key, err := t.validateKey(ti.Get.Key)
+		if err != nil {
+			return nil, err
+		}
+		projected, err := projectExpression(t.items[key], ti.Get.ProjectionExpression, ti.Get.ExpressionAttributeNames)
+		if err != nil {
+			return nil, err
+		}
+		output.Responses = append(output.Responses, &dynamodb.ItemResponse{Item: projected})
+	}
+
+	return output, nil
+}
+
+// read describes a Query or Scan
+type read struct {
+	index         *index
+	keyCondition  condition
+	filter        condition
+	names         map[string]*string
+	values        map[string]*dynamodb.AttributeValue
+	projection    *string
+	selectCount   bool
+	forward       bool
+	limit         int64
+	startKey      item
+	segment       int64
+	totalSegments int64
+	query         bool
+}
+
+func (t *table) run(r read) ([]map[string]*dynamodb.AttributeValue, int64, item, error) {
+	hashKey, rangeKey := t.hashKey, t.rangeKey
+	if r.index != nil {
+		hashKey, rangeKey = r.index.hashKey, r.index.rangeKey
+	}
+
+	// Items are ordered by the key of the table or index, then by the table key
+	order := []string{hashKey, rangeKey, t.hashKey, t.rangeKey}
+	if r.query {
+		order = []string{rangeKey, t.hashKey, t.rangeKey}
+	}
+
+	candidates := []item{}
+	for _, i := range t.items {
+		if _, ok := i[hashKey]; !ok {
+			continue
+		}
+		if _, ok := i[rangeKey]; rangeKey != "" && !ok {
+			continue
+		}
+		if r.totalSegments > 0 {
+			h := fnv.New32a()
+			h.Write([]byte(fmt.Sprint(attributeString(i[t.hashKey]))))
+			if int64(h.Sum32())%r.totalSegments != r.segment {
+				continue
+			}
+		}
+		if r.keyCondition != nil {
+			ok, err := env{item: i, values: r.values}.test(r.keyCondition)
+			if err != nil {
+				return nil, 0, nil, validationError("Invalid KeyConditionExpression: %s", err)
+			}
+			if !ok {
+				continue
+			}
+		}
+		candidates = append(candidates, i)
+	}
+
+	sort.Slice(candidates, func(a, b int) bool {
+		cmp := compareKeys(candidates[a], candidates[b], order)
+		if r.forward {
+			return cmp < 0
+		}
+		return cmp > 0
+	})
+
+	if r.startKey != nil {
+		start := len(candidates)
+		for index, i := range candidates {
+			cmp := compareKeys(i, r.startKey, order)
+			if (r.forward && cmp > 0) || (!r.forward && cmp < 0) {
+				start = index
+				break
+			}
+		}
+		candidates = candidates[start:]
+	}
+
+	var lastKey item
+	if r.limit > 0 && int64(len(candidates)) >= r.limit {
+		candidates = candidates[:r.limit]
+
+		last := candidates[len(candidates)-1]
+		lastKey = t.keyAttributes(last)
+		if r.index != nil {
+			lastKey[hashKey] = copyValue(last[hashKey])
+			if rangeKey != "" {
+				lastKey[rangeKey] = copyValue(last[rangeKey])
+			}
+		}
+	}
+
+	results := []map[string]*dynamodb.AttributeValue{}
+	for _, i := range candidates {
+		if r.filter != nil {
+			ok, err := env{item: i, values: r.values}.test(r.filter)
+			if err != nil {
+				return nil, 0, nil, validationError("Invalid FilterExpression: %s", err)
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		if r.selectCount {
+			results = append(results, nil)
+			continue
+		}
+
+		projected := copyItem(i)
+		if r.index != nil {
+			projected = t.projectIndex(r.index, i)
+		}
+		projected, err := projectExpression(projected, r.projection, r.names)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		results = append(results, projected)
+	}
+
+	return results, int64(len(candidates)), lastKey, nil
+}
+
+func (t *table) projectIndex(idx *index, i item) item {
+	projection := aws.StringValue(idx.projection.ProjectionType)
+	if projection == dynamodb.ProjectionTypeAll {
+		return copyItem(i)
+	}
+
+	out := t.keyAttributes(i)
+	out[idx.hashKey] = copyValue(i[idx.hashKey])
+	if idx.rangeKey != "" {
+		out[idx.rangeKey] = copyValue(i[idx.rangeKey])
+	}
+	if projection == dynamodb.ProjectionTypeInclude {
+		for _, name := range aws.StringValueSlice(idx.projection.NonKeyAttributes) {
+			if v, ok := i[name]; ok {
+				out[name] = copyValue(v)
+			}
+		}
+	}
+	return out
+}
+
+func compareKeys(a, b item, order []string) int {
+	for _, name := range order {
+		if name == "" {
+			continue
+		}
+		x, y := a[name], b[name]
+		if x == nil || y == nil {
+			continue
+		}
+		if cmp, ok := compare(x, y); ok && cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+func attributeString(v *dynamodb.AttributeValue) string {
+	switch {
+	case v == nil:
+		return ""
+	case v.S != nil:
+		return *v.S
+	case v.N != nil:
+		return *v.N
+	default:
+		return string(v.B)
+	}
+}
+
+func (t *table) indexNamed(name *string) (*index, error) {
+	if name == nil {
+		return nil, nil
+	}
+	idx, ok := t.indexes[*name]
+	if !ok {
+		return nil, validationError("The table does not have the specified index: %s", *name)
+	}
+	return idx, nil
+}
+
+func (db *DB) Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	return db.QueryWithContext(context.Background(), input)
+}
+
+func (db *DB) QueryWithContext(ctx aws.Context, input *dynamodb.QueryInput, opts ...request.Option) (*dynamodb.QueryOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, err := db.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.KeyConditionExpression == nil {
+		return nil, validationError("KeyConditionExpression must be specified")
+	}
+
+	r := read{
+		names:       input.ExpressionAttributeNames,
+		values:      input.ExpressionAttributeValues,
+		projection:  input.ProjectionExpression,
+		selectCount: aws.StringValue(input.Select) == dynamodb.SelectCount,
+		forward:     input.ScanIndexForward == nil || *input.ScanIndexForward,
+		limit:       aws.Int64Value(input.Limit),
+		startKey:    input.ExclusiveStartKey,
+		query:       true,
+	}
+	if r.index, err = t.indexNamed(input.IndexName); err != nil {
+		return nil, err
+	}
+	if r.keyCondition, err = parseCondition(*input.KeyConditionExpression, input.ExpressionAttributeNames); err != nil {
+		return nil, validationError("Invalid KeyConditionExpression: %s", err)
+	}
+	if input.FilterExpression != nil {
+		if r.filter, err = parseCondition(*input.FilterExpression, input.ExpressionAttributeNames); err != nil {
+			return nil, validationError("Invalid FilterExpression: %s", err)
+		}
+	}
+
+	items, scanned, lastKey, err := t.run(r)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &dynamodb.QueryOutput{
+		Count:            aws.Int64(int64(len(items))),
+		ScannedCount:     aws.Int64(scanned),
+		LastEvaluatedKey: lastKey,
+	}
+	if !r.selectCount {
+		output.Items = items
+	}
+
+	return output, nil
+}
+
+func (db *DB) Scan(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	return db.ScanWithContext(context.Background(), input)
+}
+
+func (db *DB) ScanWithContext(ctx aws.Context, input *dynamodb.ScanInput, opts ...request.Option) (*dynamodb.ScanOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, err := db.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	r := read{
+		names:         input.ExpressionAttributeNames,
+		values:        input.ExpressionAttributeValues,
+		projection:    input.ProjectionExpression,
+		selectCount:   aws.StringValue(input.Select) == dynamodb.SelectCount,
+		forward:       true,
+		limit:         aws.Int64Value(input.Limit),
+		startKey:      input.ExclusiveStartKey,
+		segment:       aws.Int64Value(input.Segment),
+		totalSegments: aws.Int64Value(input.TotalSegments),
+	}
+	if r.index, err = t.indexNamed(input.IndexName); err != nil {
+		return nil, err
+	}
+	if input.FilterExpression != nil {
+		if r.filter, err = parseCondition(*input.FilterExpression, input.ExpressionAttributeNames); err != nil {
+			return nil, validationError("Invalid FilterExpression: %s", err)
+		}
+	}
+
+	items, scanned, lastKey, err := t.run(r)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &dynamodb.ScanOutput{
+		Count:            aws.Int64(int64(len(items))),
+		ScannedCount:     aws.Int64(scanned),
+		LastEvaluatedKey: lastKey,
+	}
+	if !r.selectCount {
+		output.Items = items
+	}
+
+	return output, nil
+}
+
+func (db *DB) QueryPages(input *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool) error {
+	return db.QueryPagesWithContext(context.Background(), input, fn)
+}
+
+func (db *DB) QueryPagesWithContext(ctx aws.Context, input *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool, opts ...request.Option) error {
+	page := *input
+	for {
+		out, err := db.QueryWithContext(ctx, &page, opts...)
+		if err != nil {
+			return err
+		}
+		last := len(out.LastEvaluatedKey) == 0
+		if !fn(out, last) || last {
+			return nil
+		}
+		page.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+}
+
+func (db *DB) ScanPages(input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+	return db.ScanPagesWithContext(context.Background(), input, fn)
+}
+
+func (db *DB) ScanPagesWithContext(ctx aws.Context, input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool, opts ...request.Option) error {
+	page := *input
+	for {
+		out, err := db.ScanWithContext(ctx, &page, opts...)
+		if err != nil {
+			return err
+		}
+		last := len(out.LastEvaluatedKey) == 0
+		if !fn(out, last) || last {
+			return nil
+		}
+		page.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+}
+
+// consumed returns the capacity used by a request, if the request asked for it
+func (t *table) consumed(mode *string, units float64, write bool) *dynamodb.ConsumedCapacity {
+	if mode == nil || *mode == dynamodb.ReturnConsumedCapacityNone {
+		return nil
+	}
+
+	consumed := &dynamodb.ConsumedCapacity{
+		TableName:     t.desc.TableName,
+		CapacityUnits: aws.Float64(units),
+	}
+	if write {
+		consumed.WriteCapacityUnits = aws.Float64(units)
+	} else {
+		consumed.ReadCapacityUnits = aws.Float64(units)
+	}
+	return consumed
+}
+
+// readUnits is the capacity used to read the item, one unit per 4KB or half that for eventually consistent reads
+func readUnits(i item, consistent bool) float64 {
+	units := float64((itemSize(i) + 4095) / 4096)
+	if units == 0 {
+		units = 1
+	}
+	if !consistent {
+		units /= 2
+	}
+	return units
+}
+
+// writeUnits is the capacity used to replace the old item with the new one, one unit per 1KB of the larger item
+func writeUnits(old, new item) float64 {
+	size := itemSize(old)
+	if n := itemSize(new); n > size {
+		size = n
+	}
+	units := float64((size + 1023) / 1024)
+	if units == 0 {
+		units = 1
+	}
+	return units
+}
+
+// itemSize approximates the DynamoDB item size
+func itemSize(i item) int {
+	size := 0
+	for name, v := range i {
+		size += len(name) + valueSize(v)
+	}
+	return size
+}
+
+func valueSize(v *dynamodb.AttributeValue) int {
+	switch {
+	case v == nil:
+		return 1
+	case v.S != nil:
+		return len(*v.S)
+	case v.N != nil:
+		return (len(*v.N)+1)/2 + 1
+	case v.B != nil:
+		return len(v.B)
+	case v.L != nil:
+		size := 3
+		for _, e := range v.L {
+			size += 1 + valueSize(e)
+		}
+		return size
+	case v.M != nil:
+		return 3 + itemSize(v.M) + len(v.M)
+	}
+	size := 1
+	for _, s := range v.SS {
+		size += len(*s)
+	}
+	for _, n := range v.NS {
+		size += len(*n)
+	}
+	for _, b := range v.BS {
+		size += len(b)
+	}
+	return size
+}
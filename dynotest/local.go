@@ -0,0 +1,220 @@
+package dynotest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+)
+
+// EndpointEnv names the environment variable that points the harness at an already running DynamoDB Local instead of
+// starting a container
+const EndpointEnv = "DYNAMODB_ENDPOINT"
+
+// DefaultImage is the container image started by StartLocal
+const DefaultImage = "amazon/dynamodb-local"
+
+var ErrLocalNotReady = errors.New("dynamodb local did not become ready")
+
+// LocalOptions configures StartLocal
+type LocalOptions struct {
+	// Endpoint of a running DynamoDB Local. When empty, the EndpointEnv environment variable is used. When both are
+	// empty, a container is started with docker.
+	Endpoint string
+
+	// Image is the container image to run. Defaults to DefaultImage.
+	Image string
+
+	// Tables are created once DynamoDB Local is ready and deleted by Close
+	Tables []*dynamodb.CreateTableInput
+
+	// Timeout is how long to wait for DynamoDB Local to accept requests. Defaults to 30 seconds.
+	Timeout time.Duration
+}
+
+// Local is a DynamoDB Local instance for integration tests
+type Local struct {
+	// Endpoint is the URL clients connect to
+	Endpoint string
+
+	session   *session.Session
+	container string
+
+	mu     sync.Mutex
+	tables []string
+}
+
+// StartLocal connects to DynamoDB Local, starting a container when no endpoint is configured, waits for it to accept
+// requests, and creates the requested tables. Close must be called to clean up.
+func StartLocal(ctx context.Context, opts *LocalOptions) (*Local, error) {
+	if opts == nil {
+		opts = &LocalOptions{}
+	}
+
+	l := &Local{Endpoint: opts.Endpoint}
+	if l.Endpoint == "" {
+		l.Endpoint = os.Getenv(EndpointEnv)
+	}
+
+	if l.Endpoint == "" {
+		if err := l.startContainer(ctx, opts.Image); err != nil {
+			return nil, err
+		}
+	}
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(l.Endpoint),
+		Credentials: credentials.NewStaticCredentials("dyno", "dyno", ""),
+	})
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+	l.session = sess
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	if err := l.wait(ctx, timeout); err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	for _, input := range opts.Tables {
+		if err := l.CreateTable(ctx, input); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
+
+func (l *Local) startContainer(ctx context.Context, image string) error {
+	if image == "" {
+		image = DefaultImage
+	}
+
+	id, err := docker(ctx, "run", "--detach", "--rm", "--publish", "127.0.0.1::8000", image, "-jar", "DynamoDBLocal.jar", "-inMemory")
+	if err != nil {
+		return err
+	}
+	l.container = id
+
+	address, err := docker(ctx, "port", id, "8000/tcp")
+	if err != nil {
+		l.Close()
+		return err
+	}
+	// docker may list an address per interface, they all point at the same port
+	l.Endpoint = "http://" + strings.Fields(address)[0]
+
+	return nil
+}
+
+func (l *Local) wait(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	db := l.Client()
+	for {
+		_, err := db.ListTablesWithContext(ctx, &dynamodb.ListTablesInput{})
+		if err == nil {
+			return nil
+		}
+		if sleepContext(ctx, 100*time.Millisecond) != nil {
+			return fmt.Errorf("%w: %s", ErrLocalNotReady, err)
+		}
+	}
+}
+
+// Client returns a DynamoDB client for the instance
+func (l *Local) Client() *dynamodb.DynamoDB {
+	return dynamodb.New(l.session)
+}
+
+// Streams returns a DynamoDB Streams client for the instance
+func (l *Local) Streams() *dynamodbstreams.DynamoDBStreams {
+	return dynamodbstreams.New(l.session)
+}
+
+// CreateTable creates a table and waits for it to become active. The table is deleted by Close.
+func (l *Local) CreateTable(ctx context.Context, input *dynamodb.CreateTableInput) error {
+	db := l.Client()
+
+	if _, err := db.CreateTableWithContext(ctx, input); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.tables = append(l.tables, aws.StringValue(input.TableName))
+	l.mu.Unlock()
+
+	return db.WaitUntilTableExistsWithContext(ctx, &dynamodb.DescribeTableInput{TableName: input.TableName})
+}
+
+// Close deletes the tables created by the harness and stops the container, if one was started
+func (l *Local) Close() error {
+	l.mu.Lock()
+	tables := l.tables
+	l.tables = nil
+	l.mu.Unlock()
+
+	var err error
+	if l.session != nil {
+		db := l.Client()
+		for _, name := range tables {
+			if _, derr := db.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String(name)}); derr != nil && err == nil {
+				err = derr
+			}
+		}
+	}
+
+	if l.container != "" {
+		if _, derr := docker(context.Background(), "rm", "--force", l.container); derr != nil && err == nil {
+			err = derr
+		}
+		l.container = ""
+	}
+
+	return err
+}
+
+func docker(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("docker %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,218 @@
+package dynotest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// UpdateGoldenEnv names the environment variable that makes Golden rewrite golden files instead of comparing them
+const UpdateGoldenEnv = "DYNOTEST_UPDATE_GOLDEN"
+
+// SnapshotOptions configures Snapshot
+type SnapshotOptions struct {
+	// Prefix limits the snapshot to items whose partition key begins with it
+	Prefix string
+
+	// Ignore lists attributes left out of the snapshot, like timestamps and generated IDs
+	Ignore []string
+}
+
+// Snapshot returns the items in a table as canonical JSON. Items are ordered by key, attributes by name, and set
+// members by value, so the same table contents always produce the same bytes.
+func Snapshot(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName string, opts *SnapshotOptions) ([]byte, error) {
+	if opts == nil {
+		opts = &SnapshotOptions{}
+	}
+
+	desc, err := db.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+	if err != nil {
+		return nil, err
+	}
+	hashKey, rangeKey := keySchema(desc.Table.KeySchema)
+
+	input := &dynamodb.ScanInput{TableName: aws.String(tableName)}
+	if opts.Prefix != "" {
+		input.FilterExpression = aws.String("begins_with(#pk, :prefix)")
+		input.ExpressionAttributeNames = map[string]*string{"#pk": aws.String(hashKey)}
+		input.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{":prefix": {S: aws.String(opts.Prefix)}}
+	}
+
+	items := []item{}
+	err = db.ScanPagesWithContext(ctx, input, func(page *dynamodb.ScanOutput, _ bool) bool {
+		items = append(items, page.Items...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	order := []string{hashKey, rangeKey}
+	sort.SliceStable(items, func(a, b int) bool {
+		return compareKeys(items[a], items[b], order) < 0
+	})
+
+	snapshot := make([]map[string]interface{}, len(items))
+	for index, i := range items {
+		out := make(map[string]interface{}, len(i))
+		for name, v := range i {
+			out[name] = snapshotValue(v)
+		}
+		for _, name := range opts.Ignore {
+			delete(out, name)
+		}
+		snapshot[index] = out
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
+
+func snapshotValue(v *dynamodb.AttributeValue) map[string]interface{} {
+	switch {
+	case v.S != nil:
+		return map[string]interface{}{"S": *v.S}
+	case v.N != nil:
+		return map[string]interface{}{"N": *v.N}
+	case v.B != nil:
+		return map[string]interface{}{"B": v.B}
+	case v.BOOL != nil:
+		return map[string]interface{}{"BOOL": *v.BOOL}
+	case v.NULL != nil:
+		return map[string]interface{}{"NULL": *v.NULL}
+	case v.SS != nil:
+		ss := aws.StringValueSlice(v.SS)
+		sort.Strings(ss)
+		return map[string]interface{}{"SS": ss}
+	case v.NS != nil:
+		ns := aws.StringValueSlice(v.NS)
+		sort.Slice(ns, func(a, b int) bool { return compareNumbers(ns[a], ns[b]) < 0 })
+		return map[string]interface{}{"NS": ns}
+	case v.BS != nil:
+		bs := append([][]byte{}, v.BS...)
+		sort.Slice(bs, func(a, b int) bool { return bytes.Compare(bs[a], bs[b]) < 0 })
+		return map[string]interface{}{"BS": bs}
+	case v.L != nil:
+		l := make([]interface{}, len(v.L))
+		for index, e := range v.L {
+			l[index] = snapshotValue(e)
+		}
+		return map[string]interface{}{"L": l}
+	case v.M != nil:
+		m := make(map[string]interface{}, len(v.M))
+		for name, e := range v.M {
+			m[name] = snapshotValue(e)
+		}
+		return map[string]interface{}{"M": m}
+	}
+	return map[string]interface{}{}
+}
+
+// Golden fails the test when the snapshot does not match the golden file, reporting a line diff. When the
+// UpdateGoldenEnv environment variable is set, the golden file is written instead.
+func Golden(t testing.TB, path string, snapshot []byte) {
+	t.Helper()
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, snapshot, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	golden, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%s (set %s=1 to create it)", err, UpdateGoldenEnv)
+	}
+
+	if !bytes.Equal(golden, snapshot) {
+		t.Errorf("snapshot does not match %s (set %s=1 to update it)\n%s", path, UpdateGoldenEnv, diff(string(golden), string(snapshot)))
+	}
+}
+
+// diff returns a line diff of a and b, with unchanged lines far from a change left out
+func diff(a, b string) string {
+	x := strings.Split(strings.TrimSuffix(a, "\n"), "\n")
+	y := strings.Split(strings.TrimSuffix(b, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	type line struct {
+		op   byte
+		text string
+	}
+	lines := []line{}
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			lines = append(lines, line{' ', x[i]})
+			i++
+			j++
+		case j < len(y) && (i == len(x) || lcs[i][j+1] >= lcs[i+1][j]):
+			lines = append(lines, line{'+', y[j]})
+			j++
+		default:
+			lines = append(lines, line{'-', x[i]})
+			i++
+		}
+	}
+
+	const context = 3
+	var out strings.Builder
+	out.WriteString("--- golden\n+++ snapshot\n")
+	skipped := false
+	for index, l := range lines {
+		near := false
+		for k := index - context; k <= index+context; k++ {
+			if k >= 0 && k < len(lines) && lines[k].op != ' ' {
+				near = true
+				break
+			}
+		}
+		if !near {
+			if !skipped {
+				out.WriteString("...\n")
+				skipped = true
+			}
+			continue
+		}
+		skipped = false
+		fmt.Fprintf(&out, "%c %s\n", l.op, l.text)
+	}
+
+	return out.String()
+}
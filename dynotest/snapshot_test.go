@@ -0,0 +1,60 @@
+package dynotest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/maddiesch/dyno/dynotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	db := newTable(t)
+
+	fixtures := dynotest.NewFixtures(db, "Test")
+	err := fixtures.Load(ctx,
+		map[string]interface{}{"PK": "b", "SK": "1", "Colors": []string{"red", "blue"}},
+		map[string]interface{}{"PK": "a", "SK": "2", "Count": 2, "UpdatedAt": "{{rfc3339 now}}"},
+		map[string]interface{}{"PK": "a", "SK": "1", "Count": 1, "UpdatedAt": "{{rfc3339 now}}"},
+	)
+	require.NoError(t, err)
+
+	t.Run("given the whole table", func(t *testing.T) {
+		snapshot, err := dynotest.Snapshot(ctx, db, "Test", &dynotest.SnapshotOptions{Ignore: []string{"UpdatedAt"}})
+
+		require.NoError(t, err)
+		dynotest.Golden(t, "testdata/snapshot.golden.json", snapshot)
+	})
+
+	t.Run("given a prefix", func(t *testing.T) {
+		snapshot, err := dynotest.Snapshot(ctx, db, "Test", &dynotest.SnapshotOptions{Prefix: "b"})
+
+		require.NoError(t, err)
+		assert.NotContains(t, string(snapshot), `"a"`)
+		assert.Contains(t, string(snapshot), `"blue"`)
+	})
+
+	t.Run("given a mismatch", func(t *testing.T) {
+		r := &recorder{TB: t}
+
+		dynotest.Golden(r, "testdata/snapshot.golden.json", []byte("[]\n"))
+
+		require.Len(t, r.errors, 1)
+		assert.Contains(t, r.errors[0], "--- golden")
+		assert.Contains(t, r.errors[0], "+ []")
+	})
+}
@@ -0,0 +1,43 @@
+// Package dynoxray reports dyno operations to AWS X-Ray.
+//
+// Set the tracer on the types that support one, e.g. lock.Tracer(dynoxray.Tracer{}), and pass a context that carries
+// an X-Ray segment. Each operation becomes a subsegment with dyno's annotations, and AWS calls made with the context
+// are nested under it when the DynamoDB client is instrumented with xray.AWS. A context without a segment is handled
+// by the configured xray ContextMissingStrategy, which panics by default.
+package dynoxray
+
+import (
+	"context"
+
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/maddiesch/dyno"
+)
+
+// Tracer records dyno operations as X-Ray subsegments
+type Tracer struct{}
+
+var _ dyno.Tracer = Tracer{}
+
+func (Tracer) StartSpan(ctx context.Context, name string) (context.Context, dyno.Span) {
+	ctx, seg := xray.BeginSubsegment(ctx, name)
+	if seg == nil { // There is no segment in the context and the ContextMissingStrategy didn't panic
+		return ctx, span{}
+	}
+	return ctx, span{seg: seg}
+}
+
+type span struct {
+	seg *xray.Segment
+}
+
+func (s span) Annotate(key string, value interface{}) {
+	if s.seg != nil {
+		s.seg.AddAnnotation(key, value)
+	}
+}
+
+func (s span) End(err error) {
+	if s.seg != nil {
+		s.seg.Close(err)
+	}
+}
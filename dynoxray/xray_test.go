@@ -0,0 +1,44 @@
+package dynoxray
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-xray-sdk-go/strategy/ctxmissing"
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracer(t *testing.T) {
+	t.Run("given a segment", func(t *testing.T) {
+		ctx, root := xray.BeginSegment(context.Background(), "test")
+		defer root.Close(nil)
+
+		ctx, span := Tracer{}.StartSpan(ctx, "dyno.Lock.Acquire")
+		span.Annotate("dyno_lock", "test-lock")
+		span.End(errors.New("boom"))
+
+		seg := xray.GetSegment(ctx)
+		require.NotNil(t, seg)
+		assert.Equal(t, "dyno.Lock.Acquire", seg.Name)
+		assert.True(t, seg.Fault)
+		if !seg.Dummy {
+			assert.Equal(t, "test-lock", seg.Annotations["dyno_lock"])
+		}
+	})
+
+	t.Run("given no segment", func(t *testing.T) {
+		ctx, err := xray.ContextWithConfig(context.Background(), xray.Config{
+			ContextMissingStrategy: ctxmissing.NewDefaultLogErrorStrategy(),
+		})
+		require.NoError(t, err)
+
+		ctx, span := Tracer{}.StartSpan(ctx, "dyno.Lock.Acquire")
+		span.Annotate("dyno_lock", "test-lock")
+		span.End(nil)
+
+		assert.Nil(t, xray.GetSegment(ctx))
+	})
+}
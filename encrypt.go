@@ -0,0 +1,493 @@
+package dyno
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+)
+
+// materialAttribute holds the encrypted data key, the encrypted and signed attribute names, and the signature
+const materialAttribute = "Dyno_Material"
+
+const encryptionAlgorithm = "AES-256-GCM/HMAC-SHA256"
+
+var (
+	ErrSignatureMismatch  = errors.New("item signature does not match its attributes")
+	ErrUnknownAlgorithm   = errors.New("item was encrypted with an unknown algorithm")
+	ErrInvalidCiphertext  = errors.New("encrypted attribute is too short")
+	ErrMissingDescription = errors.New("item does not have a material description")
+	ErrInvalidDescription = errors.New("item has an invalid material description")
+)
+
+// Keyring provides the data keys used to encrypt items
+type Keyring interface {
+	// GenerateDataKey returns a new 256 bit data key and its encrypted form
+	GenerateDataKey(ctx context.Context) (plaintext, encrypted []byte, err error)
+
+	// DecryptDataKey returns the plaintext of a data key returned by GenerateDataKey
+	DecryptDataKey(ctx context.Context, encrypted []byte) ([]byte, error)
+}
+
+// KMSKeyring generates data keys with a KMS customer master key. Data keys are cached so that writing and reading many
+// items doesn't call KMS for every item.
+type KMSKeyring struct {
+	client kmsiface.KMSAPI
+	keyID  string
+
+	// EncryptionContext is sent with every KMS request
+	EncryptionContext map[string]string
+
+	// MaxAge is how long a data key is reused for new items, and how long decrypted data keys are cached.
+	// Defaults to 5 minutes.
+	MaxAge time.Duration
+
+	// MaxUses is how many items a data key encrypts before a new one is generated. Defaults to 1000.
+	MaxUses int
+
+	mu        sync.Mutex
+	current   *cachedDataKey
+	decrypted map[string]*cachedDataKey
+}
+
+type cachedDataKey struct {
+	plaintext []byte
+	encrypted []byte
+	createdAt time.Time
+	uses      int
+}
+
+func NewKMSKeyring(client kmsiface.KMSAPI, keyID string) *KMSKeyring {
+	return &KMSKeyring{
+		client:    client,
+		keyID:     keyID,
+		MaxAge:    5 * time.Minute,
+		MaxUses:   1000,
+		decrypted: map[string]*cachedDataKey{},
+	}
+}
+
+func (k *KMSKeyring) GenerateDataKey(ctx context.Context) ([]byte, []byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.current != nil && time.Since(k.current.createdAt) < k.MaxAge && k.current.uses < k.MaxUses {
+		k.current.uses++
+		return k.current.plaintext, k.current.encrypted, nil
+	}
+
+	result, err := k.client.GenerateDataKeyWithContext(ctx, &kms.GenerateDataKeyInput{
+		KeyId:             aws.String(k.keyID),
+		KeySpec:           aws.String(kms.DataKeySpecAes256),
+		EncryptionContext: aws.StringMap(k.EncryptionContext),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	k.current = &cachedDataKey{
+		plaintext: result.Plaintext,
+		encrypted: result.CiphertextBlob,
+		createdAt: time.Now(),
+		uses:      1,
+	}
+	k.decrypted[string(result.CiphertextBlob)] = k.current
+
+	return result.Plaintext, result.CiphertextBlob, nil
+}
+
+func (k *KMSKeyring) DecryptDataKey(ctx context.Context, encrypted []byte) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if cached, ok := k.decrypted[string(encrypted)]; ok && time.Since(cached.createdAt) < k.MaxAge {
+		return cached.plaintext, nil
+	}
+
+	result, err := k.client.DecryptWithContext(ctx, &kms.DecryptInput{
+		KeyId:             aws.String(k.keyID),
+		CiphertextBlob:    encrypted,
+		EncryptionContext: aws.StringMap(k.EncryptionContext),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for key, cached := range k.decrypted {
+		if time.Since(cached.createdAt) >= k.MaxAge {
+			delete(k.decrypted, key)
+		}
+	}
+	k.decrypted[string(encrypted)] = &cachedDataKey{
+		plaintext: result.Plaintext,
+		encrypted: encrypted,
+		createdAt: time.Now(),
+	}
+
+	return result.Plaintext, nil
+}
+
+// RawKeyring wraps data keys with a local 256 bit AES key. It's intended for tests and local development.
+type RawKeyring struct {
+	aead cipher.AEAD
+}
+
+func NewRawKeyring(key []byte) (*RawKeyring, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return &RawKeyring{aead: aead}, nil
+}
+
+func (k *RawKeyring) GenerateDataKey(ctx context.Context) ([]byte, []byte, error) {
+	plaintext := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, plaintext); err != nil {
+		return nil, nil, err
+	}
+
+	encrypted, err := seal(k.aead, plaintext, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return plaintext, encrypted, nil
+}
+
+func (k *RawKeyring) DecryptDataKey(ctx context.Context, encrypted []byte) ([]byte, error) {
+	return unseal(k.aead, encrypted, nil)
+}
+
+// Encryptor encrypts attributes of an item before it's written and decrypts them after it's read.
+//
+// Every item gets a material description attribute holding its encrypted data key and an HMAC signature over the
+// item's key, the encrypted attributes and any additional signed attributes, so tampering with them or moving the
+// item to another key is detected by Decrypt.
+type Encryptor struct {
+	keyring    Keyring
+	pk         string
+	sk         string
+	attributes []string
+
+	// SignedAttributes are left in plaintext but are covered by the signature
+	SignedAttributes []string
+}
+
+// NewEncryptor returns an Encryptor for items in a table with the given key attributes. The sort key may be empty.
+func NewEncryptor(keyring Keyring, primaryKey, sortKey string, attributes ...string) *Encryptor {
+	return &Encryptor{
+		keyring:    keyring,
+		pk:         primaryKey,
+		sk:         sortKey,
+		attributes: attributes,
+	}
+}
+
+// Encrypt returns a copy of the item with the attributes encrypted and a material description added
+func (e *Encryptor) Encrypt(ctx context.Context, item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	plaintext, encrypted, err := e.keyring.GenerateDataKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newGCM(plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]*dynamodb.AttributeValue, len(item)+1)
+	for name, av := range item {
+		out[name] = av
+	}
+
+	encryptedNames := []string{}
+	for _, name := range e.attributes {
+		av, ok := item[name]
+		if !ok {
+			continue
+		}
+
+		data, err := json.Marshal(attributeValueJSON(av))
+		if err != nil {
+			return nil, err
+		}
+
+		ciphertext, err := seal(aead, data, []byte(name))
+		if err != nil {
+			return nil, err
+		}
+
+		out[name] = &dynamodb.AttributeValue{B: ciphertext}
+		encryptedNames = append(encryptedNames, name)
+	}
+
+	signedNames := []string{}
+	for _, name := range e.SignedAttributes {
+		if _, ok := item[name]; ok {
+			signedNames = append(signedNames, name)
+		}
+	}
+
+	sort.Strings(encryptedNames)
+	sort.Strings(signedNames)
+
+	material := map[string]*dynamodb.AttributeValue{
+		"Algorithm": {S: aws.String(encryptionAlgorithm)},
+		"Key":       {B: encrypted},
+	}
+	if len(encryptedNames) > 0 {
+		material["Encrypted"] = &dynamodb.AttributeValue{SS: aws.StringSlice(encryptedNames)}
+	}
+	if len(signedNames) > 0 {
+		material["Signed"] = &dynamodb.AttributeValue{SS: aws.StringSlice(signedNames)}
+	}
+
+	signature, err := signItem(plaintext, out, material, e.keys())
+	if err != nil {
+		return nil, err
+	}
+	material["Signature"] = &dynamodb.AttributeValue{B: signature}
+	out[materialAttribute] = &dynamodb.AttributeValue{M: material}
+
+	return out, nil
+}
+
+// Decrypt verifies the item's signature and returns a copy of it with the attributes decrypted
+func (e *Encryptor) Decrypt(ctx context.Context, item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	desc, ok := item[materialAttribute]
+	if !ok || desc.M == nil {
+		return nil, ErrMissingDescription
+	}
+	if algorithm := desc.M["Algorithm"]; algorithm == nil || aws.StringValue(algorithm.S) != encryptionAlgorithm {
+		return nil, ErrUnknownAlgorithm
+	}
+
+	material := make(map[string]*dynamodb.AttributeValue, len(desc.M))
+	for name, av := range desc.M {
+		if av == nil {
+			return nil, ErrInvalidDescription
+		}
+		if name != "Signature" {
+			material[name] = av
+		}
+	}
+
+	key, ok := material["Key"]
+	if !ok || len(key.B) == 0 {
+		return nil, ErrInvalidDescription
+	}
+
+	plaintext, err := e.keyring.DecryptDataKey(ctx, key.B)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := signItem(plaintext, item, material, e.keys())
+	if err != nil {
+		return nil, err
+	}
+	if sig, ok := desc.M["Signature"]; !ok || !hmac.Equal(signature, sig.B) {
+		return nil, ErrSignatureMismatch
+	}
+
+	aead, err := newGCM(plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]*dynamodb.AttributeValue, len(item))
+	for name, av := range item {
+		if name != materialAttribute {
+			out[name] = av
+		}
+	}
+
+	if names, ok := material["Encrypted"]; ok {
+		for _, name := range aws.StringValueSlice(names.SS) {
+			ciphertext, ok := item[name]
+			if !ok || ciphertext == nil {
+				return nil, ErrInvalidDescription
+			}
+
+			data, err := unseal(aead, ciphertext.B, []byte(name))
+			if err != nil {
+				return nil, err
+			}
+
+			av := &dynamodb.AttributeValue{}
+			if err := json.Unmarshal(data, av); err != nil {
+				return nil, err
+			}
+			out[name] = av
+		}
+	}
+
+	return out, nil
+}
+
+// keys returns the names of the item's key attributes, which are always signed
+func (e *Encryptor) keys() []string {
+	if e.sk == "" {
+		return []string{e.pk}
+	}
+	return []string{e.pk, e.sk}
+}
+
+// signItem signs the material description, the key attributes, and every attribute the description names as encrypted
+// or signed. They're signed in a canonical form, so the signature still matches once DynamoDB has reordered their sets
+// and normalized their numbers.
+func signItem(key []byte, item, material map[string]*dynamodb.AttributeValue, keys []string) ([]byte, error) {
+	names := append([]string{}, keys...)
+	for _, list := range []string{"Encrypted", "Signed"} {
+		if av, ok := material[list]; ok {
+			names = append(names, aws.StringValueSlice(av.SS)...)
+		}
+	}
+	sort.Strings(names)
+
+	signed := map[string]*dynamodb.AttributeValue{
+		materialAttribute: {M: material},
+	}
+	for _, name := range names {
+		if av, ok := item[name]; ok {
+			signed[name] = av
+		}
+	}
+
+	data, err := json.Marshal(canonicalItemJSON(signed))
+	if err != nil {
+		return nil, err
+	}
+
+	derive := hmac.New(sha256.New, key)
+	derive.Write([]byte("dyno-item-signature"))
+
+	mac := hmac.New(sha256.New, derive.Sum(nil))
+	mac.Write(data)
+
+	return mac.Sum(nil), nil
+}
+
+// canonicalItemJSON is itemJSON with set members sorted and numbers normalized, the way an item is compared once it's
+// been stored
+func canonicalItemJSON(item map[string]*dynamodb.AttributeValue) map[string]interface{} {
+	out := make(map[string]interface{}, len(item))
+	for name, av := range item {
+		out[name] = canonicalValueJSON(av)
+	}
+	return out
+}
+
+func canonicalValueJSON(av *dynamodb.AttributeValue) map[string]interface{} {
+	switch {
+	case av == nil || av.S != nil:
+		return attributeValueJSON(av)
+	case av.N != nil:
+		return map[string]interface{}{"N": canonicalNumber(*av.N)}
+	case av.B != nil || av.BOOL != nil:
+		return attributeValueJSON(av)
+	case av.SS != nil:
+		members := aws.StringValueSlice(av.SS)
+		sort.Strings(members)
+		return map[string]interface{}{"SS": members}
+	case av.NS != nil:
+		members := make([]string, len(av.NS))
+		for i, n := range av.NS {
+			members[i] = canonicalNumber(aws.StringValue(n))
+		}
+		sort.Strings(members)
+		return map[string]interface{}{"NS": members}
+	case av.BS != nil:
+		members := append([][]byte{}, av.BS...)
+		sort.Slice(members, func(i, j int) bool { return bytes.Compare(members[i], members[j]) < 0 })
+		return map[string]interface{}{"BS": members}
+	case av.L != nil:
+		list := make([]interface{}, len(av.L))
+		for i, v := range av.L {
+			list[i] = canonicalValueJSON(v)
+		}
+		return map[string]interface{}{"L": list}
+	case av.M != nil:
+		return map[string]interface{}{"M": canonicalItemJSON(av.M)}
+	default:
+		return attributeValueJSON(av)
+	}
+}
+
+// canonicalNumber returns a number as its significant digits and an exponent, e.g. "1.50" and "15e-1" are both "15e-1",
+// so numbers DynamoDB stores the same way compare equal. A string that isn't a number is returned as is.
+func canonicalNumber(n string) string {
+	s := strings.TrimPrefix(strings.TrimSpace(n), "+")
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+
+	exponent := 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return n
+		}
+		s, exponent = s[:i], e
+	}
+
+	digits := s
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		digits = s[:i] + s[i+1:]
+		exponent -= len(s) - i - 1
+	}
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return n
+	}
+
+	digits = strings.TrimLeft(digits, "0")
+	if digits == "" {
+		return "0"
+	}
+	trimmed := strings.TrimRight(digits, "0")
+	exponent += len(digits) - len(trimmed)
+
+	return fmt.Sprintf("%s%se%d", sign, trimmed, exponent)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts the plaintext with a random nonce, which is prepended to the ciphertext
+func seal(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func unseal(aead cipher.AEAD, ciphertext, additional []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+	return aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], additional)
+}
@@ -0,0 +1,234 @@
+package dyno
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingKMS struct {
+	kmsiface.KMSAPI
+	keyring   *RawKeyring
+	generated int
+	decrypted int
+}
+
+func (c *countingKMS) GenerateDataKeyWithContext(ctx aws.Context, input *kms.GenerateDataKeyInput, opts ...request.Option) (*kms.GenerateDataKeyOutput, error) {
+	c.generated++
+	plaintext, encrypted, err := c.keyring.GenerateDataKey(ctx)
+	return &kms.GenerateDataKeyOutput{Plaintext: plaintext, CiphertextBlob: encrypted}, err
+}
+
+func (c *countingKMS) DecryptWithContext(ctx aws.Context, input *kms.DecryptInput, opts ...request.Option) (*kms.DecryptOutput, error) {
+	c.decrypted++
+	plaintext, err := c.keyring.DecryptDataKey(ctx, input.CiphertextBlob)
+	return &kms.DecryptOutput{Plaintext: plaintext}, err
+}
+
+func TestEncryptor(t *testing.T) {
+	ctx := context.Background()
+	keyring, err := NewRawKeyring(make([]byte, 32))
+	require.NoError(t, err)
+
+	encryptor := NewEncryptor(keyring, "PK", "SK", "SSN", "Address")
+	encryptor.SignedAttributes = []string{"Email"}
+
+	item := map[string]*dynamodb.AttributeValue{
+		"PK":      {S: aws.String("user-1")},
+		"SK":      {S: aws.String("profile")},
+		"Email":   {S: aws.String("user-1@example.com")},
+		"SSN":     {S: aws.String("123-45-6789")},
+		"Address": {M: map[string]*dynamodb.AttributeValue{"City": {S: aws.String("Portland")}}},
+		"Visits":  {N: aws.String("3")},
+	}
+
+	encrypted, err := encryptor.Encrypt(ctx, item)
+	require.NoError(t, err)
+
+	t.Run("Encrypt", func(t *testing.T) {
+		assert.Nil(t, encrypted["SSN"].S)
+		assert.NotEmpty(t, encrypted["SSN"].B)
+		assert.NotEmpty(t, encrypted["Address"].B)
+		assert.Equal(t, "3", aws.StringValue(encrypted["Visits"].N))
+		assert.Contains(t, encrypted, materialAttribute)
+	})
+
+	t.Run("Decrypt", func(t *testing.T) {
+		decrypted, err := encryptor.Decrypt(ctx, encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, item, decrypted)
+	})
+
+	t.Run("given a modified signed attribute", func(t *testing.T) {
+		tampered := copyItem(encrypted)
+		tampered["Email"] = &dynamodb.AttributeValue{S: aws.String("user-2@example.com")}
+
+		_, err := encryptor.Decrypt(ctx, tampered)
+		assert.Equal(t, ErrSignatureMismatch, err)
+	})
+
+	t.Run("given an item moved to another key", func(t *testing.T) {
+		for _, key := range []string{"PK", "SK"} {
+			tampered := copyItem(encrypted)
+			tampered[key] = &dynamodb.AttributeValue{S: aws.String("user-2")}
+
+			_, err := encryptor.Decrypt(ctx, tampered)
+			assert.Equal(t, ErrSignatureMismatch, err, key)
+		}
+	})
+
+	t.Run("given an invalid material description", func(t *testing.T) {
+		cases := map[string]struct {
+			material map[string]*dynamodb.AttributeValue
+			err      error
+		}{
+			"without an algorithm": {map[string]*dynamodb.AttributeValue{"Key": {B: []byte("key")}}, ErrUnknownAlgorithm},
+			"without a key":        {map[string]*dynamodb.AttributeValue{"Algorithm": {S: aws.String(encryptionAlgorithm)}}, ErrInvalidDescription},
+			"with a null key":      {map[string]*dynamodb.AttributeValue{"Algorithm": {S: aws.String(encryptionAlgorithm)}, "Key": nil}, ErrInvalidDescription},
+		}
+		for name, c := range cases {
+			tampered := copyItem(encrypted)
+			tampered[materialAttribute] = &dynamodb.AttributeValue{M: c.material}
+
+			_, err := encryptor.Decrypt(ctx, tampered)
+			assert.Equal(t, c.err, err, name)
+		}
+
+		_, err := encryptor.Decrypt(ctx, map[string]*dynamodb.AttributeValue{"PK": {S: aws.String("user-1")}})
+		assert.Equal(t, ErrMissingDescription, err)
+	})
+
+	t.Run("given a swapped encrypted attribute", func(t *testing.T) {
+		other, err := encryptor.Encrypt(ctx, item)
+		require.NoError(t, err)
+
+		tampered := copyItem(encrypted)
+		tampered["SSN"] = other["SSN"]
+
+		_, err = encryptor.Decrypt(ctx, tampered)
+		assert.Equal(t, ErrSignatureMismatch, err)
+	})
+
+	t.Run("given an unsigned attribute change", func(t *testing.T) {
+		changed := copyItem(encrypted)
+		changed["Visits"] = &dynamodb.AttributeValue{N: aws.String("4")}
+
+		_, err := encryptor.Decrypt(ctx, changed)
+		assert.NoError(t, err)
+	})
+}
+
+func TestEncryptorStoredItem(t *testing.T) {
+	ctx := context.Background()
+	keyring, err := NewRawKeyring(make([]byte, 32))
+	require.NoError(t, err)
+
+	encryptor := NewEncryptor(keyring, "PK", "SK", "SSN", "Address")
+	encryptor.SignedAttributes = []string{"Tags", "Prices", "Total"}
+
+	item := map[string]*dynamodb.AttributeValue{
+		"PK":      {S: aws.String(NewKSUID())},
+		"SK":      {S: aws.String("profile")},
+		"SSN":     {S: aws.String("123-45-6789")},
+		"Address": {M: map[string]*dynamodb.AttributeValue{"City": {S: aws.String("Portland")}}},
+		"Tags":    {SS: aws.StringSlice([]string{"vip", "beta", "early"})},
+		"Prices":  {NS: aws.StringSlice([]string{"20.00", "1.50", "3"})},
+		"Total":   {N: aws.String("24.50")},
+	}
+	encrypted, err := encryptor.Encrypt(ctx, item)
+	require.NoError(t, err)
+
+	t.Run("given an item read back from the table", func(t *testing.T) {
+		_, err := testClient.PutItem(&dynamodb.PutItemInput{TableName: aws.String(tableName), Item: encrypted})
+		require.NoError(t, err)
+
+		result, err := testClient.GetItem(&dynamodb.GetItemInput{
+			TableName:      aws.String(tableName),
+			Key:            map[string]*dynamodb.AttributeValue{"PK": item["PK"], "SK": item["SK"]},
+			ConsistentRead: aws.Bool(true),
+		})
+		require.NoError(t, err)
+
+		decrypted, err := encryptor.Decrypt(ctx, result.Item)
+		require.NoError(t, err)
+		assert.Equal(t, "123-45-6789", aws.StringValue(decrypted["SSN"].S))
+	})
+
+	t.Run("given sets reordered and numbers normalized", func(t *testing.T) {
+		stored := copyItem(encrypted)
+		stored["Tags"] = &dynamodb.AttributeValue{SS: aws.StringSlice([]string{"early", "beta", "vip"})}
+		stored["Prices"] = &dynamodb.AttributeValue{NS: aws.StringSlice([]string{"3", "20", "1.5"})}
+		stored["Total"] = &dynamodb.AttributeValue{N: aws.String("24.5")}
+
+		material := map[string]*dynamodb.AttributeValue{}
+		for name, av := range encrypted[materialAttribute].M {
+			material[name] = av
+		}
+		material["Encrypted"] = &dynamodb.AttributeValue{SS: aws.StringSlice([]string{"SSN", "Address"})}
+		stored[materialAttribute] = &dynamodb.AttributeValue{M: material}
+
+		_, err := encryptor.Decrypt(ctx, stored)
+		assert.NoError(t, err)
+
+		stored["Total"] = &dynamodb.AttributeValue{N: aws.String("245")}
+		_, err = encryptor.Decrypt(ctx, stored)
+		assert.Equal(t, ErrSignatureMismatch, err)
+	})
+}
+
+func TestCanonicalNumber(t *testing.T) {
+	for n, canonical := range map[string]string{
+		"1.50":  "15e-1",
+		"01.5":  "15e-1",
+		"15e-1": "15e-1",
+		"100":   "1e2",
+		"1E2":   "1e2",
+		"-0.0":  "0",
+		"0":     "0",
+		"-2.50": "-25e-1",
+		"+7":    "7e0",
+		"abc":   "abc",
+	} {
+		assert.Equal(t, canonical, canonicalNumber(n), n)
+	}
+}
+
+func TestKMSKeyring(t *testing.T) {
+	ctx := context.Background()
+	raw, err := NewRawKeyring(make([]byte, 32))
+	require.NoError(t, err)
+
+	client := &countingKMS{keyring: raw}
+	keyring := NewKMSKeyring(client, "alias/dyno")
+	keyring.MaxUses = 2
+
+	_, first, err := keyring.GenerateDataKey(ctx)
+	require.NoError(t, err)
+	_, second, err := keyring.GenerateDataKey(ctx)
+	require.NoError(t, err)
+	_, third, err := keyring.GenerateDataKey(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, first, second)
+	assert.NotEqual(t, first, third)
+	assert.Equal(t, 2, client.generated)
+
+	_, err = keyring.DecryptDataKey(ctx, first)
+	require.NoError(t, err)
+	assert.Equal(t, 0, client.decrypted, "generated keys are cached")
+
+	_, other, err := raw.GenerateDataKey(ctx)
+	require.NoError(t, err)
+	_, err = keyring.DecryptDataKey(ctx, other)
+	require.NoError(t, err)
+	_, err = keyring.DecryptDataKey(ctx, other)
+	require.NoError(t, err)
+	assert.Equal(t, 1, client.decrypted)
+}
@@ -0,0 +1,170 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ErrorClass is the kind of failure an error from DynamoDB represents
+type ErrorClass int
+
+const (
+	// ErrorClassNone is the class of a nil error
+	ErrorClassNone ErrorClass = iota
+
+	// ErrorClassUnknown is an error that isn't from DynamoDB or isn't recognized
+	ErrorClassUnknown
+
+	// ErrorClassThrottling is a request rejected for exceeding capacity or a request rate limit
+	ErrorClassThrottling
+
+	// ErrorClassConditionalCheckFailed is a write whose condition expression, or a transaction where a condition,
+	// didn't hold
+	ErrorClassConditionalCheckFailed
+
+	// ErrorClassTransactionConflict is a request that conflicted with a transaction on the same item
+	ErrorClassTransactionConflict
+
+	// ErrorClassTransient is a server side failure or a network error that is likely to succeed on retry
+	ErrorClassTransient
+
+	// ErrorClassValidation is a malformed request that will fail again if it's retried
+	ErrorClassValidation
+
+	// ErrorClassNotFound is a request for a table or index that doesn't exist
+	ErrorClassNotFound
+
+	// ErrorClassCanceled is a request abandoned because its context was canceled or timed out
+	ErrorClassCanceled
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorClassNone:
+		return "none"
+	case ErrorClassThrottling:
+		return "throttling"
+	case ErrorClassConditionalCheckFailed:
+		return "conditional check failed"
+	case ErrorClassTransactionConflict:
+		return "transaction conflict"
+	case ErrorClassTransient:
+		return "transient"
+	case ErrorClassValidation:
+		return "validation"
+	case ErrorClassNotFound:
+		return "not found"
+	case ErrorClassCanceled:
+		return "canceled"
+	default:
+		return "unknown"
+	}
+}
+
+// Classify returns the class of a DynamoDB error, looking through wrapped errors. A canceled transaction is classified
+// by the most significant of its cancellation reasons.
+func Classify(err error) ErrorClass {
+	if err == nil {
+		return ErrorClassNone
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClassCanceled
+	}
+
+	var canceled *dynamodb.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		return classifyCancellation(canceled.CancellationReasons)
+	}
+
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return ErrorClassUnknown
+	}
+
+	if class, ok := classifyCode(aerr.Code()); ok {
+		return class
+	}
+
+	var failure awserr.RequestFailure
+	if errors.As(err, &failure) && failure.StatusCode() >= 500 {
+		return ErrorClassTransient
+	}
+
+	return ErrorClassUnknown
+}
+
+// IsRetryable returns true for throttling, transaction conflicts, and transient failures
+func IsRetryable(err error) bool {
+	switch Classify(err) {
+	case ErrorClassThrottling, ErrorClassTransactionConflict, ErrorClassTransient:
+		return true
+	default:
+		return false
+	}
+}
+
+func classifyCode(code string) (ErrorClass, bool) {
+	switch code {
+	case dynamodb.ErrCodeProvisionedThroughputExceededException,
+		dynamodb.ErrCodeRequestLimitExceeded,
+		dynamodb.ErrCodeLimitExceededException,
+		"ThrottlingException",
+		"ThrottlingError":
+		return ErrorClassThrottling, true
+	case dynamodb.ErrCodeConditionalCheckFailedException, "ConditionalCheckFailed":
+		return ErrorClassConditionalCheckFailed, true
+	case dynamodb.ErrCodeTransactionConflictException,
+		dynamodb.ErrCodeTransactionInProgressException,
+		"TransactionConflict":
+		return ErrorClassTransactionConflict, true
+	case dynamodb.ErrCodeInternalServerError,
+		"ServiceUnavailable",
+		request.ErrCodeRequestError,
+		request.ErrCodeResponseTimeout,
+		request.ErrCodeRead:
+		return ErrorClassTransient, true
+	case "ValidationException",
+		"SerializationException",
+		dynamodb.ErrCodeItemCollectionSizeLimitExceededException,
+		dynamodb.ErrCodeIdempotentParameterMismatchException:
+		return ErrorClassValidation, true
+	case dynamodb.ErrCodeResourceNotFoundException:
+		return ErrorClassNotFound, true
+	case request.CanceledErrorCode:
+		return ErrorClassCanceled, true
+	}
+	return ErrorClassUnknown, false
+}
+
+// classifyCancellation picks the reason that decides what the caller should do. A failed condition won't change on
+// retry, so it outranks the retryable reasons.
+func classifyCancellation(reasons []*dynamodb.CancellationReason) ErrorClass {
+	rank := []ErrorClass{
+		ErrorClassValidation,
+		ErrorClassConditionalCheckFailed,
+		ErrorClassThrottling,
+		ErrorClassTransactionConflict,
+	}
+
+	found := map[ErrorClass]bool{}
+	for _, reason := range reasons {
+		if reason == nil {
+			continue
+		}
+		if class, ok := classifyCode(aws.StringValue(reason.Code)); ok {
+			found[class] = true
+		}
+	}
+
+	for _, class := range rank {
+		if found[class] {
+			return class
+		}
+	}
+	return ErrorClassTransactionConflict
+}
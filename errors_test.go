@@ -0,0 +1,59 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	cases := map[string]struct {
+		err       error
+		class     ErrorClass
+		retryable bool
+	}{
+		"nil":                 {nil, ErrorClassNone, false},
+		"unknown":             {errors.New("boom"), ErrorClassUnknown, false},
+		"canceled":            {context.Canceled, ErrorClassCanceled, false},
+		"throughput":          {&dynamodb.ProvisionedThroughputExceededException{}, ErrorClassThrottling, true},
+		"throttling":          {awserr.New("ThrottlingException", "slow down", nil), ErrorClassThrottling, true},
+		"conditional":         {&dynamodb.ConditionalCheckFailedException{}, ErrorClassConditionalCheckFailed, false},
+		"wrapped conditional": {fmt.Errorf("put: %w", &dynamodb.ConditionalCheckFailedException{}), ErrorClassConditionalCheckFailed, false},
+		"conflict":            {&dynamodb.TransactionConflictException{}, ErrorClassTransactionConflict, true},
+		"internal":            {&dynamodb.InternalServerError{}, ErrorClassTransient, true},
+		"5xx":                 {awserr.NewRequestFailure(awserr.New("Unavailable", "down", nil), 503, "id"), ErrorClassTransient, true},
+		"4xx":                 {awserr.NewRequestFailure(awserr.New("Teapot", "no", nil), 418, "id"), ErrorClassUnknown, false},
+		"validation":          {awserr.New("ValidationException", "bad", nil), ErrorClassValidation, false},
+		"not found":           {&dynamodb.ResourceNotFoundException{}, ErrorClassNotFound, false},
+		"canceled transaction with a failed condition": {&dynamodb.TransactionCanceledException{
+			CancellationReasons: []*dynamodb.CancellationReason{
+				{Code: aws.String("TransactionConflict")},
+				{Code: aws.String("ConditionalCheckFailed")},
+			},
+		}, ErrorClassConditionalCheckFailed, false},
+		"canceled transaction with a conflict": {&dynamodb.TransactionCanceledException{
+			CancellationReasons: []*dynamodb.CancellationReason{
+				{Code: aws.String("None")},
+				{Code: aws.String("TransactionConflict")},
+			},
+		}, ErrorClassTransactionConflict, true},
+		"canceled transaction with throttling": {&dynamodb.TransactionCanceledException{
+			CancellationReasons: []*dynamodb.CancellationReason{
+				{Code: aws.String("ThrottlingError")},
+			},
+		}, ErrorClassThrottling, true},
+	}
+
+	for name, c := range cases {
+		t.Run(fmt.Sprintf("given %s", name), func(t *testing.T) {
+			assert.Equal(t, c.class, Classify(c.err))
+			assert.Equal(t, c.retryable, IsRetryable(c.err))
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package dyno
+
+import (
+	"time"
+)
+
+// LockEventType is what happened to a lock
+type LockEventType string
+
+const (
+	// LockAcquired is a lock acquired by this Lock
+	LockAcquired LockEventType = "acquired"
+
+	// LockContended is an attempt to acquire a lock that found it held. It's sent once for each holder.
+	LockContended LockEventType = "contended"
+
+	// LockStolen is a lock acquired by this Lock after the holder's lease expired
+	LockStolen LockEventType = "stolen"
+
+	// LockRenewed is a held lock whose lease was renewed
+	LockRenewed LockEventType = "renewed"
+
+	// LockExtended is a held lock renewed with a new lease by Extend
+	LockExtended LockEventType = "extended"
+
+	// LockReleased is a held lock that was released
+	LockReleased LockEventType = "released"
+
+	// LockLost is a lock found to be held by someone else after this Lock acquired it
+	LockLost LockEventType = "lost"
+
+	// LockHoldExceeded is a lock still held when its hold budget ran out. It's sent from the budget's timer, rather
+	// than from a call to the lock's methods.
+	LockHoldExceeded LockEventType = "hold exceeded"
+)
+
+// LockEvent describes something that happened to a lock
+type LockEvent struct {
+	Type LockEventType
+	Name string
+	Time time.Time
+
+	// LockID is the ID this Lock holds, or held, the lock with. It's empty for LockContended.
+	LockID string
+
+	// Holder is the lock ID of the holder that was found holding the lock, for LockContended and LockStolen
+	Holder string
+
+	// Wait is how long it took to acquire the lock, for LockAcquired and LockStolen
+	Wait time.Duration
+}
+
+// OnEvent calls fn with each event in the lock's lifecycle. It's called synchronously, so it must not block or call
+// the lock's methods. A renewal from a Heartbeat can report an event while the lock is being acquired, so fn may be
+// called concurrently.
+func (l *Lock) OnEvent(fn func(LockEvent)) {
+	l.onEvent = fn
+}
+
+func (l *Lock) event(e LockEvent) {
+	e.Name = l.name
+	e.Time = time.Now()
+
+	l.stats.record(e)
+	l.hold.observe(l, e)
+	if l.order != nil {
+		l.order.observe(l, e)
+	}
+	if l.logger != nil {
+		l.logger.Printf("dyno: lock %s %s lock_id=%s holder=%s wait=%s", l.name, e.Type, e.LockID, e.Holder, e.Wait)
+	}
+	if l.onEvent != nil {
+		l.onEvent(e)
+	}
+}
@@ -0,0 +1,47 @@
+package dyno
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockEvents(t *testing.T) {
+	ctx := context.Background()
+	events := []LockEvent{}
+	record := func(e LockEvent) { events = append(events, e) }
+	types := func() []LockEventType {
+		found := []LockEventType{}
+		for _, e := range events {
+			found = append(found, e.Type)
+		}
+		return found
+	}
+
+	holder := NewLock(testClient, tableName, "PK", "SK", "testing-lock-events")
+	holder.OnEvent(record)
+	waiter := NewLock(testClient, tableName, "PK", "SK", "testing-lock-events")
+	waiter.OnEvent(record)
+
+	require.NoError(t, holder.Acquire(30*time.Second))
+	assert.Equal(t, ErrLockAcquireTimeout, waiter.AcquireWithTimeout(30*time.Second, 50*time.Millisecond))
+	require.NoError(t, holder.Renew(ctx))
+	require.NoError(t, holder.Extend(ctx, time.Minute))
+	info, err := DescribeLock(ctx, testClient, tableName, "PK", "SK", "testing-lock-events")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, info.Lease)
+	require.NoError(t, holder.Release())
+
+	require.NoError(t, waiter.Acquire(30*time.Second))
+	require.NoError(t, ForceRelease(ctx, testClient, tableName, "PK", "SK", "testing-lock-events", ""))
+	assert.Equal(t, ErrLockNotOwned, waiter.Renew(ctx))
+
+	assert.Equal(t, []LockEventType{LockAcquired, LockContended, LockRenewed, LockExtended, LockReleased, LockAcquired, LockLost}, types())
+	assert.Equal(t, "testing-lock-events", events[0].Name)
+	assert.Equal(t, events[0].LockID, events[1].Holder)
+	assert.Empty(t, events[1].LockID)
+	assert.Equal(t, events[5].LockID, events[6].LockID)
+}
@@ -0,0 +1,104 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+var ErrExportFailed = errors.New("table export failed")
+
+// ExportOptions configures ExportTableToS3
+type ExportOptions struct {
+	// Prefix is the S3 key prefix the export is written under
+	Prefix string
+
+	// Format is DYNAMODB_JSON (the default) or ION
+	Format string
+
+	// ExportTime is the point in time to export. The zero value exports the current state of the table.
+	ExportTime time.Time
+
+	// BucketOwner is the account ID that owns the bucket, if it isn't the caller's account
+	BucketOwner string
+
+	// KMSKeyID encrypts the export with the KMS key instead of S3 managed keys
+	KMSKeyID string
+}
+
+// ExportTableToS3 starts a native export of the table to the bucket, blocks until it completes, and returns the S3 URL
+// of the export manifest. Point in time recovery must be enabled on the table.
+func ExportTableToS3(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName, bucket string, opts *ExportOptions) (string, error) {
+	if opts == nil {
+		opts = &ExportOptions{}
+	}
+
+	table, err := db.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	input := &dynamodb.ExportTableToPointInTimeInput{
+		TableArn: table.Table.TableArn,
+		S3Bucket: aws.String(bucket),
+	}
+	if opts.Prefix != "" {
+		input.S3Prefix = aws.String(opts.Prefix)
+	}
+	if opts.Format != "" {
+		input.ExportFormat = aws.String(opts.Format)
+	}
+	if !opts.ExportTime.IsZero() {
+		input.ExportTime = aws.Time(opts.ExportTime)
+	}
+	if opts.BucketOwner != "" {
+		input.S3BucketOwner = aws.String(opts.BucketOwner)
+	}
+	if opts.KMSKeyID != "" {
+		input.S3SseAlgorithm = aws.String(dynamodb.S3SseAlgorithmKms)
+		input.S3SseKmsKeyId = aws.String(opts.KMSKeyID)
+	}
+
+	result, err := db.ExportTableToPointInTimeWithContext(ctx, input)
+	if err != nil {
+		return "", err
+	}
+
+	desc, err := WaitForExport(ctx, db, aws.StringValue(result.ExportDescription.ExportArn))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("s3://%s/%s", bucket, aws.StringValue(desc.ExportManifest)), nil
+}
+
+// WaitForExport blocks until the export has completed
+func WaitForExport(ctx context.Context, db dynamodbiface.DynamoDBAPI, exportArn string) (*dynamodb.ExportDescription, error) {
+	for {
+		result, err := db.DescribeExportWithContext(ctx, &dynamodb.DescribeExportInput{
+			ExportArn: aws.String(exportArn),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		desc := result.ExportDescription
+		switch aws.StringValue(desc.ExportStatus) {
+		case dynamodb.ExportStatusCompleted:
+			return desc, nil
+		case dynamodb.ExportStatusFailed:
+			return nil, fmt.Errorf("%w: %s: %s", ErrExportFailed, aws.StringValue(desc.FailureCode), aws.StringValue(desc.FailureMessage))
+		}
+
+		if err := sleepContext(ctx, tablePollInterval); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryExports records the exports started through the client it wraps, and describes them with the given status
+type memoryExports struct {
+	dynamodbiface.DynamoDBAPI
+
+	status  string
+	exports []*dynamodb.ExportTableToPointInTimeInput
+}
+
+func (m *memoryExports) ExportTableToPointInTimeWithContext(ctx aws.Context, input *dynamodb.ExportTableToPointInTimeInput, opts ...request.Option) (*dynamodb.ExportTableToPointInTimeOutput, error) {
+	m.exports = append(m.exports, input)
+	return &dynamodb.ExportTableToPointInTimeOutput{
+		ExportDescription: &dynamodb.ExportDescription{ExportArn: aws.String("arn:aws:dynamodb:export/1")},
+	}, nil
+}
+
+func (m *memoryExports) DescribeExportWithContext(ctx aws.Context, input *dynamodb.DescribeExportInput, opts ...request.Option) (*dynamodb.DescribeExportOutput, error) {
+	return &dynamodb.DescribeExportOutput{
+		ExportDescription: &dynamodb.ExportDescription{
+			ExportArn:      input.ExportArn,
+			ExportStatus:   aws.String(m.status),
+			ExportManifest: aws.String("exports/AWSDynamoDB/1/manifest-summary.json"),
+			FailureCode:    aws.String("S3AccessDenied"),
+			FailureMessage: aws.String("access denied"),
+		},
+	}, nil
+}
+
+func TestExportTableToS3(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("given a completed export", func(t *testing.T) {
+		db := &memoryExports{DynamoDBAPI: testClient, status: dynamodb.ExportStatusCompleted}
+		at := time.Now().Add(-time.Hour)
+
+		url, err := ExportTableToS3(ctx, db, tableName, "backups", &ExportOptions{
+			Prefix:     "exports",
+			Format:     dynamodb.ExportFormatIon,
+			ExportTime: at,
+			KMSKeyID:   "key-id",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "s3://backups/exports/AWSDynamoDB/1/manifest-summary.json", url)
+
+		require.Len(t, db.exports, 1)
+		input := db.exports[0]
+		assert.NotEmpty(t, aws.StringValue(input.TableArn))
+		assert.Equal(t, "backups", aws.StringValue(input.S3Bucket))
+		assert.Equal(t, "exports", aws.StringValue(input.S3Prefix))
+		assert.Equal(t, dynamodb.ExportFormatIon, aws.StringValue(input.ExportFormat))
+		assert.Equal(t, at, aws.TimeValue(input.ExportTime))
+		assert.Equal(t, dynamodb.S3SseAlgorithmKms, aws.StringValue(input.S3SseAlgorithm))
+		assert.Equal(t, "key-id", aws.StringValue(input.S3SseKmsKeyId))
+	})
+
+	t.Run("given default options", func(t *testing.T) {
+		db := &memoryExports{DynamoDBAPI: testClient, status: dynamodb.ExportStatusCompleted}
+
+		_, err := ExportTableToS3(ctx, db, tableName, "backups", nil)
+		require.NoError(t, err)
+
+		input := db.exports[0]
+		assert.Nil(t, input.S3Prefix)
+		assert.Nil(t, input.ExportFormat)
+		assert.Nil(t, input.ExportTime)
+		assert.Nil(t, input.S3SseAlgorithm)
+	})
+
+	t.Run("given a failed export", func(t *testing.T) {
+		db := &memoryExports{DynamoDBAPI: testClient, status: dynamodb.ExportStatusFailed}
+
+		_, err := ExportTableToS3(ctx, db, tableName, "backups", nil)
+		assert.True(t, errors.Is(err, ErrExportFailed))
+		assert.Contains(t, err.Error(), "S3AccessDenied")
+	})
+}
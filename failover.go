@@ -0,0 +1,259 @@
+package dyno
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// FailoverRegion is a region a Failover sends requests to
+type FailoverRegion struct {
+	Region string
+
+	// Endpoint defaults to the region's DynamoDB endpoint
+	Endpoint string
+
+	// TableNames maps table names to the names of their replicas in this region. Tables that aren't in it keep their
+	// name, which is always the case for Global Tables.
+	TableNames map[string]string
+}
+
+// Failover sends a client's requests to a secondary region after the primary returns Threshold transient errors in a
+// row, and back to the primary once a health check succeeds. Install it on a client configured for the primary region,
+// and call Run to check the primary's health while failed over. Every dyno primitive using the client follows it.
+type Failover struct {
+	primary   FailoverRegion
+	secondary FailoverRegion
+
+	mu         sync.Mutex
+	failedOver bool
+	failures   int
+
+	// Threshold is how many transient errors in a row from the primary trigger a failover. Defaults to 5.
+	Threshold int
+
+	// HealthCheckInterval is how often Run checks the primary while failed over. Defaults to 30 seconds.
+	HealthCheckInterval time.Duration
+
+	// HealthCheck returns nil when the primary is healthy. The requests it makes through the client always go to the
+	// primary. Defaults to listing one table.
+	HealthCheck func(ctx context.Context, db dynamodbiface.DynamoDBAPI) error
+
+	// OnSwitch is called with the region requests are sent to after a failover or failback
+	OnSwitch func(active FailoverRegion)
+}
+
+func NewFailover(primary, secondary FailoverRegion) *Failover {
+	return &Failover{
+		primary:             primary,
+		secondary:           secondary,
+		Threshold:           5,
+		HealthCheckInterval: 30 * time.Second,
+		HealthCheck: func(ctx context.Context, db dynamodbiface.DynamoDBAPI) error {
+			_, err := db.ListTablesWithContext(ctx, &dynamodb.ListTablesInput{Limit: aws.Int64(1)})
+			return err
+		},
+	}
+}
+
+// Install adds the failover to the client's handlers, e.g. failover.Install(&client.Handlers)
+func (f *Failover) Install(handlers *request.Handlers) {
+	handlers.Validate.PushFrontNamed(request.NamedHandler{
+		Name: "dyno.Failover.Route",
+		Fn:   f.route,
+	})
+	handlers.Complete.PushFrontNamed(request.NamedHandler{
+		Name: "dyno.Failover.Complete",
+		Fn:   f.complete,
+	})
+}
+
+// Active returns the region requests are being sent to
+func (f *Failover) Active() FailoverRegion {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failedOver {
+		return f.secondary
+	}
+	return f.primary
+}
+
+// FailedOver returns true while requests are sent to the secondary region
+func (f *Failover) FailedOver() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.failedOver
+}
+
+// Run checks the primary's health on the interval while failed over, and fails back when it's healthy. It returns
+// when the context is done.
+func (f *Failover) Run(ctx context.Context, db dynamodbiface.DynamoDBAPI) error {
+	ticker := time.NewTicker(f.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if !f.FailedOver() {
+			continue
+		}
+		if err := f.HealthCheck(context.WithValue(ctx, failoverPrimaryKey{}, true), db); err == nil {
+			f.switchTo(false)
+		}
+	}
+}
+
+// failoverPrimaryKey marks the context of a health check, which always goes to the primary
+type failoverPrimaryKey struct{}
+
+// failoverRegionKey is the request's context key for the region it was sent to
+type failoverRegionKey struct{}
+
+func (f *Failover) route(r *request.Request) {
+	secondary := f.FailedOver() && r.Context().Value(failoverPrimaryKey{}) == nil
+	r.SetContext(context.WithValue(r.Context(), failoverRegionKey{}, secondary))
+	if !secondary {
+		return
+	}
+
+	endpoint := f.secondary.Endpoint
+	if endpoint == "" {
+		resolved, err := endpoints.DefaultResolver().EndpointFor(dynamodb.EndpointsID, f.secondary.Region)
+		if err != nil {
+			r.Error = err
+			return
+		}
+		endpoint = resolved.URL
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		r.Error = err
+		return
+	}
+
+	r.Config.Region = aws.String(f.secondary.Region)
+	r.ClientInfo.SigningRegion = f.secondary.Region
+	r.ClientInfo.Endpoint = endpoint
+	r.HTTPRequest.URL.Scheme = u.Scheme
+	r.HTTPRequest.URL.Host = u.Host
+	r.HTTPRequest.Host = ""
+	renameTables(reflect.ValueOf(r.Params), f.secondary.TableNames)
+}
+
+func (f *Failover) complete(r *request.Request) {
+	secondary, _ := r.Context().Value(failoverRegionKey{}).(bool)
+	if secondary {
+		// Give the caller back their input, and output, with the names they used
+		reverse := make(map[string]string, len(f.secondary.TableNames))
+		for from, to := range f.secondary.TableNames {
+			reverse[to] = from
+		}
+		renameTables(reflect.ValueOf(r.Params), reverse)
+		renameTables(reflect.ValueOf(r.Data), reverse)
+		return
+	}
+	if r.Context().Value(failoverPrimaryKey{}) != nil {
+		return
+	}
+
+	f.mu.Lock()
+	if Classify(r.Error) != ErrorClassTransient {
+		f.failures = 0
+		f.mu.Unlock()
+		return
+	}
+	f.failures++
+	failover := f.failures >= f.Threshold && !f.failedOver
+	f.mu.Unlock()
+
+	if failover {
+		f.switchTo(true)
+	}
+}
+
+func (f *Failover) switchTo(secondary bool) {
+	f.mu.Lock()
+	if f.failedOver == secondary {
+		f.mu.Unlock()
+		return
+	}
+	f.failedOver = secondary
+	f.failures = 0
+	f.mu.Unlock()
+
+	if f.OnSwitch != nil {
+		f.OnSwitch(f.Active())
+	}
+}
+
+// tableKeyedFields are the batch request and response fields that are maps keyed by table name
+var tableKeyedFields = map[string]bool{
+	"RequestItems":          true,
+	"Responses":             true,
+	"UnprocessedItems":      true,
+	"UnprocessedKeys":       true,
+	"ItemCollectionMetrics": true,
+}
+
+// renameTables renames the TableName fields, and the table name keys of batch requests and responses, found in a
+// request's input or output
+func renameTables(v reflect.Value, names map[string]string) {
+	if len(names) == 0 {
+		return
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			renameTables(v.Index(i), names)
+		}
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if v.Type().Field(i).PkgPath != "" {
+			continue
+		}
+
+		switch {
+		case v.Type().Field(i).Name == "TableName" && field.Type() == reflect.TypeOf((*string)(nil)):
+			if name, ok := names[aws.StringValue(field.Interface().(*string))]; ok {
+				field.Set(reflect.ValueOf(aws.String(name)))
+			}
+		case tableKeyedFields[v.Type().Field(i).Name] && field.Kind() == reflect.Map && !field.IsNil():
+			for _, key := range field.MapKeys() {
+				if name, ok := names[key.String()]; ok {
+					value := field.MapIndex(key)
+					field.SetMapIndex(key, reflect.Value{})
+					field.SetMapIndex(reflect.ValueOf(name), value)
+				}
+			}
+		case nestedRequest(field.Type()):
+			renameTables(field, names)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package dyno
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailover(t *testing.T) {
+	client := dynamodb.New(session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("dyno", "dyno", ""),
+		MaxRetries:  aws.Int(0),
+	})))
+
+	healthy := false
+	hosts := []string{}
+	tables := []string{}
+	client.Handlers.Send.Clear()
+	client.Handlers.Send.PushBack(func(r *request.Request) {
+		hosts = append(hosts, r.HTTPRequest.URL.Host)
+		if input, ok := r.Params.(*dynamodb.BatchGetItemInput); ok {
+			for table := range input.RequestItems {
+				tables = append(tables, table)
+			}
+		}
+
+		r.HTTPResponse = &http.Response{StatusCode: 200, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader("{}"))}
+		if !healthy && strings.Contains(r.HTTPRequest.URL.Host, "us-east-1") {
+			r.Error = awserr.NewRequestFailure(awserr.New(dynamodb.ErrCodeInternalServerError, "down", nil), 500, "")
+		}
+	})
+
+	failover := NewFailover(
+		FailoverRegion{Region: "us-east-1"},
+		FailoverRegion{Region: "us-west-2", TableNames: map[string]string{"Users": "Users-West"}},
+	)
+	failover.Threshold = 2
+	failover.HealthCheckInterval = 10 * time.Millisecond
+	switches := make(chan FailoverRegion, 2)
+	failover.OnSwitch = func(active FailoverRegion) { switches <- active }
+	failover.Install(&client.Handlers)
+
+	input := &dynamodb.BatchGetItemInput{
+		RequestItems: map[string]*dynamodb.KeysAndAttributes{
+			"Users": {Keys: []map[string]*dynamodb.AttributeValue{{"PK": {S: aws.String("1")}}}},
+		},
+	}
+
+	t.Run("given transient errors from the primary", func(t *testing.T) {
+		_, err := client.BatchGetItem(input)
+		assert.Error(t, err)
+		assert.False(t, failover.FailedOver())
+
+		_, err = client.BatchGetItem(input)
+		assert.Error(t, err)
+		require.True(t, failover.FailedOver())
+		assert.Equal(t, "us-west-2", (<-switches).Region)
+
+		_, err = client.BatchGetItem(input)
+		require.NoError(t, err)
+		assert.Equal(t, "dynamodb.us-west-2.amazonaws.com", hosts[len(hosts)-1])
+		assert.Equal(t, "Users-West", tables[len(tables)-1])
+		assert.Contains(t, input.RequestItems, "Users", "the input's table names are restored")
+	})
+
+	t.Run("given the primary recovers", func(t *testing.T) {
+		healthy = true
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go failover.Run(ctx, client)
+
+		select {
+		case active := <-switches:
+			assert.Equal(t, "us-east-1", active.Region)
+		case <-time.After(time.Second):
+			t.Fatal("failover didn't fail back")
+		}
+
+		_, err := client.BatchGetItem(input)
+		require.NoError(t, err)
+		assert.Equal(t, "dynamodb.us-east-1.amazonaws.com", hosts[len(hosts)-1])
+		assert.Equal(t, "Users", tables[len(tables)-1])
+	})
+}
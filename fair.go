@@ -0,0 +1,147 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// LockWaiter is a contender waiting for a lock in fairness mode
+type LockWaiter struct {
+	// ID is the lock ID the waiter will hold the lock with
+	ID       string
+	Priority int
+	Since    time.Time
+
+	// ExpiresAt is when the waiter is assumed to have given up, unless it polls the lock again
+	ExpiresAt time.Time
+}
+
+// ahead returns true if the waiter gets the lock before the other
+func (w LockWaiter) ahead(other LockWaiter) bool {
+	if w.Priority != other.Priority {
+		return w.Priority > other.Priority
+	}
+	if !w.Since.Equal(other.Since) {
+		return w.Since.Before(other.Since)
+	}
+	return w.ID < other.ID
+}
+
+// Fair makes waiters take turns for the lock. Waiters register while they wait, and only try to take the lock when no
+// other waiter is ahead of them. Waiters with a higher priority go first, then those that have waited longest. Every
+// process using the lock should use fairness mode, since waiters that don't register don't wait their turn.
+//
+// Registering costs a write each time a waiter polls the lock.
+func (l *Lock) Fair(priority int) {
+	l.fair = true
+	l.priority = priority
+}
+
+// Waiters returns the contenders waiting for the lock, in the order they'll get it. Only waiters in fairness mode are
+// listed.
+func (l *Lock) Waiters(ctx context.Context) ([]LockWaiter, error) {
+	return ListLockWaiters(ctx, l.db, l.tn, l.pk, l.sk, l.name)
+}
+
+// ListLockWaiters returns the contenders waiting for the named lock in fairness mode, in the order they'll get it
+func ListLockWaiters(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey, name string) ([]LockWaiter, error) {
+	result, err := db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(tableName),
+		Key:            lockWaitersKey(primaryKey, sortKey, name),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return lockWaiters(result.Item, time.Now()), nil
+}
+
+func lockWaitersKey(primaryKey, sortKey, name string) map[string]*dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{}
+	item[primaryKey] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_LockWaiters/%s", name))}
+
+	if sortKey != "" {
+		item[sortKey] = &dynamodb.AttributeValue{S: aws.String("Dyno_LockWaitersSortKeyValue")}
+	}
+
+	return item
+}
+
+// lockWaiters returns the waiters in the item that haven't expired, in queue order
+func lockWaiters(item map[string]*dynamodb.AttributeValue, now time.Time) []LockWaiter {
+	waiters := []LockWaiter{}
+	for id, av := range GetMap(item, "Dyno_Waiters") {
+		waiter := LockWaiter{ID: id, Priority: int(GetInt(av.M, "Priority", 0))}
+		waiter.Since, _ = ParseTime(av.M["Since"], TimeUnixMillis)
+		waiter.ExpiresAt, _ = ParseTime(av.M["ExpiresAt"], TimeUnixMillis)
+		if waiter.ExpiresAt.After(now) {
+			waiters = append(waiters, waiter)
+		}
+	}
+
+	sort.Slice(waiters, func(i, j int) bool { return waiters[i].ahead(waiters[j]) })
+	return waiters
+}
+
+// waiterAhead returns true if another waiter is ahead of this one. If this one hasn't registered, any waiter is. It
+// also returns the registrations of other waiters that expired, for registerWaiter to remove.
+func (l *Lock) waiterAhead(ctx context.Context, self LockWaiter, registered bool) (bool, map[string]*dynamodb.AttributeValue, error) {
+	result, err := l.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:              aws.String(l.tn),
+		Key:                    lockWaitersKey(l.pk, l.sk, l.name),
+		ConsistentRead:         aws.Bool(!l.eventual),
+		ReturnConsumedCapacity: returnConsumedCapacity(l.metrics),
+	})
+	if err != nil {
+		return false, nil, err
+	}
+	RecordCapacity(l.metrics, "Lock", false, result.ConsumedCapacity)
+
+	now := l.clock.Now()
+	expired := expiredMapEntries(result.Item, "Dyno_Waiters", "ExpiresAt", self.ID, now)
+	for _, waiter := range lockWaiters(result.Item, now) {
+		if waiter.ID != self.ID && (!registered || waiter.ahead(self)) {
+			return true, expired, nil
+		}
+	}
+	return false, expired, nil
+}
+
+// registerWaiter adds the waiter to the queue, or extends its registration if it's already in it. A waiter that stops
+// polling expires after a few of the longest poll intervals, and its registration is removed along with the next one.
+func (l *Lock) registerWaiter(ctx context.Context, self LockWaiter, expired map[string]*dynamodb.AttributeValue) error {
+	key := lockWaitersKey(l.pk, l.sk, l.name)
+	expiresAt := l.clock.Now().Add(3*l.maxPoll + time.Second)
+
+	err := setMapEntry(ctx, l.db, l.tn, key, "Dyno_Waiters", self.ID, Map(map[string]*dynamodb.AttributeValue{
+		"Priority":  Int(int64(self.Priority)),
+		"Since":     TimeValue(self.Since, TimeUnixMillis),
+		"ExpiresAt": TimeValue(expiresAt, TimeUnixMillis),
+	}))
+	if err != nil {
+		return err
+	}
+
+	return removeExpiredMapEntries(ctx, l.db, l.tn, key, "Dyno_Waiters", "ExpiresAt", expired)
+}
+
+// unregisterWaiter removes the waiter from the queue once it has the lock or has given up. It's done even if the
+// acquire's context is done, so the waiters behind it don't wait for the registration to expire.
+func (l *Lock) unregisterWaiter(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	l.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                aws.String(l.tn),
+		Key:                      lockWaitersKey(l.pk, l.sk, l.name),
+		UpdateExpression:         aws.String("REMOVE #ws.#w"),
+		ExpressionAttributeNames: map[string]*string{"#ws": aws.String("Dyno_Waiters"), "#w": aws.String(id)},
+	})
+}
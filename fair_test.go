@@ -0,0 +1,86 @@
+package dyno
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFairLock(t *testing.T) {
+	ctx := context.Background()
+	newLock := func(priority int) *Lock {
+		return NewLockWithOptions(testClient, tableName, "fair-lock", WithFairness(priority), WithBackoff(10*time.Millisecond, 50*time.Millisecond))
+	}
+
+	holder := newLock(0)
+	require.NoError(t, holder.Acquire(time.Minute))
+
+	acquired := make(chan int, 2)
+	contend := func(priority int) {
+		lock := newLock(priority)
+		if assert.NoError(t, lock.AcquireWithTimeout(time.Minute, 10*time.Second)) {
+			time.Sleep(50 * time.Millisecond)
+			acquired <- priority
+			assert.NoError(t, lock.Release())
+		}
+	}
+	waitFor := func(n int) []LockWaiter {
+		for i := 0; i < 100; i++ {
+			waiters, err := holder.Waiters(ctx)
+			require.NoError(t, err)
+			if len(waiters) == n {
+				return waiters
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("expected %d waiters", n)
+		return nil
+	}
+
+	go contend(0)
+	waitFor(1)
+	go contend(5)
+	waiters := waitFor(2)
+
+	t.Run("given waiters with different priorities", func(t *testing.T) {
+		assert.Equal(t, 5, waiters[0].Priority)
+		assert.Equal(t, 0, waiters[1].Priority)
+		assert.True(t, waiters[0].Since.After(waiters[1].Since))
+	})
+
+	t.Run("given the lock is released", func(t *testing.T) {
+		require.NoError(t, holder.Release())
+
+		assert.Equal(t, 5, <-acquired)
+		assert.Equal(t, 0, <-acquired)
+		waitFor(0)
+
+		require.NoError(t, holder.AcquireWithTimeout(time.Minute, time.Second))
+		require.NoError(t, holder.Release())
+	})
+	t.Run("given a waiter that gave up", func(t *testing.T) {
+		name := "fair-lock/" + NewKSUID()
+		held := NewLockWithOptions(testClient, tableName, name, WithFairness(0))
+		require.NoError(t, held.Acquire(time.Minute))
+		defer held.Release()
+
+		key := lockWaitersKey("PK", "SK", name)
+		require.NoError(t, setMapEntry(ctx, testClient, tableName, key, "Dyno_Waiters", "gone", Map(map[string]*dynamodb.AttributeValue{
+			"Priority":  Int(0),
+			"Since":     TimeValue(time.Now().Add(-time.Minute), TimeUnixMillis),
+			"ExpiresAt": TimeValue(time.Now().Add(-time.Second), TimeUnixMillis),
+		})))
+
+		waiter := NewLockWithOptions(testClient, tableName, name, WithFairness(0), WithBackoff(10*time.Millisecond, 50*time.Millisecond))
+		assert.Equal(t, ErrLockAcquireTimeout, waiter.AcquireWithTimeout(time.Minute, 100*time.Millisecond))
+
+		result, err := testClient.GetItem(&dynamodb.GetItemInput{TableName: aws.String(tableName), Key: key})
+		require.NoError(t, err)
+		assert.NotContains(t, GetMap(result.Item, "Dyno_Waiters"), "gone", "registering removes the expired waiter")
+	})
+}
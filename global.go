@@ -0,0 +1,122 @@
+package dyno
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// GlobalLock is a Lock for a table replicated with Global Tables. Conditional writes are only checked against the
+// local replica, so two regions can acquire the same lock before either sees the other's write. Replication settles
+// the conflict by keeping the last write, so after acquiring, a GlobalLock waits for the settle delay and reads the
+// lock back. If another region's acquire won, it goes back to waiting for the lock.
+//
+// This is only safe if SettleDelay is longer than the replication lag, which Global Tables doesn't bound. Use the
+// epoch as a fencing token for anything that must not be written by two holders.
+type GlobalLock struct {
+	*Lock
+
+	region string
+
+	// mu guards epoch, which is set while the lock's local mutex is held for a whole acquire
+	mu    sync.Mutex
+	epoch int64
+
+	// SettleDelay is how long to wait after acquiring for a conflicting acquire from another region to replicate.
+	// Defaults to 2 seconds.
+	SettleDelay time.Duration
+}
+
+// NewGlobalLock returns a lock in the named region's replica of the table. The region is recorded on the lock item.
+func NewGlobalLock(db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey, name, region string) *GlobalLock {
+	g := &GlobalLock{
+		Lock:        NewLock(db, tableName, primaryKey, sortKey, name),
+		region:      region,
+		SettleDelay: 2 * time.Second,
+	}
+	g.Lock.stamp = g.stamp
+
+	return g
+}
+
+// Epoch returns the epoch of the last acquire. Epochs are derived from the clock, and increase with each acquire as
+// long as the regions' clocks are roughly in sync.
+func (g *GlobalLock) Epoch() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.epoch
+}
+
+func (g *GlobalLock) Acquire(lease time.Duration) error {
+	return g.AcquireWithTimeout(lease, time.Duration(0))
+}
+
+func (g *GlobalLock) AcquireWithTimeout(lease, duration time.Duration) error {
+	return g.AcquireContext(context.Background(), lease, duration)
+}
+
+// AcquireContext acquires the lock and verifies it's still held once the settle delay has passed. Metrics receive a
+// "GlobalLockConflict" count each time an acquire is lost to another region.
+func (g *GlobalLock) AcquireContext(ctx context.Context, lease, duration time.Duration) error {
+	start := time.Now()
+
+	for {
+		remaining := duration - time.Since(start)
+		if remaining < 0 {
+			remaining = 0
+		}
+		if err := g.Lock.AcquireContext(ctx, lease, remaining); err != nil {
+			return err
+		}
+
+		held, err := g.verify(ctx)
+		if err != nil {
+			g.Lock.Release()
+			return err
+		}
+		if held {
+			return nil
+		}
+
+		// Another region's acquire replicated over ours. It holds the lock now, so there is nothing to release.
+		countMetric(g.metrics, "GlobalLockConflict", 1, g.dimensions())
+		g.disown()
+
+		if start.Add(duration).Before(time.Now()) {
+			return ErrLockAcquireTimeout
+		}
+	}
+}
+
+// verify waits for the settle delay and checks that the lock item still has this lock's ID
+func (g *GlobalLock) verify(ctx context.Context) (bool, error) {
+	if err := sleepContext(ctx, g.SettleDelay); err != nil {
+		return false, err
+	}
+
+	info, err := DescribeLock(ctx, g.db, g.tn, g.pk, g.sk, g.name)
+	if err != nil {
+		return false, err
+	}
+
+	return info != nil && info.LockID == g.ownedID(), nil
+}
+
+func (g *GlobalLock) stamp(item map[string]*dynamodb.AttributeValue) {
+	g.mu.Lock()
+	epoch := time.Now().UnixNano()
+	if epoch <= g.epoch {
+		epoch = g.epoch + 1
+	}
+	g.epoch = epoch
+	g.mu.Unlock()
+
+	item["Dyno_Region"] = &dynamodb.AttributeValue{S: aws.String(g.region)}
+	item["Dyno_Epoch"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(epoch, 10))}
+}
@@ -0,0 +1,96 @@
+package dyno
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replicatingDB overwrites the first lock acquired through it, as if another region's acquire replicated over it
+type replicatingDB struct {
+	dynamodbiface.DynamoDBAPI
+	replicated bool
+}
+
+func (db *replicatingDB) PutItemWithContext(ctx aws.Context, input *dynamodb.PutItemInput, opts ...request.Option) (*dynamodb.PutItemOutput, error) {
+	output, err := db.DynamoDBAPI.PutItemWithContext(ctx, input, opts...)
+	if err != nil || db.replicated {
+		return output, err
+	}
+	db.replicated = true
+
+	item := map[string]*dynamodb.AttributeValue{}
+	for k, v := range input.Item {
+		item[k] = v
+	}
+	item["Dyno_LockID"] = &dynamodb.AttributeValue{S: aws.String("other-region")}
+	item["Dyno_Region"] = &dynamodb.AttributeValue{S: aws.String("us-west-2")}
+	_, err = db.DynamoDBAPI.PutItemWithContext(ctx, &dynamodb.PutItemInput{TableName: input.TableName, Item: item})
+
+	return output, err
+}
+
+func TestGlobalLock(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("given an uncontended lock", func(t *testing.T) {
+		lock := NewGlobalLock(testClient, tableName, "PK", "SK", "testing-global-lock", "us-east-1")
+		lock.SettleDelay = 10 * time.Millisecond
+
+		require.NoError(t, lock.Acquire(30*time.Second))
+		defer lock.Release()
+
+		info, err := DescribeLock(ctx, testClient, tableName, "PK", "SK", "testing-global-lock")
+		require.NoError(t, err)
+		require.NotNil(t, info)
+		assert.Equal(t, "us-east-1", info.Region)
+		assert.Equal(t, lock.Epoch(), info.Epoch)
+	})
+
+	t.Run("given an acquire from another region", func(t *testing.T) {
+		metrics := &countingMetrics{}
+		lock := NewGlobalLock(&replicatingDB{DynamoDBAPI: testClient}, tableName, "PK", "SK", "testing-global-conflict", "us-east-1")
+		lock.SettleDelay = 10 * time.Millisecond
+		lock.Metrics(metrics)
+		defer ForceRelease(ctx, testClient, tableName, "PK", "SK", "testing-global-conflict", "")
+
+		err := lock.AcquireWithTimeout(30*time.Second, 100*time.Millisecond)
+
+		assert.Equal(t, ErrLockAcquireTimeout, err)
+		assert.Equal(t, ErrLockNotOwned, lock.Release())
+		assert.Equal(t, float64(1), metrics.counts["GlobalLockConflict"])
+
+		info, err := DescribeLock(ctx, testClient, tableName, "PK", "SK", "testing-global-conflict")
+		require.NoError(t, err)
+		assert.Equal(t, "other-region", info.LockID)
+	})
+
+	t.Run("given Epoch is read while acquiring", func(t *testing.T) {
+		holder := NewLock(testClient, tableName, "PK", "SK", "testing-global-epoch")
+		require.NoError(t, holder.Acquire(30*time.Second))
+		defer holder.Release()
+
+		lock := NewGlobalLock(testClient, tableName, "PK", "SK", "testing-global-epoch", "us-east-1")
+		lock.SettleDelay = 10 * time.Millisecond
+		acquired := make(chan error, 1)
+		go func() { acquired <- lock.AcquireWithTimeout(30*time.Second, 300*time.Millisecond) }()
+
+		start := time.Now()
+		for time.Since(start) < 100*time.Millisecond {
+			lock.Epoch()
+		}
+		select {
+		case err := <-acquired:
+			t.Fatalf("acquire returned early: %v", err)
+		default:
+		}
+		assert.Equal(t, ErrLockAcquireTimeout, <-acquired)
+	})
+}
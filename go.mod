@@ -3,7 +3,9 @@ module github.com/maddiesch/dyno
 go 1.13
 
 require (
-	github.com/aws/aws-sdk-go v1.23.21
+	github.com/aws/aws-sdk-go v1.35.24
+	github.com/aws/aws-xray-sdk-go v1.1.0
 	github.com/segmentio/ksuid v1.0.2
 	github.com/stretchr/testify v1.4.0
+	gopkg.in/yaml.v2 v2.2.8
 )
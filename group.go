@@ -0,0 +1,407 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Roster is a group's membership, as of one read of the group
+type Roster struct {
+	// Generation is incremented each time a member joins, leaves, or is evicted for letting its lease expire
+	Generation int64
+
+	// Epoch is incremented each time a new leader is elected. Use it as a fencing token for work only the leader does.
+	Epoch int64
+
+	// Leader is the ID of the member elected leader, or empty before one has been
+	Leader string
+
+	// Members are the IDs of the members, sorted
+	Members []string
+
+	assignments        map[string][]int
+	assignedGeneration int64
+	expired            []string
+}
+
+// Partitions returns the partitions the leader assigned to the member. It's nil until the leader has assigned the
+// partitions for the roster's generation, so members don't work from assignments made for a different set of members.
+func (r Roster) Partitions(memberID string) []int {
+	if r.assignedGeneration != r.Generation {
+		return nil
+	}
+	return r.assignments[memberID]
+}
+
+// Group is a named group of members, e.g. the instances of a service sharing a stream, with a leader elected among
+// them that evicts members whose lease expired and assigns partitions of the work to the rest. It's a lightweight
+// alternative to ZooKeeper for distributing work.
+//
+// Members heartbeat to stay in the group. The roster is kept in a map attribute on a single item, and the leader is
+// elected with a Lock, both in the same table.
+type Group struct {
+	db   dynamodbiface.DynamoDBAPI
+	tn   string
+	pk   string
+	sk   string
+	name string
+	id   string
+
+	mu      sync.Mutex
+	leader  *Lock
+	leading bool
+
+	// Lease is how long a member stays in the group, and the leader stays leader, without a heartbeat. Defaults to 30
+	// seconds.
+	Lease time.Duration
+
+	// Partitions is the number of partitions the leader assigns to the members, round robin in member ID order. No
+	// partitions are assigned if it's zero.
+	Partitions int
+}
+
+// NewGroup returns the member of the named group with the ID. IDs must be unique within the group.
+func NewGroup(db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey, name, memberID string) *Group {
+	return &Group{
+		db:     db,
+		tn:     tableName,
+		pk:     primaryKey,
+		sk:     sortKey,
+		name:   name,
+		id:     memberID,
+		leader: NewLock(db, tableName, primaryKey, sortKey, fmt.Sprintf("Dyno_GroupLeader/%s", name)),
+		Lease:  30 * time.Second,
+	}
+}
+
+func (g *Group) key() map[string]*dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{}
+	item[g.pk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_Group/%s", g.name))}
+
+	if g.sk != "" {
+		item[g.sk] = &dynamodb.AttributeValue{S: aws.String("Dyno_GroupSortKeyValue")}
+	}
+
+	return item
+}
+
+// IsLeader returns true if the member was elected leader by its last heartbeat
+func (g *Group) IsLeader() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.leading
+}
+
+// Join adds the member to the group, or renews its lease if it's already in it
+func (g *Group) Join(ctx context.Context) error {
+	names := map[string]*string{
+		"#ms": aws.String("Dyno_Members"),
+		"#m":  aws.String(g.id),
+		"#g":  aws.String("Dyno_Generation"),
+	}
+
+	for {
+		expires := Int(unixMilli(time.Now().Add(g.Lease)))
+
+		_, err := g.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+			TableName:                 aws.String(g.tn),
+			Key:                       g.key(),
+			UpdateExpression:          aws.String("SET #ms.#m = :x"),
+			ConditionExpression:       aws.String("attribute_exists(#ms.#m)"),
+			ExpressionAttributeNames:  map[string]*string{"#ms": names["#ms"], "#m": names["#m"]},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":x": expires},
+		})
+		if Classify(err) != ErrorClassConditionalCheckFailed {
+			return err
+		}
+
+		// A new member changes the generation
+		_, err = g.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+			TableName:                 aws.String(g.tn),
+			Key:                       g.key(),
+			UpdateExpression:          aws.String("SET #ms.#m = :x ADD #g :one"),
+			ConditionExpression:       aws.String("attribute_exists(#ms)"),
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":x": expires, ":one": Int(1)},
+		})
+		if Classify(err) != ErrorClassConditionalCheckFailed {
+			return err
+		}
+
+		// The first member creates the roster, unless another member got there first
+		_, err = g.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+			TableName:                aws.String(g.tn),
+			Key:                      g.key(),
+			UpdateExpression:         aws.String("SET #ms = :ms ADD #g :one"),
+			ConditionExpression:      aws.String("attribute_not_exists(#ms)"),
+			ExpressionAttributeNames: map[string]*string{"#ms": names["#ms"], "#g": names["#g"]},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":ms":  Map(map[string]*dynamodb.AttributeValue{g.id: expires}),
+				":one": Int(1),
+			},
+		})
+		if Classify(err) != ErrorClassConditionalCheckFailed {
+			return err
+		}
+	}
+}
+
+// Leave removes the member from the group, giving up leadership if it has it
+func (g *Group) Leave(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.leading {
+		g.leading = false
+		if err := g.leader.Release(); err != nil {
+			return err
+		}
+	}
+
+	_, err := g.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(g.tn),
+		Key:                 g.key(),
+		UpdateExpression:    aws.String("REMOVE #ms.#m ADD #g :one"),
+		ConditionExpression: aws.String("attribute_exists(#ms.#m)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#ms": aws.String("Dyno_Members"),
+			"#m":  aws.String(g.id),
+			"#g":  aws.String("Dyno_Generation"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":one": Int(1)},
+	})
+	if Classify(err) == ErrorClassConditionalCheckFailed { // Already evicted
+		return nil
+	}
+	return err
+}
+
+// Roster reads the group's membership
+func (g *Group) Roster(ctx context.Context) (Roster, error) {
+	result, err := g.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(g.tn),
+		Key:            g.key(),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return Roster{}, err
+	}
+
+	item := result.Item
+	roster := Roster{
+		Generation:         GetInt(item, "Dyno_Generation", 0),
+		Epoch:              GetInt(item, "Dyno_Epoch", 0),
+		Leader:             GetString(item, "Dyno_Leader", ""),
+		Members:            []string{},
+		assignments:        map[string][]int{},
+		assignedGeneration: GetInt(item, "Dyno_AssignedGeneration", 0),
+	}
+
+	now := unixMilli(time.Now())
+	for id, av := range GetMap(item, "Dyno_Members") {
+		roster.Members = append(roster.Members, id)
+		if av.N != nil {
+			if ms, err := strconv.ParseInt(*av.N, 10, 64); err == nil && ms < now {
+				roster.expired = append(roster.expired, id)
+			}
+		}
+	}
+	sort.Strings(roster.Members)
+	sort.Strings(roster.expired)
+
+	for id, av := range GetMap(item, "Dyno_Assignments") {
+		partitions := []int{}
+		for _, p := range av.L {
+			if n, err := strconv.Atoi(aws.StringValue(p.N)); err == nil {
+				partitions = append(partitions, n)
+			}
+		}
+		roster.assignments[id] = partitions
+	}
+
+	return roster, nil
+}
+
+// Heartbeat renews the member's lease and campaigns for leadership. If the member is the leader it evicts expired
+// members and, when the roster changed, assigns the partitions. It returns the roster as the heartbeat left it.
+// Heartbeat more often than the lease, e.g. every third of it.
+func (g *Group) Heartbeat(ctx context.Context) (Roster, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.Join(ctx); err != nil {
+		return Roster{}, err
+	}
+	if err := g.campaign(ctx); err != nil {
+		return Roster{}, err
+	}
+
+	roster, err := g.Roster(ctx)
+	if err != nil || !g.leading {
+		return roster, err
+	}
+
+	return g.lead(ctx, roster)
+}
+
+// groupCampaignTimeout is how long a member waits for the leader lock on each heartbeat. It's long enough for the
+// lock to see an expired leader twice, which it needs to before taking over.
+const groupCampaignTimeout = 100 * time.Millisecond
+
+func (g *Group) campaign(ctx context.Context) error {
+	if g.leading {
+		err := g.leader.Renew(ctx)
+		if err != ErrLockNotOwned {
+			return err
+		}
+		g.leading = false
+	}
+
+	err := g.leader.AcquireContext(ctx, g.Lease, groupCampaignTimeout)
+	if err == ErrLockAcquireTimeout {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	g.leading = true
+
+	_, err = g.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(g.tn),
+		Key:                       g.key(),
+		UpdateExpression:          aws.String("SET #l = :me ADD #e :one"),
+		ExpressionAttributeNames:  map[string]*string{"#l": aws.String("Dyno_Leader"), "#e": aws.String("Dyno_Epoch")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":me": Str(g.id), ":one": Int(1)},
+	})
+	return err
+}
+
+// lead evicts the roster's expired members and assigns the partitions in a single update, as long as the roster hasn't
+// changed and the member is still the leader. If it has, the next heartbeat tries again.
+func (g *Group) lead(ctx context.Context, roster Roster) (Roster, error) {
+	evict := len(roster.expired) > 0
+	assign := g.Partitions > 0 && roster.assignedGeneration != roster.Generation
+	if !evict && !assign {
+		return roster, nil
+	}
+
+	names := map[string]*string{
+		"#ms": aws.String("Dyno_Members"),
+		"#g":  aws.String("Dyno_Generation"),
+		"#l":  aws.String("Dyno_Leader"),
+	}
+	values := map[string]*dynamodb.AttributeValue{
+		":g":  Int(roster.Generation),
+		":me": Str(g.id),
+	}
+	update := ""
+
+	members := roster.Members
+	if evict {
+		members = []string{}
+		expired := map[string]bool{}
+		removes := ""
+		for i, id := range roster.expired {
+			expired[id] = true
+			name := fmt.Sprintf("#x%d", i)
+			names[name] = aws.String(id)
+			if removes != "" {
+				removes += ", "
+			}
+			removes += "#ms." + name
+		}
+		for _, id := range roster.Members {
+			if !expired[id] {
+				members = append(members, id)
+			}
+		}
+		update = "REMOVE " + removes + " ADD #g :one "
+		values[":one"] = Int(1)
+		roster.Generation++
+		roster.Members = members
+		roster.expired = nil
+	}
+
+	if g.Partitions > 0 {
+		roster.assignments = assignPartitions(members, g.Partitions)
+		assignments := map[string]*dynamodb.AttributeValue{}
+		for id, partitions := range roster.assignments {
+			list := []*dynamodb.AttributeValue{}
+			for _, p := range partitions {
+				list = append(list, Int(int64(p)))
+			}
+			assignments[id] = List(list...)
+		}
+		roster.assignedGeneration = roster.Generation
+
+		update += "SET #as = :as, #ag = :ag"
+		names["#as"] = aws.String("Dyno_Assignments")
+		names["#ag"] = aws.String("Dyno_AssignedGeneration")
+		values[":as"] = Map(assignments)
+		values[":ag"] = Int(roster.assignedGeneration)
+	}
+
+	_, err := g.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(g.tn),
+		Key:                       g.key(),
+		UpdateExpression:          aws.String(update),
+		ConditionExpression:       aws.String("#g = :g AND #l = :me"),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	if Classify(err) == ErrorClassConditionalCheckFailed {
+		return g.Roster(ctx)
+	}
+	if err != nil {
+		return Roster{}, err
+	}
+
+	return roster, nil
+}
+
+// assignPartitions deals the partitions out to the members round robin
+func assignPartitions(members []string, partitions int) map[string][]int {
+	assignments := map[string][]int{}
+	if len(members) == 0 {
+		return assignments
+	}
+	for _, id := range members {
+		assignments[id] = []int{}
+	}
+	for p := 0; p < partitions; p++ {
+		id := members[p%len(members)]
+		assignments[id] = append(assignments[id], p)
+	}
+	return assignments
+}
+
+// Run joins the group and heartbeats every third of the lease until the context is done, then leaves it. fn is called
+// with the roster after the first heartbeat, and each time the generation, epoch, or assignments change.
+func (g *Group) Run(ctx context.Context, fn func(Roster)) error {
+	defer g.Leave(context.Background())
+
+	var last *Roster
+	for {
+		roster, err := g.Heartbeat(ctx)
+		if err != nil && ctx.Err() == nil && !IsRetryable(err) {
+			return err
+		}
+		if err == nil && (last == nil || roster.Generation != last.Generation || roster.Epoch != last.Epoch || roster.assignedGeneration != last.assignedGeneration) {
+			fn(roster)
+			last = &roster
+		}
+
+		if err := sleepContext(ctx, g.Lease/3); err != nil {
+			return err
+		}
+	}
+}
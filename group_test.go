@@ -0,0 +1,76 @@
+package dyno
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroup(t *testing.T) {
+	ctx := context.Background()
+
+	member := func(id string) *Group {
+		g := NewGroup(testClient, tableName, "PK", "SK", "testing-group", id)
+		g.Lease = time.Second
+		g.Partitions = 5
+		return g
+	}
+	a, b, c := member("a"), member("b"), member("c")
+	defer a.leader.Release()
+	defer b.leader.Release()
+	defer c.leader.Release()
+
+	t.Run("given members joining", func(t *testing.T) {
+		_, err := a.Heartbeat(ctx)
+		require.NoError(t, err)
+		_, err = b.Heartbeat(ctx)
+		require.NoError(t, err)
+		assert.True(t, a.IsLeader())
+		assert.False(t, b.IsLeader())
+
+		roster, err := b.Roster(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, roster.Members)
+		assert.Equal(t, "a", roster.Leader)
+		assert.Nil(t, roster.Partitions("b"), "the leader hasn't assigned partitions for b yet")
+
+		roster, err = a.Heartbeat(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []int{0, 2, 4}, roster.Partitions("a"))
+		assert.Equal(t, []int{1, 3}, roster.Partitions("b"))
+	})
+
+	t.Run("given a member that leaves", func(t *testing.T) {
+		before, err := a.Roster(ctx)
+		require.NoError(t, err)
+
+		require.NoError(t, b.Leave(ctx))
+		roster, err := a.Heartbeat(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a"}, roster.Members)
+		assert.Equal(t, before.Generation+1, roster.Generation)
+		assert.Equal(t, []int{0, 1, 2, 3, 4}, roster.Partitions("a"))
+	})
+
+	t.Run("given a leader that stops heartbeating", func(t *testing.T) {
+		before, err := c.Heartbeat(ctx)
+		require.NoError(t, err)
+		assert.False(t, c.IsLeader())
+
+		time.Sleep(1100 * time.Millisecond)
+		roster, err := c.Heartbeat(ctx)
+		require.NoError(t, err)
+		assert.True(t, c.IsLeader())
+		assert.Equal(t, "c", roster.Leader)
+		assert.Equal(t, before.Epoch+1, roster.Epoch)
+		assert.Equal(t, []string{"c"}, roster.Members, "the old leader was evicted")
+		assert.Equal(t, []int{0, 1, 2, 3, 4}, roster.Partitions("c"))
+
+		_, err = a.Heartbeat(ctx)
+		require.NoError(t, err)
+		assert.False(t, a.IsLeader())
+	})
+}
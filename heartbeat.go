@@ -0,0 +1,193 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// maxTransactItems is the largest number of actions DynamoDB accepts in a single transaction
+const maxTransactItems = 25
+
+// Heartbeat renews the leases of many held locks on one timer, renewing up to 25 at a time in a single transaction.
+// The locks can be in different tables, but must be reachable with the heartbeat's client.
+//
+// Remove a lock from the heartbeat before releasing it. A lock that's being acquired holds up the heartbeat until it
+// is.
+type Heartbeat struct {
+	db dynamodbiface.DynamoDBAPI
+
+	mu    sync.Mutex
+	locks map[*Lock]struct{}
+
+	// Interval is how often the leases are renewed. It should be well under the shortest lease. Defaults to 10 seconds.
+	Interval time.Duration
+
+	// OnFailure is called for each lock that couldn't be renewed. A lock that was lost to another holder is reported
+	// with ErrLockNotOwned and removed from the heartbeat.
+	OnFailure func(lock *Lock, err error)
+
+	// Metrics receives the capacity consumed by the renewals as the "Heartbeat" operation
+	Metrics Metrics
+}
+
+func NewHeartbeat(db dynamodbiface.DynamoDBAPI) *Heartbeat {
+	return &Heartbeat{
+		db:       db,
+		locks:    map[*Lock]struct{}{},
+		Interval: 10 * time.Second,
+	}
+}
+
+// Add starts renewing the lock's lease
+func (h *Heartbeat) Add(lock *Lock) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.locks[lock] = struct{}{}
+}
+
+// Remove stops renewing the lock's lease
+func (h *Heartbeat) Remove(lock *Lock) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.locks, lock)
+}
+
+// Run renews the leases on the interval until the context is done. A panic while renewing, e.g. in OnFailure or a
+// lock's event callback, stops the renewals and is returned as a *PanicError.
+func (h *Heartbeat) Run(ctx context.Context) (err error) {
+	ticker := time.NewTicker(h.Interval)
+	defer ticker.Stop()
+
+	defer func() {
+		if v := recover(); v != nil {
+			err = recovered(v)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		for lock, err := range h.Beat(ctx) {
+			if h.OnFailure != nil {
+				h.OnFailure(lock, err)
+			}
+		}
+	}
+}
+
+// Beat renews every lock's lease once, and returns the locks that failed to renew
+func (h *Heartbeat) Beat(ctx context.Context) map[*Lock]error {
+	h.mu.Lock()
+	locks := make([]*Lock, 0, len(h.locks))
+	for lock := range h.locks {
+		locks = append(locks, lock)
+	}
+	h.mu.Unlock()
+
+	failures := map[*Lock]error{}
+
+	pending := []*Lock{}
+	renewals := map[*Lock]*lockRenewal{}
+	for _, lock := range locks {
+		err := lock.syncClock(ctx)
+		if err != nil {
+			failures[lock] = err
+			continue
+		}
+		r, err := lock.renewal(lock.clock.Now(), 0)
+		if err != nil {
+			failures[lock] = err
+			continue
+		}
+		pending = append(pending, lock)
+		renewals[lock] = r
+	}
+
+	for len(pending) > 0 {
+		n := len(pending)
+		if n > maxTransactItems {
+			n = maxTransactItems
+		}
+
+		for lock, err := range h.renew(ctx, pending[:n], renewals) {
+			failures[lock] = err
+		}
+		pending = pending[n:]
+	}
+
+	for lock, err := range failures {
+		if err == ErrLockNotOwned {
+			h.Remove(lock)
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+	return failures
+}
+
+// renew renews a transaction's worth of locks. A lost lock cancels the transaction, so it's retried without them.
+func (h *Heartbeat) renew(ctx context.Context, locks []*Lock, renewals map[*Lock]*lockRenewal) map[*Lock]error {
+	failures := map[*Lock]error{}
+	lost := awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "lock is held by another lock ID", nil)
+
+	for len(locks) > 0 {
+		items := make([]*dynamodb.TransactWriteItem, len(locks))
+		for i, lock := range locks {
+			items[i] = &dynamodb.TransactWriteItem{Update: renewals[lock].update}
+		}
+
+		result, err := h.db.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems:          items,
+			ReturnConsumedCapacity: returnConsumedCapacity(h.Metrics),
+		})
+		if result != nil {
+			RecordCapacity(h.Metrics, "Heartbeat", true, result.ConsumedCapacity...)
+		}
+
+		var canceled *dynamodb.TransactionCanceledException
+		if !errors.As(err, &canceled) || len(canceled.CancellationReasons) != len(locks) {
+			for _, lock := range locks {
+				if err := lock.renewed(renewals[lock], err); err != nil {
+					failures[lock] = err
+				}
+			}
+			return failures
+		}
+
+		retry := []*Lock{}
+		for i, reason := range canceled.CancellationReasons {
+			switch aws.StringValue(reason.Code) {
+			case "None":
+				retry = append(retry, locks[i])
+			case "ConditionalCheckFailed":
+				failures[locks[i]] = locks[i].renewed(renewals[locks[i]], lost)
+			default:
+				failures[locks[i]] = err
+			}
+		}
+		if len(retry) == len(locks) {
+			for _, lock := range locks {
+				failures[lock] = err
+			}
+			return failures
+		}
+		locks = retry
+	}
+
+	return failures
+}
@@ -0,0 +1,118 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeartbeat(t *testing.T) {
+	ctx := context.Background()
+	key := []byte("shared-signing-key")
+	heartbeat := NewHeartbeat(testClient)
+
+	locks := []*Lock{}
+	for i := 0; i < 30; i++ {
+		lock := NewLock(testClient, tableName, "PK", "SK", fmt.Sprintf("testing-heartbeat-%d", i))
+		lock.SigningKey(key)
+		require.NoError(t, lock.Acquire(30*time.Second))
+		defer lock.Release()
+
+		heartbeat.Add(lock)
+		locks = append(locks, lock)
+	}
+
+	acquiredAt := func(lock *Lock) time.Time {
+		info, err := DescribeLock(ctx, testClient, tableName, "PK", "SK", lock.name)
+		require.NoError(t, err)
+		require.NotNil(t, info)
+		return info.AcquiredAt
+	}
+
+	t.Run("given held locks", func(t *testing.T) {
+		before := acquiredAt(locks[0])
+		time.Sleep(5 * time.Millisecond)
+
+		assert.Empty(t, heartbeat.Beat(ctx))
+		assert.True(t, acquiredAt(locks[0]).After(before))
+		assert.True(t, acquiredAt(locks[29]).After(before))
+
+		_, err := locks[0].getCurrentLeaseContext(ctx)
+		assert.NoError(t, err, "the renewed item is signed")
+	})
+
+	t.Run("given a held lock that's being acquired again", func(t *testing.T) {
+		acquiring := make(chan error, 1)
+		go func() { acquiring <- locks[1].AcquireWithTimeout(30*time.Second, 300*time.Millisecond) }()
+		time.Sleep(50 * time.Millisecond)
+
+		start := time.Now()
+		assert.Empty(t, heartbeat.Beat(ctx))
+		assert.True(t, time.Since(start) < 200*time.Millisecond, "the renewal waited %s for the acquire", time.Since(start))
+		assert.Equal(t, ErrLockAcquireTimeout, <-acquiring)
+	})
+
+	t.Run("given a lost lock", func(t *testing.T) {
+		require.NoError(t, ForceRelease(ctx, testClient, tableName, "PK", "SK", locks[3].name, ""))
+		other := NewLock(testClient, tableName, "PK", "SK", locks[3].name)
+		require.NoError(t, other.Acquire(30*time.Second))
+		defer other.Release()
+
+		failures := heartbeat.Beat(ctx)
+
+		assert.Equal(t, map[*Lock]error{locks[3]: ErrLockNotOwned}, failures)
+		assert.Equal(t, ErrLockNotOwned, locks[3].Renew(ctx))
+		assert.Empty(t, heartbeat.Beat(ctx), "the lost lock was removed")
+	})
+}
+
+func TestLockRenew(t *testing.T) {
+	ctx := context.Background()
+	lock := NewLock(testClient, tableName, "PK", "SK", "testing-renew")
+	require.NoError(t, lock.Acquire(30*time.Second))
+	defer lock.Release()
+
+	before, err := lock.getCurrentLeaseContext(ctx)
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+
+	require.NoError(t, lock.Renew(ctx))
+
+	after, err := lock.getCurrentLeaseContext(ctx)
+	require.NoError(t, err)
+	assert.True(t, after.acquiredAt.After(before.acquiredAt))
+	assert.Equal(t, after.acquiredAt.Add(30*time.Second), after.expiresAt)
+}
+
+func TestLeaseContextExpired(t *testing.T) {
+	waitingSince := time.Now().Add(-time.Minute)
+
+	t.Run("given a renewed lease", func(t *testing.T) {
+		current := &leaseContext{duration: 30 * time.Second, acquiredAt: time.Now()}
+
+		assert.False(t, current.expired(waitingSince, time.Now()))
+	})
+
+	t.Run("given a lease that ran out", func(t *testing.T) {
+		current := &leaseContext{duration: 30 * time.Second, acquiredAt: time.Now().Add(-31 * time.Second)}
+
+		assert.True(t, current.expired(time.Now(), time.Now()))
+	})
+
+	t.Run("given a recorded expiry", func(t *testing.T) {
+		current := &leaseContext{duration: 30 * time.Second, acquiredAt: time.Now(), expiresAt: time.Now().Add(-time.Second)}
+
+		assert.True(t, current.expired(time.Now(), time.Now()), "the holder's expiry wins over the lease duration")
+	})
+
+	t.Run("given a lease without an acquired time", func(t *testing.T) {
+		current := &leaseContext{duration: 30 * time.Second}
+
+		assert.True(t, current.expired(waitingSince, time.Now()))
+		assert.False(t, current.expired(time.Now(), time.Now()))
+	})
+}
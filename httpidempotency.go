@@ -0,0 +1,196 @@
+package dyno
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// IdempotencyMiddleware replays the responses of HTTP requests with an idempotency key, so a client can retry a request
+// without it being handled twice. The first request with a key is handled and its response is stored. Later requests
+// with the key get the stored response, with an Idempotent-Replayed header.
+//
+// A duplicate that arrives while the first request is being handled gets 409 Conflict, and a key reused for a request
+// with a different method, path or body gets 422 Unprocessable Entity. Responses with a 5xx status aren't stored, so
+// the request can be retried. A request body larger than MaxRequestSize gets 413 Request Entity Too Large.
+type IdempotencyMiddleware struct {
+	store *IdempotencyStore
+
+	// Header is the request header with the idempotency key. Defaults to Idempotency-Key.
+	Header string
+
+	// MaxBodySize is the largest response body that's stored. Larger responses aren't replayed; later requests with
+	// the key get 422 Unprocessable Entity instead. Defaults to 64KB.
+	MaxBodySize int
+
+	// MaxRequestSize is the largest request body that's read to fingerprint the request. Defaults to 1MB.
+	MaxRequestSize int64
+
+	// Scope returns what idempotency keys are unique within, e.g. the authenticated user, so clients can't replay each
+	// other's responses. Defaults to every request sharing one scope.
+	Scope func(*http.Request) string
+}
+
+// storedResponse is the part of a response that's replayed
+type storedResponse struct {
+	Status    int
+	Header    http.Header
+	Body      []byte
+	Truncated bool
+}
+
+func NewIdempotencyMiddleware(store *IdempotencyStore) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		store:          store,
+		Header:         "Idempotency-Key",
+		MaxBodySize:    64 * 1024,
+		MaxRequestSize: 1024 * 1024,
+	}
+}
+
+// Handler wraps next. Requests without an idempotency key are passed straight through.
+func (m *IdempotencyMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(m.Header)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if m.Scope != nil {
+			key = m.Scope(r) + "/" + key
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, m.MaxRequestSize))
+		if err != nil && int64(len(body)) == m.MaxRequestSize {
+			http.Error(w, "the request body is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.Error(w, "reading the request body failed", http.StatusBadRequest)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		claim, outcome, err := m.store.Begin(r.Context(), "Dyno_HTTP/"+key, requestFingerprint(r, body))
+		switch err {
+		case nil:
+		case ErrIdempotencyInProgress:
+			http.Error(w, "a request with this idempotency key is in progress", http.StatusConflict)
+			return
+		case ErrIdempotencyKeyMismatch:
+			http.Error(w, "this idempotency key was used for a different request", http.StatusUnprocessableEntity)
+			return
+		default:
+			http.Error(w, "checking the idempotency key failed", http.StatusInternalServerError)
+			return
+		}
+
+		if claim == nil {
+			replayResponse(w, outcome)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, max: m.MaxBodySize}
+		completed := false
+		defer func() {
+			// The request can be retried if the handler panicked or failed
+			if !completed {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				claim.Release(ctx)
+			}
+		}()
+
+		next.ServeHTTP(recorder, r)
+		if recorder.status == 0 {
+			recorder.WriteHeader(http.StatusOK)
+		}
+		if recorder.status >= 500 {
+			return
+		}
+
+		stored, err := json.Marshal(storedResponse{
+			Status:    recorder.status,
+			Header:    recorder.header,
+			Body:      recorder.body.Bytes(),
+			Truncated: recorder.truncated,
+		})
+		if err != nil {
+			return
+		}
+
+		// The response has been sent, so the outcome is stored even if the client has gone away
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		completed = claim.Complete(ctx, stored) == nil
+	})
+}
+
+// requestFingerprint identifies the request, so a key can't be reused for a different one
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replayResponse(w http.ResponseWriter, outcome []byte) {
+	var stored storedResponse
+	if err := json.Unmarshal(outcome, &stored); err != nil {
+		http.Error(w, "the stored response is invalid", http.StatusInternalServerError)
+		return
+	}
+	if stored.Truncated {
+		http.Error(w, "the response to this idempotency key was too large to store", http.StatusUnprocessableEntity)
+		return
+	}
+
+	for name, values := range stored.Header {
+		if name != "Date" && name != "Content-Length" {
+			w.Header()[name] = values
+		}
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(stored.Status)
+	w.Write(stored.Body)
+}
+
+// responseRecorder passes a response through to the client, and keeps a copy of it
+type responseRecorder struct {
+	http.ResponseWriter
+
+	status    int
+	header    http.Header
+	body      bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status != 0 {
+		return
+	}
+	r.status = status
+	r.header = r.ResponseWriter.Header().Clone()
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	if !r.truncated {
+		if r.body.Len()+len(b) > r.max {
+			r.truncated = true
+			r.body.Reset()
+		} else {
+			r.body.Write(b)
+		}
+	}
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,105 @@
+package dyno
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	calls := 0
+	started, unblock := make(chan struct{}), make(chan struct{})
+	middleware := NewIdempotencyMiddleware(NewIdempotencyStore(testClient, tableName, "PK", "SK"))
+	middleware.MaxBodySize = 16
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/fail":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/slow":
+			close(started)
+			<-unblock
+		case "/large":
+			w.Write([]byte(strings.Repeat("x", 32)))
+		default:
+			w.Header().Set("Location", "/orders/1")
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, "order %d", calls)
+		}
+	}))
+	serve := func(path, key, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if key != "" {
+			r.Header.Set("Idempotency-Key", key)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("given a request that's repeated", func(t *testing.T) {
+		key := ksuid.New().String()
+		first := serve("/orders", key, "{}")
+		assert.Equal(t, http.StatusCreated, first.Code)
+		assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+
+		replay := serve("/orders", key, "{}")
+		assert.Equal(t, http.StatusCreated, replay.Code)
+		assert.Equal(t, "/orders/1", replay.Header().Get("Location"))
+		assert.Equal(t, "true", replay.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, first.Body.String(), replay.Body.String())
+
+		assert.Equal(t, http.StatusUnprocessableEntity, serve("/orders", key, `{"different":true}`).Code)
+	})
+
+	t.Run("given a request without a key", func(t *testing.T) {
+		before := calls
+		serve("/orders", "", "{}")
+		serve("/orders", "", "{}")
+		assert.Equal(t, before+2, calls)
+	})
+
+	t.Run("given a request that failed", func(t *testing.T) {
+		key := ksuid.New().String()
+		before := calls
+		assert.Equal(t, http.StatusServiceUnavailable, serve("/fail", key, "").Code)
+		assert.Equal(t, http.StatusServiceUnavailable, serve("/fail", key, "").Code)
+		assert.Equal(t, before+2, calls)
+	})
+
+	t.Run("given a response larger than the limit", func(t *testing.T) {
+		key := ksuid.New().String()
+		assert.Len(t, serve("/large", key, "").Body.String(), 32)
+
+		replay := serve("/large", key, "")
+		assert.Equal(t, http.StatusUnprocessableEntity, replay.Code)
+		assert.Empty(t, replay.Header().Get("Idempotent-Replayed"))
+	})
+
+	t.Run("given a request body larger than the limit", func(t *testing.T) {
+		middleware.MaxRequestSize = 8
+		defer func() { middleware.MaxRequestSize = 1024 * 1024 }()
+
+		before := calls
+		assert.Equal(t, http.StatusRequestEntityTooLarge, serve("/orders", ksuid.New().String(), strings.Repeat("x", 32)).Code)
+		assert.Equal(t, http.StatusCreated, serve("/orders", ksuid.New().String(), "{}").Code)
+		assert.Equal(t, before+1, calls)
+	})
+
+	t.Run("given a request that's in progress", func(t *testing.T) {
+		key := ksuid.New().String()
+		done := make(chan int)
+		go func() { done <- serve("/slow", key, "").Code }()
+		<-started
+
+		assert.Equal(t, http.StatusConflict, serve("/slow", key, "").Code)
+
+		close(unblock)
+		assert.Equal(t, http.StatusOK, <-done)
+	})
+}
@@ -0,0 +1,74 @@
+package dyno
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// RateLimitMiddleware limits HTTP requests per key with a RateLimiter, so the limit holds across every instance of a
+// service. Requests over the limit get 429 Too Many Requests with a Retry-After header. Every response has
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers. Rejected requests are counted by the
+// limiter's Metrics.
+type RateLimitMiddleware struct {
+	limiter *RateLimiter
+
+	// Key returns what requests are limited by, e.g. an API key or tenant. Requests with an empty key aren't limited.
+	// Defaults to the client's IP address.
+	Key func(*http.Request) string
+
+	// FailOpen lets requests through if the limiter can't be checked, rather than failing them with 503 Service
+	// Unavailable. Defaults to true.
+	FailOpen bool
+}
+
+func NewRateLimitMiddleware(limiter *RateLimiter) *RateLimitMiddleware {
+	return &RateLimitMiddleware{
+		limiter:  limiter,
+		Key:      clientIP,
+		FailOpen: true,
+	}
+}
+
+// clientIP is the address the request came from. Behind a load balancer, use a Key that reads the forwarded address.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Handler wraps next
+func (m *RateLimitMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := m.Key(r)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		limit, err := m.limiter.Allow(r.Context(), key)
+		if err != nil {
+			if m.FailOpen {
+				next.ServeHTTP(w, r)
+			} else {
+				http.Error(w, "checking the rate limit failed", http.StatusServiceUnavailable)
+			}
+			return
+		}
+
+		w.Header().Set("X-RateLimit-Limit", strconv.FormatInt(limit.Limit, 10))
+		w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(limit.Remaining, 10))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(limit.ResetAt.Unix(), 10))
+		if !limit.Allowed {
+			// Retry-After is in whole seconds, rounded up so a retry doesn't arrive before the window ends
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limit.RetryAfter.Seconds()))))
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
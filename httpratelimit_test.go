@@ -0,0 +1,51 @@
+package dyno
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	limiter := NewRateLimiter(testClient, tableName, "PK", "SK", "testing-http-limiter", 2, time.Hour)
+	middleware := NewRateLimitMiddleware(limiter)
+	middleware.Key = func(r *http.Request) string { return r.Header.Get("X-Api-Key") }
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	serve := func(apiKey string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Api-Key", apiKey)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("given requests under the limit", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, serve("key-a").Code)
+
+		w := serve("key-a")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	})
+
+	t.Run("given a request over the limit", func(t *testing.T) {
+		w := serve("key-a")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+		retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+		assert.NoError(t, err)
+		assert.True(t, retryAfter > 0 && retryAfter <= 3600)
+	})
+
+	t.Run("given requests without a key", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusNoContent, serve("").Code)
+		}
+	})
+}
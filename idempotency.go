@@ -0,0 +1,207 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+var (
+	ErrIdempotencyInProgress  = errors.New("operation with the idempotency key is in progress")
+	ErrIdempotencyKeyMismatch = errors.New("idempotency key was used for a different operation")
+	ErrIdempotencyClaimLost   = errors.New("idempotency key is no longer claimed by this operation")
+)
+
+const (
+	idempotencyInProgress = "IN_PROGRESS"
+	idempotencyCompleted  = "COMPLETED"
+)
+
+// IdempotencyStore records the outcomes of operations by an idempotency key, so an operation that's repeated, like a
+// redelivered message or a retried request, returns the first outcome instead of running again.
+//
+// An operation claims its key before it runs, with a lease. While the lease lasts, repeats of the operation are
+// rejected with ErrIdempotencyInProgress. If the operation crashes, the lease runs out and a repeat can claim the key
+// and run it. Outcomes are kept for the TTL, with the table's TTL enabled on Dyno_ExpiresAt.
+type IdempotencyStore struct {
+	db dynamodbiface.DynamoDBAPI
+	tn string
+	pk string
+	sk string
+
+	// Lease is how long an operation has to complete before a repeat can run it. It should be longer than the
+	// operation takes. Defaults to five minutes.
+	Lease time.Duration
+
+	// TTL is how long outcomes are kept. Repeats after it run the operation again. Defaults to 24 hours.
+	TTL time.Duration
+}
+
+func NewIdempotencyStore(db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey string) *IdempotencyStore {
+	return &IdempotencyStore{
+		db:    db,
+		tn:    tableName,
+		pk:    primaryKey,
+		sk:    sortKey,
+		Lease: 5 * time.Minute,
+		TTL:   24 * time.Hour,
+	}
+}
+
+// IdempotencyClaim is an operation's claim of its idempotency key, until it completes or releases it
+type IdempotencyClaim struct {
+	Key string
+
+	store *IdempotencyStore
+	id    string
+}
+
+func (s *IdempotencyStore) key(key string) map[string]*dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{}
+	item[s.pk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_Idempotency/%s", key))}
+
+	if s.sk != "" {
+		item[s.sk] = &dynamodb.AttributeValue{S: aws.String("Dyno_IdempotencySortKeyValue")}
+	}
+
+	return item
+}
+
+// Begin claims the key for an operation. If the key already has an outcome, the claim is nil and the outcome is
+// returned instead. ErrIdempotencyInProgress is returned if another operation holds the key's lease.
+//
+// The fingerprint identifies the operation, e.g. a hash of a request's body, so a key reused for a different
+// operation is rejected with ErrIdempotencyKeyMismatch. It can be empty.
+func (s *IdempotencyStore) Begin(ctx context.Context, key, fingerprint string) (*IdempotencyClaim, []byte, error) {
+	claim := &IdempotencyClaim{Key: key, store: s, id: NewKSUID()}
+	now := time.Now()
+
+	item := s.key(key)
+	item["Dyno_Status"] = Str(idempotencyInProgress)
+	item["Dyno_ClaimID"] = Str(claim.id)
+	item["Dyno_LeaseExpiresAtMs"] = TimeValue(now.Add(s.Lease), TimeUnixMillis)
+	item["Dyno_ExpiresAt"] = TimeValue(now.Add(s.Lease+s.TTL), TimeUnixSeconds)
+	if fingerprint != "" {
+		item["Dyno_Fingerprint"] = Str(fingerprint)
+	}
+
+	for {
+		// Keys are free if they've never been claimed, their operation's lease ran out, or their outcome expired and
+		// TTL hasn't deleted it yet
+		_, err := s.db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+			TableName:           aws.String(s.tn),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(#pk) OR (#st = :inprogress AND #le < :nowms) OR #x < :now"),
+			ExpressionAttributeNames: map[string]*string{
+				"#pk": aws.String(s.pk),
+				"#st": aws.String("Dyno_Status"),
+				"#le": aws.String("Dyno_LeaseExpiresAtMs"),
+				"#x":  aws.String("Dyno_ExpiresAt"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":inprogress": Str(idempotencyInProgress),
+				":nowms":      TimeValue(now, TimeUnixMillis),
+				":now":        TimeValue(now, TimeUnixSeconds),
+			},
+		})
+		if err == nil {
+			return claim, nil, nil
+		}
+		if Classify(err) != ErrorClassConditionalCheckFailed {
+			return nil, nil, err
+		}
+
+		result, err := s.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+			TableName:      aws.String(s.tn),
+			Key:            s.key(key),
+			ConsistentRead: aws.Bool(true),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if result.Item == nil { // Released while it was being read
+			continue
+		}
+
+		if fingerprint != "" && GetString(result.Item, "Dyno_Fingerprint", "") != fingerprint {
+			return nil, nil, ErrIdempotencyKeyMismatch
+		}
+		if GetString(result.Item, "Dyno_Status", "") != idempotencyCompleted {
+			return nil, nil, ErrIdempotencyInProgress
+		}
+		return nil, GetBytes(result.Item, "Dyno_Outcome", []byte{}), nil
+	}
+}
+
+// Complete records the operation's outcome, which is returned to repeats of it. ErrIdempotencyClaimLost is returned if
+// the lease ran out and a repeat claimed the key.
+func (c *IdempotencyClaim) Complete(ctx context.Context, outcome []byte) error {
+	s := c.store
+	input := &dynamodb.UpdateItemInput{
+		TableName:           aws.String(s.tn),
+		Key:                 s.key(c.Key),
+		UpdateExpression:    aws.String("SET #st = :completed, #x = :x REMOVE #le"),
+		ConditionExpression: aws.String("#id = :id"),
+		ExpressionAttributeNames: map[string]*string{
+			"#st": aws.String("Dyno_Status"),
+			"#x":  aws.String("Dyno_ExpiresAt"),
+			"#le": aws.String("Dyno_LeaseExpiresAtMs"),
+			"#id": aws.String("Dyno_ClaimID"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":completed": Str(idempotencyCompleted),
+			":x":         TimeValue(time.Now().Add(s.TTL), TimeUnixSeconds),
+			":id":        Str(c.id),
+		},
+	}
+	if len(outcome) > 0 {
+		input.UpdateExpression = aws.String("SET #st = :completed, #x = :x, #o = :o REMOVE #le")
+		input.ExpressionAttributeNames["#o"] = aws.String("Dyno_Outcome")
+		input.ExpressionAttributeValues[":o"] = Bytes(outcome)
+	}
+
+	_, err := s.db.UpdateItemWithContext(ctx, input)
+	if Classify(err) == ErrorClassConditionalCheckFailed {
+		return ErrIdempotencyClaimLost
+	}
+	return err
+}
+
+// Release gives up the claim without an outcome, so the operation can be repeated straight away, e.g. after it failed.
+// ErrIdempotencyClaimLost is returned if the lease ran out and a repeat claimed the key.
+func (c *IdempotencyClaim) Release(ctx context.Context) error {
+	return conditionalDelete(ctx, c.store.db, &dynamodb.DeleteItemInput{
+		TableName:                 aws.String(c.store.tn),
+		Key:                       c.store.key(c.Key),
+		ConditionExpression:       aws.String("#id = :id"),
+		ExpressionAttributeNames:  map[string]*string{"#id": aws.String("Dyno_ClaimID")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":id": Str(c.id)},
+	}, ErrIdempotencyClaimLost)
+}
+
+// Do runs fn once for the key, and returns its outcome. Repeats return the first outcome without calling fn. If fn
+// returns an error, the claim is released so the operation can be repeated.
+func (s *IdempotencyStore) Do(ctx context.Context, key string, fn func(context.Context) ([]byte, error)) ([]byte, error) {
+	claim, outcome, err := s.Begin(ctx, key, "")
+	if err != nil || claim == nil {
+		return outcome, err
+	}
+
+	outcome, err = fn(ctx)
+	if err != nil {
+		if releaseErr := claim.Release(ctx); releaseErr != nil && releaseErr != ErrIdempotencyClaimLost {
+			return nil, fmt.Errorf("%w (releasing the idempotency key: %v)", err, releaseErr)
+		}
+		return nil, err
+	}
+
+	if err := claim.Complete(ctx, outcome); err != nil {
+		return nil, err
+	}
+	return outcome, nil
+}
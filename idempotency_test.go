@@ -0,0 +1,77 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewIdempotencyStore(testClient, tableName, "PK", "SK")
+
+	t.Run("given a key that's been completed", func(t *testing.T) {
+		calls := 0
+		fn := func(context.Context) ([]byte, error) {
+			calls++
+			return []byte("done"), nil
+		}
+
+		outcome, err := store.Do(ctx, "completed-key", fn)
+		require.NoError(t, err)
+		assert.Equal(t, "done", string(outcome))
+
+		outcome, err = store.Do(ctx, "completed-key", fn)
+		require.NoError(t, err)
+		assert.Equal(t, "done", string(outcome))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("given a key whose operation failed", func(t *testing.T) {
+		failure := errors.New("failed")
+		_, err := store.Do(ctx, "failed-key", func(context.Context) ([]byte, error) { return nil, failure })
+		assert.Equal(t, failure, err)
+
+		outcome, err := store.Do(ctx, "failed-key", func(context.Context) ([]byte, error) { return []byte("retried"), nil })
+		require.NoError(t, err)
+		assert.Equal(t, "retried", string(outcome))
+	})
+
+	t.Run("given a key that's in progress", func(t *testing.T) {
+		claim, _, err := store.Begin(ctx, "in-progress-key", "a")
+		require.NoError(t, err)
+		require.NotNil(t, claim)
+
+		_, _, err = store.Begin(ctx, "in-progress-key", "a")
+		assert.Equal(t, ErrIdempotencyInProgress, err)
+
+		_, _, err = store.Begin(ctx, "in-progress-key", "b")
+		assert.Equal(t, ErrIdempotencyKeyMismatch, err)
+
+		require.NoError(t, claim.Complete(ctx, []byte("first")))
+		again, outcome, err := store.Begin(ctx, "in-progress-key", "a")
+		require.NoError(t, err)
+		assert.Nil(t, again)
+		assert.Equal(t, "first", string(outcome))
+	})
+
+	t.Run("given a claim whose lease ran out", func(t *testing.T) {
+		short := NewIdempotencyStore(testClient, tableName, "PK", "SK")
+		short.Lease = 10 * time.Millisecond
+
+		crashed, _, err := short.Begin(ctx, "crashed-key", "")
+		require.NoError(t, err)
+		time.Sleep(20 * time.Millisecond)
+
+		retry, _, err := short.Begin(ctx, "crashed-key", "")
+		require.NoError(t, err)
+		require.NotNil(t, retry)
+
+		assert.Equal(t, ErrIdempotencyClaimLost, crashed.Complete(ctx, []byte("late")))
+		assert.NoError(t, retry.Complete(ctx, []byte("retried")))
+	})
+}
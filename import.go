@@ -0,0 +1,206 @@
+package dyno
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// ImportColumn maps a CSV column to an attribute
+type ImportColumn struct {
+	// Attribute is the attribute name. Defaults to the column header.
+	Attribute string
+
+	// Type is the attribute type: S (the default), N, or BOOL
+	Type string
+}
+
+// Importer loads newline delimited JSON or CSV into a table
+type Importer struct {
+	db dynamodbiface.DynamoDBAPI
+	tn string
+
+	// Columns maps CSV column headers to attributes. When it is nil every column is imported as a string attribute
+	// named after the header, otherwise only the mapped columns are imported.
+	Columns map[string]ImportColumn
+
+	// DryRun parses and marshals every row without writing anything
+	DryRun bool
+
+	// ItemsPerSecond limits how fast items are written. Zero is unlimited.
+	ItemsPerSecond int
+
+	// Progress is called with the number of rows imported so far after every batch
+	Progress func(rows int)
+}
+
+func NewImporter(db dynamodbiface.DynamoDBAPI, tableName string) *Importer {
+	return &Importer{
+		db: db,
+		tn: tableName,
+	}
+}
+
+// ImportJSON imports one item per line of JSON objects and returns the number of rows imported
+func (i *Importer) ImportJSON(ctx context.Context, r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 400*1024)
+
+	return i.run(ctx, func() (map[string]*dynamodb.AttributeValue, error) {
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+
+			decoder := json.NewDecoder(bytes.NewReader(line))
+			decoder.UseNumber()
+
+			var row map[string]interface{}
+			if err := decoder.Decode(&row); err != nil {
+				return nil, err
+			}
+
+			return dynamodbattribute.MarshalMap(jsonNumbers(row))
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	})
+}
+
+// ImportCSV imports one item per CSV row. The first row must be the column headers.
+func (i *Importer) ImportCSV(ctx context.Context, r io.Reader) (int, error) {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err != nil {
+		return 0, err
+	}
+
+	return i.run(ctx, func() (map[string]*dynamodb.AttributeValue, error) {
+		record, err := reader.Read()
+		if err != nil {
+			return nil, err
+		}
+
+		item := map[string]*dynamodb.AttributeValue{}
+		for index, value := range record {
+			column, ok := i.column(header[index])
+			if !ok || value == "" {
+				continue
+			}
+
+			av, err := csvAttributeValue(value, column.Type)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", header[index], err)
+			}
+			item[column.Attribute] = av
+		}
+
+		return item, nil
+	})
+}
+
+func (i *Importer) column(header string) (ImportColumn, bool) {
+	if i.Columns == nil {
+		return ImportColumn{Attribute: header}, true
+	}
+
+	column, ok := i.Columns[header]
+	if column.Attribute == "" {
+		column.Attribute = header
+	}
+
+	return column, ok
+}
+
+func (i *Importer) run(ctx context.Context, next func() (map[string]*dynamodb.AttributeValue, error)) (int, error) {
+	writer := NewBatchWriter(i.db, i.tn)
+	limiter := newRateLimiter(i.ItemsPerSecond)
+	rows := 0
+
+	for {
+		item, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return rows, fmt.Errorf("import row %d: %w", rows+1, err)
+		}
+
+		rows++
+		if i.DryRun {
+			continue
+		}
+
+		if err := limiter.wait(ctx, 1); err != nil {
+			return rows, err
+		}
+		if err := writer.Put(ctx, item); err != nil {
+			return rows, err
+		}
+		if writer.Pending() == 0 && i.Progress != nil {
+			i.Progress(rows)
+		}
+	}
+
+	if !i.DryRun {
+		if err := writer.Flush(ctx); err != nil {
+			return rows, err
+		}
+	}
+	if i.Progress != nil {
+		i.Progress(rows)
+	}
+
+	return rows, nil
+}
+
+func csvAttributeValue(value, kind string) (*dynamodb.AttributeValue, error) {
+	switch kind {
+	case "", "S":
+		return &dynamodb.AttributeValue{S: aws.String(value)}, nil
+	case "N":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, err
+		}
+		return &dynamodb.AttributeValue{N: aws.String(value)}, nil
+	case "BOOL":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, err
+		}
+		return &dynamodb.AttributeValue{BOOL: aws.Bool(b)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported attribute type %s", kind)
+	}
+}
+
+// jsonNumbers replaces json.Number values with dynamodbattribute.Number so they're stored without losing precision
+func jsonNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		return dynamodbattribute.Number(v)
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = jsonNumbers(value)
+		}
+	case []interface{}:
+		for index, value := range v {
+			v[index] = jsonNumbers(value)
+		}
+	}
+	return v
+}
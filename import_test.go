@@ -0,0 +1,87 @@
+package dyno
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImporter(t *testing.T) {
+	table, drop := createTestTable(t, "dyno-test-import")
+	defer drop()
+
+	ctx := context.Background()
+	get := func(pk string) map[string]*dynamodb.AttributeValue {
+		result, err := testClient.GetItem(&dynamodb.GetItemInput{
+			TableName: aws.String(table),
+			Key: map[string]*dynamodb.AttributeValue{
+				"PK": {S: aws.String(pk)},
+				"SK": {S: aws.String("profile")},
+			},
+		})
+		require.NoError(t, err)
+		return result.Item
+	}
+
+	t.Run("ImportJSON", func(t *testing.T) {
+		input := strings.NewReader(`{"PK":"user-1","SK":"profile","Age":30,"Tags":["a","b"]}
+
+{"PK":"user-2","SK":"profile","Balance":12345678901234567890}
+`)
+
+		rows, err := NewImporter(testClient, table).ImportJSON(ctx, input)
+		require.NoError(t, err)
+		assert.Equal(t, 2, rows)
+
+		assert.Equal(t, "30", aws.StringValue(get("user-1")["Age"].N))
+		assert.Equal(t, "12345678901234567890", aws.StringValue(get("user-2")["Balance"].N))
+	})
+
+	t.Run("ImportCSV", func(t *testing.T) {
+		input := strings.NewReader("id,kind,age,active,ignored\nuser-3,profile,41,true,x\n")
+
+		importer := NewImporter(testClient, table)
+		importer.Columns = map[string]ImportColumn{
+			"id":     {Attribute: "PK"},
+			"kind":   {Attribute: "SK"},
+			"age":    {Attribute: "Age", Type: "N"},
+			"active": {Attribute: "Active", Type: "BOOL"},
+		}
+
+		var progress int
+		importer.Progress = func(rows int) { progress = rows }
+
+		rows, err := importer.ImportCSV(ctx, input)
+		require.NoError(t, err)
+		assert.Equal(t, 1, rows)
+		assert.Equal(t, 1, progress)
+
+		item := get("user-3")
+		assert.Equal(t, "41", aws.StringValue(item["Age"].N))
+		assert.True(t, aws.BoolValue(item["Active"].BOOL))
+		assert.NotContains(t, item, "ignored")
+	})
+
+	t.Run("given a dry run", func(t *testing.T) {
+		importer := NewImporter(testClient, table)
+		importer.DryRun = true
+
+		rows, err := importer.ImportCSV(ctx, strings.NewReader("PK,SK\nuser-4,profile\n"))
+		require.NoError(t, err)
+		assert.Equal(t, 1, rows)
+		assert.Empty(t, get("user-4"))
+	})
+
+	t.Run("given an invalid row", func(t *testing.T) {
+		importer := NewImporter(testClient, table)
+		importer.Columns = map[string]ImportColumn{"PK": {}, "SK": {}, "Age": {Type: "N"}}
+
+		_, err := importer.ImportCSV(ctx, strings.NewReader("PK,SK,Age\nuser-5,profile,old\n"))
+		assert.Error(t, err)
+	})
+}
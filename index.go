@@ -0,0 +1,135 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+var (
+	ErrIndexNotFound = errors.New("global secondary index not found")
+	ErrIndexDeleting = errors.New("global secondary index is being deleted")
+)
+
+// IndexProgress is reported while waiting for a global secondary index to become available
+type IndexProgress struct {
+	TableName   string
+	IndexName   string
+	Status      string
+	Backfilling bool
+	ItemCount   int64
+	SizeBytes   int64
+	// Retries is the number of times the index creation was rejected because the table was busy
+	Retries int
+}
+
+// CreateIndexOptions configures CreateGlobalSecondaryIndex
+type CreateIndexOptions struct {
+	// AttributeDefinitions for the index keys. Attributes already defined on the table can be omitted.
+	AttributeDefinitions []*dynamodb.AttributeDefinition
+
+	// PollInterval is how often the table is described while waiting. Defaults to 5 seconds.
+	PollInterval time.Duration
+
+	// Progress is called after every poll of the table
+	Progress func(IndexProgress)
+}
+
+func (o *CreateIndexOptions) pollInterval() time.Duration {
+	if o == nil || o.PollInterval <= 0 {
+		return 5 * time.Second
+	}
+	return o.PollInterval
+}
+
+func (o *CreateIndexOptions) progress(p IndexProgress) {
+	if o != nil && o.Progress != nil {
+		o.Progress(p)
+	}
+}
+
+// CreateGlobalSecondaryIndex adds the index to an existing table and blocks until it is ACTIVE and has finished
+// backfilling.
+//
+// DynamoDB only allows one index to be created or deleted on a table at a time, so while the table is busy with
+// another index mutation the request is retried until it is accepted or the context is done. If the index already
+// exists, it only waits for the index to become available.
+func CreateGlobalSecondaryIndex(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName string, index *dynamodb.CreateGlobalSecondaryIndexAction, opts *CreateIndexOptions) error {
+	indexName := aws.StringValue(index.IndexName)
+
+	desc, err := describeIndex(ctx, db, tableName, indexName)
+	if err != nil && err != ErrIndexNotFound {
+		return err
+	}
+
+	retries := 0
+	for desc == nil {
+		input := &dynamodb.UpdateTableInput{
+			TableName: aws.String(tableName),
+			GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{
+				{Create: index},
+			},
+		}
+		if opts != nil {
+			input.AttributeDefinitions = opts.AttributeDefinitions
+		}
+
+		_, err := db.UpdateTableWithContext(ctx, input)
+		if err == nil {
+			break
+		}
+		if Classify(err) != ErrorClassThrottling && !isAwsErrorCode(err, dynamodb.ErrCodeResourceInUseException) {
+			return err
+		}
+
+		// Another index is being created or deleted. Wait for it to finish and try again.
+		retries++
+		opts.progress(IndexProgress{
+			TableName: tableName,
+			IndexName: indexName,
+			Retries:   retries,
+		})
+
+		if err := sleepContext(ctx, opts.pollInterval()); err != nil {
+			return err
+		}
+	}
+
+	return waitForIndex(ctx, db, tableName, indexName, retries, opts)
+}
+
+func waitForIndex(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName, indexName string, retries int, opts *CreateIndexOptions) error {
+	return WaitUntilIndexActive(ctx, db, tableName, indexName, &WaitOptions{
+		PollInterval:    opts.pollInterval(),
+		MaxPollInterval: opts.pollInterval(),
+		Progress: func(p TableProgress) {
+			for _, index := range p.Indexes {
+				if index.IndexName == indexName {
+					index.Retries = retries
+					opts.progress(index)
+				}
+			}
+		},
+	})
+}
+
+func describeIndex(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName, indexName string) (*dynamodb.GlobalSecondaryIndexDescription, error) {
+	result, err := db.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, index := range result.Table.GlobalSecondaryIndexes {
+		if aws.StringValue(index.IndexName) == indexName {
+			return index, nil
+		}
+	}
+
+	return nil, ErrIndexNotFound
+}
@@ -0,0 +1,65 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateGlobalSecondaryIndex(t *testing.T) {
+	name := fmt.Sprintf("dyno-test-index-%s", ksuid.New().String())
+
+	_, err := testClient.CreateTable(&dynamodb.CreateTableInput{
+		TableName:   aws.String(name),
+		BillingMode: aws.String("PAY_PER_REQUEST"),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("PK"), AttributeType: aws.String("S")},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("PK"), KeyType: aws.String("HASH")},
+		},
+	})
+	require.NoError(t, err)
+	defer testClient.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String(name)})
+
+	index := &dynamodb.CreateGlobalSecondaryIndexAction{
+		IndexName: aws.String("GSI1"),
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("GSI1PK"), KeyType: aws.String("HASH")},
+		},
+		Projection: &dynamodb.Projection{ProjectionType: aws.String("KEYS_ONLY")},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	t.Run("creates the index and waits for it", func(t *testing.T) {
+		var last IndexProgress
+		err := CreateGlobalSecondaryIndex(ctx, testClient, name, index, &CreateIndexOptions{
+			AttributeDefinitions: []*dynamodb.AttributeDefinition{
+				{AttributeName: aws.String("GSI1PK"), AttributeType: aws.String("S")},
+			},
+			PollInterval: 100 * time.Millisecond,
+			Progress:     func(p IndexProgress) { last = p },
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "GSI1", last.IndexName)
+		assert.Equal(t, dynamodb.IndexStatusActive, last.Status)
+		assert.False(t, last.Backfilling)
+	})
+
+	t.Run("given an existing index", func(t *testing.T) {
+		err := CreateGlobalSecondaryIndex(ctx, testClient, name, index, &CreateIndexOptions{
+			PollInterval: 100 * time.Millisecond,
+		})
+		assert.NoError(t, err)
+	})
+}
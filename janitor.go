@@ -0,0 +1,138 @@
+package dyno
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Janitor finds locks whose lease expired more than a grace period ago and reports or deletes them. Locks acquired by
+// versions of dyno that didn't record Dyno_AcquiredAt are skipped.
+type Janitor struct {
+	db dynamodbiface.DynamoDBAPI
+	tn string
+	pk string
+	sk string
+
+	// GracePeriod is how long after its lease expires a lock is considered stale. Defaults to one minute.
+	GracePeriod time.Duration
+
+	// Delete removes stale locks. Without it, stale locks are only reported.
+	Delete bool
+
+	// Interval is how often Run sweeps. Defaults to one minute.
+	Interval time.Duration
+
+	// ConsistentRead scans with strongly consistent reads, at twice the read capacity. Stale locks are only deleted
+	// if they're unchanged, so an eventually consistent scan is safe, and is the default.
+	ConsistentRead bool
+
+	// Report is called for every stale lock found
+	Report func(LockInfo)
+
+	// Metrics receives a "StaleLocks" count of the stale locks found and a "StaleLocksDeleted" count of those deleted
+	// by each sweep
+	Metrics Metrics
+}
+
+func NewJanitor(db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey string) *Janitor {
+	return &Janitor{
+		db:          db,
+		tn:          tableName,
+		pk:          primaryKey,
+		sk:          sortKey,
+		GracePeriod: time.Minute,
+		Interval:    time.Minute,
+	}
+}
+
+// Run sweeps on the interval until the context is done
+func (j *Janitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.Sweep(ctx); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep scans the lock namespace once and returns the stale locks it found. A stale lock that is released or
+// re-acquired while the sweep is running is left alone.
+func (j *Janitor) Sweep(ctx context.Context) ([]LockInfo, error) {
+	locks, err := listLocks(ctx, j.db, j.tn, j.pk, j.ConsistentRead)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	stale := []LockInfo{}
+	deleted := 0
+
+	for _, lock := range locks {
+		if lock.AcquiredAt.IsZero() || !lock.ExpiredAt().Add(j.GracePeriod).Before(now) {
+			continue
+		}
+
+		stale = append(stale, lock)
+		if j.Report != nil {
+			j.Report(lock)
+		}
+
+		if !j.Delete {
+			continue
+		}
+		removed, err := j.delete(ctx, lock)
+		if err != nil {
+			return stale, err
+		}
+		if removed {
+			deleted++
+		}
+	}
+
+	countMetric(j.Metrics, "StaleLocks", float64(len(stale)), nil)
+	if j.Delete {
+		countMetric(j.Metrics, "StaleLocksDeleted", float64(deleted), nil)
+	}
+
+	return stale, nil
+}
+
+// delete removes the lock item if it's still held by the same lock ID, and hasn't been renewed since it went stale
+func (j *Janitor) delete(ctx context.Context, lock LockInfo) (bool, error) {
+	input := &dynamodb.DeleteItemInput{
+		TableName:           aws.String(j.tn),
+		Key:                 lockKey(j.pk, j.sk, lock.Name),
+		ConditionExpression: aws.String("#id = :id AND #at = :at AND attribute_not_exists(#lm)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#id": aws.String("Dyno_LockID"),
+			"#at": aws.String("Dyno_AcquiredAt"),
+			"#lm": aws.String("Dyno_ExpiresAtMs"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":id": {S: aws.String(lock.LockID)},
+			":at": TimeValue(lock.AcquiredAt, TimeUnixMillis),
+		},
+	}
+	if !lock.expiresAt.IsZero() {
+		input.ConditionExpression = aws.String("#id = :id AND #at = :at AND #lm < :cutoff")
+		input.ExpressionAttributeValues[":cutoff"] = TimeValue(time.Now().Add(-j.GracePeriod), TimeUnixMillis)
+	}
+
+	_, err := j.db.DeleteItemWithContext(ctx, input)
+	if Classify(err) == ErrorClassConditionalCheckFailed {
+		return false, nil
+	}
+	return err == nil, err
+}
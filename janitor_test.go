@@ -0,0 +1,111 @@
+package dyno
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJanitor(t *testing.T) {
+	ctx := context.Background()
+	table, drop := createTestTable(t, "dyno-test-janitor")
+	defer drop()
+
+	held := NewLock(testClient, table, "PK", "SK", "held")
+	require.NoError(t, held.Acquire(time.Hour))
+	abandoned := NewLock(testClient, table, "PK", "SK", "abandoned")
+	require.NoError(t, abandoned.Acquire(0))
+	released := NewLock(testClient, table, "PK", "SK", "released")
+	require.NoError(t, released.Acquire(0))
+	require.NoError(t, released.Release())
+
+	time.Sleep(10 * time.Millisecond)
+
+	t.Run("given report only", func(t *testing.T) {
+		counter := &countingMetrics{}
+		janitor := NewJanitor(testClient, table, "PK", "SK")
+		janitor.GracePeriod = 0
+		janitor.Metrics = counter
+		reported := []string{}
+		janitor.Report = func(lock LockInfo) {
+			reported = append(reported, lock.Name)
+		}
+
+		stale, err := janitor.Sweep(ctx)
+
+		require.NoError(t, err)
+		require.Len(t, stale, 1)
+		assert.Equal(t, "abandoned", stale[0].Name)
+		assert.Equal(t, []string{"abandoned"}, reported)
+		assert.Equal(t, 1.0, counter.counts["StaleLocks"])
+
+		again, err := janitor.Sweep(ctx)
+		require.NoError(t, err)
+		assert.Len(t, again, 1)
+	})
+
+	t.Run("given a grace period", func(t *testing.T) {
+		janitor := NewJanitor(testClient, table, "PK", "SK")
+		janitor.GracePeriod = time.Hour
+
+		stale, err := janitor.Sweep(ctx)
+
+		require.NoError(t, err)
+		assert.Empty(t, stale)
+	})
+
+	t.Run("given delete", func(t *testing.T) {
+		counter := &countingMetrics{}
+		janitor := NewJanitor(testClient, table, "PK", "SK")
+		janitor.GracePeriod = 0
+		janitor.Delete = true
+		janitor.Metrics = counter
+
+		stale, err := janitor.Sweep(ctx)
+		require.NoError(t, err)
+		assert.Len(t, stale, 1)
+		assert.Equal(t, 1.0, counter.counts["StaleLocksDeleted"])
+
+		stale, err = janitor.Sweep(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, stale)
+
+		other := NewLock(testClient, table, "PK", "SK", "abandoned")
+		require.NoError(t, other.AcquireWithTimeout(time.Minute, 0))
+		assert.NoError(t, abandoned.Release())
+	})
+
+	t.Run("given a stale lock renewed during the sweep", func(t *testing.T) {
+		renewed := NewLock(testClient, table, "PK", "SK", "renewed")
+		require.NoError(t, renewed.Acquire(0))
+		time.Sleep(10 * time.Millisecond)
+
+		janitor := NewJanitor(testClient, table, "PK", "SK")
+		janitor.GracePeriod = 0
+		janitor.Delete = true
+		janitor.Report = func(lock LockInfo) {
+			_, err := testClient.UpdateItem(&dynamodb.UpdateItemInput{
+				TableName:        aws.String(table),
+				Key:              renewed.key(),
+				UpdateExpression: aws.String("SET Dyno_ExpiresAtMs = :lm"),
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":lm": TimeValue(time.Now().Add(time.Hour), TimeUnixMillis),
+				},
+			})
+			require.NoError(t, err)
+		}
+
+		stale, err := janitor.Sweep(ctx)
+		require.NoError(t, err)
+		require.Len(t, stale, 1)
+
+		info, err := DescribeLock(ctx, testClient, table, "PK", "SK", "renewed")
+		require.NoError(t, err)
+		assert.Equal(t, aws.StringValue(renewed.owned), info.LockID)
+	})
+}
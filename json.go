@@ -0,0 +1,46 @@
+package dyno
+
+import (
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// itemJSON converts an item into the DynamoDB JSON format, e.g. {"Name":{"S":"value"}}, with only the set type
+// descriptors included. encoding/json sorts map keys, so the output is deterministic.
+func itemJSON(item map[string]*dynamodb.AttributeValue) map[string]interface{} {
+	out := make(map[string]interface{}, len(item))
+	for name, av := range item {
+		out[name] = attributeValueJSON(av)
+	}
+	return out
+}
+
+func attributeValueJSON(av *dynamodb.AttributeValue) map[string]interface{} {
+	switch {
+	case av == nil:
+		return map[string]interface{}{"NULL": true}
+	case av.S != nil:
+		return map[string]interface{}{"S": *av.S}
+	case av.N != nil:
+		return map[string]interface{}{"N": *av.N}
+	case av.B != nil:
+		return map[string]interface{}{"B": av.B}
+	case av.BOOL != nil:
+		return map[string]interface{}{"BOOL": *av.BOOL}
+	case av.SS != nil:
+		return map[string]interface{}{"SS": av.SS}
+	case av.NS != nil:
+		return map[string]interface{}{"NS": av.NS}
+	case av.BS != nil:
+		return map[string]interface{}{"BS": av.BS}
+	case av.L != nil:
+		list := make([]interface{}, len(av.L))
+		for i, v := range av.L {
+			list[i] = attributeValueJSON(v)
+		}
+		return map[string]interface{}{"L": list}
+	case av.M != nil:
+		return map[string]interface{}{"M": itemJSON(av.M)}
+	default:
+		return map[string]interface{}{"NULL": true}
+	}
+}
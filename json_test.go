@@ -0,0 +1,27 @@
+package dyno
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestItemJSON(t *testing.T) {
+	item := map[string]*dynamodb.AttributeValue{
+		"Name":  {S: aws.String("dyno")},
+		"Count": {N: aws.String("3")},
+		"Tags":  {SS: []*string{aws.String("a")}},
+		"Meta": {M: map[string]*dynamodb.AttributeValue{
+			"List": {L: []*dynamodb.AttributeValue{{BOOL: aws.Bool(true)}, {NULL: aws.Bool(true)}}},
+		}},
+	}
+
+	data, err := json.Marshal(itemJSON(item))
+	require.NoError(t, err)
+
+	assert.Equal(t, `{"Count":{"N":"3"},"Meta":{"M":{"List":{"L":[{"BOOL":true},{"NULL":true}]}}},"Name":{"S":"dyno"},"Tags":{"SS":["a"]}}`, string(data))
+}
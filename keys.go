@@ -0,0 +1,96 @@
+package dyno
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/ksuid"
+)
+
+var ErrInvalidSortKey = errors.New("not a time sort key with the prefix")
+
+// NewKSUID returns a new KSUID string. KSUIDs are unique, and sort by the second they were created in.
+func NewKSUID() string {
+	return ksuid.New().String()
+}
+
+// NewULID returns a new ULID string. ULIDs are unique, and sort by the millisecond they were created in.
+func NewULID() string {
+	return ULIDAt(time.Now())
+}
+
+// crockford is the base32 alphabet ULIDs are encoded with
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULIDAt returns a new ULID string for the time, e.g. to backfill keys for items created in the past
+func ULIDAt(t time.Time) string {
+	var id [16]byte
+	ms := uint64(unixMilli(t))
+	id[0], id[1] = byte(ms>>40), byte(ms>>32)
+	binary.BigEndian.PutUint32(id[2:6], uint32(ms))
+	if _, err := rand.Read(id[6:]); err != nil {
+		panic(fmt.Sprintf("reading random bytes for a ULID: %v", err))
+	}
+
+	// 26 characters of 5 bits each hold the 128 bit ID, with the 2 spare bits at the front
+	var out [26]byte
+	hi, lo := binary.BigEndian.Uint64(id[:8]), binary.BigEndian.Uint64(id[8:])
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// timeSortKeyFormat is fixed width, so keys sort in time order
+const timeSortKeyFormat = "2006-01-02T15:04:05.000000000Z"
+
+// TimeSortKey returns a sort key that sorts by time, oldest first, e.g. "order/2020-11-15T08:30:15.250000000Z/<KSUID>".
+// The KSUID keeps keys for the same time unique.
+func TimeSortKey(prefix string, t time.Time) string {
+	return TimeSortKeyBound(prefix, t) + "/" + NewKSUID()
+}
+
+// TimeSortKeyBound returns the start of the TimeSortKeys for the time, which sorts before all of them. Use it with
+// BETWEEN or > to query the keys for a time range.
+func TimeSortKeyBound(prefix string, t time.Time) string {
+	return prefix + "/" + t.UTC().Format(timeSortKeyFormat)
+}
+
+// ReverseTimeSortKey returns a sort key that sorts by time, newest first, so queries can read the latest items without
+// ScanIndexForward. The time is stored as the nanoseconds until the end of time, zero padded.
+func ReverseTimeSortKey(prefix string, t time.Time) string {
+	return ReverseTimeSortKeyBound(prefix, t) + "/" + NewKSUID()
+}
+
+// ReverseTimeSortKeyBound returns the start of the ReverseTimeSortKeys for the time, which sorts before all of them.
+// Because the keys are reversed, it sorts after the keys for later times.
+func ReverseTimeSortKeyBound(prefix string, t time.Time) string {
+	return fmt.Sprintf("%s/%019d", prefix, math.MaxInt64-t.UnixNano())
+}
+
+// ParseReverseTimeSortKey returns the time a ReverseTimeSortKey, or bound, was created for
+func ParseReverseTimeSortKey(prefix, key string) (time.Time, error) {
+	if len(key) < len(prefix)+20 || key[:len(prefix)+1] != prefix+"/" {
+		return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidSortKey, key)
+	}
+	n, err := strconv.ParseInt(key[len(prefix)+1:len(prefix)+20], 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, math.MaxInt64-n), nil
+}
+
+// ParseTimeSortKey returns the time a TimeSortKey, or bound, was created for
+func ParseTimeSortKey(prefix, key string) (time.Time, error) {
+	if len(key) < len(prefix)+1+len(timeSortKeyFormat) || key[:len(prefix)+1] != prefix+"/" {
+		return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidSortKey, key)
+	}
+	return time.Parse(timeSortKeyFormat, key[len(prefix)+1:len(prefix)+1+len(timeSortKeyFormat)])
+}
@@ -0,0 +1,71 @@
+package dyno
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestULID(t *testing.T) {
+	at := time.Date(2020, 11, 15, 8, 30, 15, 250*int(time.Millisecond), time.UTC)
+
+	t.Run("given a time", func(t *testing.T) {
+		id := ULIDAt(at)
+
+		assert.Len(t, id, 26)
+		assert.Equal(t, "01EQ5GDCPJ", id[:10], "the timestamp is encoded first")
+		assert.NotEqual(t, id, ULIDAt(at))
+	})
+
+	t.Run("given later times", func(t *testing.T) {
+		assert.True(t, ULIDAt(at) < ULIDAt(at.Add(time.Millisecond)))
+		assert.True(t, ULIDAt(at) < NewULID())
+	})
+}
+
+func TestTimeSortKey(t *testing.T) {
+	at := time.Date(2020, 11, 15, 8, 30, 15, 250*int(time.Millisecond), time.UTC)
+	times := []time.Time{at.Add(time.Hour), at, at.Add(time.Millisecond), at.Add(-48 * time.Hour)}
+
+	t.Run("given oldest first", func(t *testing.T) {
+		keys := []string{}
+		for _, t := range times {
+			keys = append(keys, TimeSortKey("order", t))
+		}
+		sort.Strings(keys)
+
+		for i, want := range []time.Time{times[3], times[1], times[2], times[0]} {
+			parsed, err := ParseTimeSortKey("order", keys[i])
+			require.NoError(t, err)
+			assert.True(t, want.Equal(parsed))
+		}
+		assert.True(t, TimeSortKeyBound("order", at) < TimeSortKey("order", at))
+		assert.Regexp(t, `^order/2020-11-15T08:30:15\.250000000Z/\w{27}$`, TimeSortKey("order", at))
+	})
+
+	t.Run("given newest first", func(t *testing.T) {
+		keys := []string{}
+		for _, t := range times {
+			keys = append(keys, ReverseTimeSortKey("order", t))
+		}
+		sort.Strings(keys)
+
+		for i, want := range []time.Time{times[0], times[2], times[1], times[3]} {
+			parsed, err := ParseReverseTimeSortKey("order", keys[i])
+			require.NoError(t, err)
+			assert.True(t, want.Equal(parsed))
+		}
+	})
+
+	t.Run("given another prefix", func(t *testing.T) {
+		_, err := ParseTimeSortKey("order", TimeSortKey("invoice", at))
+		assert.True(t, errors.Is(err, ErrInvalidSortKey))
+
+		_, err = ParseReverseTimeSortKey("order", "order/1")
+		assert.True(t, errors.Is(err, ErrInvalidSortKey))
+	})
+}
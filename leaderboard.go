@@ -0,0 +1,265 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+var ErrPlayerNotFound = errors.New("player is not on the leaderboard")
+
+// LeaderboardEntry is a player's place on a leaderboard
+type LeaderboardEntry struct {
+	Player string
+	Score  int64
+
+	// Rank is 1 for the highest score. Players with the same score are ranked by player ID, highest first.
+	Rank int64
+}
+
+// Leaderboard ranks players by score. Each player has an item whose sort key is their score, so the top scores are a
+// query of the board's partition. Changing a score moves the player's item, by deleting it and putting it with the
+// new score in a transaction, along with a second item that records each player's current score.
+//
+// The table must have a sort key.
+type Leaderboard struct {
+	db   dynamodbiface.DynamoDBAPI
+	tn   string
+	pk   string
+	sk   string
+	name string
+
+	// MaxAttempts is the number of times a score update is tried when other updates to the player's score conflict
+	// with it. Defaults to 10.
+	MaxAttempts int
+}
+
+func NewLeaderboard(db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey, name string) *Leaderboard {
+	return &Leaderboard{
+		db:          db,
+		tn:          tableName,
+		pk:          primaryKey,
+		sk:          sortKey,
+		name:        name,
+		MaxAttempts: 10,
+	}
+}
+
+func (b *Leaderboard) partition() *dynamodb.AttributeValue {
+	return Str(fmt.Sprintf("Dyno_Leaderboard/%s", b.name))
+}
+
+// playerKey is the key of the item that records the player's score
+func (b *Leaderboard) playerKey(player string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		b.pk: b.partition(),
+		b.sk: Str("Dyno_Player/" + player),
+	}
+}
+
+// scoreKey is the key of the item that ranks the player by score. Flipping the sign bit makes negative scores sort
+// before positive ones, and zero padding makes them sort in numeric order.
+func (b *Leaderboard) scoreKey(player string, score int64) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		b.pk: b.partition(),
+		b.sk: Str(fmt.Sprintf("Dyno_Score/%020d/%s", uint64(score)^(1<<63), player)),
+	}
+}
+
+// Score returns the player's score, and false if they aren't on the board
+func (b *Leaderboard) Score(ctx context.Context, player string) (int64, bool, error) {
+	result, err := b.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(b.tn),
+		Key:            b.playerKey(player),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	if result.Item == nil {
+		return 0, false, nil
+	}
+
+	return GetInt(result.Item, "Dyno_Score", 0), true, nil
+}
+
+// SetScore sets the player's score, adding them to the board if they aren't on it
+func (b *Leaderboard) SetScore(ctx context.Context, player string, score int64) error {
+	_, err := b.update(ctx, player, func(int64) int64 { return score })
+	return err
+}
+
+// AddScore adds delta to the player's score, starting from zero if they aren't on the board, and returns the new score
+func (b *Leaderboard) AddScore(ctx context.Context, player string, delta int64) (int64, error) {
+	return b.update(ctx, player, func(current int64) int64 { return current + delta })
+}
+
+// update moves the player's score item from its current score to the new one. The transaction fails if the player's
+// score changed since it was read, and is retried with the changed score.
+func (b *Leaderboard) update(ctx context.Context, player string, fn func(int64) int64) (int64, error) {
+	backoff := 10 * time.Millisecond
+
+	for attempt := 1; ; attempt++ {
+		current, exists, err := b.Score(ctx, player)
+		if err != nil {
+			return 0, err
+		}
+		score := fn(current)
+		if exists && score == current {
+			return score, nil
+		}
+
+		ranked := b.scoreKey(player, score)
+		ranked["Dyno_Player"] = Str(player)
+		ranked["Dyno_Score"] = Int(score)
+		record := b.playerKey(player)
+		record["Dyno_Score"] = Int(score)
+
+		items := []*dynamodb.TransactWriteItem{{
+			Put: &dynamodb.Put{TableName: aws.String(b.tn), Item: ranked},
+		}}
+		if exists {
+			items = append(items,
+				&dynamodb.TransactWriteItem{Delete: &dynamodb.Delete{
+					TableName: aws.String(b.tn),
+					Key:       b.scoreKey(player, current),
+				}},
+				&dynamodb.TransactWriteItem{Put: &dynamodb.Put{
+					TableName:                 aws.String(b.tn),
+					Item:                      record,
+					ConditionExpression:       aws.String("#s = :s"),
+					ExpressionAttributeNames:  map[string]*string{"#s": aws.String("Dyno_Score")},
+					ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":s": Int(current)},
+				}},
+			)
+		} else {
+			items = append(items, &dynamodb.TransactWriteItem{Put: &dynamodb.Put{
+				TableName:                aws.String(b.tn),
+				Item:                     record,
+				ConditionExpression:      aws.String("attribute_not_exists(#s)"),
+				ExpressionAttributeNames: map[string]*string{"#s": aws.String("Dyno_Score")},
+			}})
+		}
+
+		_, err = b.db.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+		if err == nil {
+			return score, nil
+		}
+		if class := Classify(err); class != ErrorClassConditionalCheckFailed && !IsRetryable(err) {
+			return 0, err
+		}
+		if attempt >= b.MaxAttempts {
+			return 0, ErrUpdateConflict
+		}
+
+		// Jitter keeps updates that conflicted from retrying in lockstep
+		if err := sleepContext(ctx, backoff/2+time.Duration(rand.Int63n(int64(backoff)))); err != nil {
+			return 0, err
+		}
+		if backoff < time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// Remove takes the player off the board
+func (b *Leaderboard) Remove(ctx context.Context, player string) error {
+	score, exists, err := b.Score(ctx, player)
+	if err != nil || !exists {
+		return err
+	}
+
+	_, err = b.db.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{Delete: &dynamodb.Delete{TableName: aws.String(b.tn), Key: b.scoreKey(player, score)}},
+			{Delete: &dynamodb.Delete{
+				TableName:                 aws.String(b.tn),
+				Key:                       b.playerKey(player),
+				ConditionExpression:       aws.String("#s = :s"),
+				ExpressionAttributeNames:  map[string]*string{"#s": aws.String("Dyno_Score")},
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":s": Int(score)},
+			}},
+		},
+	})
+	if Classify(err) == ErrorClassConditionalCheckFailed { // The score changed while it was being removed
+		return b.Remove(ctx, player)
+	}
+	return err
+}
+
+// Top returns the n highest scoring players, highest first
+func (b *Leaderboard) Top(ctx context.Context, n int64) ([]LeaderboardEntry, error) {
+	entries := []LeaderboardEntry{}
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(b.tn),
+		KeyConditionExpression:    aws.String("#pk = :pk AND begins_with(#sk, :prefix)"),
+		ExpressionAttributeNames:  map[string]*string{"#pk": aws.String(b.pk), "#sk": aws.String(b.sk)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":pk": b.partition(), ":prefix": Str("Dyno_Score/")},
+		ScanIndexForward:          aws.Bool(false),
+	}
+
+	for int64(len(entries)) < n {
+		input.Limit = aws.Int64(n - int64(len(entries)))
+		result, err := b.db.QueryWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Items {
+			entries = append(entries, LeaderboardEntry{
+				Player: GetString(item, "Dyno_Player", ""),
+				Score:  GetInt(item, "Dyno_Score", 0),
+				Rank:   int64(len(entries)) + 1,
+			})
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	return entries, nil
+}
+
+// Rank returns the player's place on the board, by counting the players above them. ErrPlayerNotFound is returned if
+// they aren't on it.
+func (b *Leaderboard) Rank(ctx context.Context, player string) (LeaderboardEntry, error) {
+	score, exists, err := b.Score(ctx, player)
+	if err != nil {
+		return LeaderboardEntry{}, err
+	}
+	if !exists {
+		return LeaderboardEntry{}, ErrPlayerNotFound
+	}
+
+	// Score items sort after the player items, so everything after the player's score item is a higher score
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(b.tn),
+		KeyConditionExpression:    aws.String("#pk = :pk AND #sk > :sk"),
+		ExpressionAttributeNames:  map[string]*string{"#pk": aws.String(b.pk), "#sk": aws.String(b.sk)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":pk": b.partition(), ":sk": b.scoreKey(player, score)[b.sk]},
+		Select:                    aws.String(dynamodb.SelectCount),
+	}
+
+	var above int64
+	for {
+		result, err := b.db.QueryWithContext(ctx, input)
+		if err != nil {
+			return LeaderboardEntry{}, err
+		}
+		above += aws.Int64Value(result.Count)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	return LeaderboardEntry{Player: player, Score: score, Rank: above + 1}, nil
+}
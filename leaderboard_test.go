@@ -0,0 +1,75 @@
+package dyno
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaderboard(t *testing.T) {
+	ctx := context.Background()
+	board := NewLeaderboard(testClient, tableName, "PK", "SK", "testing-board")
+
+	t.Run("given scores", func(t *testing.T) {
+		require.NoError(t, board.SetScore(ctx, "alice", 50))
+		require.NoError(t, board.SetScore(ctx, "bob", -20))
+		require.NoError(t, board.SetScore(ctx, "carol", 120))
+		require.NoError(t, board.SetScore(ctx, "dave", 7))
+
+		top, err := board.Top(ctx, 3)
+		require.NoError(t, err)
+		assert.Equal(t, []LeaderboardEntry{
+			{Player: "carol", Score: 120, Rank: 1},
+			{Player: "alice", Score: 50, Rank: 2},
+			{Player: "dave", Score: 7, Rank: 3},
+		}, top)
+
+		entry, err := board.Rank(ctx, "bob")
+		require.NoError(t, err)
+		assert.Equal(t, LeaderboardEntry{Player: "bob", Score: -20, Rank: 4}, entry)
+	})
+
+	t.Run("given a changed score", func(t *testing.T) {
+		score, err := board.AddScore(ctx, "dave", 100)
+		require.NoError(t, err)
+		assert.Equal(t, int64(107), score)
+
+		top, err := board.Top(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, top, 4, "the old score was removed")
+		assert.Equal(t, "dave", top[1].Player)
+	})
+
+	t.Run("given concurrent updates", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := board.AddScore(ctx, "erin", 1)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		entry, err := board.Rank(ctx, "erin")
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), entry.Score)
+		assert.Equal(t, int64(4), entry.Rank)
+	})
+
+	t.Run("given a removed player", func(t *testing.T) {
+		require.NoError(t, board.Remove(ctx, "carol"))
+		require.NoError(t, board.Remove(ctx, "carol"))
+
+		_, err := board.Rank(ctx, "carol")
+		assert.Equal(t, ErrPlayerNotFound, err)
+
+		top, err := board.Top(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "dave", top[0].Player)
+	})
+}
@@ -1,66 +1,185 @@
 package dyno
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"strconv"
+	"io"
+	"math"
+	"math/rand"
 	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/segmentio/ksuid"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 )
 
 type Lock struct {
-	db            *dynamodb.DynamoDB
+	db            dynamodbiface.DynamoDBAPI
 	tn            string
 	pk            string
 	sk            string
 	name          string
-	owned         *string
 	local         sync.Mutex
 	expiresAt     time.Time
 	expiresAtName string
+	signingKey    []byte
+	tracer        Tracer
+	metrics       Metrics
+	minPoll       time.Duration
+	maxPoll       time.Duration
+	eventual      bool
+	watcher       *LockWatcher
+	onEvent       func(LockEvent)
+	stats         lockStats
+	order         *LockOrderChecker
+	hold          lockHold
+
+	// server is the clock leases are timed by when they follow the server's time, and skew is how far clocks may
+	// still disagree
+	server *ServerClock
+	skew   time.Duration
+
+	// fair waiters register in the lock's queue and wait their turn
+	fair     bool
+	priority int
+
+	// stamp adds attributes to the lock item before each attempt to acquire it
+	stamp func(item map[string]*dynamodb.AttributeValue)
+
+	// deleteOnRelease deletes the lock item on release rather than leaving its key behind, for locks named after an
+	// open set of keys
+	deleteOnRelease bool
+
+	// state guards the ID and item of the held lock. local is held for a whole acquire or release, so renewals, which
+	// may come from a Heartbeat at any time, only take state.
+	state sync.Mutex
+	owned *string
+	item  map[string]*dynamodb.AttributeValue
+
+	clock  Clock
+	logger Logger
 }
 
-func NewLock(db *dynamodb.DynamoDB, tableName, primaryKey, sortKey, name string) *Lock {
-	return &Lock{
-		db:   db,
-		tn:   tableName,
-		pk:   primaryKey,
-		sk:   sortKey,
-		name: name,
-	}
+// NewLock returns a lock in a table with the given key names. It's the same as NewLockWithOptions with WithPrimaryKey
+// and WithSortKey.
+func NewLock(db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey, name string) *Lock {
+	return NewLockWithOptions(db, tableName, name, WithPrimaryKey(primaryKey), WithSortKey(sortKey))
 }
 
 var (
 	ErrLockAcquireTimeout       = errors.New("failed to acquire lock within timeout")
 	ErrLockNotOwned             = errors.New("lock not owned by this lock")
+	ErrLockSignatureMismatch    = errors.New("lock item signature does not match its attributes")
 	errLockAcquiredBeforeExpire = errors.New("lock was acquired before expiration")
 )
 
+// Expiration writes at to the named attribute, for use as the table's TTL. The lock always records when its lease runs
+// out as Dyno_ExpiresAt, so using that as the TTL attribute removes abandoned lock items without a separate expiry.
 func (l *Lock) Expiration(name string, at time.Time) {
 	l.expiresAtName = name
 	l.expiresAt = at
 }
 
+// SigningKey enables HMAC signatures on the lock item. Every process using the lock must share the key. A lock item
+// that was modified by anything else is reported with ErrLockSignatureMismatch instead of being honored.
+func (l *Lock) SigningKey(key []byte) {
+	l.signingKey = key
+}
+
+// Tracer records a span for each Acquire, annotated with the lock name and the number of retries
+func (l *Lock) Tracer(t Tracer) {
+	l.tracer = t
+}
+
+// Metrics reports the capacity consumed by the lock as the "Lock" operation, a "LockContention" count each time
+// Acquire finds the lock held, and the "LockWait" timing of successful acquires. Counts and timings have a "Lock"
+// dimension with the lock name.
+func (l *Lock) Metrics(m Metrics) {
+	l.metrics = m
+}
+
+// PollInterval sets how often a held lock is polled while waiting for it. Polling starts at min, and backs off towards
+// max the longer the same holder keeps the lock and the more often other waiters are seen taking it. Defaults to 25ms
+// and one second.
+func (l *Lock) PollInterval(min, max time.Duration) {
+	l.minPoll = min
+	l.maxPoll = max
+}
+
+// EventuallyConsistentReads makes waiters read the lock item with eventually consistent reads, at half the read
+// capacity. A waiter may see a holder that already released the lock, and wait longer than it needs to, but never
+// takes a lock that is held. Reads are strongly consistent by default.
+func (l *Lock) EventuallyConsistentReads(eventual bool) {
+	l.eventual = eventual
+}
+
+// Watcher makes waiters wake as soon as the watcher sees the lock item change. They still poll at the maximum poll
+// interval, in case the stream falls behind.
+func (l *Lock) Watcher(w *LockWatcher) {
+	l.watcher = w
+}
+
+// ServerTime times leases by the server's clock instead of the local one, so a waiter whose clock runs ahead can't
+// take a lock before its lease runs out. Another holder's lease is only treated as expired once it's been expired for
+// the skew allowance, which must cover the clock's accuracy. Every process using the lock should use it.
+func (l *Lock) ServerTime(c *ServerClock, skew time.Duration) {
+	l.server = c
+	l.clock = c
+	l.skew = skew
+}
+
 func (l *Lock) Acquire(lease time.Duration) error {
 	return l.AcquireWithTimeout(lease, time.Duration(0))
 }
 
 func (l *Lock) AcquireWithTimeout(lease, duration time.Duration) error {
+	return l.AcquireContext(context.Background(), lease, duration)
+}
+
+// AcquireContext is AcquireWithTimeout with a context for cancellation and tracing
+func (l *Lock) AcquireContext(ctx context.Context, lease, duration time.Duration) (err error) {
 	l.local.Lock()
 	defer l.local.Unlock()
 
+	ctx, span := startSpan(ctx, l.tracer, "dyno.Lock.Acquire")
+	span.Annotate("dyno_lock", l.name)
+	retries := 0
+	defer func() {
+		span.Annotate("dyno_retries", retries)
+		span.End(err)
+	}()
+
 	start := time.Now()
-	lockID := ksuid.New().String()
+	waitingSince := l.clock.Now()
+	lockID := NewKSUID()
 	var lastLeaseID string
+	polls, handovers := 0, 0
+
+	self := LockWaiter{ID: lockID, Priority: l.priority, Since: waitingSince}
+	registered := false
+	defer func() {
+		if registered {
+			l.unregisterWaiter(lockID)
+		}
+	}()
+
+	var wake <-chan struct{}
+	if l.watcher != nil {
+		var stop func()
+		wake, stop = l.watcher.subscribe(l.name)
+		defer stop()
+	}
 
 	item := l.key()
 	item["Dyno_LockID"] = &dynamodb.AttributeValue{S: aws.String(lockID)}
-	item["Dyno_Lease"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(int(lease / time.Second)))}
-	if l.expiresAtName != "" {
+	item["Dyno_Lease"] = DurationValue(lease, time.Second)
+	if l.expiresAtName != "" && !l.expiresAt.IsZero() {
 		item[l.expiresAtName] = &dynamodb.AttributeValue{N: aws.String(fmt.Sprintf("%d", l.expiresAt.Unix()))}
 	}
 	input := &dynamodb.PutItemInput{
@@ -69,32 +188,95 @@ func (l *Lock) AcquireWithTimeout(lease, duration time.Duration) error {
 		ExpressionAttributeNames: map[string]*string{
 			"#id": aws.String("Dyno_LockID"),
 		},
-		Item: item,
+		Item:                   item,
+		ReturnConsumedCapacity: returnConsumedCapacity(l.metrics),
 	}
 
-	for {
+	for ; ; retries++ {
 		sleep := true
 
-		_, err := l.db.PutItem(input)
+		if err := l.syncClock(ctx); err != nil {
+			return err
+		}
+
+		// Wait for the waiters ahead in the queue to take their turns
+		var expired map[string]*dynamodb.AttributeValue
+		if l.fair {
+			var ahead bool
+			var err error
+			ahead, expired, err = l.waiterAhead(ctx, self, registered)
+			if err != nil {
+				return err
+			}
+			if ahead {
+				if err := l.registerWaiter(ctx, self, expired); err != nil {
+					return err
+				}
+				registered = true
+
+				if start.Add(duration).Before(time.Now()) {
+					return ErrLockAcquireTimeout
+				}
+				if err := l.wait(ctx, wake, polls, handovers); err != nil {
+					return err
+				}
+				polls++
+				continue
+			}
+		}
+
+		now := l.clock.Now()
+		item["Dyno_AcquiredAt"] = TimeValue(now, TimeUnixMillis)
+		l.setLeaseExpiry(item, now.Add(lease))
+		if l.stamp != nil {
+			l.stamp(item)
+		}
+		if l.signingKey != nil {
+			item["Dyno_Signature"] = &dynamodb.AttributeValue{B: l.signature(item)}
+		}
+
+		result, err := l.db.PutItemWithContext(ctx, input)
+		if result != nil {
+			RecordCapacity(l.metrics, "Lock", true, result.ConsumedCapacity)
+		}
 		if err == nil { // We own the lock
-			l.owned = aws.String(lockID)
+			l.own(lockID, item)
+			timingMetric(l.metrics, "LockWait", time.Since(start), l.dimensions())
+			l.event(LockEvent{Type: LockAcquired, LockID: lockID, Wait: time.Since(start)})
 			return nil
 		}
 
 		sleep = true
 
-		if isAwsErrorCode(err, dynamodb.ErrCodeConditionalCheckFailedException) { // Failed to acquire the lock. Owned by someone else
-			context, err := l.getCurrentLeaseContext()
+		class := Classify(err)
+		if class != ErrorClassConditionalCheckFailed && !IsRetryable(err) {
+			return err
+		}
+
+		if class == ErrorClassConditionalCheckFailed { // Failed to acquire the lock. Owned by someone else
+			countMetric(l.metrics, "LockContention", 1, l.dimensions())
+
+			if l.fair {
+				if err := l.registerWaiter(ctx, self, expired); err != nil {
+					return err
+				}
+				registered = true
+			}
+
+			current, err := l.getCurrentLeaseContext(ctx)
 			if err != nil { // Unknown error
 				return err
 			}
-			if context == nil { // The lock was released before we could fetch the current context.
+			if current == nil { // The lock was released before we could fetch the current context.
 				sleep = false
 			} else {
 				// The lock has expired by the person we expect it to be.
-				if lastLeaseID == context.id && start.Add(context.duration).Before(time.Now()) {
-					err := l.expireAndAcquire(context.id, lockID)
+				if lastLeaseID == current.id && current.expired(waitingSince, l.clock.Now().Add(-l.skew)) {
+					err := l.expireAndAcquire(ctx, input, current.id)
 					if err == nil { // We own the lock
+						l.own(lockID, item)
+						timingMetric(l.metrics, "LockWait", time.Since(start), l.dimensions())
+						l.event(LockEvent{Type: LockStolen, LockID: lockID, Holder: current.id, Wait: time.Since(start)})
 						return nil
 					}
 					// the error will be errLockAcquiredBeforeExpire if the lock was acquired by someone else
@@ -104,7 +286,16 @@ func (l *Lock) AcquireWithTimeout(lease, duration time.Duration) error {
 					}
 				}
 
-				lastLeaseID = context.id
+				if lastLeaseID == current.id {
+					polls++
+				} else {
+					l.event(LockEvent{Type: LockContended, Holder: current.id})
+					if lastLeaseID != "" {
+						handovers++
+					}
+					polls = 0
+				}
+				lastLeaseID = current.id
 			}
 		}
 
@@ -113,9 +304,10 @@ func (l *Lock) AcquireWithTimeout(lease, duration time.Duration) error {
 			return ErrLockAcquireTimeout
 		}
 
-		// Wait 25ms before trying to acquire the lock again.
 		if sleep {
-			time.Sleep(25 * time.Millisecond)
+			if err := l.wait(ctx, wake, polls, handovers); err != nil {
+				return err
+			}
 		}
 	}
 }
@@ -125,27 +317,55 @@ func (l *Lock) Release() error {
 	l.local.Lock()
 	defer l.local.Unlock()
 
-	if l.owned == nil {
+	id := l.ownedID()
+	if id == "" {
 		return ErrLockNotOwned
 	}
 
 	input := &dynamodb.UpdateItemInput{
 		TableName:           aws.String(l.tn),
 		Key:                 l.key(),
-		UpdateExpression:    aws.String("REMOVE #id, #ls"),
+		UpdateExpression:    aws.String("REMOVE #id, #ls, #at, #lx, #lm, #rg, #ep, #sig"),
 		ConditionExpression: aws.String("#id = :id"),
 		ExpressionAttributeNames: map[string]*string{
-			"#id": aws.String("Dyno_LockID"),
-			"#ls": aws.String("Dyno_Lease"),
+			"#id":  aws.String("Dyno_LockID"),
+			"#ls":  aws.String("Dyno_Lease"),
+			"#at":  aws.String("Dyno_AcquiredAt"),
+			"#lx":  aws.String("Dyno_ExpiresAt"),
+			"#lm":  aws.String("Dyno_ExpiresAtMs"),
+			"#rg":  aws.String("Dyno_Region"),
+			"#ep":  aws.String("Dyno_Epoch"),
+			"#sig": aws.String("Dyno_Signature"),
 		},
 		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
-			":id": {S: l.owned},
+			":id": {S: aws.String(id)},
 		},
+		ReturnConsumedCapacity: returnConsumedCapacity(l.metrics),
 	}
 
-	_, err := l.db.UpdateItem(input)
-	if isAwsErrorCode(err, dynamodb.ErrCodeConditionalCheckFailedException) {
-		l.owned = nil
+	var err error
+	if l.deleteOnRelease {
+		var result *dynamodb.DeleteItemOutput
+		result, err = l.db.DeleteItem(&dynamodb.DeleteItemInput{
+			TableName:                 input.TableName,
+			Key:                       input.Key,
+			ConditionExpression:       input.ConditionExpression,
+			ExpressionAttributeNames:  map[string]*string{"#id": aws.String("Dyno_LockID")},
+			ExpressionAttributeValues: input.ExpressionAttributeValues,
+			ReturnConsumedCapacity:    input.ReturnConsumedCapacity,
+		})
+		if result != nil {
+			RecordCapacity(l.metrics, "Lock", true, result.ConsumedCapacity)
+		}
+	} else {
+		var result *dynamodb.UpdateItemOutput
+		result, err = l.db.UpdateItem(input)
+		if result != nil {
+			RecordCapacity(l.metrics, "Lock", true, result.ConsumedCapacity)
+		}
+	}
+	if Classify(err) == ErrorClassConditionalCheckFailed {
+		l.forget(id, LockLost)
 		return nil
 	}
 
@@ -153,53 +373,395 @@ func (l *Lock) Release() error {
 		return err
 	}
 
+	l.forget(id, LockReleased)
+
+	return nil
+}
+
+// disown forgets a lock that was lost without releasing it
+func (l *Lock) disown() {
+	l.forget(l.ownedID(), LockLost)
+}
+
+// own records the lock as held with the given ID and item
+func (l *Lock) own(id string, item map[string]*dynamodb.AttributeValue) {
+	l.state.Lock()
+	defer l.state.Unlock()
+
+	l.owned = aws.String(id)
+	l.item = item
+}
+
+// ownedID returns the ID of the held lock, or an empty string if it isn't held
+func (l *Lock) ownedID() string {
+	l.state.Lock()
+	defer l.state.Unlock()
+
+	return aws.StringValue(l.owned)
+}
+
+// forget records the lock as no longer held, if it's still held with the ID, and reports the event
+func (l *Lock) forget(id string, event LockEventType) {
+	l.state.Lock()
+	defer l.state.Unlock()
+
+	if id == "" || aws.StringValue(l.owned) != id {
+		return
+	}
 	l.owned = nil
+	l.event(LockEvent{Type: event, LockID: id})
+}
+
+// wait sleeps until it's time to poll the lock again, or the watcher sees the lock item change
+func (l *Lock) wait(ctx context.Context, wake <-chan struct{}, polls, handovers int) error {
+	if wake == nil {
+		return sleepContext(ctx, l.pollInterval(polls, handovers))
+	}
+
+	timer := time.NewTimer(l.maxPoll)
+	defer timer.Stop()
 
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-wake:
+	case <-timer.C:
+	}
 	return nil
 }
 
+// pollInterval grows by half for each poll that found the same holder, and by half again for each handover to
+// another waiter, with up to 25% jitter so waiters don't poll in step
+func (l *Lock) pollInterval(polls, handovers int) time.Duration {
+	interval := float64(l.minPoll) * math.Pow(1.5, float64(polls)) * (1 + float64(handovers)/2)
+	interval *= 0.75 + rand.Float64()/2
+	if interval > float64(l.maxPoll) {
+		interval = float64(l.maxPoll)
+	}
+
+	return time.Duration(interval)
+}
+
+func (l *Lock) dimensions() map[string]string {
+	return map[string]string{"Lock": l.name}
+}
+
 type leaseContext struct {
-	id       string
-	duration time.Duration
+	id         string
+	duration   time.Duration
+	acquiredAt time.Time
+	expiresAt  time.Time
+}
+
+// expired returns true if the lease has passed the expiry recorded by the holder. Leases from versions of dyno that
+// didn't record it are timed from when they were acquired or renewed, or failing that from when the waiter started
+// waiting.
+func (c *leaseContext) expired(waitingSince, now time.Time) bool {
+	if !c.expiresAt.IsZero() {
+		return c.expiresAt.Before(now)
+	}
+	if c.acquiredAt.IsZero() {
+		return waitingSince.Add(c.duration).Before(now)
+	}
+	return c.acquiredAt.Add(c.duration).Before(now)
 }
 
 func (l *Lock) key() map[string]*dynamodb.AttributeValue {
+	return lockKey(l.pk, l.sk, l.name)
+}
+
+func lockKey(primaryKey, sortKey, name string) map[string]*dynamodb.AttributeValue {
 	item := map[string]*dynamodb.AttributeValue{}
-	item[l.pk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_Lock/%s", l.name))}
+	item[primaryKey] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_Lock/%s", name))}
 
-	if l.sk != "" {
-		item[l.sk] = &dynamodb.AttributeValue{S: aws.String("Dyno_LockSortKeyValue")}
+	if sortKey != "" {
+		item[sortKey] = &dynamodb.AttributeValue{S: aws.String("Dyno_LockSortKeyValue")}
 	}
 
 	return item
 }
 
-func (l *Lock) getCurrentLeaseContext() (*leaseContext, error) {
+func (l *Lock) getCurrentLeaseContext(ctx context.Context) (*leaseContext, error) {
 	input := &dynamodb.GetItemInput{
 		TableName:            aws.String(l.tn),
 		Key:                  l.key(),
-		ProjectionExpression: aws.String("Dyno_LockID, Dyno_Lease"),
+		ProjectionExpression: aws.String("#id, #ls, #at, #lx, #lm, #rg, #ep, #sig"),
+		ExpressionAttributeNames: map[string]*string{
+			"#id":  aws.String("Dyno_LockID"),
+			"#ls":  aws.String("Dyno_Lease"),
+			"#at":  aws.String("Dyno_AcquiredAt"),
+			"#lx":  aws.String("Dyno_ExpiresAt"),
+			"#lm":  aws.String("Dyno_ExpiresAtMs"),
+			"#rg":  aws.String("Dyno_Region"),
+			"#ep":  aws.String("Dyno_Epoch"),
+			"#sig": aws.String("Dyno_Signature"),
+		},
+		ConsistentRead:         aws.Bool(!l.eventual),
+		ReturnConsumedCapacity: returnConsumedCapacity(l.metrics),
+	}
+	if l.expiresAtName != "" && l.expiresAtName != "Dyno_ExpiresAt" {
+		input.ProjectionExpression = aws.String("#id, #ls, #at, #lx, #lm, #rg, #ep, #sig, #exp")
+		input.ExpressionAttributeNames["#exp"] = aws.String(l.expiresAtName)
 	}
 
-	result, err := l.db.GetItem(input)
+	result, err := l.db.GetItemWithContext(ctx, input)
 	if err != nil {
 		return nil, err
 	}
+	RecordCapacity(l.metrics, "Lock", false, result.ConsumedCapacity)
 	if len(result.Item) == 0 {
 		return nil, nil
 	}
 
-	raw, err := strconv.ParseInt(aws.StringValue(result.Item["Dyno_Lease"].N), 10, 64)
+	if l.signingKey != nil {
+		signature, ok := result.Item["Dyno_Signature"]
+		if !ok || !hmac.Equal(signature.B, l.signature(result.Item)) {
+			return nil, ErrLockSignatureMismatch
+		}
+	}
+
+	lease, err := ParseDuration(result.Item["Dyno_Lease"], time.Second)
 	if err != nil {
 		return nil, err
 	}
 
-	return &leaseContext{
+	current := &leaseContext{
 		id:       aws.StringValue(result.Item["Dyno_LockID"].S),
-		duration: time.Duration(raw) * time.Second,
-	}, nil
+		duration: lease,
+	}
+	if at, err := ParseTime(result.Item["Dyno_AcquiredAt"], TimeUnixMillis); err == nil {
+		current.acquiredAt = at
+	}
+	if exp, err := ParseTime(result.Item["Dyno_ExpiresAtMs"], TimeUnixMillis); err == nil {
+		current.expiresAt = exp
+	}
+
+	return current, nil
+}
+
+// signature is an HMAC of the lock's name and the attributes that decide who holds it
+func (l *Lock) signature(item map[string]*dynamodb.AttributeValue) []byte {
+	mac := hmac.New(sha256.New, l.signingKey)
+	writeSignedField(mac, []byte(l.name))
+
+	names := []string{"Dyno_LockID", "Dyno_Lease", "Dyno_AcquiredAt", "Dyno_ExpiresAt", "Dyno_ExpiresAtMs", "Dyno_Region", "Dyno_Epoch"}
+	if l.expiresAtName != "Dyno_ExpiresAt" {
+		names = append(names, l.expiresAtName)
+	}
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if av, ok := item[name]; ok && av != nil {
+			kind, value := signedValue(av)
+			writeSignedField(mac, []byte(name))
+			writeSignedField(mac, []byte(kind))
+			writeSignedField(mac, value)
+		}
+	}
+
+	return mac.Sum(nil)
+}
+
+// writeSignedField writes a length prefixed field, so different attributes can never encode to the same bytes
+func writeSignedField(w io.Writer, data []byte) {
+	var size [8]byte
+	binary.BigEndian.PutUint64(size[:], uint64(len(data)))
+	w.Write(size[:])
+	w.Write(data)
+}
+
+// signedValue returns the attribute's type and the bytes of its value
+func signedValue(av *dynamodb.AttributeValue) (string, []byte) {
+	switch {
+	case av.S != nil:
+		return "S", []byte(*av.S)
+	case av.N != nil:
+		return "N", []byte(*av.N)
+	case av.B != nil:
+		return "B", av.B
+	case av.BOOL != nil:
+		return "BOOL", []byte(fmt.Sprint(*av.BOOL))
+	case av.NULL != nil:
+		return "NULL", nil
+	default:
+		data, _ := json.Marshal(attributeValueJSON(av))
+		return "JSON", data
+	}
+}
+
+// Renew extends the lock's lease from now. If the lock was lost to another holder it's no longer owned and
+// ErrLockNotOwned is returned.
+func (l *Lock) Renew(ctx context.Context) error {
+	return l.renew(ctx, 0)
+}
+
+// Extend renews the lock with a new lease, which later renewals keep. If the lock was lost to another holder it's no
+// longer owned and ErrLockNotOwned is returned.
+func (l *Lock) Extend(ctx context.Context, lease time.Duration) error {
+	return l.renew(ctx, lease)
+}
+
+// renew renews the lock's lease, or replaces it when lease is set
+func (l *Lock) renew(ctx context.Context, lease time.Duration) error {
+	if err := l.syncClock(ctx); err != nil {
+		return err
+	}
+	r, err := l.renewal(l.clock.Now(), lease)
+	if err != nil {
+		return err
+	}
+
+	result, err := l.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 r.update.TableName,
+		Key:                       r.update.Key,
+		UpdateExpression:          r.update.UpdateExpression,
+		ConditionExpression:       r.update.ConditionExpression,
+		ExpressionAttributeNames:  r.update.ExpressionAttributeNames,
+		ExpressionAttributeValues: r.update.ExpressionAttributeValues,
+		ReturnConsumedCapacity:    returnConsumedCapacity(l.metrics),
+	})
+	if result != nil {
+		RecordCapacity(l.metrics, "Lock", true, result.ConsumedCapacity)
+	}
+
+	return l.renewed(r, err)
+}
+
+// lockRenewal is the update that renews a lock, the lock item it leaves behind, and the event it reports
+type lockRenewal struct {
+	id     string
+	update *dynamodb.Update
+	item   map[string]*dynamodb.AttributeValue
+	event  LockEventType
+}
+
+// renewal builds the update that renews the lock's lease, or replaces it when lease is set
+func (l *Lock) renewal(now time.Time, lease time.Duration) (*lockRenewal, error) {
+	l.state.Lock()
+	defer l.state.Unlock()
+
+	if l.owned == nil {
+		return nil, ErrLockNotOwned
+	}
+
+	item := make(map[string]*dynamodb.AttributeValue, len(l.item))
+	for k, v := range l.item {
+		item[k] = v
+	}
+	event := LockRenewed
+	if lease > 0 {
+		item["Dyno_Lease"] = DurationValue(lease, time.Second)
+		event = LockExtended
+	} else {
+		lease, _ = ParseDuration(item["Dyno_Lease"], time.Second)
+	}
+	item["Dyno_AcquiredAt"] = TimeValue(now, TimeUnixMillis)
+	l.setLeaseExpiry(item, now.Add(lease))
+
+	sets := "SET #at = :at, #lx = :lx, #lm = :lm"
+	update := &dynamodb.Update{
+		TableName:           aws.String(l.tn),
+		Key:                 l.key(),
+		ConditionExpression: aws.String("#id = :id"),
+		ExpressionAttributeNames: map[string]*string{
+			"#id": aws.String("Dyno_LockID"),
+			"#at": aws.String("Dyno_AcquiredAt"),
+			"#lx": aws.String("Dyno_ExpiresAt"),
+			"#lm": aws.String("Dyno_ExpiresAtMs"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":id": {S: l.owned},
+			":at": item["Dyno_AcquiredAt"],
+			":lx": item["Dyno_ExpiresAt"],
+			":lm": item["Dyno_ExpiresAtMs"],
+		},
+	}
+	if event == LockExtended {
+		sets += ", #ls = :ls"
+		update.ExpressionAttributeNames["#ls"] = aws.String("Dyno_Lease")
+		update.ExpressionAttributeValues[":ls"] = item["Dyno_Lease"]
+	}
+	if l.leaseTTL() {
+		sets += ", #ttl = :lx"
+		update.ExpressionAttributeNames["#ttl"] = aws.String(l.expiresAtName)
+	}
+	if l.signingKey != nil {
+		item["Dyno_Signature"] = &dynamodb.AttributeValue{B: l.signature(item)}
+		sets += ", #sig = :sig"
+		update.ExpressionAttributeNames["#sig"] = aws.String("Dyno_Signature")
+		update.ExpressionAttributeValues[":sig"] = item["Dyno_Signature"]
+	}
+	update.UpdateExpression = aws.String(sets)
+
+	return &lockRenewal{id: *l.owned, update: update, item: item, event: event}, nil
+}
+
+// renewed records the result of a renewal
+func (l *Lock) renewed(r *lockRenewal, err error) error {
+	l.state.Lock()
+	defer l.state.Unlock()
+
+	if aws.StringValue(l.owned) != r.id {
+		return ErrLockNotOwned
+	}
+	if Classify(err) == ErrorClassConditionalCheckFailed {
+		l.event(LockEvent{Type: LockLost, LockID: r.id})
+		l.owned = nil
+		return ErrLockNotOwned
+	}
+	if err != nil {
+		return err
+	}
+
+	l.item = r.item
+	l.event(LockEvent{Type: r.event, LockID: r.id})
+	return nil
+}
+
+// expireAndAcquire takes over a lock whose lease expired, as long as it's still held by the same lock ID and, when the
+// holder recorded its expiry, that has passed. A renewal that races the takeover wins.
+func (l *Lock) expireAndAcquire(ctx context.Context, input *dynamodb.PutItemInput, currentID string) error {
+	takeover := *input
+	takeover.ConditionExpression = aws.String("#id = :current AND (attribute_not_exists(#lm) OR #lm < :now)")
+	takeover.ExpressionAttributeNames = map[string]*string{
+		"#id": aws.String("Dyno_LockID"),
+		"#lm": aws.String("Dyno_ExpiresAtMs"),
+	}
+	takeover.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+		":current": {S: aws.String(currentID)},
+		":now":     TimeValue(l.clock.Now().Add(-l.skew), TimeUnixMillis),
+	}
+
+	result, err := l.db.PutItemWithContext(ctx, &takeover)
+	if result != nil {
+		RecordCapacity(l.metrics, "Lock", true, result.ConsumedCapacity)
+	}
+	if Classify(err) == ErrorClassConditionalCheckFailed {
+		return errLockAcquiredBeforeExpire
+	}
+	return err
+}
+
+// syncClock measures the server clock's offset, if leases follow it and it's due
+func (l *Lock) syncClock(ctx context.Context) error {
+	if l.server == nil {
+		return nil
+	}
+	return l.server.sync(ctx)
+}
+
+// setLeaseExpiry records when the lease runs out on the lock item, in milliseconds for waiters deciding whether to take
+// the lock over, and in seconds for use as the table's TTL
+func (l *Lock) setLeaseExpiry(item map[string]*dynamodb.AttributeValue, expiresAt time.Time) {
+	item["Dyno_ExpiresAt"] = TimeValue(expiresAt, TimeUnixSeconds)
+	item["Dyno_ExpiresAtMs"] = TimeValue(expiresAt, TimeUnixMillis)
+	if l.leaseTTL() {
+		item[l.expiresAtName] = item["Dyno_ExpiresAt"]
+	}
 }
 
-func (l *Lock) expireAndAcquire(currentID, newID string) error {
-	return errors.New("testing")
+// leaseTTL returns true if the TTL attribute follows the lease, rather than a fixed expiration
+func (l *Lock) leaseTTL() bool {
+	return l.expiresAtName != "" && l.expiresAtName != "Dyno_ExpiresAt" && l.expiresAt.IsZero()
 }
@@ -1,9 +1,14 @@
 package dyno
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -35,3 +40,144 @@ func TestLock(t *testing.T) {
 		})
 	})
 }
+
+func TestLockExpiry(t *testing.T) {
+	ctx := context.Background()
+	holder := NewLock(testClient, tableName, "PK", "SK", "testing-lock-expiry")
+	require.NoError(t, holder.Acquire(time.Second))
+
+	info, err := DescribeLock(ctx, testClient, tableName, "PK", "SK", "testing-lock-expiry")
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, info.AcquiredAt.Add(time.Second), info.ExpiredAt())
+
+	t.Run("given an expired lease", func(t *testing.T) {
+		waiter := NewLock(testClient, tableName, "PK", "SK", "testing-lock-expiry")
+		require.NoError(t, waiter.AcquireContext(ctx, 30*time.Second, 5*time.Second))
+		defer waiter.Release()
+
+		assert.NoError(t, holder.Release(), "the holder lost the lock")
+
+		info, err := DescribeLock(ctx, testClient, tableName, "PK", "SK", "testing-lock-expiry")
+		require.NoError(t, err)
+		assert.Equal(t, aws.StringValue(waiter.owned), info.LockID)
+	})
+}
+
+func TestLockSigningKey(t *testing.T) {
+	key := []byte("shared-signing-key")
+	lock1 := NewLock(testClient, tableName, "PK", "SK", "testing-signed-lock")
+	lock1.SigningKey(key)
+	lock2 := NewLock(testClient, tableName, "PK", "SK", "testing-signed-lock")
+	lock2.SigningKey(key)
+
+	err := lock1.Acquire(30 * time.Second)
+	require.NoError(t, err)
+	defer lock1.Release()
+
+	t.Run("given an untouched lock item", func(t *testing.T) {
+		err := lock2.Acquire(30 * time.Second)
+		assert.Equal(t, ErrLockAcquireTimeout, err)
+	})
+
+	t.Run("given a modified lock item", func(t *testing.T) {
+		_, err := testClient.UpdateItem(&dynamodb.UpdateItemInput{
+			TableName:        aws.String(tableName),
+			Key:              lock1.key(),
+			UpdateExpression: aws.String("SET Dyno_Lease = :ls"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":ls": {N: aws.String("1")},
+			},
+		})
+		require.NoError(t, err)
+
+		err = lock2.Acquire(30 * time.Second)
+		assert.Equal(t, ErrLockSignatureMismatch, err)
+	})
+
+	t.Run("given attributes with the same text", func(t *testing.T) {
+		signature := func(item map[string]*dynamodb.AttributeValue) []byte {
+			return lock1.signature(item)
+		}
+
+		assert.NotEqual(t,
+			signature(map[string]*dynamodb.AttributeValue{"Dyno_Region": {S: aws.String("1")}}),
+			signature(map[string]*dynamodb.AttributeValue{"Dyno_Region": {N: aws.String("1")}}),
+		)
+		assert.NotEqual(t,
+			signature(map[string]*dynamodb.AttributeValue{"Dyno_LockID": {S: aws.String("a\nDyno_Region=b")}}),
+			signature(map[string]*dynamodb.AttributeValue{"Dyno_LockID": {S: aws.String("a")}, "Dyno_Region": {S: aws.String("b")}}),
+		)
+		assert.NotEqual(t,
+			signature(map[string]*dynamodb.AttributeValue{"Dyno_Epoch": {B: []byte("1")}}),
+			signature(map[string]*dynamodb.AttributeValue{"Dyno_Epoch": {B: []byte("2")}}),
+		)
+	})
+}
+
+func TestLockPollBackoff(t *testing.T) {
+	holder := NewLock(testClient, tableName, "PK", "SK", "testing-poll-backoff")
+	require.NoError(t, holder.Acquire(30*time.Second))
+	defer holder.Release()
+
+	metrics := &countingMetrics{}
+	waiter := NewLock(testClient, tableName, "PK", "SK", "testing-poll-backoff")
+	waiter.Metrics(metrics)
+	waiter.PollInterval(10*time.Millisecond, time.Second)
+
+	assert.Equal(t, ErrLockAcquireTimeout, waiter.AcquireWithTimeout(30*time.Second, 300*time.Millisecond))
+	assert.True(t, metrics.counts["LockContention"] < 15, "polled %v times", metrics.counts["LockContention"])
+}
+
+func TestLockPollInterval(t *testing.T) {
+	lock := NewLock(testClient, tableName, "PK", "SK", "testing-poll-interval")
+	lock.PollInterval(10*time.Millisecond, 100*time.Millisecond)
+
+	t.Run("given a newly held lock", func(t *testing.T) {
+		interval := lock.pollInterval(0, 0)
+
+		assert.True(t, interval >= 7*time.Millisecond && interval <= 13*time.Millisecond, interval.String())
+	})
+
+	t.Run("given a long held lock", func(t *testing.T) {
+		assert.Equal(t, 100*time.Millisecond, lock.pollInterval(20, 0))
+	})
+
+	t.Run("given other waiters taking the lock", func(t *testing.T) {
+		assert.True(t, lock.pollInterval(0, 4) >= 22*time.Millisecond)
+	})
+}
+
+// consistencyRecorder records whether each GetItem was strongly consistent
+type consistencyRecorder struct {
+	dynamodbiface.DynamoDBAPI
+	reads []bool
+}
+
+func (r *consistencyRecorder) GetItemWithContext(ctx aws.Context, input *dynamodb.GetItemInput, opts ...request.Option) (*dynamodb.GetItemOutput, error) {
+	r.reads = append(r.reads, aws.BoolValue(input.ConsistentRead))
+	return r.DynamoDBAPI.GetItemWithContext(ctx, input, opts...)
+}
+
+func TestLockReadConsistency(t *testing.T) {
+	ctx := context.Background()
+	holder := NewLock(testClient, tableName, "PK", "SK", "testing-read-consistency")
+	require.NoError(t, holder.Acquire(30*time.Second))
+	defer holder.Release()
+
+	t.Run("given the default", func(t *testing.T) {
+		db := &consistencyRecorder{DynamoDBAPI: testClient}
+		NewLock(db, tableName, "PK", "SK", "testing-read-consistency").getCurrentLeaseContext(ctx)
+
+		assert.Equal(t, []bool{true}, db.reads)
+	})
+
+	t.Run("given eventually consistent reads", func(t *testing.T) {
+		db := &consistencyRecorder{DynamoDBAPI: testClient}
+		lock := NewLock(db, tableName, "PK", "SK", "testing-read-consistency")
+		lock.EventuallyConsistentReads(true)
+		lock.getCurrentLeaseContext(ctx)
+
+		assert.Equal(t, []bool{false}, db.reads)
+	})
+}
@@ -0,0 +1,169 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+var ErrLockNotHeld = errors.New("lock is not held")
+
+// LockInfo describes the holder of a lock
+type LockInfo struct {
+	Name   string
+	LockID string
+	Lease  time.Duration
+
+	// AcquiredAt is zero for locks acquired by versions of dyno that didn't record it
+	AcquiredAt time.Time
+
+	// Region and Epoch are only set for a GlobalLock
+	Region string
+	Epoch  int64
+
+	expiresAt time.Time
+}
+
+// ExpiredAt returns when the lock's lease runs out, or the zero time if it's not known
+func (i LockInfo) ExpiredAt() time.Time {
+	if !i.expiresAt.IsZero() {
+		return i.expiresAt
+	}
+	if i.AcquiredAt.IsZero() {
+		return time.Time{}
+	}
+	return i.AcquiredAt.Add(i.Lease)
+}
+
+// lockInfoAttributes are the names of the attributes parsed by lockInfo
+var lockInfoAttributes = map[string]*string{
+	"#id": aws.String("Dyno_LockID"),
+	"#ls": aws.String("Dyno_Lease"),
+	"#at": aws.String("Dyno_AcquiredAt"),
+	"#lm": aws.String("Dyno_ExpiresAtMs"),
+	"#rg": aws.String("Dyno_Region"),
+	"#ep": aws.String("Dyno_Epoch"),
+}
+
+func lockInfo(item map[string]*dynamodb.AttributeValue, primaryKey string) (LockInfo, bool) {
+	id, ls := item["Dyno_LockID"], item["Dyno_Lease"]
+	if id == nil || ls == nil {
+		return LockInfo{}, false
+	}
+
+	lease, err := ParseDuration(ls, time.Second)
+	if err != nil {
+		return LockInfo{}, false
+	}
+
+	info := LockInfo{
+		Name:   strings.TrimPrefix(aws.StringValue(item[primaryKey].S), "Dyno_Lock/"),
+		LockID: aws.StringValue(id.S),
+		Lease:  lease,
+	}
+	if at, err := ParseTime(item["Dyno_AcquiredAt"], TimeUnixMillis); err == nil {
+		info.AcquiredAt = at
+	}
+	if exp, err := ParseTime(item["Dyno_ExpiresAtMs"], TimeUnixMillis); err == nil {
+		info.expiresAt = exp
+	}
+	if rg := item["Dyno_Region"]; rg != nil {
+		info.Region = aws.StringValue(rg.S)
+	}
+	if ep := item["Dyno_Epoch"]; ep != nil {
+		info.Epoch, _ = strconv.ParseInt(aws.StringValue(ep.N), 10, 64)
+	}
+
+	return info, true
+}
+
+// ListLocks scans the table for held locks. The scan is eventually consistent, so a lock acquired or released in the
+// last moment may be missed or still listed.
+func ListLocks(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName, primaryKey string) ([]LockInfo, error) {
+	return listLocks(ctx, db, tableName, primaryKey, false)
+}
+
+func listLocks(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName, primaryKey string, consistent bool) ([]LockInfo, error) {
+	names := map[string]*string{"#pk": aws.String(primaryKey)}
+	for k, v := range lockInfoAttributes {
+		names[k] = v
+	}
+
+	input := &dynamodb.ScanInput{
+		TableName:                aws.String(tableName),
+		FilterExpression:         aws.String("begins_with(#pk, :prefix) AND attribute_exists(#id)"),
+		ProjectionExpression:     aws.String("#pk, #id, #ls, #at, #lm, #rg, #ep"),
+		ConsistentRead:           aws.Bool(consistent),
+		ExpressionAttributeNames: names,
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":prefix": {S: aws.String("Dyno_Lock/")},
+		},
+	}
+
+	locks := []LockInfo{}
+	err := db.ScanPagesWithContext(ctx, input, func(page *dynamodb.ScanOutput, _ bool) bool {
+		for _, item := range page.Items {
+			if info, ok := lockInfo(item, primaryKey); ok {
+				locks = append(locks, info)
+			}
+		}
+		return true
+	})
+
+	return locks, err
+}
+
+// DescribeLock returns the holder of the named lock, or nil if it's not held
+func DescribeLock(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey, name string) (*LockInfo, error) {
+	result, err := db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(tableName),
+		Key:            lockKey(primaryKey, sortKey, name),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info, ok := lockInfo(result.Item, primaryKey)
+	if !ok {
+		return nil, nil
+	}
+	return &info, nil
+}
+
+// ForceRelease releases the named lock regardless of who holds it. When lockID isn't empty the lock is only released
+// if it's still held by that ID. ErrLockNotHeld is returned if there was nothing to release.
+func ForceRelease(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey, name, lockID string) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName:           aws.String(tableName),
+		Key:                 lockKey(primaryKey, sortKey, name),
+		UpdateExpression:    aws.String("REMOVE #id, #ls, #at, #lx, #lm, #rg, #ep, #sig"),
+		ConditionExpression: aws.String("attribute_exists(#id)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#id":  aws.String("Dyno_LockID"),
+			"#ls":  aws.String("Dyno_Lease"),
+			"#at":  aws.String("Dyno_AcquiredAt"),
+			"#lx":  aws.String("Dyno_ExpiresAt"),
+			"#lm":  aws.String("Dyno_ExpiresAtMs"),
+			"#rg":  aws.String("Dyno_Region"),
+			"#ep":  aws.String("Dyno_Epoch"),
+			"#sig": aws.String("Dyno_Signature"),
+		},
+	}
+	if lockID != "" {
+		input.ConditionExpression = aws.String("#id = :id")
+		input.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{":id": {S: aws.String(lockID)}}
+	}
+
+	_, err := db.UpdateItemWithContext(ctx, input)
+	if Classify(err) == ErrorClassConditionalCheckFailed {
+		return ErrLockNotHeld
+	}
+	return err
+}
@@ -0,0 +1,55 @@
+package dyno
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockAdmin(t *testing.T) {
+	ctx := context.Background()
+	table, drop := createTestTable(t, "dyno-test-lock-admin")
+	defer drop()
+
+	lock := NewLock(testClient, table, "PK", "SK", "admin")
+	require.NoError(t, lock.Acquire(time.Minute))
+	released := NewLock(testClient, table, "PK", "SK", "released")
+	require.NoError(t, released.Acquire(time.Minute))
+	require.NoError(t, released.Release())
+
+	t.Run("ListLocks", func(t *testing.T) {
+		locks, err := ListLocks(ctx, testClient, table, "PK")
+
+		require.NoError(t, err)
+		require.Len(t, locks, 1)
+		assert.Equal(t, "admin", locks[0].Name)
+		assert.Equal(t, time.Minute, locks[0].Lease)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), locks[0].ExpiredAt(), 5*time.Second)
+	})
+
+	t.Run("DescribeLock", func(t *testing.T) {
+		info, err := DescribeLock(ctx, testClient, table, "PK", "SK", "admin")
+		require.NoError(t, err)
+		require.NotNil(t, info)
+		assert.Equal(t, *lock.owned, info.LockID)
+
+		info, err = DescribeLock(ctx, testClient, table, "PK", "SK", "released")
+		require.NoError(t, err)
+		assert.Nil(t, info)
+	})
+
+	t.Run("ForceRelease", func(t *testing.T) {
+		assert.Equal(t, ErrLockNotHeld, ForceRelease(ctx, testClient, table, "PK", "SK", "admin", "someone-else"))
+		assert.Equal(t, ErrLockNotHeld, ForceRelease(ctx, testClient, table, "PK", "SK", "missing", ""))
+
+		require.NoError(t, ForceRelease(ctx, testClient, table, "PK", "SK", "admin", *lock.owned))
+
+		info, err := DescribeLock(ctx, testClient, table, "PK", "SK", "admin")
+		require.NoError(t, err)
+		assert.Nil(t, info)
+		assert.NoError(t, lock.Release())
+	})
+}
@@ -0,0 +1,266 @@
+package dyno
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Logger is satisfied by *log.Logger
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// RequestLogger logs every DynamoDB request made by a client, with expression placeholders replaced by the names and
+// values they stand for, the keys, and the result code. Install it on a client to enable it.
+type RequestLogger struct {
+	logger Logger
+
+	// Redact lists attributes whose values are replaced with [REDACTED], wherever they appear in keys, items, and
+	// expressions
+	Redact []string
+
+	// MaxValueLength truncates longer string and binary values. Defaults to 128, negative disables truncation.
+	MaxValueLength int
+}
+
+func NewRequestLogger(logger Logger) *RequestLogger {
+	return &RequestLogger{
+		logger:         logger,
+		MaxValueLength: 128,
+	}
+}
+
+// Install adds the logger to the client's handlers, e.g. logger.Install(&client.Handlers)
+func (l *RequestLogger) Install(handlers *request.Handlers) {
+	handlers.Complete.PushBackNamed(request.NamedHandler{
+		Name: "dyno.RequestLogger",
+		Fn:   l.log,
+	})
+}
+
+func (l *RequestLogger) log(r *request.Request) {
+	result := "OK"
+	if r.Error != nil {
+		result = r.Error.Error()
+		if aerr, ok := r.Error.(awserr.Error); ok {
+			result = aerr.Code()
+		}
+	}
+
+	parts := []string{r.Operation.Name}
+	l.describe(reflect.ValueOf(r.Params), &parts)
+	parts = append(parts, fmt.Sprintf("-> %s retries=%d duration=%s", result, r.RetryCount, time.Since(r.Time).Round(time.Millisecond)))
+
+	l.logger.Printf("dynamodb %s", strings.Join(parts, " "))
+}
+
+var expressionFields = []string{
+	"KeyConditionExpression",
+	"ConditionExpression",
+	"UpdateExpression",
+	"FilterExpression",
+	"ProjectionExpression",
+}
+
+var itemFields = []string{"Key", "Item", "ExclusiveStartKey"}
+
+// describe appends the tables, keys, items, and rendered expressions found in a request's input
+func (l *RequestLogger) describe(v reflect.Value, parts *[]string) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			l.describe(v.Index(i), parts)
+		}
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+
+	if table, ok := structField(v, "TableName").(*string); ok && table != nil {
+		*parts = append(*parts, "table="+*table)
+	}
+	for _, name := range itemFields {
+		if i, ok := structField(v, name).(map[string]*dynamodb.AttributeValue); ok && i != nil {
+			*parts = append(*parts, fmt.Sprintf("%s=%s", strings.ToLower(name), l.renderItem(i)))
+		}
+	}
+	if keys, ok := structField(v, "Keys").([]map[string]*dynamodb.AttributeValue); ok {
+		for _, key := range keys {
+			*parts = append(*parts, "key="+l.renderItem(key))
+		}
+	}
+
+	names, _ := structField(v, "ExpressionAttributeNames").(map[string]*string)
+	values, _ := structField(v, "ExpressionAttributeValues").(map[string]*dynamodb.AttributeValue)
+	for _, name := range expressionFields {
+		if expr, ok := structField(v, name).(*string); ok && expr != nil {
+			*parts = append(*parts, fmt.Sprintf("%s=%q", strings.TrimSuffix(name, "Expression"), l.RenderExpression(*expr, names, values)))
+		}
+	}
+
+	// Batches nest their requests by table name
+	if f := v.FieldByName("RequestItems"); f.IsValid() && f.Kind() == reflect.Map {
+		keys := f.MapKeys()
+		sort.Slice(keys, func(a, b int) bool { return keys[a].String() < keys[b].String() })
+		for _, key := range keys {
+			*parts = append(*parts, "table="+key.String())
+			l.describe(f.MapIndex(key), parts)
+		}
+	}
+
+	// Transactions and batch requests nest their operations in structs
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).PkgPath == "" && nestedRequest(v.Field(i).Type()) {
+			l.describe(v.Field(i), parts)
+		}
+	}
+}
+
+// structField returns the value of the struct's field, or nil if it doesn't have one
+func structField(v reflect.Value, name string) interface{} {
+	f := v.FieldByName(name)
+	if !f.IsValid() {
+		return nil
+	}
+	return f.Interface()
+}
+
+var attributeValueType = reflect.TypeOf(dynamodb.AttributeValue{})
+
+// nestedRequest returns true for struct pointers, and slices of them, other than attribute values
+func nestedRequest(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct && t.Elem() != attributeValueType
+}
+
+var expressionTokens = regexp.MustCompile(`[#:][A-Za-z0-9_]+|[A-Za-z_][A-Za-z0-9_]*\s*\(?`)
+
+var expressionKeywords = map[string]bool{
+	"AND": true, "OR": true, "NOT": true, "BETWEEN": true, "IN": true,
+	"SET": true, "REMOVE": true, "ADD": true, "DELETE": true,
+}
+
+// RenderExpression replaces the placeholders in an expression with the attribute names and values they stand for.
+// Values compared with a redacted attribute are redacted.
+func (l *RequestLogger) RenderExpression(expr string, names map[string]*string, values map[string]*dynamodb.AttributeValue) string {
+	last := ""
+
+	return expressionTokens.ReplaceAllStringFunc(expr, func(token string) string {
+		switch {
+		case token[0] == '#':
+			if name, ok := names[token]; ok && name != nil {
+				last = *name
+				return *name
+			}
+			return token
+		case token[0] == ':':
+			v, ok := values[token]
+			if !ok {
+				return token
+			}
+			if l.redacted(last) {
+				return "[REDACTED]"
+			}
+			return l.renderValue(v)
+		case strings.HasSuffix(token, "("): // function name
+			return token
+		default:
+			if !expressionKeywords[strings.ToUpper(token)] {
+				last = token
+			}
+			return token
+		}
+	})
+}
+
+func (l *RequestLogger) redacted(name string) bool {
+	for _, r := range l.Redact {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *RequestLogger) renderItem(i map[string]*dynamodb.AttributeValue) string {
+	names := make([]string, 0, len(i))
+	for name := range i {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rendered := make([]string, len(names))
+	for index, name := range names {
+		if l.redacted(name) {
+			rendered[index] = name + ":[REDACTED]"
+		} else {
+			rendered[index] = name + ":" + l.renderValue(i[name])
+		}
+	}
+	return "{" + strings.Join(rendered, ", ") + "}"
+}
+
+func (l *RequestLogger) renderValue(v *dynamodb.AttributeValue) string {
+	if v == nil {
+		return "null"
+	}
+
+	switch {
+	case v.S != nil:
+		return fmt.Sprintf("%q", l.truncate(*v.S))
+	case v.N != nil:
+		return *v.N
+	case v.B != nil:
+		return "b64:" + l.truncate(base64.StdEncoding.EncodeToString(v.B))
+	case v.BOOL != nil:
+		return fmt.Sprint(*v.BOOL)
+	case v.NULL != nil:
+		return "null"
+	case v.L != nil:
+		rendered := make([]string, len(v.L))
+		for index, e := range v.L {
+			rendered[index] = l.renderValue(e)
+		}
+		return "[" + strings.Join(rendered, ", ") + "]"
+	case v.M != nil:
+		return l.renderItem(v.M)
+	}
+
+	rendered := []string{}
+	for _, s := range v.SS {
+		rendered = append(rendered, fmt.Sprintf("%q", l.truncate(*s)))
+	}
+	for _, n := range v.NS {
+		rendered = append(rendered, *n)
+	}
+	for _, b := range v.BS {
+		rendered = append(rendered, "b64:"+l.truncate(base64.StdEncoding.EncodeToString(b)))
+	}
+	return "<<" + strings.Join(rendered, ", ") + ">>"
+}
+
+func (l *RequestLogger) truncate(s string) string {
+	if l.MaxValueLength < 0 || len(s) <= l.MaxValueLength {
+		return s
+	}
+	return fmt.Sprintf("%s...(%d bytes)", s[:l.MaxValueLength], len(s))
+}
@@ -0,0 +1,127 @@
+package dyno
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryLogger struct {
+	lines []string
+}
+
+func (m *memoryLogger) Printf(format string, v ...interface{}) {
+	m.lines = append(m.lines, fmt.Sprintf(format, v...))
+}
+
+func TestRequestLogger(t *testing.T) {
+	logger := &memoryLogger{}
+	requests := NewRequestLogger(logger)
+	requests.Redact = []string{"Password"}
+
+	client := dynamodb.New(session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("dyno", "dyno", ""),
+		MaxRetries:  aws.Int(0),
+	})))
+	var sendErr error
+	client.Handlers.Send.Clear()
+	client.Handlers.Send.PushBack(func(r *request.Request) {
+		r.HTTPResponse = &http.Response{StatusCode: 200, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader("{}"))}
+		r.Error = sendErr
+	})
+	requests.Install(&client.Handlers)
+
+	t.Run("given a conditional put", func(t *testing.T) {
+		logger.lines = nil
+		sendErr = awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+
+		client.PutItem(&dynamodb.PutItemInput{
+			TableName: aws.String("Users"),
+			Item: map[string]*dynamodb.AttributeValue{
+				"PK":       {S: aws.String("User/1")},
+				"Password": {S: aws.String("hunter2")},
+				"Tags":     {SS: aws.StringSlice([]string{"a"})},
+			},
+			ConditionExpression: aws.String("attribute_not_exists(#pk) OR (#pw = :pw AND Version < :v)"),
+			ExpressionAttributeNames: map[string]*string{
+				"#pk": aws.String("PK"),
+				"#pw": aws.String("Password"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":pw": {S: aws.String("hunter1")},
+				":v":  {N: aws.String("3")},
+			},
+		})
+
+		require.Len(t, logger.lines, 1)
+		line := logger.lines[0]
+		assert.True(t, strings.HasPrefix(line, "dynamodb PutItem table=Users "), line)
+		assert.Contains(t, line, `item={PK:"User/1", Password:[REDACTED], Tags:<<"a">>}`)
+		assert.Contains(t, line, `Condition="attribute_not_exists(PK) OR (Password = [REDACTED] AND Version < 3)"`)
+		assert.Contains(t, line, "-> ConditionalCheckFailedException")
+		assert.NotContains(t, line, "hunter")
+	})
+
+	t.Run("given a transaction", func(t *testing.T) {
+		logger.lines = nil
+		sendErr = nil
+
+		client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+			TransactItems: []*dynamodb.TransactWriteItem{
+				{Update: &dynamodb.Update{
+					TableName:                 aws.String("Counters"),
+					Key:                       map[string]*dynamodb.AttributeValue{"PK": {S: aws.String("a")}},
+					UpdateExpression:          aws.String("ADD #c :one"),
+					ExpressionAttributeNames:  map[string]*string{"#c": aws.String("Count")},
+					ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":one": {N: aws.String("1")}},
+				}},
+				{Delete: &dynamodb.Delete{
+					TableName: aws.String("Counters"),
+					Key:       map[string]*dynamodb.AttributeValue{"PK": {S: aws.String("b")}},
+				}},
+			},
+		})
+
+		require.Len(t, logger.lines, 1)
+		assert.Contains(t, logger.lines[0], `table=Counters key={PK:"a"} Update="ADD Count 1" table=Counters key={PK:"b"} -> OK`)
+	})
+
+	t.Run("given a batch write", func(t *testing.T) {
+		logger.lines = nil
+
+		client.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]*dynamodb.WriteRequest{
+				"Events": {{DeleteRequest: &dynamodb.DeleteRequest{Key: map[string]*dynamodb.AttributeValue{"PK": {N: aws.String("7")}}}}},
+			},
+		})
+
+		require.Len(t, logger.lines, 1)
+		assert.Contains(t, logger.lines[0], "dynamodb BatchWriteItem table=Events key={PK:7} -> OK")
+	})
+}
+
+func TestRenderExpression(t *testing.T) {
+	logger := NewRequestLogger(&memoryLogger{})
+	logger.MaxValueLength = 4
+
+	rendered := logger.RenderExpression("SET #n = :name, Flags = list_append(Flags, :flags) REMOVE Stale", map[string]*string{
+		"#n": aws.String("Name"),
+	}, map[string]*dynamodb.AttributeValue{
+		":name":  {S: aws.String("abcdefgh")},
+		":flags": {L: []*dynamodb.AttributeValue{{BOOL: aws.Bool(true)}, {NULL: aws.Bool(true)}}},
+	})
+
+	assert.Equal(t, `SET Name = "abcd...(8 bytes)", Flags = list_append(Flags, [true, null]) REMOVE Stale`, rendered)
+}
@@ -0,0 +1,95 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// setMapEntry sets an entry of a map attribute, creating the map, and the item, if they don't exist yet
+func setMapEntry(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName string, key map[string]*dynamodb.AttributeValue, name, entry string, value *dynamodb.AttributeValue) error {
+	for {
+		_, err := db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+			TableName:                 aws.String(tableName),
+			Key:                       key,
+			UpdateExpression:          aws.String("SET #m.#e = :v"),
+			ConditionExpression:       aws.String("attribute_exists(#m)"),
+			ExpressionAttributeNames:  map[string]*string{"#m": aws.String(name), "#e": aws.String(entry)},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":v": value},
+		})
+		if Classify(err) != ErrorClassConditionalCheckFailed {
+			return err
+		}
+
+		// A nested attribute can't be set until its map exists, so the map is created with the entry in it, unless
+		// another writer created it first
+		_, err = db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+			TableName:                 aws.String(tableName),
+			Key:                       key,
+			UpdateExpression:          aws.String("SET #m = :m"),
+			ConditionExpression:       aws.String("attribute_not_exists(#m)"),
+			ExpressionAttributeNames:  map[string]*string{"#m": aws.String(name)},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":m": Map(map[string]*dynamodb.AttributeValue{entry: value})},
+		})
+		if Classify(err) != ErrorClassConditionalCheckFailed {
+			return err
+		}
+	}
+}
+
+// maxExpiredMapEntries keeps the expression removing expired entries well under DynamoDB's 4KB limit. Any more are
+// removed by a later write.
+const maxExpiredMapEntries = 25
+
+// expiredMapEntries returns the entries of a map attribute, other than skip, whose expiry in unix milliseconds has
+// passed, along with the expiry that was read
+func expiredMapEntries(item map[string]*dynamodb.AttributeValue, name, expiry, skip string, now time.Time) map[string]*dynamodb.AttributeValue {
+	expired := map[string]*dynamodb.AttributeValue{}
+	for id, av := range GetMap(item, name) {
+		if len(expired) == maxExpiredMapEntries {
+			break
+		}
+		expiresAt, err := ParseTime(av.M[expiry], TimeUnixMillis)
+		if id != skip && err == nil && !expiresAt.After(now) {
+			expired[id] = av.M[expiry]
+		}
+	}
+	return expired
+}
+
+// removeExpiredMapEntries removes entries returned by expiredMapEntries from a map attribute. They're only removed if
+// none were renewed since they were read, otherwise they're left for a later write.
+func removeExpiredMapEntries(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName string, key map[string]*dynamodb.AttributeValue, name, expiry string, expired map[string]*dynamodb.AttributeValue) error {
+	if len(expired) == 0 {
+		return nil
+	}
+
+	names := map[string]*string{"#m": aws.String(name), "#x": aws.String(expiry)}
+	values := map[string]*dynamodb.AttributeValue{}
+	paths, conditions := []string{}, []string{}
+	for id, expiresAt := range expired {
+		i := len(paths)
+		names[fmt.Sprintf("#e%d", i)] = aws.String(id)
+		values[fmt.Sprintf(":x%d", i)] = expiresAt
+		paths = append(paths, fmt.Sprintf("#m.#e%d", i))
+		conditions = append(conditions, fmt.Sprintf("#m.#e%d.#x = :x%d", i, i))
+	}
+
+	_, err := db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(tableName),
+		Key:                       key,
+		UpdateExpression:          aws.String("REMOVE " + strings.Join(paths, ", ")),
+		ConditionExpression:       aws.String(strings.Join(conditions, " AND ")),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	if Classify(err) == ErrorClassConditionalCheckFailed {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,164 @@
+package dyno
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Metrics receives measurements from dyno operations. Implementations must be safe for concurrent use, and should
+// embed NopMetrics so they keep compiling when measurements are added.
+type Metrics interface {
+	// ConsumedCapacity records the capacity consumed by a DynamoDB call made for the operation, e.g. "Lock"
+	ConsumedCapacity(operation string, capacity Capacity)
+
+	// Count adds value to the named counter, e.g. "LockContention"
+	Count(name string, value float64, dimensions map[string]string)
+
+	// Timing records how long something took, e.g. "LockWait"
+	Timing(name string, d time.Duration, dimensions map[string]string)
+}
+
+// NopMetrics ignores every measurement
+type NopMetrics struct{}
+
+func (NopMetrics) ConsumedCapacity(string, Capacity) {}
+
+func (NopMetrics) Count(string, float64, map[string]string) {}
+
+func (NopMetrics) Timing(string, time.Duration, map[string]string) {}
+
+// Capacity is an amount of read and write capacity units
+type Capacity struct {
+	Table      string
+	ReadUnits  float64
+	WriteUnits float64
+}
+
+// Add returns the sum of both capacities. The table is kept only when both are for the same table.
+func (c Capacity) Add(o Capacity) Capacity {
+	if c.Table != o.Table {
+		c.Table = ""
+	}
+	c.ReadUnits += o.ReadUnits
+	c.WriteUnits += o.WriteUnits
+	return c
+}
+
+// RecordCapacity reports the consumed capacity returned by DynamoDB to the metrics, if there are any. Use it to
+// attribute application reads and writes alongside dyno's own. Units that DynamoDB doesn't split into reads and writes
+// are counted as writes when write is true.
+func RecordCapacity(m Metrics, operation string, write bool, consumed ...*dynamodb.ConsumedCapacity) {
+	if m == nil {
+		return
+	}
+
+	for _, cc := range consumed {
+		if cc == nil {
+			continue
+		}
+
+		capacity := Capacity{
+			Table:      aws.StringValue(cc.TableName),
+			ReadUnits:  aws.Float64Value(cc.ReadCapacityUnits),
+			WriteUnits: aws.Float64Value(cc.WriteCapacityUnits),
+		}
+		if cc.ReadCapacityUnits == nil && cc.WriteCapacityUnits == nil {
+			if write {
+				capacity.WriteUnits = aws.Float64Value(cc.CapacityUnits)
+			} else {
+				capacity.ReadUnits = aws.Float64Value(cc.CapacityUnits)
+			}
+		}
+
+		m.ConsumedCapacity(operation, capacity)
+	}
+}
+
+func countMetric(m Metrics, name string, value float64, dimensions map[string]string) {
+	if m != nil {
+		m.Count(name, value, dimensions)
+	}
+}
+
+func timingMetric(m Metrics, name string, d time.Duration, dimensions map[string]string) {
+	if m != nil {
+		m.Timing(name, d, dimensions)
+	}
+}
+
+// returnConsumedCapacity asks DynamoDB for the consumed capacity when there are metrics to report it to
+func returnConsumedCapacity(m Metrics) *string {
+	if m == nil {
+		return nil
+	}
+	return aws.String(dynamodb.ReturnConsumedCapacityTotal)
+}
+
+// CapacityCounter is a Metrics that totals the consumed capacity per operation. Other measurements are ignored.
+type CapacityCounter struct {
+	NopMetrics
+
+	mu     sync.Mutex
+	totals map[string]Capacity
+}
+
+func NewCapacityCounter() *CapacityCounter {
+	return &CapacityCounter{totals: map[string]Capacity{}}
+}
+
+func (c *CapacityCounter) ConsumedCapacity(operation string, capacity Capacity) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	total, ok := c.totals[operation]
+	if !ok {
+		total.Table = capacity.Table
+	}
+	c.totals[operation] = total.Add(capacity)
+}
+
+// Operation returns the capacity consumed by the operation
+func (c *CapacityCounter) Operation(operation string) Capacity {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.totals[operation]
+}
+
+// Operations returns the capacity consumed by each operation
+func (c *CapacityCounter) Operations() map[string]Capacity {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	totals := make(map[string]Capacity, len(c.totals))
+	for operation, total := range c.totals {
+		totals[operation] = total
+	}
+	return totals
+}
+
+// Total returns the capacity consumed by every operation
+func (c *CapacityCounter) Total() Capacity {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var total Capacity
+	first := true
+	for _, capacity := range c.totals {
+		if first {
+			total.Table = capacity.Table
+			first = false
+		}
+		total = total.Add(capacity)
+	}
+	return total
+}
+
+// Reset clears the totals
+func (c *CapacityCounter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.totals = map[string]Capacity{}
+}
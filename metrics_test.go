@@ -0,0 +1,92 @@
+package dyno
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingMetrics totals counts by name
+type countingMetrics struct {
+	NopMetrics
+
+	mu     sync.Mutex
+	counts map[string]float64
+}
+
+func (c *countingMetrics) Count(name string, value float64, dimensions map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counts == nil {
+		c.counts = map[string]float64{}
+	}
+	c.counts[name] += value
+}
+
+func TestCapacityCounter(t *testing.T) {
+	t.Run("given capacity for several operations", func(t *testing.T) {
+		counter := NewCapacityCounter()
+
+		RecordCapacity(counter, "Lock", true, &dynamodb.ConsumedCapacity{TableName: aws.String("a"), CapacityUnits: aws.Float64(1)})
+		RecordCapacity(counter, "Lock", false, &dynamodb.ConsumedCapacity{TableName: aws.String("a"), CapacityUnits: aws.Float64(0.5)})
+		RecordCapacity(counter, "App", false, &dynamodb.ConsumedCapacity{
+			TableName:          aws.String("b"),
+			CapacityUnits:      aws.Float64(3),
+			ReadCapacityUnits:  aws.Float64(2),
+			WriteCapacityUnits: aws.Float64(1),
+		}, nil)
+
+		assert.Equal(t, Capacity{Table: "a", ReadUnits: 0.5, WriteUnits: 1}, counter.Operation("Lock"))
+		assert.Equal(t, Capacity{Table: "b", ReadUnits: 2, WriteUnits: 1}, counter.Operation("App"))
+		assert.Equal(t, Capacity{ReadUnits: 2.5, WriteUnits: 2}, counter.Total())
+		assert.Len(t, counter.Operations(), 2)
+
+		counter.Reset()
+		assert.Equal(t, Capacity{}, counter.Total())
+	})
+
+	t.Run("given no metrics", func(t *testing.T) {
+		assert.NotPanics(t, func() {
+			RecordCapacity(nil, "Lock", true, &dynamodb.ConsumedCapacity{CapacityUnits: aws.Float64(1)})
+		})
+	})
+}
+
+func TestConsumedCapacity(t *testing.T) {
+	t.Run("given a lock", func(t *testing.T) {
+		counter := NewCapacityCounter()
+		lock := NewLock(testClient, tableName, "PK", "SK", "test-capacity-lock")
+		lock.Metrics(counter)
+
+		require.NoError(t, lock.Acquire(time.Minute))
+		require.NoError(t, lock.Release())
+
+		capacity := counter.Operation("Lock")
+		assert.Equal(t, tableName, capacity.Table)
+		assert.True(t, capacity.WriteUnits >= 2)
+	})
+
+	t.Run("given a batch writer", func(t *testing.T) {
+		ctx := context.Background()
+		counter := NewCapacityCounter()
+		writer := NewBatchWriter(testClient, tableName)
+		writer.Metrics = counter
+
+		for _, sk := range []string{"1", "2"} {
+			require.NoError(t, writer.Put(ctx, map[string]*dynamodb.AttributeValue{
+				"PK": {S: aws.String("test-capacity-batch")},
+				"SK": {S: aws.String(sk)},
+			}))
+		}
+		require.NoError(t, writer.Flush(ctx))
+
+		assert.True(t, counter.Operation("BatchWriter").WriteUnits >= 2)
+	})
+}
@@ -0,0 +1,157 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// MigrationFunc performs a single migration
+type MigrationFunc func(ctx context.Context, db dynamodbiface.DynamoDBAPI) error
+
+var ErrMigrationFailed = errors.New("migration failed")
+
+// Migrator runs registered migrations exactly once per environment.
+//
+// Applied migrations are recorded in a migrations partition of the table, and a Lock is held while they run so only
+// one deployer executes them at a time.
+type Migrator struct {
+	db         dynamodbiface.DynamoDBAPI
+	tn         string
+	pk         string
+	sk         string
+	env        string
+	migrations map[string]MigrationFunc
+
+	// Lease is the lease used for the migration lock. Defaults to 5 minutes.
+	Lease time.Duration
+
+	// Timeout is how long to wait for another deployer's migrations to finish. Defaults to 10 minutes.
+	Timeout time.Duration
+}
+
+func NewMigrator(db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey, environment string) *Migrator {
+	return &Migrator{
+		db:         db,
+		tn:         tableName,
+		pk:         primaryKey,
+		sk:         sortKey,
+		env:        environment,
+		migrations: map[string]MigrationFunc{},
+		Lease:      5 * time.Minute,
+		Timeout:    10 * time.Minute,
+	}
+}
+
+// Register adds a migration. Migrations run in the lexical order of their IDs, so a sortable prefix like a date is
+// recommended. It panics if the ID has already been registered.
+func (m *Migrator) Register(id string, fn MigrationFunc) {
+	if _, ok := m.migrations[id]; ok {
+		panic(fmt.Sprintf("dyno: migration %s registered twice", id))
+	}
+	m.migrations[id] = fn
+}
+
+// Run executes every registered migration that hasn't been applied to the environment and returns the IDs of the
+// migrations it ran. It stops at the first migration that fails. The lock's lease is renewed while the migrations run,
+// and the context passed to them is canceled if the lock is lost.
+func (m *Migrator) Run(ctx context.Context) ([]string, error) {
+	lock := NewLock(m.db, m.tn, m.pk, m.sk, fmt.Sprintf("Dyno_Migrations/%s", m.env))
+
+	ran := []string{}
+	err := lock.Do(ctx, m.Lease, m.Timeout, func(ctx context.Context) error {
+		ids := make([]string, 0, len(m.migrations))
+		for id := range m.migrations {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			applied, err := m.applied(ctx, id)
+			if err != nil {
+				return err
+			}
+			if applied {
+				continue
+			}
+
+			if err := m.migrations[id](ctx, m.db); err != nil {
+				return fmt.Errorf("%w %s: %v", ErrMigrationFailed, id, err)
+			}
+
+			if err := m.record(ctx, id); err != nil {
+				return err
+			}
+
+			ran = append(ran, id)
+		}
+		return nil
+	})
+
+	return ran, err
+}
+
+// Pending returns the IDs of registered migrations that haven't been applied to the environment
+func (m *Migrator) Pending(ctx context.Context) ([]string, error) {
+	pending := []string{}
+	for id := range m.migrations {
+		applied, err := m.applied(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if !applied {
+			pending = append(pending, id)
+		}
+	}
+	sort.Strings(pending)
+
+	return pending, nil
+}
+
+func (m *Migrator) key(id string) map[string]*dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{}
+
+	if m.sk == "" {
+		item[m.pk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_Migrations/%s/%s", m.env, id))}
+	} else {
+		item[m.pk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_Migrations/%s", m.env))}
+		item[m.sk] = &dynamodb.AttributeValue{S: aws.String(id)}
+	}
+
+	return item
+}
+
+func (m *Migrator) applied(ctx context.Context, id string) (bool, error) {
+	result, err := m.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(m.tn),
+		Key:            m.key(id),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return len(result.Item) > 0, nil
+}
+
+func (m *Migrator) record(ctx context.Context, id string) error {
+	item := m.key(id)
+	item["Dyno_AppliedAt"] = TimeValue(time.Now(), TimeUnixSeconds)
+
+	_, err := m.db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(m.tn),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(#pk)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#pk": aws.String(m.pk),
+		},
+	})
+
+	return err
+}
@@ -0,0 +1,77 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	calls := map[string]int{}
+	migration := func(id string) MigrationFunc {
+		return func(context.Context, dynamodbiface.DynamoDBAPI) error {
+			calls[id]++
+			return nil
+		}
+	}
+
+	t.Run("runs pending migrations once in order", func(t *testing.T) {
+		m := NewMigrator(testClient, tableName, "PK", "SK", "test-env")
+		m.Register("002-second", migration("002-second"))
+		m.Register("001-first", migration("001-first"))
+
+		ran, err := m.Run(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"001-first", "002-second"}, ran)
+
+		ran, err = m.Run(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, ran)
+
+		assert.Equal(t, 1, calls["001-first"])
+		assert.Equal(t, 1, calls["002-second"])
+	})
+
+	t.Run("given a failing migration", func(t *testing.T) {
+		m := NewMigrator(testClient, tableName, "PK", "SK", "test-env")
+		m.Register("001-first", migration("001-first"))
+		m.Register("003-broken", func(context.Context, dynamodbiface.DynamoDBAPI) error {
+			return errors.New("boom")
+		})
+
+		_, err := m.Run(ctx)
+		assert.True(t, errors.Is(err, ErrMigrationFailed))
+
+		pending, err := m.Pending(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"003-broken"}, pending)
+	})
+
+	t.Run("given a migration that outlives the lease", func(t *testing.T) {
+		env := "test-env-" + ksuid.New().String()
+		m := NewMigrator(testClient, tableName, "PK", "SK", env)
+		m.Lease = time.Second
+		m.Register("001-slow", func(ctx context.Context, db dynamodbiface.DynamoDBAPI) error {
+			other := NewLock(testClient, tableName, "PK", "SK", "Dyno_Migrations/"+env)
+			assert.Equal(t, ErrLockAcquireTimeout, other.AcquireContext(ctx, time.Second, 2500*time.Millisecond))
+			return nil
+		})
+
+		ran, err := m.Run(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"001-slow"}, ran)
+	})
+
+	t.Run("Register panics on duplicate IDs", func(t *testing.T) {
+		m := NewMigrator(testClient, tableName, "PK", "SK", "test-env")
+		m.Register("001-first", migration("001-first"))
+		assert.Panics(t, func() { m.Register("001-first", migration("001-first")) })
+	})
+}
@@ -0,0 +1,162 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MultiLock acquires several locks one at a time in a canonical order, by table and then name, so that two MultiLocks
+// sharing some of their locks can't deadlock waiting on each other.
+type MultiLock struct {
+	locks []*Lock
+	held  []bool
+	mu    sync.Mutex
+}
+
+// NewMultiLock returns a lock that's held while all of the given locks are. The order they're given in doesn't matter.
+func NewMultiLock(locks ...*Lock) *MultiLock {
+	sorted := make([]*Lock, len(locks))
+	copy(sorted, locks)
+	sort.SliceStable(sorted, func(a, b int) bool {
+		return lockOrderKey(sorted[a]) < lockOrderKey(sorted[b])
+	})
+
+	return &MultiLock{
+		locks: sorted,
+		held:  make([]bool, len(sorted)),
+	}
+}
+
+func (m *MultiLock) Acquire(lease time.Duration) error {
+	return m.AcquireWithTimeout(lease, time.Duration(0))
+}
+
+func (m *MultiLock) AcquireWithTimeout(lease, duration time.Duration) error {
+	return m.AcquireContext(context.Background(), lease, duration)
+}
+
+// AcquireContext acquires each lock in order, waiting up to duration for each one. If any lock can't be acquired the
+// ones already held are released, in reverse order, and the error names the lock that failed.
+func (m *MultiLock) AcquireContext(ctx context.Context, lease, duration time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, lock := range m.locks {
+		if err := lock.AcquireContext(ctx, lease, duration); err != nil {
+			m.release()
+			return fmt.Errorf("acquiring lock %s: %w", lock.name, err)
+		}
+		m.held[i] = true
+	}
+
+	return nil
+}
+
+// Release releases every lock that was acquired, in reverse order. It returns the first error, after trying them all.
+func (m *MultiLock) Release() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.release()
+}
+
+func (m *MultiLock) release() error {
+	var first error
+	released := false
+
+	for i := len(m.locks) - 1; i >= 0; i-- {
+		if !m.held[i] {
+			continue
+		}
+		m.held[i] = false
+		released = true
+
+		if err := m.locks[i].Release(); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	if !released {
+		return ErrLockNotOwned
+	}
+	return first
+}
+
+func lockOrderKey(l *Lock) string {
+	return l.tn + "/" + l.name
+}
+
+// LockOrderChecker reports locks that are acquired while holding each other in inconsistent orders, which can deadlock
+// when the two orders race. It sees every lock using it as held by the whole process, so locks held by unrelated
+// goroutines at the same time count as nested; it's meant for tests and staging rather than production.
+type LockOrderChecker struct {
+	mu       sync.Mutex
+	held     map[string]bool
+	before   map[[2]string]bool
+	reported map[[2]string]bool
+
+	// Logger, if set, logs each inconsistent order
+	Logger Logger
+
+	// OnInconsistentOrder is called once for each pair of locks seen in both orders, with the lock that was held and
+	// the lock acquired while holding it. Locks are named by table and name.
+	OnInconsistentOrder func(held, acquired string)
+}
+
+func NewLockOrderChecker() *LockOrderChecker {
+	return &LockOrderChecker{
+		held:     map[string]bool{},
+		before:   map[[2]string]bool{},
+		reported: map[[2]string]bool{},
+	}
+}
+
+// CheckOrder has the checker track when the lock is acquired and released
+func (l *Lock) CheckOrder(c *LockOrderChecker) {
+	l.order = c
+}
+
+func (c *LockOrderChecker) observe(l *Lock, e LockEvent) {
+	switch e.Type {
+	case LockAcquired, LockStolen:
+		c.acquired(lockOrderKey(l))
+	case LockReleased, LockLost:
+		c.released(lockOrderKey(l))
+	}
+}
+
+func (c *LockOrderChecker) acquired(key string) {
+	c.mu.Lock()
+	inconsistent := []string{}
+	for held := range c.held {
+		c.before[[2]string{held, key}] = true
+
+		pair := [2]string{key, held}
+		if c.before[pair] && !c.reported[pair] {
+			c.reported[pair] = true
+			inconsistent = append(inconsistent, held)
+		}
+	}
+	c.held[key] = true
+	c.mu.Unlock()
+
+	sort.Strings(inconsistent)
+	for _, held := range inconsistent {
+		if c.Logger != nil {
+			c.Logger.Printf("dyno: lock %s acquired while holding %s, which has also been acquired while holding it", key, held)
+		}
+		if c.OnInconsistentOrder != nil {
+			c.OnInconsistentOrder(held, key)
+		}
+	}
+}
+
+func (c *LockOrderChecker) released(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.held, key)
+}
@@ -0,0 +1,94 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiLock(t *testing.T) {
+	ctx := context.Background()
+	names := []string{"testing-multi-c", "testing-multi-a", "testing-multi-b"}
+	multi := func() *MultiLock {
+		locks := []*Lock{}
+		for _, name := range names {
+			locks = append(locks, NewLock(testClient, tableName, "PK", "SK", name))
+		}
+		return NewMultiLock(locks...)
+	}
+	held := func(name string) bool {
+		info, err := DescribeLock(ctx, testClient, tableName, "PK", "SK", name)
+		require.NoError(t, err)
+		return info != nil
+	}
+
+	t.Run("given free locks", func(t *testing.T) {
+		lock := multi()
+		assert.Equal(t, "testing-multi-a", lock.locks[0].name)
+		assert.Equal(t, "testing-multi-c", lock.locks[2].name)
+
+		require.NoError(t, lock.Acquire(30*time.Second))
+		for _, name := range names {
+			assert.True(t, held(name))
+		}
+
+		require.NoError(t, lock.Release())
+		for _, name := range names {
+			assert.False(t, held(name))
+		}
+		assert.Equal(t, ErrLockNotOwned, lock.Release())
+	})
+
+	t.Run("given a lock held by someone else", func(t *testing.T) {
+		other := NewLock(testClient, tableName, "PK", "SK", "testing-multi-b")
+		require.NoError(t, other.Acquire(30*time.Second))
+		defer other.Release()
+
+		lock := multi()
+		err := lock.AcquireWithTimeout(30*time.Second, 50*time.Millisecond)
+
+		assert.True(t, errors.Is(err, ErrLockAcquireTimeout))
+		assert.Contains(t, err.Error(), "testing-multi-b")
+		assert.False(t, held("testing-multi-a"), "the acquired lock was rolled back")
+		assert.False(t, held("testing-multi-c"))
+	})
+}
+
+func TestLockOrderChecker(t *testing.T) {
+	checker := NewLockOrderChecker()
+	reported := [][2]string{}
+	checker.OnInconsistentOrder = func(held, acquired string) {
+		reported = append(reported, [2]string{held, acquired})
+	}
+
+	a := NewLock(testClient, tableName, "PK", "SK", "testing-order-a")
+	a.CheckOrder(checker)
+	b := NewLock(testClient, tableName, "PK", "SK", "testing-order-b")
+	b.CheckOrder(checker)
+
+	t.Run("given a consistent order", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			require.NoError(t, a.Acquire(30*time.Second))
+			require.NoError(t, b.Acquire(30*time.Second))
+			require.NoError(t, b.Release())
+			require.NoError(t, a.Release())
+		}
+
+		assert.Empty(t, reported)
+	})
+
+	t.Run("given an inconsistent order", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			require.NoError(t, b.Acquire(30*time.Second))
+			require.NoError(t, a.Acquire(30*time.Second))
+			require.NoError(t, a.Release())
+			require.NoError(t, b.Release())
+		}
+
+		assert.Equal(t, [][2]string{{tableName + "/testing-order-b", tableName + "/testing-order-a"}}, reported)
+	})
+}
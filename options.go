@@ -0,0 +1,134 @@
+package dyno
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Clock returns the current time. It's satisfied by dynotest.ManualClock.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// LockOption configures a lock created with NewLockWithOptions
+type LockOption func(*Lock)
+
+// NewLockWithOptions returns a lock in a table whose keys are named "PK" and "SK", unless the options say otherwise.
+//
+// The key names can be changed, but the lock item's other attributes always have their Dyno_ names, e.g. Dyno_LockID
+// and Dyno_ExpiresAt. ListLocks, DescribeLock, ForceRelease, the Janitor and the CLI read lock items without a Lock, so
+// they wouldn't find attributes that were renamed. Use WithTTLAttribute for a table whose TTL attribute has another
+// name.
+func NewLockWithOptions(db dynamodbiface.DynamoDBAPI, tableName, name string, options ...LockOption) *Lock {
+	l := &Lock{
+		db:      db,
+		tn:      tableName,
+		pk:      "PK",
+		sk:      "SK",
+		name:    name,
+		minPoll: 25 * time.Millisecond,
+		maxPoll: time.Second,
+		clock:   systemClock{},
+	}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+// WithPrimaryKey sets the name of the table's partition key
+func WithPrimaryKey(name string) LockOption {
+	return func(l *Lock) { l.pk = name }
+}
+
+// WithSortKey sets the name of the table's sort key. An empty name is for a table without one.
+func WithSortKey(name string) LockOption {
+	return func(l *Lock) { l.sk = name }
+}
+
+// WithBackoff is the option for PollInterval
+func WithBackoff(min, max time.Duration) LockOption {
+	return func(l *Lock) { l.PollInterval(min, max) }
+}
+
+// WithClock sets the clock used to time leases, both the expiry the lock writes and whether another holder's has
+// passed. Waiting and polling still use real time.
+func WithClock(c Clock) LockOption {
+	return func(l *Lock) { l.clock = c }
+}
+
+// WithServerTime is the option for ServerTime
+func WithServerTime(c *ServerClock, skew time.Duration) LockOption {
+	return func(l *Lock) { l.ServerTime(c, skew) }
+}
+
+// WithFairness is the option for Fair
+func WithFairness(priority int) LockOption {
+	return func(l *Lock) { l.Fair(priority) }
+}
+
+// WithLogger logs each of the lock's lifecycle events
+func WithLogger(logger Logger) LockOption {
+	return func(l *Lock) { l.logger = logger }
+}
+
+// WithTTLAttribute also writes the lease expiry, in seconds, to the named attribute whenever it's acquired or renewed,
+// for tables whose TTL attribute isn't Dyno_ExpiresAt. Use Expiration for a fixed expiry instead.
+func WithTTLAttribute(name string) LockOption {
+	return func(l *Lock) {
+		l.expiresAtName = name
+		l.expiresAt = time.Time{}
+	}
+}
+
+// WithExpiration is the option for Expiration
+func WithExpiration(name string, at time.Time) LockOption {
+	return func(l *Lock) { l.Expiration(name, at) }
+}
+
+// WithSigningKey is the option for SigningKey
+func WithSigningKey(key []byte) LockOption {
+	return func(l *Lock) { l.SigningKey(key) }
+}
+
+// WithTracer is the option for Tracer
+func WithTracer(t Tracer) LockOption {
+	return func(l *Lock) { l.Tracer(t) }
+}
+
+// WithMetrics is the option for Metrics
+func WithMetrics(m Metrics) LockOption {
+	return func(l *Lock) { l.Metrics(m) }
+}
+
+// WithEventuallyConsistentReads is the option for EventuallyConsistentReads
+func WithEventuallyConsistentReads() LockOption {
+	return func(l *Lock) { l.EventuallyConsistentReads(true) }
+}
+
+// WithWatcher is the option for Watcher
+func WithWatcher(w *LockWatcher) LockOption {
+	return func(l *Lock) { l.Watcher(w) }
+}
+
+// WithEvents is the option for OnEvent
+func WithEvents(fn func(LockEvent)) LockOption {
+	return func(l *Lock) { l.OnEvent(fn) }
+}
+
+// WithHoldBudget is the option for HoldBudget
+func WithHoldBudget(budget time.Duration, cancel bool) LockOption {
+	return func(l *Lock) { l.HoldBudget(budget, cancel) }
+}
+
+// WithOrderChecker is the option for CheckOrder
+func WithOrderChecker(c *LockOrderChecker) LockOption {
+	return func(l *Lock) { l.CheckOrder(c) }
+}
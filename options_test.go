@@ -0,0 +1,74 @@
+package dyno
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixedClock struct{ now time.Time }
+
+func (c fixedClock) Now() time.Time {
+	return c.now
+}
+
+func TestNewLockWithOptions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("given the defaults", func(t *testing.T) {
+		lock := NewLockWithOptions(testClient, tableName, "testing-options")
+
+		assert.Equal(t, "PK", lock.pk)
+		assert.Equal(t, "SK", lock.sk)
+		assert.Equal(t, 25*time.Millisecond, lock.minPoll)
+	})
+
+	t.Run("given NewLock", func(t *testing.T) {
+		lock := NewLock(testClient, tableName, "ID", "", "testing-options")
+
+		assert.Equal(t, "ID", lock.pk)
+		assert.Equal(t, "", lock.sk)
+		assert.NotNil(t, lock.clock)
+	})
+
+	t.Run("given a clock and a TTL attribute", func(t *testing.T) {
+		at := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		out := &bytes.Buffer{}
+		lock := NewLockWithOptions(testClient, tableName, "testing-options",
+			WithClock(fixedClock{at}),
+			WithTTLAttribute("TTL"),
+			WithBackoff(time.Millisecond, 10*time.Millisecond),
+			WithLogger(log.New(out, "", 0)),
+		)
+		require.NoError(t, lock.Acquire(30*time.Second))
+		defer lock.Release()
+
+		ttl := func() string {
+			result, err := testClient.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+				TableName:      aws.String(tableName),
+				Key:            lock.key(),
+				ConsistentRead: aws.Bool(true),
+			})
+			require.NoError(t, err)
+			return aws.StringValue(result.Item["TTL"].N)
+		}
+
+		info, err := DescribeLock(ctx, testClient, tableName, "PK", "SK", "testing-options")
+		require.NoError(t, err)
+		assert.Equal(t, at, info.AcquiredAt.UTC())
+		assert.Equal(t, strconv.FormatInt(at.Add(30*time.Second).Unix(), 10), ttl())
+		assert.Contains(t, out.String(), "dyno: lock testing-options acquired")
+
+		lock.clock = fixedClock{at.Add(time.Minute)}
+		require.NoError(t, lock.Renew(ctx))
+		assert.Equal(t, strconv.FormatInt(at.Add(90*time.Second).Unix(), 10), ttl(), "the TTL follows the lease")
+	})
+}
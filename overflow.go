@@ -0,0 +1,196 @@
+package dyno
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// overflowAttribute holds the S3 location and checksum of an item stored in S3
+const overflowAttribute = "Dyno_Overflow"
+
+var ErrOverflowChecksum = errors.New("overflow object checksum does not match the item")
+
+// OverflowStore writes items that are too large for DynamoDB to S3, leaving a pointer item with the keys, and resolves
+// the pointers when the items are read.
+type OverflowStore struct {
+	db     dynamodbiface.DynamoDBAPI
+	tn     string
+	pk     string
+	sk     string
+	s3     s3iface.S3API
+	bucket string
+
+	// Prefix is prepended to the S3 object keys
+	Prefix string
+
+	// Threshold is the item size in bytes above which the item is stored in S3. Defaults to 350KB.
+	Threshold int
+
+	// ConsistentRead makes Get use strongly consistent reads
+	ConsistentRead bool
+}
+
+func NewOverflowStore(db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey string, s3 s3iface.S3API, bucket string) *OverflowStore {
+	return &OverflowStore{
+		db:        db,
+		tn:        tableName,
+		pk:        primaryKey,
+		sk:        sortKey,
+		s3:        s3,
+		bucket:    bucket,
+		Threshold: 350 * 1024,
+	}
+}
+
+// Put writes the item, storing it in S3 if it is larger than the threshold
+func (o *OverflowStore) Put(ctx context.Context, item map[string]*dynamodb.AttributeValue) error {
+	stored := item
+	if SizeOf(item) > o.Threshold {
+		body, err := json.Marshal(itemJSON(item))
+		if err != nil {
+			return err
+		}
+
+		pointer, err := o.upload(ctx, body)
+		if err != nil {
+			return err
+		}
+
+		stored = o.key(item)
+		stored[overflowAttribute] = pointer
+	}
+
+	result, err := o.db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName:    aws.String(o.tn),
+		Item:         stored,
+		ReturnValues: aws.String(dynamodb.ReturnValueAllOld),
+	})
+	if err != nil {
+		return err
+	}
+
+	// The item replaced an overflowed item, so its object isn't referenced anymore
+	return o.remove(ctx, result.Attributes)
+}
+
+// Get reads the item with the key, resolving it from S3 if it overflowed
+func (o *OverflowStore) Get(ctx context.Context, key map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	result, err := o.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(o.tn),
+		Key:            key,
+		ConsistentRead: aws.Bool(o.ConsistentRead),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Item) == 0 {
+		return nil, nil
+	}
+
+	return o.Resolve(ctx, result.Item)
+}
+
+// Delete deletes the item with the key and its S3 object
+func (o *OverflowStore) Delete(ctx context.Context, key map[string]*dynamodb.AttributeValue) error {
+	result, err := o.db.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName:    aws.String(o.tn),
+		Key:          key,
+		ReturnValues: aws.String(dynamodb.ReturnValueAllOld),
+	})
+	if err != nil {
+		return err
+	}
+
+	return o.remove(ctx, result.Attributes)
+}
+
+// Resolve returns the full item for an item read from the table, e.g. by a Query. Items that didn't overflow are
+// returned unchanged.
+func (o *OverflowStore) Resolve(ctx context.Context, item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	pointer, ok := item[overflowAttribute]
+	if !ok {
+		return item, nil
+	}
+
+	result, err := o.s3.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: pointer.M["Bucket"].S,
+		Key:    pointer.M["Key"].S,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer result.Body.Close()
+
+	body, err := ioutil.ReadAll(result.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(body)
+	if hex.EncodeToString(sum[:]) != aws.StringValue(pointer.M["SHA256"].S) {
+		return nil, ErrOverflowChecksum
+	}
+
+	// The field names of AttributeValue match the DynamoDB JSON type descriptors
+	resolved := map[string]*dynamodb.AttributeValue{}
+	if err := json.Unmarshal(body, &resolved); err != nil {
+		return nil, err
+	}
+
+	return resolved, nil
+}
+
+func (o *OverflowStore) key(item map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	key := map[string]*dynamodb.AttributeValue{o.pk: item[o.pk]}
+	if o.sk != "" {
+		key[o.sk] = item[o.sk]
+	}
+	return key
+}
+
+func (o *OverflowStore) upload(ctx context.Context, body []byte) (*dynamodb.AttributeValue, error) {
+	sum := sha256.Sum256(body)
+	key := fmt.Sprintf("%s%s.json", o.Prefix, NewKSUID())
+
+	_, err := o.s3.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(o.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.AttributeValue{M: map[string]*dynamodb.AttributeValue{
+		"Bucket": {S: aws.String(o.bucket)},
+		"Key":    {S: aws.String(key)},
+		"SHA256": {S: aws.String(hex.EncodeToString(sum[:]))},
+	}}, nil
+}
+
+func (o *OverflowStore) remove(ctx context.Context, item map[string]*dynamodb.AttributeValue) error {
+	pointer, ok := item[overflowAttribute]
+	if !ok {
+		return nil
+	}
+
+	_, err := o.s3.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: pointer.M["Bucket"].S,
+		Key:    pointer.M["Key"].S,
+	})
+
+	return err
+}
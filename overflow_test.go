@@ -0,0 +1,103 @@
+package dyno
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryS3 struct {
+	s3iface.S3API
+	objects map[string][]byte
+}
+
+func (m *memoryS3) PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+	body, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.objects[aws.StringValue(input.Key)] = body
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (m *memoryS3) GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(bytes.NewReader(m.objects[aws.StringValue(input.Key)]))}, nil
+}
+
+func (m *memoryS3) DeleteObjectWithContext(ctx aws.Context, input *s3.DeleteObjectInput, opts ...request.Option) (*s3.DeleteObjectOutput, error) {
+	delete(m.objects, aws.StringValue(input.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func TestOverflowStore(t *testing.T) {
+	ctx := context.Background()
+	objects := &memoryS3{objects: map[string][]byte{}}
+	store := NewOverflowStore(testClient, tableName, "PK", "SK", objects, "bucket")
+	store.Threshold = 1024
+
+	key := map[string]*dynamodb.AttributeValue{
+		"PK": {S: aws.String("overflow")},
+		"SK": {S: aws.String("item")},
+	}
+	item := map[string]*dynamodb.AttributeValue{
+		"PK":     key["PK"],
+		"SK":     key["SK"],
+		"Body":   {S: aws.String(strings.Repeat("dyno ", 1000))},
+		"Counts": {NS: []*string{aws.String("1"), aws.String("2")}},
+		"Data":   {B: []byte{0, 1, 2}},
+	}
+
+	t.Run("given a large item", func(t *testing.T) {
+		require.NoError(t, store.Put(ctx, item))
+		assert.Len(t, objects.objects, 1)
+
+		raw, err := testClient.GetItem(&dynamodb.GetItemInput{TableName: aws.String(tableName), Key: key})
+		require.NoError(t, err)
+		assert.Contains(t, raw.Item, overflowAttribute)
+		assert.NotContains(t, raw.Item, "Body")
+
+		resolved, err := store.Get(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, item, resolved)
+	})
+
+	t.Run("given a modified object", func(t *testing.T) {
+		for k := range objects.objects {
+			objects.objects[k] = []byte("{}")
+		}
+
+		_, err := store.Get(ctx, key)
+		assert.Equal(t, ErrOverflowChecksum, err)
+	})
+
+	t.Run("given a small item", func(t *testing.T) {
+		small := map[string]*dynamodb.AttributeValue{"PK": key["PK"], "SK": key["SK"], "Body": {S: aws.String("small")}}
+
+		require.NoError(t, store.Put(ctx, small))
+		assert.Empty(t, objects.objects, "the replaced item's object is removed")
+
+		resolved, err := store.Get(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, small, resolved)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, store.Put(ctx, item))
+		require.NoError(t, store.Delete(ctx, key))
+
+		assert.Empty(t, objects.objects)
+		resolved, err := store.Get(ctx, key)
+		require.NoError(t, err)
+		assert.Nil(t, resolved)
+	})
+}
@@ -0,0 +1,278 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+var (
+	ErrAccessPatternNotFound = errors.New("access pattern is not registered")
+	ErrMissingKeyParameter   = errors.New("missing a parameter for the key")
+	ErrInvalidKeyFormat      = errors.New("invalid key format")
+)
+
+// AccessPattern is a named query against a table or one of its indexes, for single-table designs where indexes are
+// overloaded with generic key attributes like GSI1PK and GSI1SK. Keys are built from formats with placeholders for the
+// parameters, e.g. "CUSTOMER#{CustomerID}".
+type AccessPattern struct {
+	Name string
+
+	// IndexName is the index queried, or empty for the table
+	IndexName string
+
+	// PartitionKey and SortKey are the names of the key attributes, e.g. "GSI1PK" and "GSI1SK". SortKey is empty if
+	// the table or index doesn't have one.
+	PartitionKey string
+	SortKey      string
+
+	// PartitionFormat and SortFormat are the formats of the keys' values. A query must have every parameter of the
+	// partition key. It can leave out parameters from the end of the sort key, to query the items whose sort key begins
+	// with the part before the first missing one.
+	PartitionFormat string
+	SortFormat      string
+}
+
+// Params are the values for the placeholders of an access pattern's keys. Times are formatted in UTC with a fixed
+// width, so they sort in time order. Everything else is formatted with fmt.Sprint, so pad numbers that need to sort.
+type Params map[string]interface{}
+
+// QueryOptions configures a query of an access pattern
+type QueryOptions struct {
+	// Limit is the most items to return. The query returns one page, with the key to start the next page from, if
+	// it's set, or every page if it isn't.
+	Limit int64
+
+	// Descending returns items in descending sort key order
+	Descending bool
+
+	// StartKey is the key to continue a query from
+	StartKey map[string]*dynamodb.AttributeValue
+
+	// ConsistentRead uses strongly consistent reads. Global secondary indexes don't support them.
+	ConsistentRead bool
+}
+
+// AccessPatterns is a registry of a table's access patterns
+type AccessPatterns struct {
+	db dynamodbiface.DynamoDBAPI
+	tn string
+
+	mu       sync.RWMutex
+	patterns map[string]AccessPattern
+}
+
+func NewAccessPatterns(db dynamodbiface.DynamoDBAPI, tableName string) *AccessPatterns {
+	return &AccessPatterns{
+		db:       db,
+		tn:       tableName,
+		patterns: map[string]AccessPattern{},
+	}
+}
+
+// Register adds the access patterns, replacing any registered with the same names
+func (a *AccessPatterns) Register(patterns ...AccessPattern) error {
+	for _, p := range patterns {
+		if p.Name == "" || p.PartitionKey == "" {
+			return fmt.Errorf("%w: access pattern %q needs a name and partition key", ErrInvalidKeyFormat, p.Name)
+		}
+		if (p.SortKey == "") != (p.SortFormat == "") {
+			return fmt.Errorf("%w: access pattern %q needs both a sort key and its format, or neither", ErrInvalidKeyFormat, p.Name)
+		}
+		for _, format := range []string{p.PartitionFormat, p.SortFormat} {
+			if _, err := parseKeyFormat(format); err != nil {
+				return fmt.Errorf("access pattern %q: %w", p.Name, err)
+			}
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, p := range patterns {
+		a.patterns[p.Name] = p
+	}
+	return nil
+}
+
+func (a *AccessPatterns) pattern(name string) (AccessPattern, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	p, ok := a.patterns[name]
+	if !ok {
+		return AccessPattern{}, fmt.Errorf("%w: %s", ErrAccessPatternNotFound, name)
+	}
+	return p, nil
+}
+
+// Keys returns the key attributes of the named access pattern for the params, to add to an item so the pattern finds
+// it. Every parameter of both keys is required.
+func (a *AccessPatterns) Keys(name string, params Params) (map[string]*dynamodb.AttributeValue, error) {
+	p, err := a.pattern(name)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]*dynamodb.AttributeValue{}
+	pk, complete, err := expandKeyFormat(p.PartitionFormat, params)
+	if err != nil {
+		return nil, err
+	}
+	if !complete {
+		return nil, fmt.Errorf("%w: %s of %s", ErrMissingKeyParameter, p.PartitionKey, name)
+	}
+	keys[p.PartitionKey] = Str(pk)
+
+	if p.SortKey != "" {
+		sk, complete, err := expandKeyFormat(p.SortFormat, params)
+		if err != nil {
+			return nil, err
+		}
+		if !complete {
+			return nil, fmt.Errorf("%w: %s of %s", ErrMissingKeyParameter, p.SortKey, name)
+		}
+		keys[p.SortKey] = Str(sk)
+	}
+
+	return keys, nil
+}
+
+// Query returns the items the named access pattern finds for the params, and the key to continue from if there are
+// more. opts can be nil.
+func (a *AccessPatterns) Query(ctx context.Context, name string, params Params, opts *QueryOptions) ([]map[string]*dynamodb.AttributeValue, map[string]*dynamodb.AttributeValue, error) {
+	p, err := a.pattern(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts == nil {
+		opts = &QueryOptions{}
+	}
+
+	pk, complete, err := expandKeyFormat(p.PartitionFormat, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !complete {
+		return nil, nil, fmt.Errorf("%w: %s of %s", ErrMissingKeyParameter, p.PartitionKey, name)
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(a.tn),
+		KeyConditionExpression:    aws.String("#pk = :pk"),
+		ExpressionAttributeNames:  map[string]*string{"#pk": aws.String(p.PartitionKey)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":pk": Str(pk)},
+		ScanIndexForward:          aws.Bool(!opts.Descending),
+		ExclusiveStartKey:         opts.StartKey,
+	}
+	if p.IndexName != "" {
+		input.IndexName = aws.String(p.IndexName)
+	}
+	if opts.ConsistentRead {
+		input.ConsistentRead = aws.Bool(true)
+	}
+	if opts.Limit > 0 {
+		input.Limit = aws.Int64(opts.Limit)
+	}
+
+	if p.SortKey != "" {
+		sk, complete, err := expandKeyFormat(p.SortFormat, params)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch {
+		case complete:
+			input.KeyConditionExpression = aws.String("#pk = :pk AND #sk = :sk")
+		case sk != "":
+			input.KeyConditionExpression = aws.String("#pk = :pk AND begins_with(#sk, :sk)")
+		}
+		if sk != "" {
+			input.ExpressionAttributeNames["#sk"] = aws.String(p.SortKey)
+			input.ExpressionAttributeValues[":sk"] = Str(sk)
+		}
+	}
+
+	items := []map[string]*dynamodb.AttributeValue{}
+	for {
+		result, err := a.db.QueryWithContext(ctx, input)
+		if err != nil {
+			return nil, nil, err
+		}
+		items = append(items, result.Items...)
+
+		if opts.Limit > 0 || len(result.LastEvaluatedKey) == 0 {
+			return items, result.LastEvaluatedKey, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// keyFormatPart is a literal, or a placeholder for the named parameter
+type keyFormatPart struct {
+	literal string
+	param   string
+}
+
+func parseKeyFormat(format string) ([]keyFormatPart, error) {
+	parts := []keyFormatPart{}
+	for format != "" {
+		open := strings.IndexByte(format, '{')
+		if close := strings.IndexByte(format, '}'); close >= 0 && (open < 0 || close < open) {
+			return nil, fmt.Errorf("%w: unexpected } in %q", ErrInvalidKeyFormat, format)
+		}
+		if open < 0 {
+			parts = append(parts, keyFormatPart{literal: format})
+			break
+		}
+		if open > 0 {
+			parts = append(parts, keyFormatPart{literal: format[:open]})
+		}
+
+		close := strings.IndexByte(format[open:], '}')
+		if close < 0 {
+			return nil, fmt.Errorf("%w: unclosed { in %q", ErrInvalidKeyFormat, format)
+		}
+		param := format[open+1 : open+close]
+		if param == "" || strings.ContainsRune(param, '{') {
+			return nil, fmt.Errorf("%w: bad placeholder in %q", ErrInvalidKeyFormat, format)
+		}
+		parts = append(parts, keyFormatPart{param: param})
+		format = format[open+close+1:]
+	}
+	return parts, nil
+}
+
+// expandKeyFormat fills in the format's placeholders with the params, up to the first missing one. It returns true if
+// none were missing.
+func expandKeyFormat(format string, params Params) (string, bool, error) {
+	parts, err := parseKeyFormat(format)
+	if err != nil {
+		return "", false, err
+	}
+
+	var b strings.Builder
+	for _, part := range parts {
+		if part.param == "" {
+			b.WriteString(part.literal)
+			continue
+		}
+
+		v, ok := params[part.param]
+		if !ok {
+			return b.String(), false, nil
+		}
+		if t, ok := v.(time.Time); ok {
+			b.WriteString(t.UTC().Format(timeSortKeyFormat))
+		} else {
+			b.WriteString(fmt.Sprint(v))
+		}
+	}
+	return b.String(), true, nil
+}
@@ -0,0 +1,108 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessPatterns(t *testing.T) {
+	ctx := context.Background()
+	name := fmt.Sprintf("dyno-test-patterns-%s", ksuid.New().String())
+
+	_, err := testClient.CreateTable(&dynamodb.CreateTableInput{
+		TableName:   aws.String(name),
+		BillingMode: aws.String("PAY_PER_REQUEST"),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("PK"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("SK"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("GSI1PK"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("GSI1SK"), AttributeType: aws.String("S")},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("PK"), KeyType: aws.String("HASH")},
+			{AttributeName: aws.String("SK"), KeyType: aws.String("RANGE")},
+		},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{{
+			IndexName: aws.String("GSI1"),
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{AttributeName: aws.String("GSI1PK"), KeyType: aws.String("HASH")},
+				{AttributeName: aws.String("GSI1SK"), KeyType: aws.String("RANGE")},
+			},
+			Projection: &dynamodb.Projection{ProjectionType: aws.String("ALL")},
+		}},
+	})
+	require.NoError(t, err)
+	defer testClient.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String(name)})
+
+	patterns := NewAccessPatterns(testClient, name)
+	require.NoError(t, patterns.Register(
+		AccessPattern{Name: "Order", PartitionKey: "PK", SortKey: "SK", PartitionFormat: "ORDER#{OrderID}", SortFormat: "ORDER"},
+		AccessPattern{Name: "OrdersByCustomer", IndexName: "GSI1", PartitionKey: "GSI1PK", SortKey: "GSI1SK", PartitionFormat: "CUSTOMER#{CustomerID}", SortFormat: "ORDER#{Date}#{OrderID}"},
+	))
+
+	at := time.Date(2020, 11, 15, 8, 30, 0, 0, time.UTC)
+	for i, day := range []int{0, 1, 1} {
+		params := Params{"OrderID": i, "CustomerID": "c1", "Date": at.AddDate(0, 0, day)}
+		item := map[string]*dynamodb.AttributeValue{"Total": Int(int64(i * 10))}
+		for _, pattern := range []string{"Order", "OrdersByCustomer"} {
+			keys, err := patterns.Keys(pattern, params)
+			require.NoError(t, err)
+			for k, v := range keys {
+				item[k] = v
+			}
+		}
+		_, err := testClient.PutItem(&dynamodb.PutItemInput{TableName: aws.String(name), Item: item})
+		require.NoError(t, err)
+	}
+
+	t.Run("given every parameter", func(t *testing.T) {
+		items, _, err := patterns.Query(ctx, "Order", Params{"OrderID": 1}, nil)
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, int64(10), GetInt(items[0], "Total", 0))
+	})
+
+	t.Run("given parameters from the start of the sort key", func(t *testing.T) {
+		items, _, err := patterns.Query(ctx, "OrdersByCustomer", Params{"CustomerID": "c1"}, &QueryOptions{Descending: true})
+		require.NoError(t, err)
+		require.Len(t, items, 3)
+		assert.Equal(t, int64(20), GetInt(items[0], "Total", 0))
+
+		items, _, err = patterns.Query(ctx, "OrdersByCustomer", Params{"CustomerID": "c1", "Date": at.AddDate(0, 0, 1)}, nil)
+		require.NoError(t, err)
+		assert.Len(t, items, 2)
+	})
+
+	t.Run("given a limit", func(t *testing.T) {
+		items, next, err := patterns.Query(ctx, "OrdersByCustomer", Params{"CustomerID": "c1"}, &QueryOptions{Limit: 2})
+		require.NoError(t, err)
+		assert.Len(t, items, 2)
+
+		items, _, err = patterns.Query(ctx, "OrdersByCustomer", Params{"CustomerID": "c1"}, &QueryOptions{Limit: 2, StartKey: next})
+		require.NoError(t, err)
+		assert.Len(t, items, 1)
+	})
+
+	t.Run("given missing parameters or patterns", func(t *testing.T) {
+		_, _, err := patterns.Query(ctx, "OrdersByCustomer", Params{"Date": at}, nil)
+		assert.True(t, errors.Is(err, ErrMissingKeyParameter))
+
+		_, err = patterns.Keys("OrdersByCustomer", Params{"CustomerID": "c1"})
+		assert.True(t, errors.Is(err, ErrMissingKeyParameter))
+
+		_, _, err = patterns.Query(ctx, "Missing", Params{}, nil)
+		assert.True(t, errors.Is(err, ErrAccessPatternNotFound))
+
+		err = patterns.Register(AccessPattern{Name: "Bad", PartitionKey: "PK", PartitionFormat: "ORDER#{OrderID"})
+		assert.True(t, errors.Is(err, ErrInvalidKeyFormat))
+	})
+}
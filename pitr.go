@@ -0,0 +1,74 @@
+package dyno
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// DescribePointInTimeRecovery returns the point in time recovery status and restorable window of the table
+func DescribePointInTimeRecovery(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName string) (*dynamodb.PointInTimeRecoveryDescription, error) {
+	result, err := db.DescribeContinuousBackupsWithContext(ctx, &dynamodb.DescribeContinuousBackupsInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.ContinuousBackupsDescription.PointInTimeRecoveryDescription, nil
+}
+
+// PointInTimeRecoveryEnabled returns true if point in time recovery is enabled on the table
+func PointInTimeRecoveryEnabled(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName string) (bool, error) {
+	desc, err := DescribePointInTimeRecovery(ctx, db, tableName)
+	if err != nil {
+		return false, err
+	}
+
+	return desc != nil && aws.StringValue(desc.PointInTimeRecoveryStatus) == dynamodb.PointInTimeRecoveryStatusEnabled, nil
+}
+
+// EnsurePointInTimeRecovery enables point in time recovery on the table if it isn't already enabled
+func EnsurePointInTimeRecovery(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName string) error {
+	enabled, err := PointInTimeRecoveryEnabled(ctx, db, tableName)
+	if err != nil || enabled {
+		return err
+	}
+
+	_, err = db.UpdateContinuousBackupsWithContext(ctx, &dynamodb.UpdateContinuousBackupsInput{
+		TableName: aws.String(tableName),
+		PointInTimeRecoverySpecification: &dynamodb.PointInTimeRecoverySpecification{
+			PointInTimeRecoveryEnabled: aws.Bool(true),
+		},
+	})
+
+	return err
+}
+
+// RestoreToPointInTime restores the source table as it was at the given time into a new table and blocks until the
+// new table is ACTIVE. A zero time restores the latest restorable time.
+func RestoreToPointInTime(ctx context.Context, db dynamodbiface.DynamoDBAPI, srcTable, dstTable string, at time.Time) (*dynamodb.TableDescription, error) {
+	input := &dynamodb.RestoreTableToPointInTimeInput{
+		SourceTableName: aws.String(srcTable),
+		TargetTableName: aws.String(dstTable),
+	}
+	if at.IsZero() {
+		input.UseLatestRestorableTime = aws.Bool(true)
+	} else {
+		input.RestoreDateTime = aws.Time(at)
+	}
+
+	result, err := db.RestoreTableToPointInTimeWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := WaitUntilTableActive(ctx, db, dstTable, nil); err != nil {
+		return nil, err
+	}
+
+	return result.TableDescription, nil
+}
@@ -0,0 +1,110 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryContinuousBackups tracks point in time recovery for the tables of the client it wraps, and restores tables by
+// creating them empty
+type memoryContinuousBackups struct {
+	dynamodbiface.DynamoDBAPI
+
+	enabled  map[string]bool
+	updates  int
+	restores []*dynamodb.RestoreTableToPointInTimeInput
+}
+
+func (m *memoryContinuousBackups) DescribeContinuousBackupsWithContext(ctx aws.Context, input *dynamodb.DescribeContinuousBackupsInput, opts ...request.Option) (*dynamodb.DescribeContinuousBackupsOutput, error) {
+	status := dynamodb.PointInTimeRecoveryStatusDisabled
+	if m.enabled[aws.StringValue(input.TableName)] {
+		status = dynamodb.PointInTimeRecoveryStatusEnabled
+	}
+	return &dynamodb.DescribeContinuousBackupsOutput{
+		ContinuousBackupsDescription: &dynamodb.ContinuousBackupsDescription{
+			ContinuousBackupsStatus: aws.String(dynamodb.ContinuousBackupsStatusEnabled),
+			PointInTimeRecoveryDescription: &dynamodb.PointInTimeRecoveryDescription{
+				PointInTimeRecoveryStatus: aws.String(status),
+			},
+		},
+	}, nil
+}
+
+func (m *memoryContinuousBackups) UpdateContinuousBackupsWithContext(ctx aws.Context, input *dynamodb.UpdateContinuousBackupsInput, opts ...request.Option) (*dynamodb.UpdateContinuousBackupsOutput, error) {
+	if m.enabled == nil {
+		m.enabled = map[string]bool{}
+	}
+	m.enabled[aws.StringValue(input.TableName)] = aws.BoolValue(input.PointInTimeRecoverySpecification.PointInTimeRecoveryEnabled)
+	m.updates++
+	return &dynamodb.UpdateContinuousBackupsOutput{}, nil
+}
+
+func (m *memoryContinuousBackups) RestoreTableToPointInTimeWithContext(ctx aws.Context, input *dynamodb.RestoreTableToPointInTimeInput, opts ...request.Option) (*dynamodb.RestoreTableToPointInTimeOutput, error) {
+	m.restores = append(m.restores, input)
+	err := EnsureLockTable(ctx, m.DynamoDBAPI, aws.StringValue(input.TargetTableName), "PK", "SK", nil)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.RestoreTableToPointInTimeOutput{
+		TableDescription: &dynamodb.TableDescription{TableName: input.TargetTableName},
+	}, nil
+}
+
+func TestPointInTimeRecovery(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("given a table without it", func(t *testing.T) {
+		db := &memoryContinuousBackups{DynamoDBAPI: testClient}
+
+		enabled, err := PointInTimeRecoveryEnabled(ctx, db, tableName)
+		require.NoError(t, err)
+		assert.False(t, enabled)
+
+		require.NoError(t, EnsurePointInTimeRecovery(ctx, db, tableName))
+		require.NoError(t, EnsurePointInTimeRecovery(ctx, db, tableName))
+		assert.Equal(t, 1, db.updates, "it's only enabled once")
+
+		enabled, err = PointInTimeRecoveryEnabled(ctx, db, tableName)
+		require.NoError(t, err)
+		assert.True(t, enabled)
+	})
+
+	t.Run("given a lock table bootstrapped with it", func(t *testing.T) {
+		db := &memoryContinuousBackups{DynamoDBAPI: testClient}
+		name := fmt.Sprintf("dyno-pitr-%s", ksuid.New().String())
+		defer testClient.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String(name)})
+
+		require.NoError(t, EnsureLockTable(ctx, db, name, "PK", "SK", &TableOptions{PointInTimeRecovery: true}))
+		assert.True(t, db.enabled[name])
+	})
+
+	t.Run("given a restore", func(t *testing.T) {
+		db := &memoryContinuousBackups{DynamoDBAPI: testClient}
+		latest := fmt.Sprintf("dyno-restored-%s", ksuid.New().String())
+		defer testClient.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String(latest)})
+		earlier := fmt.Sprintf("dyno-restored-%s", ksuid.New().String())
+		defer testClient.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String(earlier)})
+
+		desc, err := RestoreToPointInTime(ctx, db, tableName, latest, time.Time{})
+		require.NoError(t, err)
+		assert.Equal(t, latest, aws.StringValue(desc.TableName))
+		assert.True(t, aws.BoolValue(db.restores[0].UseLatestRestorableTime))
+		assert.Nil(t, db.restores[0].RestoreDateTime)
+
+		at := time.Now().Add(-time.Hour)
+		_, err = RestoreToPointInTime(ctx, db, tableName, earlier, at)
+		require.NoError(t, err)
+		assert.Nil(t, db.restores[1].UseLatestRestorableTime)
+		assert.Equal(t, at, aws.TimeValue(db.restores[1].RestoreDateTime))
+	})
+}
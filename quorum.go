@@ -0,0 +1,124 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrLockQuorumNotReached = errors.New("failed to acquire a majority of the locks")
+
+// QuorumLock acquires the same lock in several independent tables, usually in different regions, and is only held
+// while a majority of them are. A failure or partition that affects a minority of the tables doesn't release it, and
+// no two QuorumLocks can hold a majority at once.
+type QuorumLock struct {
+	locks []*Lock
+	held  []bool
+	mu    sync.Mutex
+}
+
+// NewQuorumLock returns a lock held by a majority of the given locks. Each lock should have the same name and be in a
+// different table.
+func NewQuorumLock(locks ...*Lock) *QuorumLock {
+	return &QuorumLock{
+		locks: locks,
+		held:  make([]bool, len(locks)),
+	}
+}
+
+func (q *QuorumLock) Acquire(lease time.Duration) error {
+	return q.AcquireWithTimeout(lease, time.Duration(0))
+}
+
+func (q *QuorumLock) AcquireWithTimeout(lease, duration time.Duration) error {
+	return q.AcquireContext(context.Background(), lease, duration)
+}
+
+// AcquireContext acquires every lock concurrently and stops waiting once a majority is held, or can no longer be. If
+// no majority is held, or acquiring took longer than the lease, the locks that were acquired are released and the
+// error wraps ErrLockQuorumNotReached.
+func (q *QuorumLock) AcquireContext(ctx context.Context, lease, duration time.Duration) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	start := time.Now()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		index int
+		err   error
+	}
+	results := make(chan result, len(q.locks))
+	for i, lock := range q.locks {
+		go func(i int, lock *Lock) {
+			results <- result{index: i, err: lock.AcquireContext(ctx, lease, duration)}
+		}(i, lock)
+	}
+
+	quorum := len(q.locks)/2 + 1
+	acquired, failed := 0, 0
+	var lastErr error
+
+	// Wait for every attempt, even after deciding, so that a lock acquired as the others are canceled is released
+	for range q.locks {
+		r := <-results
+		if r.err == nil {
+			q.held[r.index] = true
+			acquired++
+		} else if ctx.Err() == nil {
+			failed++
+			lastErr = r.err
+		}
+
+		if acquired >= quorum || failed > len(q.locks)-quorum {
+			cancel()
+		}
+	}
+
+	if acquired >= quorum && time.Since(start) < lease {
+		return nil
+	}
+
+	q.release()
+
+	if acquired >= quorum {
+		return fmt.Errorf("%w: the lease expired while acquiring", ErrLockQuorumNotReached)
+	}
+	if lastErr == nil {
+		lastErr = ctx.Err()
+	}
+	return fmt.Errorf("%w: acquired %d of %d: %v", ErrLockQuorumNotReached, acquired, len(q.locks), lastErr)
+}
+
+// Release releases every lock that was acquired. It returns the first error, after trying them all.
+func (q *QuorumLock) Release() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.release()
+}
+
+func (q *QuorumLock) release() error {
+	var first error
+	released := false
+
+	for i, lock := range q.locks {
+		if !q.held[i] {
+			continue
+		}
+		q.held[i] = false
+		released = true
+
+		if err := lock.Release(); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	if !released {
+		return ErrLockNotOwned
+	}
+	return first
+}
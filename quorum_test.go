@@ -0,0 +1,66 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuorumLock(t *testing.T) {
+	ctx := context.Background()
+	tables := []string{tableName}
+	for i := 0; i < 2; i++ {
+		table, drop := createTestTable(t, "dyno-test-quorum")
+		defer drop()
+		tables = append(tables, table)
+	}
+
+	quorum := func() *QuorumLock {
+		locks := []*Lock{}
+		for _, table := range tables {
+			locks = append(locks, NewLock(testClient, table, "PK", "SK", "testing-quorum-lock"))
+		}
+		return NewQuorumLock(locks...)
+	}
+	held := func(table string) bool {
+		info, err := DescribeLock(ctx, testClient, table, "PK", "SK", "testing-quorum-lock")
+		require.NoError(t, err)
+		return info != nil
+	}
+
+	t.Run("given a minority held by someone else", func(t *testing.T) {
+		other := NewLock(testClient, tables[2], "PK", "SK", "testing-quorum-lock")
+		require.NoError(t, other.Acquire(30*time.Second))
+		defer other.Release()
+
+		lock := quorum()
+		require.NoError(t, lock.AcquireWithTimeout(30*time.Second, 50*time.Millisecond))
+		assert.True(t, held(tables[0]))
+		assert.True(t, held(tables[1]))
+
+		require.NoError(t, lock.Release())
+		assert.False(t, held(tables[0]))
+		assert.False(t, held(tables[1]))
+		assert.Equal(t, ErrLockNotOwned, lock.Release())
+	})
+
+	t.Run("given a majority held by someone else", func(t *testing.T) {
+		other := NewQuorumLock(
+			NewLock(testClient, tables[1], "PK", "SK", "testing-quorum-lock"),
+			NewLock(testClient, tables[2], "PK", "SK", "testing-quorum-lock"),
+		)
+		require.NoError(t, other.Acquire(30*time.Second))
+		defer other.Release()
+
+		lock := quorum()
+		err := lock.AcquireWithTimeout(30*time.Second, 50*time.Millisecond)
+
+		assert.True(t, errors.Is(err, ErrLockQuorumNotReached))
+		assert.False(t, held(tables[0]), "the partial acquire is released")
+		assert.Equal(t, ErrLockNotOwned, lock.Release())
+	})
+}
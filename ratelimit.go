@@ -0,0 +1,121 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// RateLimit is the result of taking from a RateLimiter
+type RateLimit struct {
+	Allowed bool
+
+	// Limit is how many are allowed in a window, and Remaining is how many are left in the current one
+	Limit     int64
+	Remaining int64
+
+	// ResetAt is when the current window ends
+	ResetAt time.Time
+
+	// RetryAfter is how long to wait before trying again, if it wasn't allowed
+	RetryAfter time.Duration
+}
+
+// RateLimiter limits how often something happens per key, like requests per API key, across every process sharing the
+// table. Each key's count in a fixed window is an item, incremented with a condition that it's under the limit, so
+// taking from the limiter is a single write. Windows expire with the table's TTL, if it's enabled on Dyno_ExpiresAt.
+//
+// Because windows are fixed, up to twice the limit can happen around the end of one window and the start of the next.
+type RateLimiter struct {
+	db   dynamodbiface.DynamoDBAPI
+	tn   string
+	pk   string
+	sk   string
+	name string
+
+	// Limit is how many are allowed per key in each window
+	Limit int64
+
+	// Window is how long each window lasts
+	Window time.Duration
+
+	// Metrics receives a RateLimitRejected count each time taking from the limiter isn't allowed
+	Metrics Metrics
+}
+
+// NewRateLimiter returns the named limiter, which allows limit per key in each window
+func NewRateLimiter(db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey, name string, limit int64, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		db:     db,
+		tn:     tableName,
+		pk:     primaryKey,
+		sk:     sortKey,
+		name:   name,
+		Limit:  limit,
+		Window: window,
+	}
+}
+
+func (l *RateLimiter) key(key string, window time.Time) map[string]*dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{}
+
+	if l.sk == "" {
+		item[l.pk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_RateLimit/%s/%s/%d", l.name, key, unixMilli(window)))}
+	} else {
+		item[l.pk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_RateLimit/%s/%s", l.name, key))}
+		item[l.sk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_RateLimitWindow/%d", unixMilli(window)))}
+	}
+
+	return item
+}
+
+// Allow takes one from the key's limit
+func (l *RateLimiter) Allow(ctx context.Context, key string) (RateLimit, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+// AllowN takes n from the key's limit, if that many are left in the current window. Nothing is taken if it isn't
+// allowed, and more than the limit is never allowed.
+func (l *RateLimiter) AllowN(ctx context.Context, key string, n int64) (RateLimit, error) {
+	now := time.Now()
+	window := now.Truncate(l.Window)
+	limit := RateLimit{Limit: l.Limit, ResetAt: window.Add(l.Window)}
+	if n > l.Limit {
+		return l.rejected(limit, now), nil
+	}
+
+	result, err := l.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                aws.String(l.tn),
+		Key:                      l.key(key, window),
+		UpdateExpression:         aws.String("ADD #c :n SET #x = :x"),
+		ConditionExpression:      aws.String("attribute_not_exists(#c) OR #c <= :max"),
+		ExpressionAttributeNames: map[string]*string{"#c": aws.String("Dyno_Count"), "#x": aws.String("Dyno_ExpiresAt")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":n":   Int(n),
+			":max": Int(l.Limit - n),
+			":x":   TimeValue(limit.ResetAt.Add(time.Minute), TimeUnixSeconds),
+		},
+		ReturnValues: aws.String(dynamodb.ReturnValueUpdatedNew),
+	})
+	if Classify(err) == ErrorClassConditionalCheckFailed {
+		return l.rejected(limit, now), nil
+	}
+	if err != nil {
+		return RateLimit{}, err
+	}
+
+	limit.Allowed = true
+	limit.Remaining = l.Limit - GetInt(result.Attributes, "Dyno_Count", 0)
+	return limit, nil
+}
+
+// rejected counts a rejection and returns the limit with when to retry
+func (l *RateLimiter) rejected(limit RateLimit, now time.Time) RateLimit {
+	countMetric(l.Metrics, "RateLimitRejected", 1, map[string]string{"Limiter": l.name})
+	limit.RetryAfter = limit.ResetAt.Sub(now)
+	return limit
+}
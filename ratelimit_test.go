@@ -0,0 +1,46 @@
+package dyno
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewRateLimiter(testClient, tableName, "PK", "SK", "testing-limiter", 3, time.Hour)
+	metrics := &countingMetrics{}
+	limiter.Metrics = metrics
+
+	t.Run("given requests under the limit", func(t *testing.T) {
+		for remaining := int64(2); remaining >= 0; remaining-- {
+			limit, err := limiter.Allow(ctx, "tenant-a")
+			require.NoError(t, err)
+			assert.True(t, limit.Allowed)
+			assert.Equal(t, remaining, limit.Remaining)
+		}
+	})
+
+	t.Run("given a request over the limit", func(t *testing.T) {
+		limit, err := limiter.Allow(ctx, "tenant-a")
+		require.NoError(t, err)
+		assert.False(t, limit.Allowed)
+		assert.True(t, limit.RetryAfter > 0 && limit.RetryAfter <= time.Hour)
+		assert.Equal(t, limit.ResetAt, time.Now().Truncate(time.Hour).Add(time.Hour))
+		assert.Equal(t, 1.0, metrics.counts["RateLimitRejected"])
+	})
+
+	t.Run("given another key", func(t *testing.T) {
+		limit, err := limiter.AllowN(ctx, "tenant-b", 3)
+		require.NoError(t, err)
+		assert.True(t, limit.Allowed)
+
+		limit, err = limiter.AllowN(ctx, "tenant-c", 4)
+		require.NoError(t, err)
+		assert.False(t, limit.Allowed)
+		assert.Equal(t, 2.0, metrics.counts["RateLimitRejected"])
+	})
+}
@@ -0,0 +1,57 @@
+package dyno
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsiface"
+)
+
+// ExpiredItemFunc is called with an item that was deleted by TTL. The item is the old image from the stream, or only
+// its keys if the stream doesn't include old images.
+type ExpiredItemFunc func(ctx context.Context, item map[string]*dynamodb.AttributeValue) error
+
+// Reaper watches a table's stream for items deleted by TTL
+type Reaper struct {
+	Poller *StreamPoller
+	fn     ExpiredItemFunc
+}
+
+func NewReaper(streams dynamodbstreamsiface.DynamoDBStreamsAPI, streamArn string, fn ExpiredItemFunc) *Reaper {
+	return &Reaper{
+		Poller: NewStreamPoller(streams, streamArn),
+		fn:     fn,
+	}
+}
+
+// Run calls the handler for every TTL deletion until the context is done or the handler returns an error
+func (r *Reaper) Run(ctx context.Context) error {
+	return r.Poller.Run(ctx, func(ctx context.Context, shardID string, records []*dynamodbstreams.Record) error {
+		for _, record := range records {
+			if !isTTLDeletion(record) {
+				continue
+			}
+
+			item := record.Dynamodb.OldImage
+			if item == nil {
+				item = record.Dynamodb.Keys
+			}
+
+			if err := r.fn(ctx, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// isTTLDeletion returns true if the record is a delete made by the DynamoDB TTL process rather than a client
+func isTTLDeletion(record *dynamodbstreams.Record) bool {
+	if aws.StringValue(record.EventName) != dynamodbstreams.OperationTypeRemove || record.UserIdentity == nil {
+		return false
+	}
+
+	return aws.StringValue(record.UserIdentity.Type) == "Service" && aws.StringValue(record.UserIdentity.PrincipalId) == "dynamodb.amazonaws.com"
+}
@@ -0,0 +1,38 @@
+package dyno
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTTLDeletion(t *testing.T) {
+	t.Run("given a TTL delete", func(t *testing.T) {
+		record := &dynamodbstreams.Record{
+			EventName: aws.String("REMOVE"),
+			UserIdentity: &dynamodbstreams.Identity{
+				Type:        aws.String("Service"),
+				PrincipalId: aws.String("dynamodb.amazonaws.com"),
+			},
+		}
+		assert.True(t, isTTLDeletion(record))
+	})
+
+	t.Run("given a client delete", func(t *testing.T) {
+		record := &dynamodbstreams.Record{EventName: aws.String("REMOVE")}
+		assert.False(t, isTTLDeletion(record))
+	})
+
+	t.Run("given a modify", func(t *testing.T) {
+		record := &dynamodbstreams.Record{
+			EventName: aws.String("MODIFY"),
+			UserIdentity: &dynamodbstreams.Identity{
+				Type:        aws.String("Service"),
+				PrincipalId: aws.String("dynamodb.amazonaws.com"),
+			},
+		}
+		assert.False(t, isTTLDeletion(record))
+	})
+}
@@ -0,0 +1,241 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Endpoint is an instance of a service
+type Endpoint struct {
+	// ID identifies the instance among the service's endpoints, e.g. its host name
+	ID string
+
+	// Address is how clients reach the instance, e.g. "10.0.1.12:8080"
+	Address string
+
+	// Metadata is anything else clients need to know about the instance, e.g. its version or zone
+	Metadata map[string]string
+
+	// ExpiresAt is when the endpoint's lease runs out, unless it's registered again. It's set by Resolve.
+	ExpiresAt time.Time
+}
+
+// Registry is a service discovery registry. Services register their endpoints with a lease, and keep registering them
+// while they're healthy. Clients resolve a service's name to the endpoints whose lease hasn't run out.
+//
+// Each service's endpoints are kept in a map attribute on a single item, so a service is limited to the endpoints that
+// fit in 400KB.
+type Registry struct {
+	db dynamodbiface.DynamoDBAPI
+	tn string
+	pk string
+	sk string
+
+	mu    sync.Mutex
+	cache map[string]resolved
+
+	// Lease is how long an endpoint stays registered without being registered again. Defaults to 30 seconds.
+	Lease time.Duration
+
+	// CacheTTL is how long resolved endpoints are cached before the service is read again. Defaults to 5 seconds.
+	CacheTTL time.Duration
+}
+
+// resolved is a service's endpoints, cached
+type resolved struct {
+	endpoints []Endpoint
+	at        time.Time
+}
+
+func NewRegistry(db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey string) *Registry {
+	return &Registry{
+		db:       db,
+		tn:       tableName,
+		pk:       primaryKey,
+		sk:       sortKey,
+		cache:    map[string]resolved{},
+		Lease:    30 * time.Second,
+		CacheTTL: 5 * time.Second,
+	}
+}
+
+func (r *Registry) key(service string) map[string]*dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{}
+	item[r.pk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_Service/%s", service))}
+
+	if r.sk != "" {
+		item[r.sk] = &dynamodb.AttributeValue{S: aws.String("Dyno_ServiceSortKeyValue")}
+	}
+
+	return item
+}
+
+// Register adds the endpoint to the service, or renews its lease if it's already registered. Endpoints whose lease ran
+// out without being deregistered, e.g. because their instance crashed, are removed from the service.
+func (r *Registry) Register(ctx context.Context, service string, endpoint Endpoint) error {
+	key := r.key(service)
+	result, err := r.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:                aws.String(r.tn),
+		Key:                      key,
+		ProjectionExpression:     aws.String("#es"),
+		ExpressionAttributeNames: map[string]*string{"#es": aws.String("Dyno_Endpoints")},
+	})
+	if err != nil {
+		return err
+	}
+
+	metadata := map[string]*dynamodb.AttributeValue{}
+	for k, v := range endpoint.Metadata {
+		metadata[k] = Str(v)
+	}
+
+	value := Map(map[string]*dynamodb.AttributeValue{
+		"Address":     Str(endpoint.Address),
+		"Metadata":    Map(metadata),
+		"ExpiresAtMs": TimeValue(time.Now().Add(r.Lease), TimeUnixMillis),
+	})
+
+	if err := setMapEntry(ctx, r.db, r.tn, key, "Dyno_Endpoints", endpoint.ID, value); err != nil {
+		return err
+	}
+
+	expired := expiredMapEntries(result.Item, "Dyno_Endpoints", "ExpiresAtMs", endpoint.ID, time.Now())
+	return removeExpiredMapEntries(ctx, r.db, r.tn, key, "Dyno_Endpoints", "ExpiresAtMs", expired)
+}
+
+// Deregister removes the endpoint from the service, e.g. when the instance shuts down
+func (r *Registry) Deregister(ctx context.Context, service, id string) error {
+	_, err := r.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                aws.String(r.tn),
+		Key:                      r.key(service),
+		UpdateExpression:         aws.String("REMOVE #es.#e"),
+		ConditionExpression:      aws.String("attribute_exists(#es)"),
+		ExpressionAttributeNames: map[string]*string{"#es": aws.String("Dyno_Endpoints"), "#e": aws.String(id)},
+	})
+	if Classify(err) == ErrorClassConditionalCheckFailed { // There's nothing to remove
+		return nil
+	}
+	return err
+}
+
+// KeepRegistered registers the endpoint every third of the lease until the context is done, then deregisters it
+func (r *Registry) KeepRegistered(ctx context.Context, service string, endpoint Endpoint) error {
+	defer r.Deregister(context.Background(), service, endpoint.ID)
+
+	for {
+		if err := r.Register(ctx, service, endpoint); err != nil && ctx.Err() == nil && !IsRetryable(err) {
+			return err
+		}
+
+		if err := sleepContext(ctx, r.Lease/3); err != nil {
+			return err
+		}
+	}
+}
+
+// Resolve returns the service's endpoints whose lease hasn't run out, sorted by ID. They're cached for the CacheTTL.
+func (r *Registry) Resolve(ctx context.Context, service string) ([]Endpoint, error) {
+	r.mu.Lock()
+	cached, ok := r.cache[service]
+	r.mu.Unlock()
+
+	if !ok || time.Since(cached.at) >= r.CacheTTL {
+		endpoints, err := r.read(ctx, service)
+		if err != nil {
+			return nil, err
+		}
+		cached = resolved{endpoints: endpoints, at: time.Now()}
+
+		r.mu.Lock()
+		r.cache[service] = cached
+		r.mu.Unlock()
+	}
+
+	// Cached endpoints can run out of lease before the cache does
+	now := time.Now()
+	endpoints := []Endpoint{}
+	for _, e := range cached.endpoints {
+		if e.ExpiresAt.After(now) {
+			endpoints = append(endpoints, e)
+		}
+	}
+
+	return endpoints, nil
+}
+
+func (r *Registry) read(ctx context.Context, service string) ([]Endpoint, error) {
+	result, err := r.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:                aws.String(r.tn),
+		Key:                      r.key(service),
+		ProjectionExpression:     aws.String("#es"),
+		ExpressionAttributeNames: map[string]*string{"#es": aws.String("Dyno_Endpoints")},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := []Endpoint{}
+	for id, av := range GetMap(result.Item, "Dyno_Endpoints") {
+		expiresAt, err := ParseTime(av.M["ExpiresAtMs"], TimeUnixMillis)
+		if err != nil {
+			continue
+		}
+
+		endpoint := Endpoint{
+			ID:        id,
+			Address:   GetString(av.M, "Address", ""),
+			Metadata:  map[string]string{},
+			ExpiresAt: expiresAt,
+		}
+		for k, v := range GetMap(av.M, "Metadata") {
+			endpoint.Metadata[k] = aws.StringValue(v.S)
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Slice(endpoints, func(i, j int) bool { return endpoints[i].ID < endpoints[j].ID })
+
+	return endpoints, nil
+}
+
+// Watch resolves the service every CacheTTL until the context is done, calling fn with its endpoints the first time,
+// and each time an endpoint is added, removed, or changes address or metadata
+func (r *Registry) Watch(ctx context.Context, service string, fn func([]Endpoint)) error {
+	var last []Endpoint
+	first := true
+
+	for {
+		endpoints, err := r.Resolve(ctx, service)
+		if err != nil && ctx.Err() == nil && !IsRetryable(err) {
+			return err
+		}
+		if err == nil && (first || endpointsChanged(last, endpoints)) {
+			fn(endpoints)
+			last, first = endpoints, false
+		}
+
+		if err := sleepContext(ctx, r.CacheTTL); err != nil {
+			return err
+		}
+	}
+}
+
+// endpointsChanged compares endpoints, ignoring the leases that are renewed all the time
+func endpointsChanged(a, b []Endpoint) bool {
+	if len(a) != len(b) {
+		return true
+	}
+	for i := range a {
+		if a[i].ID != b[i].ID || a[i].Address != b[i].Address || !reflect.DeepEqual(a[i].Metadata, b[i].Metadata) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package dyno
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+
+	service := NewRegistry(testClient, tableName, "PK", "SK")
+	client := NewRegistry(testClient, tableName, "PK", "SK")
+	client.CacheTTL = 50 * time.Millisecond
+
+	t.Run("given registered endpoints", func(t *testing.T) {
+		require.NoError(t, service.Register(ctx, "testing-service", Endpoint{ID: "b", Address: "10.0.1.2:8080"}))
+		require.NoError(t, service.Register(ctx, "testing-service", Endpoint{ID: "a", Address: "10.0.1.1:8080", Metadata: map[string]string{"Zone": "us-west-2a"}}))
+
+		endpoints, err := client.Resolve(ctx, "testing-service")
+		require.NoError(t, err)
+		require.Len(t, endpoints, 2)
+		assert.Equal(t, "10.0.1.1:8080", endpoints[0].Address)
+		assert.Equal(t, "us-west-2a", endpoints[0].Metadata["Zone"])
+		assert.Equal(t, "b", endpoints[1].ID)
+
+		endpoints, err = client.Resolve(ctx, "missing-service")
+		require.NoError(t, err)
+		assert.Empty(t, endpoints)
+	})
+
+	t.Run("given a deregistered endpoint", func(t *testing.T) {
+		require.NoError(t, service.Deregister(ctx, "testing-service", "b"))
+
+		endpoints, err := client.Resolve(ctx, "testing-service")
+		require.NoError(t, err)
+		assert.Len(t, endpoints, 2, "the endpoints are cached")
+
+		time.Sleep(60 * time.Millisecond)
+		endpoints, err = client.Resolve(ctx, "testing-service")
+		require.NoError(t, err)
+		assert.Len(t, endpoints, 1)
+
+		assert.NoError(t, service.Deregister(ctx, "missing-service", "b"))
+	})
+
+	t.Run("given an endpoint whose lease runs out", func(t *testing.T) {
+		service.Lease = 20 * time.Millisecond
+		require.NoError(t, service.Register(ctx, "testing-service", Endpoint{ID: "c", Address: "10.0.1.3:8080"}))
+		time.Sleep(60 * time.Millisecond)
+
+		endpoints, err := client.Resolve(ctx, "testing-service")
+		require.NoError(t, err)
+		require.Len(t, endpoints, 1)
+		assert.Equal(t, "a", endpoints[0].ID)
+		service.Lease = 30 * time.Second
+		require.NoError(t, service.Register(ctx, "testing-service", Endpoint{ID: "a", Address: "10.0.1.1:8080"}))
+
+		result, err := testClient.GetItem(&dynamodb.GetItemInput{TableName: aws.String(tableName), Key: service.key("testing-service")})
+		require.NoError(t, err)
+		assert.NotContains(t, GetMap(result.Item, "Dyno_Endpoints"), "c", "registering removes the expired endpoint")
+		assert.Contains(t, GetMap(result.Item, "Dyno_Endpoints"), "a")
+	})
+
+	t.Run("given a watcher", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, 300*time.Millisecond)
+		defer cancel()
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			service.Deregister(ctx, "testing-service", "a")
+		}()
+
+		changes := [][]Endpoint{}
+		err := client.Watch(ctx, "testing-service", func(endpoints []Endpoint) { changes = append(changes, endpoints) })
+		assert.Equal(t, context.DeadlineExceeded, err)
+		require.Len(t, changes, 2)
+		assert.Len(t, changes[0], 1)
+		assert.Empty(t, changes[1])
+	})
+}
@@ -0,0 +1,101 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+var ErrNoServerTime = errors.New("response did not include the server time")
+
+// ServerClock is a Clock that follows DynamoDB's clock instead of the local one. It measures the local clock's offset
+// from the Date header of a DynamoDB response, so processes whose clocks disagree agree on the time.
+//
+// The Date header is in whole seconds, so the offset is only accurate to half a second plus half the request's round
+// trip. Leases timed with it need a skew allowance of at least a second.
+type ServerClock struct {
+	db dynamodbiface.DynamoDBAPI
+
+	// Clock is the local clock the offset is applied to. Defaults to the system clock.
+	Clock Clock
+
+	// SyncInterval is how long an offset is used before it's measured again. Defaults to one minute.
+	SyncInterval time.Duration
+
+	mu     sync.Mutex
+	offset time.Duration
+	synced time.Time
+}
+
+func NewServerClock(db dynamodbiface.DynamoDBAPI) *ServerClock {
+	return &ServerClock{
+		db:           db,
+		Clock:        systemClock{},
+		SyncInterval: time.Minute,
+	}
+}
+
+// Now returns the local time adjusted by the last measured offset
+func (c *ServerClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.Clock.Now().Add(c.offset)
+}
+
+// Offset returns how far the server's clock was ahead of the local one when it was last measured
+func (c *ServerClock) Offset() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.offset
+}
+
+// Sync measures the offset from the server's clock, with a ListTables request
+func (c *ServerClock) Sync(ctx context.Context) error {
+	var date string
+	sent := c.Clock.Now()
+	_, err := c.db.ListTablesWithContext(ctx, &dynamodb.ListTablesInput{Limit: aws.Int64(1)}, request.WithGetResponseHeader("Date", &date))
+	received := c.Clock.Now()
+	if err != nil {
+		return err
+	}
+
+	serverTime, err := http.ParseTime(date)
+	if err != nil {
+		return ErrNoServerTime
+	}
+
+	// The server's time is somewhere in the second the header names, and it was read about halfway through the request
+	local := sent.Add(received.Sub(sent) / 2)
+	serverTime = serverTime.Add(500 * time.Millisecond)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offset = serverTime.Sub(local)
+	c.synced = received
+	return nil
+}
+
+// sync measures the offset if it hasn't been measured within the sync interval. Once it's been measured, a failure
+// keeps the last offset rather than failing whatever needed the time.
+func (c *ServerClock) sync(ctx context.Context) error {
+	c.mu.Lock()
+	synced := c.synced
+	c.mu.Unlock()
+
+	if !synced.IsZero() && c.Clock.Now().Sub(synced) < c.SyncInterval {
+		return nil
+	}
+	if err := c.Sync(ctx); err != nil && synced.IsZero() {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package dyno
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/maddiesch/dyno/dynotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dateHeaderDB sets the Date header of ListTables responses to the system time, for clients that don't make HTTP
+// requests
+type dateHeaderDB struct {
+	dynamodbiface.DynamoDBAPI
+}
+
+func (db *dateHeaderDB) ListTablesWithContext(ctx aws.Context, input *dynamodb.ListTablesInput, opts ...request.Option) (*dynamodb.ListTablesOutput, error) {
+	result, err := db.DynamoDBAPI.ListTablesWithContext(ctx, input, opts...)
+
+	r := &request.Request{HTTPResponse: &http.Response{Header: http.Header{}}}
+	r.HTTPResponse.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.Handlers.Complete.Run(r)
+
+	return result, err
+}
+
+func TestServerClock(t *testing.T) {
+	ctx := context.Background()
+	db := &dateHeaderDB{testClient}
+
+	t.Run("given a local clock that's behind", func(t *testing.T) {
+		clock := NewServerClock(db)
+		clock.Clock = dynotest.NewManualClock(time.Now().Add(-time.Hour))
+		require.NoError(t, clock.Sync(ctx))
+
+		assert.InDelta(t, float64(time.Hour), float64(clock.Offset()), float64(2*time.Second))
+		assert.WithinDuration(t, time.Now(), clock.Now(), 2*time.Second)
+	})
+
+	t.Run("given a lease written by a holder whose clock is behind", func(t *testing.T) {
+		behind := dynotest.NewManualClock(time.Now().Add(-time.Minute))
+		skewed := NewServerClock(db)
+		skewed.Clock = behind
+
+		holder := NewLockWithOptions(db, tableName, "server-time-lock", WithServerTime(skewed, time.Second))
+		require.NoError(t, holder.Acquire(10*time.Second))
+		defer holder.Release()
+
+		waiter := NewLockWithOptions(db, tableName, "server-time-lock", WithServerTime(NewServerClock(db), time.Second), WithBackoff(10*time.Millisecond, 10*time.Millisecond))
+		assert.Equal(t, ErrLockAcquireTimeout, waiter.AcquireWithTimeout(10*time.Second, 200*time.Millisecond))
+
+		require.NoError(t, holder.Renew(ctx))
+		assert.Equal(t, ErrLockAcquireTimeout, waiter.AcquireWithTimeout(10*time.Second, 200*time.Millisecond))
+	})
+
+	t.Run("given a lease written by a holder whose clock is behind without server time", func(t *testing.T) {
+		holder := NewLockWithOptions(db, tableName, "local-time-lock", WithClock(dynotest.NewManualClock(time.Now().Add(-time.Minute))))
+		require.NoError(t, holder.Acquire(10*time.Second))
+
+		waiter := NewLockWithOptions(db, tableName, "local-time-lock", WithBackoff(10*time.Millisecond, 10*time.Millisecond))
+		require.NoError(t, waiter.AcquireWithTimeout(10*time.Second, 200*time.Millisecond))
+		waiter.Release()
+	})
+}
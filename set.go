@@ -0,0 +1,158 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Set is a set of strings shared between processes, kept in string set attributes. Adding and removing members are
+// atomic, so processes don't need to coordinate.
+//
+// An item holds up to 400KB, so large sets are split over several shard items, by the hash of each member. The number
+// of shards can't be changed once a set has members, so choose enough for the set's largest size.
+type Set struct {
+	db     dynamodbiface.DynamoDBAPI
+	tn     string
+	pk     string
+	sk     string
+	name   string
+	shards int
+
+	// ConsistentRead reads the set with strongly consistent reads. Defaults to true.
+	ConsistentRead bool
+}
+
+// NewSet returns the named set, split over the number of shards
+func NewSet(db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey, name string, shards int) *Set {
+	if shards < 1 {
+		shards = 1
+	}
+	return &Set{
+		db:             db,
+		tn:             tableName,
+		pk:             primaryKey,
+		sk:             sortKey,
+		name:           name,
+		shards:         shards,
+		ConsistentRead: true,
+	}
+}
+
+func (s *Set) key(shard int) map[string]*dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{}
+
+	if s.sk == "" {
+		item[s.pk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_Set/%s/%d", s.name, shard))}
+	} else {
+		item[s.pk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_Set/%s", s.name))}
+		item[s.sk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_SetShard/%d", shard))}
+	}
+
+	return item
+}
+
+func (s *Set) shard(member string) int {
+	h := fnv.New32a()
+	h.Write([]byte(member))
+	return int(h.Sum32() % uint32(s.shards))
+}
+
+// byShard groups the members by the shard they belong in
+func (s *Set) byShard(members []string) map[int][]string {
+	shards := map[int][]string{}
+	for _, m := range members {
+		shards[s.shard(m)] = append(shards[s.shard(m)], m)
+	}
+	return shards
+}
+
+// Add adds the members to the set. Members already in it are ignored.
+func (s *Set) Add(ctx context.Context, members ...string) error {
+	return s.update(ctx, "ADD", members)
+}
+
+// Remove removes the members from the set. Members that aren't in it are ignored.
+func (s *Set) Remove(ctx context.Context, members ...string) error {
+	return s.update(ctx, "DELETE", members)
+}
+
+func (s *Set) update(ctx context.Context, action string, members []string) error {
+	for shard, members := range s.byShard(members) {
+		_, err := s.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+			TableName:                 aws.String(s.tn),
+			Key:                       s.key(shard),
+			UpdateExpression:          aws.String(action + " #m :m"),
+			ExpressionAttributeNames:  map[string]*string{"#m": aws.String("Dyno_Members")},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":m": StringSet(members...)},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Contains returns true if the member is in the set
+func (s *Set) Contains(ctx context.Context, member string) (bool, error) {
+	members, err := s.read(ctx, s.shard(member))
+	if err != nil {
+		return false, err
+	}
+
+	for _, m := range members {
+		if m == member {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Members returns every member of the set, sorted
+func (s *Set) Members(ctx context.Context) ([]string, error) {
+	all := []string{}
+	for shard := 0; shard < s.shards; shard++ {
+		members, err := s.read(ctx, shard)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, members...)
+	}
+	sort.Strings(all)
+
+	return all, nil
+}
+
+func (s *Set) read(ctx context.Context, shard int) ([]string, error) {
+	result, err := s.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:                aws.String(s.tn),
+		Key:                      s.key(shard),
+		ConsistentRead:           aws.Bool(s.ConsistentRead),
+		ProjectionExpression:     aws.String("#m"),
+		ExpressionAttributeNames: map[string]*string{"#m": aws.String("Dyno_Members")},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return GetStringSet(result.Item, "Dyno_Members"), nil
+}
+
+// Delete removes the set and every member
+func (s *Set) Delete(ctx context.Context) error {
+	for shard := 0; shard < s.shards; shard++ {
+		_, err := s.db.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(s.tn),
+			Key:       s.key(shard),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
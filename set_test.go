@@ -0,0 +1,48 @@
+package dyno
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSet(t *testing.T) {
+	ctx := context.Background()
+
+	for _, shards := range []int{1, 4} {
+		set := NewSet(testClient, tableName, "PK", "SK", "testing-set", shards)
+		require.NoError(t, set.Delete(ctx))
+
+		t.Run("given members that were added", func(t *testing.T) {
+			require.NoError(t, set.Add(ctx, "c", "a", "b"))
+			require.NoError(t, set.Add(ctx, "a", "d"))
+
+			members, err := set.Members(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"a", "b", "c", "d"}, members)
+
+			ok, err := set.Contains(ctx, "d")
+			require.NoError(t, err)
+			assert.True(t, ok)
+		})
+
+		t.Run("given members that were removed", func(t *testing.T) {
+			require.NoError(t, set.Remove(ctx, "a", "d", "missing"))
+
+			members, err := set.Members(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"b", "c"}, members)
+
+			ok, err := set.Contains(ctx, "a")
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			require.NoError(t, set.Remove(ctx, "b", "c"))
+			members, err = set.Members(ctx)
+			require.NoError(t, err)
+			assert.Empty(t, members)
+		})
+	}
+}
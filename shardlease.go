@@ -0,0 +1,230 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+var ErrShardLeaseLost = errors.New("shard lease is held by another worker")
+
+// ShardLeases coordinates the workers of a stream consumer, like the Kinesis Client Library's lease table. Each shard
+// has a lease item that records the worker reading it and the sequence number it has checkpointed. A worker claims
+// unowned shards, renews its leases as it reads, and steals the leases of workers that stopped renewing them, picking
+// up from their checkpoints.
+//
+// Set it as a StreamPoller's Leases to run the poller on several workers without reading a shard twice.
+type ShardLeases struct {
+	db     dynamodbiface.DynamoDBAPI
+	tn     string
+	pk     string
+	sk     string
+	name   string
+	worker string
+
+	mu   sync.Mutex
+	held map[string]bool
+
+	// Lease is how long a worker owns a shard after claiming it or renewing its lease. Defaults to 30 seconds.
+	Lease time.Duration
+
+	// MaxShards is the most shards a worker reads at once, so that shards are spread over the workers. A worker holds
+	// every shard it can claim if it's zero.
+	MaxShards int
+
+	// Metrics receives a "ShardLeaseStolen" count each time an expired lease is taken from another worker
+	Metrics Metrics
+}
+
+// NewShardLeases returns the leases of the named consumer, for the worker with the ID. Consumers with different names
+// read the same stream independently.
+func NewShardLeases(db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey, name, workerID string) *ShardLeases {
+	return &ShardLeases{
+		db:     db,
+		tn:     tableName,
+		pk:     primaryKey,
+		sk:     sortKey,
+		name:   name,
+		worker: workerID,
+		held:   map[string]bool{},
+		Lease:  30 * time.Second,
+	}
+}
+
+func (s *ShardLeases) key(shardID string) map[string]*dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{}
+
+	if s.sk == "" {
+		item[s.pk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_ShardLease/%s/%s", s.name, shardID))}
+	} else {
+		item[s.pk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_ShardLease/%s", s.name))}
+		item[s.sk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_Shard/%s", shardID))}
+	}
+
+	return item
+}
+
+// Held returns the number of shards the worker holds leases on
+func (s *ShardLeases) Held() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.held)
+}
+
+// Claim takes the lease on the shard if no other worker holds it, or the other worker's lease expired. It returns the
+// shard's checkpoint, and false if the lease couldn't be claimed, the shard has been read to its end, or the worker
+// already holds MaxShards.
+func (s *ShardLeases) Claim(ctx context.Context, shardID string) (checkpoint string, claimed bool, err error) {
+	s.mu.Lock()
+	full := s.MaxShards > 0 && len(s.held) >= s.MaxShards && !s.held[shardID]
+	s.mu.Unlock()
+	if full {
+		return "", false, nil
+	}
+
+	now := time.Now()
+	result, err := s.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(s.tn),
+		Key:                 s.key(shardID),
+		UpdateExpression:    aws.String("SET #o = :me, #x = :x ADD #c :one"),
+		ConditionExpression: aws.String("(attribute_not_exists(#o) OR #o = :me OR #x < :now) AND attribute_not_exists(#d)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#o": aws.String("Dyno_Owner"),
+			"#x": aws.String("Dyno_ExpiresAtMs"),
+			"#c": aws.String("Dyno_LeaseCounter"),
+			"#d": aws.String("Dyno_Finished"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":me":  Str(s.worker),
+			":x":   TimeValue(now.Add(s.Lease), TimeUnixMillis),
+			":now": TimeValue(now, TimeUnixMillis),
+			":one": Int(1),
+		},
+		ReturnValues: aws.String(dynamodb.ReturnValueAllOld),
+	})
+	if Classify(err) == ErrorClassConditionalCheckFailed {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	if owner := GetString(result.Attributes, "Dyno_Owner", s.worker); owner != s.worker {
+		countMetric(s.Metrics, "ShardLeaseStolen", 1, map[string]string{"Consumer": s.name})
+	}
+
+	s.mu.Lock()
+	s.held[shardID] = true
+	s.mu.Unlock()
+
+	return GetString(result.Attributes, "Dyno_Checkpoint", ""), true, nil
+}
+
+// Renew extends the worker's lease on the shard. ErrShardLeaseLost is returned if another worker took it.
+func (s *ShardLeases) Renew(ctx context.Context, shardID string) error {
+	return s.update(ctx, shardID, "SET #x = :x",
+		map[string]string{"#x": "Dyno_ExpiresAtMs"},
+		map[string]*dynamodb.AttributeValue{":x": s.expiry()},
+	)
+}
+
+// Checkpoint records that the shard has been read up to and including the sequence number, and renews the lease.
+// ErrShardLeaseLost is returned if another worker took it.
+func (s *ShardLeases) Checkpoint(ctx context.Context, shardID, sequenceNumber string) error {
+	return s.update(ctx, shardID, "SET #x = :x, #cp = :cp",
+		map[string]string{"#x": "Dyno_ExpiresAtMs", "#cp": "Dyno_Checkpoint"},
+		map[string]*dynamodb.AttributeValue{":x": s.expiry(), ":cp": Str(sequenceNumber)},
+	)
+}
+
+// Finish records that the shard has been read to its end, so it's never claimed again, and gives up its lease
+func (s *ShardLeases) Finish(ctx context.Context, shardID string) error {
+	err := s.update(ctx, shardID, "SET #d = :true REMOVE #o, #x",
+		map[string]string{"#x": "Dyno_ExpiresAtMs", "#d": "Dyno_Finished"},
+		map[string]*dynamodb.AttributeValue{":true": Bool(true)},
+	)
+	s.forget(shardID)
+	return err
+}
+
+// Release gives up the worker's lease on the shard, so another worker can claim it straight away
+func (s *ShardLeases) Release(ctx context.Context, shardID string) error {
+	err := s.update(ctx, shardID, "REMOVE #o, #x", map[string]string{"#x": "Dyno_ExpiresAtMs"}, nil)
+	s.forget(shardID)
+	if err == ErrShardLeaseLost {
+		return nil
+	}
+	return err
+}
+
+// Finished returns true if the shard has been read to its end, by any worker
+func (s *ShardLeases) Finished(ctx context.Context, shardID string) (bool, error) {
+	result, err := s.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.tn),
+		Key:            s.key(shardID),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return GetBool(result.Item, "Dyno_Finished", false), nil
+}
+
+func (s *ShardLeases) expiry() *dynamodb.AttributeValue {
+	return TimeValue(time.Now().Add(s.Lease), TimeUnixMillis)
+}
+
+// update applies an update to a shard, as long as the worker holds its lease. #o is the owner attribute.
+func (s *ShardLeases) update(ctx context.Context, shardID, expression string, names map[string]string, values map[string]*dynamodb.AttributeValue) error {
+	attributeNames := map[string]*string{"#o": aws.String("Dyno_Owner")}
+	for k, v := range names {
+		attributeNames[k] = aws.String(v)
+	}
+	attributeValues := map[string]*dynamodb.AttributeValue{":me": Str(s.worker)}
+	for k, v := range values {
+		attributeValues[k] = v
+	}
+
+	_, err := s.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(s.tn),
+		Key:                       s.key(shardID),
+		UpdateExpression:          aws.String(expression),
+		ConditionExpression:       aws.String("#o = :me"),
+		ExpressionAttributeNames:  attributeNames,
+		ExpressionAttributeValues: attributeValues,
+	})
+	if Classify(err) == ErrorClassConditionalCheckFailed {
+		s.forget(shardID)
+		return ErrShardLeaseLost
+	}
+	return err
+}
+
+func (s *ShardLeases) forget(shardID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.held, shardID)
+}
+
+// releaseAll gives up every lease the worker holds
+func (s *ShardLeases) releaseAll(ctx context.Context) {
+	s.mu.Lock()
+	shards := make([]string, 0, len(s.held))
+	for id := range s.held {
+		shards = append(shards, id)
+	}
+	s.mu.Unlock()
+
+	for _, id := range shards {
+		s.Release(ctx, id)
+	}
+}
@@ -0,0 +1,149 @@
+package dyno
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardLeases(t *testing.T) {
+	ctx := context.Background()
+
+	metrics := &countingMetrics{counts: map[string]float64{}}
+	worker1 := NewShardLeases(testClient, tableName, "PK", "SK", "testing-consumer", "worker-1")
+	worker2 := NewShardLeases(testClient, tableName, "PK", "SK", "testing-consumer", "worker-2")
+	worker2.MaxShards = 1
+	worker2.Metrics = metrics
+
+	t.Run("given an unclaimed shard", func(t *testing.T) {
+		checkpoint, claimed, err := worker1.Claim(ctx, "shard-1")
+		require.NoError(t, err)
+		assert.True(t, claimed)
+		assert.Equal(t, "", checkpoint)
+		assert.Equal(t, 1, worker1.Held())
+
+		_, claimed, err = worker2.Claim(ctx, "shard-1")
+		require.NoError(t, err)
+		assert.False(t, claimed, "the lease is held")
+
+		require.NoError(t, worker1.Checkpoint(ctx, "shard-1", "100"))
+		assert.Equal(t, ErrShardLeaseLost, worker2.Checkpoint(ctx, "shard-1", "200"))
+	})
+
+	t.Run("given a worker at its maximum", func(t *testing.T) {
+		_, claimed, err := worker2.Claim(ctx, "shard-2")
+		require.NoError(t, err)
+		assert.True(t, claimed)
+
+		_, claimed, err = worker2.Claim(ctx, "shard-3")
+		require.NoError(t, err)
+		assert.False(t, claimed)
+
+		require.NoError(t, worker2.Release(ctx, "shard-2"))
+		assert.Equal(t, 0, worker2.Held())
+	})
+
+	t.Run("given an expired lease", func(t *testing.T) {
+		worker1.Lease = 10 * time.Millisecond
+		require.NoError(t, worker1.Renew(ctx, "shard-1"))
+		time.Sleep(20 * time.Millisecond)
+
+		checkpoint, claimed, err := worker2.Claim(ctx, "shard-1")
+		require.NoError(t, err)
+		assert.True(t, claimed)
+		assert.Equal(t, "100", checkpoint, "the new owner picks up from the checkpoint")
+		assert.Equal(t, float64(1), metrics.counts["ShardLeaseStolen"])
+
+		assert.Equal(t, ErrShardLeaseLost, worker1.Renew(ctx, "shard-1"))
+		assert.Equal(t, 0, worker1.Held())
+	})
+
+	t.Run("given a finished shard", func(t *testing.T) {
+		require.NoError(t, worker2.Finish(ctx, "shard-1"))
+
+		finished, err := worker1.Finished(ctx, "shard-1")
+		require.NoError(t, err)
+		assert.True(t, finished)
+
+		_, claimed, err := worker1.Claim(ctx, "shard-1")
+		require.NoError(t, err)
+		assert.False(t, claimed)
+	})
+}
+
+// fakeStream is a stream with one closed shard holding a record for each sequence number
+type fakeStream struct {
+	dynamodbstreamsiface.DynamoDBStreamsAPI
+
+	sequenceNumbers []string
+}
+
+func (f *fakeStream) DescribeStreamWithContext(ctx aws.Context, input *dynamodbstreams.DescribeStreamInput, opts ...request.Option) (*dynamodbstreams.DescribeStreamOutput, error) {
+	return &dynamodbstreams.DescribeStreamOutput{StreamDescription: &dynamodbstreams.StreamDescription{
+		Shards: []*dynamodbstreams.Shard{{
+			ShardId:             aws.String("shard-1"),
+			SequenceNumberRange: &dynamodbstreams.SequenceNumberRange{EndingSequenceNumber: aws.String("9")},
+		}},
+	}}, nil
+}
+
+func (f *fakeStream) GetShardIteratorWithContext(ctx aws.Context, input *dynamodbstreams.GetShardIteratorInput, opts ...request.Option) (*dynamodbstreams.GetShardIteratorOutput, error) {
+	return &dynamodbstreams.GetShardIteratorOutput{ShardIterator: aws.String(aws.StringValue(input.SequenceNumber))}, nil
+}
+
+func (f *fakeStream) GetRecordsWithContext(ctx aws.Context, input *dynamodbstreams.GetRecordsInput, opts ...request.Option) (*dynamodbstreams.GetRecordsOutput, error) {
+	for _, n := range f.sequenceNumbers {
+		if n > aws.StringValue(input.ShardIterator) {
+			return &dynamodbstreams.GetRecordsOutput{
+				Records:           []*dynamodbstreams.Record{{Dynamodb: &dynamodbstreams.StreamRecord{SequenceNumber: aws.String(n)}}},
+				NextShardIterator: aws.String(n),
+			}, nil
+		}
+	}
+	return &dynamodbstreams.GetRecordsOutput{}, nil
+}
+
+func TestStreamPollerLeases(t *testing.T) {
+	ctx := context.Background()
+	stream := &fakeStream{sequenceNumbers: []string{"1", "2", "3"}}
+
+	leases := NewShardLeases(testClient, tableName, "PK", "SK", "testing-poller", "worker-1")
+	_, claimed, err := leases.Claim(ctx, "shard-1")
+	require.NoError(t, err)
+	require.True(t, claimed)
+	require.NoError(t, leases.Checkpoint(ctx, "shard-1", "1"))
+	require.NoError(t, leases.Release(ctx, "shard-1"))
+
+	poller := NewStreamPoller(stream, "arn")
+	poller.IteratorType = dynamodbstreams.ShardIteratorTypeTrimHorizon
+	poller.RefreshInterval = 10 * time.Millisecond
+	poller.Leases = NewShardLeases(testClient, tableName, "PK", "SK", "testing-poller", "worker-2")
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	received := []string{}
+	go func() {
+		for {
+			finished, _ := leases.Finished(ctx, "shard-1")
+			if finished || ctx.Err() != nil {
+				cancel()
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	err = poller.Run(ctx, func(ctx context.Context, shardID string, records []*dynamodbstreams.Record) error {
+		received = append(received, aws.StringValue(records[0].Dynamodb.SequenceNumber))
+		return nil
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, []string{"2", "3"}, received, "reading starts after the checkpoint")
+}
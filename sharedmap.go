@@ -0,0 +1,211 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// SharedMap is a map of string keys to values shared between processes, with conditional mutations for coordinating
+// changes. Values are marshaled with dynamodbattribute, so any value it can marshal can be stored, and entries can
+// expire.
+//
+// Each entry is an item in the map's partition, so the table must have a sort key.
+type SharedMap struct {
+	db   dynamodbiface.DynamoDBAPI
+	tn   string
+	pk   string
+	sk   string
+	name string
+
+	// ConsistentRead reads entries with strongly consistent reads. Defaults to true.
+	ConsistentRead bool
+}
+
+// MapEntry is an entry read from a SharedMap
+type MapEntry struct {
+	Key   string
+	Value *dynamodb.AttributeValue
+
+	// ExpiresAt is when the entry expires, or zero if it doesn't
+	ExpiresAt time.Time
+}
+
+// Unmarshal unmarshals the entry's value into out
+func (e MapEntry) Unmarshal(out interface{}) error {
+	return dynamodbattribute.Unmarshal(e.Value, out)
+}
+
+func NewSharedMap(db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey, name string) *SharedMap {
+	return &SharedMap{
+		db:             db,
+		tn:             tableName,
+		pk:             primaryKey,
+		sk:             sortKey,
+		name:           name,
+		ConsistentRead: true,
+	}
+}
+
+func (m *SharedMap) partition() *dynamodb.AttributeValue {
+	return Str(fmt.Sprintf("Dyno_Map/%s", m.name))
+}
+
+func (m *SharedMap) key(key string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		m.pk: m.partition(),
+		m.sk: Str("Dyno_MapEntry/" + key),
+	}
+}
+
+// item returns the entry's item, with its expiry if the TTL isn't zero. Enable the table's TTL on Dyno_ExpiresAt to
+// remove expired entries.
+func (m *SharedMap) item(key string, value interface{}, ttl time.Duration) (map[string]*dynamodb.AttributeValue, error) {
+	av, err := dynamodbattribute.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	item := m.key(key)
+	item["Dyno_Value"] = av
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		item["Dyno_ExpiresAt"] = TimeValue(expiresAt, TimeUnixSeconds)
+		item["Dyno_ExpiresAtMs"] = TimeValue(expiresAt, TimeUnixMillis)
+	}
+	return item, nil
+}
+
+// entry returns the entry in the item, and false if there isn't one or it expired. TTL deletes expired items some
+// time after they expire, so they're checked here.
+func (m *SharedMap) entry(item map[string]*dynamodb.AttributeValue) (MapEntry, bool) {
+	value := item["Dyno_Value"]
+	if value == nil {
+		return MapEntry{}, false
+	}
+
+	entry := MapEntry{Key: strings.TrimPrefix(GetString(item, m.sk, ""), "Dyno_MapEntry/"), Value: value}
+	if expiresAt, err := ParseTime(item["Dyno_ExpiresAtMs"], TimeUnixMillis); err == nil {
+		if !expiresAt.After(time.Now()) {
+			return MapEntry{}, false
+		}
+		entry.ExpiresAt = expiresAt
+	}
+	return entry, true
+}
+
+// Get unmarshals the key's value into out, and returns false if the key isn't in the map
+func (m *SharedMap) Get(ctx context.Context, key string, out interface{}) (bool, error) {
+	result, err := m.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(m.tn),
+		Key:            m.key(key),
+		ConsistentRead: aws.Bool(m.ConsistentRead),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	entry, ok := m.entry(result.Item)
+	if !ok {
+		return false, nil
+	}
+	return true, entry.Unmarshal(out)
+}
+
+// Put sets the key's value. The entry expires after the TTL, unless it's zero.
+func (m *SharedMap) Put(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	item, err := m.item(key, value, ttl)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(m.tn),
+		Item:      item,
+	})
+	return err
+}
+
+// PutIfAbsent sets the key's value if the key isn't in the map, or its entry expired. ErrItemExists is returned if it
+// is.
+func (m *SharedMap) PutIfAbsent(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	item, err := m.item(key, value, ttl)
+	if err != nil {
+		return err
+	}
+
+	return conditionalPut(ctx, m.db, &dynamodb.PutItemInput{
+		TableName:                 aws.String(m.tn),
+		Item:                      item,
+		ConditionExpression:       aws.String("attribute_not_exists(#v) OR #x <= :now"),
+		ExpressionAttributeNames:  map[string]*string{"#v": aws.String("Dyno_Value"), "#x": aws.String("Dyno_ExpiresAtMs")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":now": TimeValue(time.Now(), TimeUnixMillis)},
+	}, ErrItemExists)
+}
+
+// CompareAndSwap sets the key's value if its current value is old. ErrAttributeMismatch is returned if it isn't, or
+// the key isn't in the map.
+func (m *SharedMap) CompareAndSwap(ctx context.Context, key string, old, value interface{}, ttl time.Duration) error {
+	item, err := m.item(key, value, ttl)
+	if err != nil {
+		return err
+	}
+	current, err := dynamodbattribute.Marshal(old)
+	if err != nil {
+		return err
+	}
+
+	return conditionalPut(ctx, m.db, &dynamodb.PutItemInput{
+		TableName:                aws.String(m.tn),
+		Item:                     item,
+		ConditionExpression:      aws.String("#v = :old AND (attribute_not_exists(#x) OR #x > :now)"),
+		ExpressionAttributeNames: map[string]*string{"#v": aws.String("Dyno_Value"), "#x": aws.String("Dyno_ExpiresAtMs")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":old": current,
+			":now": TimeValue(time.Now(), TimeUnixMillis),
+		},
+	}, ErrAttributeMismatch)
+}
+
+// Delete removes the key from the map
+func (m *SharedMap) Delete(ctx context.Context, key string) error {
+	_, err := m.db.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(m.tn),
+		Key:       m.key(key),
+	})
+	return err
+}
+
+// Range calls fn with each entry in the map, in key order, until it returns false
+func (m *SharedMap) Range(ctx context.Context, fn func(MapEntry) bool) error {
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(m.tn),
+		KeyConditionExpression:    aws.String("#pk = :pk AND begins_with(#sk, :prefix)"),
+		ExpressionAttributeNames:  map[string]*string{"#pk": aws.String(m.pk), "#sk": aws.String(m.sk)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":pk": m.partition(), ":prefix": Str("Dyno_MapEntry/")},
+		ConsistentRead:            aws.Bool(m.ConsistentRead),
+	}
+
+	for {
+		result, err := m.db.QueryWithContext(ctx, input)
+		if err != nil {
+			return err
+		}
+		for _, item := range result.Items {
+			if entry, ok := m.entry(item); ok && !fn(entry) {
+				return nil
+			}
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
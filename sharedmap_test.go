@@ -0,0 +1,78 @@
+package dyno
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharedMap(t *testing.T) {
+	ctx := context.Background()
+
+	type config struct {
+		Replicas int
+		Region   string
+	}
+	m := NewSharedMap(testClient, tableName, "PK", "SK", "testing-map")
+
+	t.Run("given values that were put", func(t *testing.T) {
+		require.NoError(t, m.Put(ctx, "api", config{Replicas: 3, Region: "us-west-2"}, 0))
+
+		var c config
+		ok, err := m.Get(ctx, "api", &c)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, config{Replicas: 3, Region: "us-west-2"}, c)
+
+		ok, err = m.Get(ctx, "missing", &c)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("given conditional mutations", func(t *testing.T) {
+		assert.Equal(t, ErrItemExists, m.PutIfAbsent(ctx, "api", config{}, 0))
+		require.NoError(t, m.PutIfAbsent(ctx, "worker", config{Replicas: 1}, 0))
+
+		assert.Equal(t, ErrAttributeMismatch, m.CompareAndSwap(ctx, "worker", config{Replicas: 2}, config{Replicas: 3}, 0))
+		require.NoError(t, m.CompareAndSwap(ctx, "worker", config{Replicas: 1}, config{Replicas: 3}, 0))
+
+		var c config
+		_, err := m.Get(ctx, "worker", &c)
+		require.NoError(t, err)
+		assert.Equal(t, 3, c.Replicas)
+	})
+
+	t.Run("given an entry that expired", func(t *testing.T) {
+		require.NoError(t, m.Put(ctx, "temporary", "value", 10*time.Millisecond))
+		time.Sleep(20 * time.Millisecond)
+
+		var s string
+		ok, err := m.Get(ctx, "temporary", &s)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, m.PutIfAbsent(ctx, "temporary", "replaced", 0))
+	})
+
+	t.Run("given a range", func(t *testing.T) {
+		require.NoError(t, m.Delete(ctx, "temporary"))
+
+		keys := []string{}
+		require.NoError(t, m.Range(ctx, func(e MapEntry) bool {
+			keys = append(keys, e.Key)
+			return true
+		}))
+		assert.Equal(t, []string{"api", "worker"}, keys)
+
+		keys = []string{}
+		require.NoError(t, m.Range(ctx, func(e MapEntry) bool {
+			var c config
+			require.NoError(t, e.Unmarshal(&c))
+			keys = append(keys, c.Region)
+			return false
+		}))
+		assert.Equal(t, []string{"us-west-2"}, keys)
+	})
+}
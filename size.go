@@ -0,0 +1,117 @@
+package dyno
+
+import (
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// MaxItemSize is the largest item DynamoDB will store
+const MaxItemSize = 400 * 1024
+
+var ErrItemTooLarge = errors.New("item is larger than the 400KB item size limit")
+
+// SizeOf returns the size of the item in bytes, as DynamoDB accounts for it when calculating the item size limit and
+// consumed capacity.
+func SizeOf(item map[string]*dynamodb.AttributeValue) int {
+	size := 0
+	for name, av := range item {
+		size += len(name) + attributeValueSize(av)
+	}
+	return size
+}
+
+// CheckSize returns ErrItemTooLarge if the item can't be stored
+func CheckSize(item map[string]*dynamodb.AttributeValue) error {
+	if SizeOf(item) > MaxItemSize {
+		return ErrItemTooLarge
+	}
+	return nil
+}
+
+// ReadUnits returns the read capacity units consumed reading an item of the size. Eventually consistent reads cost half
+// as much.
+func ReadUnits(size int, consistent bool) float64 {
+	units := math.Ceil(float64(size) / 4096)
+	if units == 0 {
+		units = 1
+	}
+	if !consistent {
+		units /= 2
+	}
+	return units
+}
+
+// WriteUnits returns the write capacity units consumed writing an item of the size
+func WriteUnits(size int) float64 {
+	units := math.Ceil(float64(size) / 1024)
+	if units == 0 {
+		units = 1
+	}
+	return units
+}
+
+func attributeValueSize(av *dynamodb.AttributeValue) int {
+	switch {
+	case av == nil:
+		return 1
+	case av.S != nil:
+		return len(*av.S)
+	case av.N != nil:
+		return numberSize(*av.N)
+	case av.B != nil:
+		return len(av.B)
+	case av.BOOL != nil, av.NULL != nil:
+		return 1
+	case av.SS != nil:
+		size := 0
+		for _, s := range av.SS {
+			size += len(*s)
+		}
+		return size
+	case av.NS != nil:
+		size := 0
+		for _, n := range av.NS {
+			size += numberSize(*n)
+		}
+		return size
+	case av.BS != nil:
+		size := 0
+		for _, b := range av.BS {
+			size += len(b)
+		}
+		return size
+	case av.L != nil:
+		// Lists and maps have 3 bytes of overhead, plus 1 byte for every element
+		size := 3
+		for _, v := range av.L {
+			size += 1 + attributeValueSize(v)
+		}
+		return size
+	case av.M != nil:
+		size := 3
+		for name, v := range av.M {
+			size += 1 + len(name) + attributeValueSize(v)
+		}
+		return size
+	default:
+		return 1
+	}
+}
+
+// numberSize is 1 byte per two significant digits plus 1 byte, with leading and trailing zeros trimmed
+func numberSize(n string) int {
+	n = strings.TrimLeft(n, "+-")
+	if i := strings.IndexAny(n, "eE"); i >= 0 {
+		n = n[:i]
+	}
+
+	digits := strings.Trim(strings.Replace(n, ".", "", 1), "0")
+	if len(digits) == 0 {
+		digits = "0"
+	}
+
+	return (len(digits)+1)/2 + 1
+}
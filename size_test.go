@@ -0,0 +1,61 @@
+package dyno
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSizeOf(t *testing.T) {
+	t.Run("scalars", func(t *testing.T) {
+		assert.Equal(t, 2+5, SizeOf(map[string]*dynamodb.AttributeValue{"PK": {S: aws.String("hello")}}))
+		assert.Equal(t, 1+3, SizeOf(map[string]*dynamodb.AttributeValue{"B": {B: []byte{1, 2, 3}}}))
+		assert.Equal(t, 4+1, SizeOf(map[string]*dynamodb.AttributeValue{"Flag": {BOOL: aws.Bool(true)}}))
+		assert.Equal(t, 4+1, SizeOf(map[string]*dynamodb.AttributeValue{"None": {NULL: aws.Bool(true)}}))
+	})
+
+	t.Run("numbers", func(t *testing.T) {
+		cases := map[string]int{
+			"0":                     2,
+			"7":                     2,
+			"12":                    2,
+			"123":                   3,
+			"1000":                  2,
+			"-0012.500":             3,
+			"1.5E10":                2,
+			strings.Repeat("9", 38): 20,
+		}
+		for n, size := range cases {
+			assert.Equal(t, size, numberSize(n), n)
+		}
+	})
+
+	t.Run("sets", func(t *testing.T) {
+		item := map[string]*dynamodb.AttributeValue{"SS": {SS: []*string{aws.String("ab"), aws.String("cde")}}}
+		assert.Equal(t, 2+5, SizeOf(item))
+	})
+
+	t.Run("documents", func(t *testing.T) {
+		item := map[string]*dynamodb.AttributeValue{
+			"L": {L: []*dynamodb.AttributeValue{{S: aws.String("ab")}, {BOOL: aws.Bool(false)}}},
+			"M": {M: map[string]*dynamodb.AttributeValue{"key": {S: aws.String("value")}}},
+		}
+		assert.Equal(t, (1+3+(1+2)+(1+1))+(1+3+(1+3+5)), SizeOf(item))
+	})
+
+	t.Run("CheckSize", func(t *testing.T) {
+		assert.NoError(t, CheckSize(map[string]*dynamodb.AttributeValue{"S": {S: aws.String("small")}}))
+		assert.Equal(t, ErrItemTooLarge, CheckSize(map[string]*dynamodb.AttributeValue{"S": {S: aws.String(strings.Repeat("x", MaxItemSize))}}))
+	})
+}
+
+func TestCapacityUnits(t *testing.T) {
+	assert.Equal(t, 1.0, ReadUnits(100, true))
+	assert.Equal(t, 0.5, ReadUnits(100, false))
+	assert.Equal(t, 2.0, ReadUnits(4097, true))
+	assert.Equal(t, 1.0, WriteUnits(1024))
+	assert.Equal(t, 2.0, WriteUnits(1025))
+}
@@ -0,0 +1,197 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// deletedAtAttribute holds the unix time an item was soft deleted
+const deletedAtAttribute = "Dyno_DeletedAt"
+
+// deletedTTLAttribute holds a deleted item's TTL from before it was deleted, or NULL if it didn't have one
+const deletedTTLAttribute = "Dyno_DeletedTTL"
+
+var ErrItemNotFound = errors.New("item not found")
+
+// SoftDelete deletes items by marking them with Dyno_DeletedAt, and reads items ignoring the ones that are marked, so
+// deleted items can be restored. Reads made without it still see deleted items.
+type SoftDelete struct {
+	db dynamodbiface.DynamoDBAPI
+	tn string
+
+	// TTLAttribute is the table's TTL attribute. When it's set, deleted items are given a TTL of Retention so DynamoDB
+	// deletes them for good. The TTL an item had before it was deleted is put back when it's restored.
+	TTLAttribute string
+
+	// Retention is how long deleted items are kept when TTLAttribute is set. Defaults to 30 days.
+	Retention time.Duration
+}
+
+func NewSoftDelete(db dynamodbiface.DynamoDBAPI, tableName string) *SoftDelete {
+	return &SoftDelete{
+		db:        db,
+		tn:        tableName,
+		Retention: 30 * 24 * time.Hour,
+	}
+}
+
+// Delete marks the item with the key as deleted. ErrItemNotFound is returned if there isn't an item that isn't
+// already deleted.
+func (s *SoftDelete) Delete(ctx context.Context, key map[string]*dynamodb.AttributeValue) error {
+	now := time.Now()
+	input := &dynamodb.UpdateItemInput{
+		TableName:           aws.String(s.tn),
+		Key:                 key,
+		UpdateExpression:    aws.String("SET #del = :now"),
+		ConditionExpression: aws.String("attribute_exists(#key) AND attribute_not_exists(#del)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#key": aws.String(keyAttribute(key)),
+			"#del": aws.String(deletedAtAttribute),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": TimeValue(now, TimeUnixSeconds),
+		},
+	}
+	if s.TTLAttribute != "" {
+		input.UpdateExpression = aws.String("SET #del = :now, #orig = if_not_exists(#ttl, :none), #ttl = :ttl")
+		input.ExpressionAttributeNames["#ttl"] = aws.String(s.TTLAttribute)
+		input.ExpressionAttributeNames["#orig"] = aws.String(deletedTTLAttribute)
+		input.ExpressionAttributeValues[":ttl"] = TimeValue(now.Add(s.Retention), TimeUnixSeconds)
+		input.ExpressionAttributeValues[":none"] = &dynamodb.AttributeValue{NULL: aws.Bool(true)}
+	}
+
+	_, err := s.db.UpdateItemWithContext(ctx, input)
+	if Classify(err) == ErrorClassConditionalCheckFailed {
+		return ErrItemNotFound
+	}
+	return err
+}
+
+// Restore un-deletes the item with the key. ErrItemNotFound is returned if there isn't a deleted item to restore.
+func (s *SoftDelete) Restore(ctx context.Context, key map[string]*dynamodb.AttributeValue) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName:           aws.String(s.tn),
+		Key:                 key,
+		UpdateExpression:    aws.String("REMOVE #del"),
+		ConditionExpression: aws.String("attribute_exists(#del)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#del": aws.String(deletedAtAttribute),
+		},
+	}
+	if s.TTLAttribute != "" {
+		if err := s.restoreTTL(ctx, input); err != nil {
+			return err
+		}
+	}
+
+	_, err := s.db.UpdateItemWithContext(ctx, input)
+	if Classify(err) == ErrorClassConditionalCheckFailed {
+		return ErrItemNotFound
+	}
+	return err
+}
+
+// restoreTTL changes a restore to put back the TTL the item had before it was deleted. The restore is conditional on
+// the item not being deleted again since its TTL was read.
+func (s *SoftDelete) restoreTTL(ctx context.Context, input *dynamodb.UpdateItemInput) error {
+	result, err := s.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:                aws.String(s.tn),
+		Key:                      input.Key,
+		ConsistentRead:           aws.Bool(true),
+		ProjectionExpression:     aws.String("#del, #orig"),
+		ExpressionAttributeNames: map[string]*string{"#del": aws.String(deletedAtAttribute), "#orig": aws.String(deletedTTLAttribute)},
+	})
+	if err != nil {
+		return err
+	}
+	deletedAt, ok := result.Item[deletedAtAttribute]
+	if !ok {
+		return ErrItemNotFound
+	}
+
+	input.ConditionExpression = aws.String("#del = :del")
+	input.ExpressionAttributeNames["#ttl"] = aws.String(s.TTLAttribute)
+	input.ExpressionAttributeNames["#orig"] = aws.String(deletedTTLAttribute)
+	input.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{":del": deletedAt}
+
+	if ttl := result.Item[deletedTTLAttribute]; ttl != nil && ttl.N != nil {
+		input.UpdateExpression = aws.String("SET #ttl = :ttl REMOVE #del, #orig")
+		input.ExpressionAttributeValues[":ttl"] = ttl
+	} else {
+		input.UpdateExpression = aws.String("REMOVE #del, #ttl, #orig")
+	}
+	return nil
+}
+
+// Get returns the item with the key, or nil if it doesn't exist or is deleted. It's read consistently, so an item just
+// deleted or restored is seen as such.
+func (s *SoftDelete) Get(ctx context.Context, key map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	result, err := s.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.tn),
+		Key:            key,
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, deleted := result.Item[deletedAtAttribute]; deleted || len(result.Item) == 0 {
+		return nil, nil
+	}
+
+	return result.Item, nil
+}
+
+// Query runs the query with deleted items filtered out. Deleted items still count towards the query's Limit.
+func (s *SoftDelete) Query(ctx context.Context, input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	query := *input
+	query.TableName = aws.String(s.tn)
+	query.FilterExpression = withoutDeleted(query.FilterExpression)
+	query.ExpressionAttributeNames = withDeletedName(query.ExpressionAttributeNames)
+
+	return s.db.QueryWithContext(ctx, &query)
+}
+
+// Scan runs the scan with deleted items filtered out. Deleted items still count towards the scan's Limit.
+func (s *SoftDelete) Scan(ctx context.Context, input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	scan := *input
+	scan.TableName = aws.String(s.tn)
+	scan.FilterExpression = withoutDeleted(scan.FilterExpression)
+	scan.ExpressionAttributeNames = withDeletedName(scan.ExpressionAttributeNames)
+
+	return s.db.ScanWithContext(ctx, &scan)
+}
+
+func withoutDeleted(filter *string) *string {
+	if filter == nil || *filter == "" {
+		return aws.String("attribute_not_exists(#dyno_del)")
+	}
+	return aws.String("(" + *filter + ") AND attribute_not_exists(#dyno_del)")
+}
+
+func withDeletedName(names map[string]*string) map[string]*string {
+	copied := map[string]*string{"#dyno_del": aws.String(deletedAtAttribute)}
+	for k, v := range names {
+		copied[k] = v
+	}
+	return copied
+}
+
+// keyAttribute returns the name of one of the key's attributes, which exists on every item with a key
+func keyAttribute(key map[string]*dynamodb.AttributeValue) string {
+	names := make([]string, 0, len(key))
+	for name := range key {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
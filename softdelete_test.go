@@ -0,0 +1,110 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoftDelete(t *testing.T) {
+	ctx := context.Background()
+	table, drop := createTestTable(t, "dyno-test-soft-delete")
+	defer drop()
+
+	key := func(i int) map[string]*dynamodb.AttributeValue {
+		return map[string]*dynamodb.AttributeValue{
+			"PK": {S: aws.String("users")},
+			"SK": {S: aws.String(fmt.Sprintf("user-%d", i))},
+		}
+	}
+	for i := 0; i < 3; i++ {
+		item := key(i)
+		item["Active"] = &dynamodb.AttributeValue{BOOL: aws.Bool(i != 2)}
+		if i == 1 {
+			item["ExpiresAt"] = &dynamodb.AttributeValue{N: aws.String("4102444800")}
+		}
+		_, err := testClient.PutItem(&dynamodb.PutItemInput{TableName: aws.String(table), Item: item})
+		require.NoError(t, err)
+	}
+
+	items := NewSoftDelete(testClient, table)
+	items.TTLAttribute = "ExpiresAt"
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, items.Delete(ctx, key(0)))
+
+		assert.Equal(t, ErrItemNotFound, items.Delete(ctx, key(0)), "given a deleted item")
+		assert.Equal(t, ErrItemNotFound, items.Delete(ctx, key(9)), "given a missing item")
+
+		result, err := testClient.GetItem(&dynamodb.GetItemInput{TableName: aws.String(table), Key: key(0)})
+		require.NoError(t, err)
+		assert.Contains(t, result.Item, "Dyno_DeletedAt")
+		assert.Contains(t, result.Item, "ExpiresAt")
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		item, err := items.Get(ctx, key(0))
+		require.NoError(t, err)
+		assert.Nil(t, item)
+
+		item, err = items.Get(ctx, key(1))
+		require.NoError(t, err)
+		assert.NotNil(t, item)
+	})
+
+	t.Run("Query", func(t *testing.T) {
+		result, err := items.Query(ctx, &dynamodb.QueryInput{
+			KeyConditionExpression: aws.String("PK = :pk"),
+			FilterExpression:       aws.String("#active = :true"),
+			ExpressionAttributeNames: map[string]*string{
+				"#active": aws.String("Active"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":pk":   {S: aws.String("users")},
+				":true": {BOOL: aws.Bool(true)},
+			},
+		})
+		require.NoError(t, err)
+
+		require.Len(t, result.Items, 1)
+		assert.Equal(t, "user-1", aws.StringValue(result.Items[0]["SK"].S))
+	})
+
+	t.Run("Scan", func(t *testing.T) {
+		result, err := items.Scan(ctx, &dynamodb.ScanInput{})
+		require.NoError(t, err)
+
+		assert.Len(t, result.Items, 2)
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		require.NoError(t, items.Restore(ctx, key(0)))
+		assert.Equal(t, ErrItemNotFound, items.Restore(ctx, key(0)))
+
+		result, err := testClient.GetItem(&dynamodb.GetItemInput{TableName: aws.String(table), Key: key(0)})
+		require.NoError(t, err)
+		assert.NotContains(t, result.Item, "Dyno_DeletedAt")
+		assert.NotContains(t, result.Item, "Dyno_DeletedTTL")
+		assert.NotContains(t, result.Item, "ExpiresAt")
+	})
+
+	t.Run("given an item with a TTL", func(t *testing.T) {
+		require.NoError(t, items.Delete(ctx, key(1)))
+
+		result, err := testClient.GetItem(&dynamodb.GetItemInput{TableName: aws.String(table), Key: key(1)})
+		require.NoError(t, err)
+		assert.NotEqual(t, "4102444800", aws.StringValue(result.Item["ExpiresAt"].N), "the retention applies while deleted")
+
+		require.NoError(t, items.Restore(ctx, key(1)))
+
+		result, err = testClient.GetItem(&dynamodb.GetItemInput{TableName: aws.String(table), Key: key(1)})
+		require.NoError(t, err)
+		assert.Equal(t, "4102444800", aws.StringValue(result.Item["ExpiresAt"].N))
+		assert.NotContains(t, result.Item, "Dyno_DeletedTTL")
+	})
+}
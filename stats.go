@@ -0,0 +1,233 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// maxWaitSamples is how many of the most recent wait times are kept for percentiles
+const maxWaitSamples = 1024
+
+// LockStats are statistics about a lock's contention. Percentiles are only tracked in memory, over the most recent
+// 1024 acquires.
+type LockStats struct {
+	// Acquires is the number of times the lock was acquired
+	Acquires int64
+
+	// Waits is the number of acquires that found the lock held and had to wait
+	Waits int64
+
+	// Steals is the number of acquires that took the lock after the holder's lease expired
+	Steals int64
+
+	// TotalWait is the time spent acquiring the lock
+	TotalWait time.Duration
+
+	// LongestHold is the longest the lock was held before it was released or lost
+	LongestHold time.Duration
+
+	P50Wait time.Duration
+	P90Wait time.Duration
+	P99Wait time.Duration
+}
+
+// AverageWait returns the mean time it took to acquire the lock
+func (s LockStats) AverageWait() time.Duration {
+	if s.Acquires == 0 {
+		return 0
+	}
+	return s.TotalWait / time.Duration(s.Acquires)
+}
+
+// lockStats collects a lock's statistics from its events
+type lockStats struct {
+	// publishing is held for a whole publish, so concurrent publishes don't both add the same counts
+	publishing sync.Mutex
+
+	mu        sync.Mutex
+	stats     LockStats
+	published LockStats
+	samples   []time.Duration
+	next      int
+	waiting   bool
+	heldSince time.Time
+}
+
+func (s *lockStats) record(e LockEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch e.Type {
+	case LockContended:
+		s.waiting = true
+	case LockAcquired, LockStolen:
+		s.stats.Acquires++
+		s.stats.TotalWait += e.Wait
+		if s.waiting {
+			s.stats.Waits++
+		}
+		if e.Type == LockStolen {
+			s.stats.Steals++
+		}
+		s.waiting = false
+		s.heldSince = e.Time
+
+		if len(s.samples) < maxWaitSamples {
+			s.samples = append(s.samples, e.Wait)
+		} else {
+			s.samples[s.next] = e.Wait
+			s.next = (s.next + 1) % maxWaitSamples
+		}
+	case LockReleased, LockLost:
+		if held := e.Time.Sub(s.heldSince); !s.heldSince.IsZero() && held > s.stats.LongestHold {
+			s.stats.LongestHold = held
+		}
+		s.heldSince = time.Time{}
+	}
+}
+
+func (s *lockStats) snapshot() LockStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats
+	if len(s.samples) == 0 {
+		return stats
+	}
+
+	sorted := append([]time.Duration(nil), s.samples...)
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a] < sorted[b] })
+	percentile := func(p float64) time.Duration { // nearest rank
+		return sorted[int(math.Ceil(p*float64(len(sorted))))-1]
+	}
+	stats.P50Wait = percentile(0.5)
+	stats.P90Wait = percentile(0.9)
+	stats.P99Wait = percentile(0.99)
+
+	return stats
+}
+
+// Stats returns the statistics of this Lock's use of the lock since it was created
+func (l *Lock) Stats() LockStats {
+	return l.stats.snapshot()
+}
+
+// PublishStats adds the statistics collected since the last publish to a rollup item in the table, shared by every
+// process using the lock. Concurrent calls publish one at a time.
+func (l *Lock) PublishStats(ctx context.Context) error {
+	l.stats.publishing.Lock()
+	defer l.stats.publishing.Unlock()
+
+	l.stats.mu.Lock()
+	current, published := l.stats.stats, l.stats.published
+	l.stats.mu.Unlock()
+
+	key := l.statsKey()
+	_, err := l.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(l.tn),
+		Key:              key,
+		UpdateExpression: aws.String("ADD #ac :ac, #wt :wt, #st :st, #tw :tw"),
+		ExpressionAttributeNames: map[string]*string{
+			"#ac": aws.String("Dyno_Acquires"),
+			"#wt": aws.String("Dyno_Waits"),
+			"#st": aws.String("Dyno_Steals"),
+			"#tw": aws.String("Dyno_TotalWaitMs"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":ac": {N: aws.String(strconv.FormatInt(current.Acquires-published.Acquires, 10))},
+			":wt": {N: aws.String(strconv.FormatInt(current.Waits-published.Waits, 10))},
+			":st": {N: aws.String(strconv.FormatInt(current.Steals-published.Steals, 10))},
+			":tw": DurationValue(current.TotalWait-published.TotalWait, time.Millisecond),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// The counts are added, so they mustn't be added again even if the longest hold can't be updated
+	l.stats.mu.Lock()
+	l.stats.published = current
+	l.stats.mu.Unlock()
+
+	_, err = l.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(l.tn),
+		Key:                 key,
+		UpdateExpression:    aws.String("SET #lh = :lh"),
+		ConditionExpression: aws.String("attribute_not_exists(#lh) OR #lh < :lh"),
+		ExpressionAttributeNames: map[string]*string{
+			"#lh": aws.String("Dyno_LongestHoldMs"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":lh": DurationValue(current.LongestHold, time.Millisecond),
+		},
+	})
+	if err != nil && Classify(err) != ErrorClassConditionalCheckFailed {
+		return err
+	}
+
+	return nil
+}
+
+func (l *Lock) statsKey() map[string]*dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{}
+	item[l.pk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_LockStats/%s", l.name))}
+
+	if l.sk != "" {
+		item[l.sk] = &dynamodb.AttributeValue{S: aws.String("Dyno_LockStatsSortKeyValue")}
+	}
+
+	return item
+}
+
+// ListLockStats scans the table for the published statistics of every lock, by lock name
+func ListLockStats(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName, primaryKey string) (map[string]LockStats, error) {
+	input := &dynamodb.ScanInput{
+		TableName:        aws.String(tableName),
+		FilterExpression: aws.String("begins_with(#pk, :prefix)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#pk": aws.String(primaryKey),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":prefix": {S: aws.String("Dyno_LockStats/")},
+		},
+	}
+
+	stats := map[string]LockStats{}
+	err := db.ScanPagesWithContext(ctx, input, func(page *dynamodb.ScanOutput, _ bool) bool {
+		for _, item := range page.Items {
+			number := func(name string) int64 {
+				if v, ok := item[name]; ok {
+					n, _ := strconv.ParseInt(aws.StringValue(v.N), 10, 64)
+					return n
+				}
+				return 0
+			}
+			duration := func(name string) time.Duration {
+				d, _ := ParseDuration(item[name], time.Millisecond)
+				return d
+			}
+
+			name := strings.TrimPrefix(aws.StringValue(item[primaryKey].S), "Dyno_LockStats/")
+			stats[name] = LockStats{
+				Acquires:    number("Dyno_Acquires"),
+				Waits:       number("Dyno_Waits"),
+				Steals:      number("Dyno_Steals"),
+				TotalWait:   duration("Dyno_TotalWaitMs"),
+				LongestHold: duration("Dyno_LongestHoldMs"),
+			}
+		}
+		return true
+	})
+
+	return stats, err
+}
@@ -0,0 +1,80 @@
+package dyno
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maddiesch/dyno/dynotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockStats(t *testing.T) {
+	ctx := context.Background()
+	table, drop := createTestTable(t, "dyno-test-lock-stats")
+	defer drop()
+
+	holder := NewLock(testClient, table, "PK", "SK", "contended")
+	waiter := NewLock(testClient, table, "PK", "SK", "contended")
+
+	require.NoError(t, holder.Acquire(30*time.Second))
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		holder.Release()
+	}()
+	require.NoError(t, waiter.AcquireWithTimeout(30*time.Second, time.Second))
+	require.NoError(t, waiter.Release())
+	require.NoError(t, waiter.Acquire(30*time.Second))
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, waiter.Release())
+
+	t.Run("Stats", func(t *testing.T) {
+		stats := waiter.Stats()
+
+		assert.Equal(t, int64(2), stats.Acquires)
+		assert.Equal(t, int64(1), stats.Waits)
+		assert.True(t, stats.P99Wait >= 40*time.Millisecond, stats.P99Wait.String())
+		assert.True(t, stats.P50Wait < 40*time.Millisecond, stats.P50Wait.String())
+		assert.True(t, stats.LongestHold >= 10*time.Millisecond, stats.LongestHold.String())
+		assert.Equal(t, stats.TotalWait/2, stats.AverageWait())
+	})
+
+	t.Run("PublishStats", func(t *testing.T) {
+		require.NoError(t, holder.PublishStats(ctx))
+		require.NoError(t, waiter.PublishStats(ctx))
+		require.NoError(t, waiter.PublishStats(ctx))
+
+		stats, err := ListLockStats(ctx, testClient, table, "PK")
+		require.NoError(t, err)
+		require.Contains(t, stats, "contended")
+
+		assert.Equal(t, int64(3), stats["contended"].Acquires)
+		assert.Equal(t, int64(1), stats["contended"].Waits)
+		assert.Equal(t, holder.Stats().LongestHold.Truncate(time.Millisecond), stats["contended"].LongestHold)
+	})
+
+	t.Run("given concurrent publishes", func(t *testing.T) {
+		slow := dynotest.NewChaos(testClient, dynotest.ChaosPolicy{Latency: 5 * time.Millisecond})
+		lock := NewLock(slow, table, "PK", "SK", "concurrent")
+		for i := 0; i < 3; i++ {
+			require.NoError(t, lock.Acquire(30*time.Second))
+			require.NoError(t, lock.Release())
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, lock.PublishStats(ctx))
+			}()
+		}
+		wg.Wait()
+
+		stats, err := ListLockStats(ctx, testClient, table, "PK")
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), stats["concurrent"].Acquires)
+	})
+}
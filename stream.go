@@ -0,0 +1,287 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsiface"
+)
+
+var ErrStreamNotEnabled = errors.New("table does not have a stream enabled")
+
+// LatestStreamArn returns the ARN of the table's current stream
+func LatestStreamArn(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName string) (string, error) {
+	result, err := db.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		return "", err
+	}
+	if result.Table.LatestStreamArn == nil {
+		return "", ErrStreamNotEnabled
+	}
+
+	return aws.StringValue(result.Table.LatestStreamArn), nil
+}
+
+// StreamHandler is called with every batch of records read from a shard. Batches from the same shard are delivered in
+// order, and a child shard isn't read until its parent has been.
+type StreamHandler func(ctx context.Context, shardID string, records []*dynamodbstreams.Record) error
+
+// StreamPoller reads every shard of a DynamoDB stream
+type StreamPoller struct {
+	streams dynamodbstreamsiface.DynamoDBStreamsAPI
+	arn     string
+
+	// IteratorType is where the shards that are open when Run starts are read from. Defaults to LATEST. Shards that
+	// open later are always read from TRIM_HORIZON so no records are missed.
+	IteratorType string
+
+	// Interval is how long to wait before polling a shard that returned no records. Defaults to 1 second.
+	Interval time.Duration
+
+	// RefreshInterval is how often the stream is described to find new shards. Defaults to 30 seconds.
+	RefreshInterval time.Duration
+
+	// Leases shares the shards with other pollers running with the same consumer's leases. Shards are only read while
+	// their lease is held, from their last checkpoint, and are checkpointed after every batch. Shards that another
+	// worker holds are tried again every RefreshInterval, so that expired leases are stolen. The handler should take
+	// well under the lease to handle a batch.
+	Leases *ShardLeases
+}
+
+func NewStreamPoller(streams dynamodbstreamsiface.DynamoDBStreamsAPI, streamArn string) *StreamPoller {
+	return &StreamPoller{
+		streams:         streams,
+		arn:             streamArn,
+		IteratorType:    dynamodbstreams.ShardIteratorTypeLatest,
+		Interval:        time.Second,
+		RefreshInterval: 30 * time.Second,
+	}
+}
+
+// Run reads the stream until the context is done or the handler returns an error
+func (p *StreamPoller) Run(ctx context.Context, handler StreamHandler) error {
+	ctx, cancel := context.WithCancel(ctx)
+
+	if p.Leases != nil {
+		defer p.Leases.releaseAll(context.Background())
+	}
+
+	// Stop the readers before waiting for them, so an error returned here doesn't leave Run waiting forever
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	errs := make(chan error, 1)
+	finished := make(chan string)
+	lost := make(chan string)
+	running := map[string]bool{}
+	done := map[string]bool{}
+	initial := true
+
+	for {
+		shards, err := p.shards(ctx)
+		if err != nil {
+			return err
+		}
+
+		known := map[string]bool{}
+		for _, shard := range shards {
+			known[aws.StringValue(shard.ShardId)] = true
+		}
+
+		for _, shard := range shards {
+			id := aws.StringValue(shard.ShardId)
+			parent := aws.StringValue(shard.ParentShardId)
+			closed := shard.SequenceNumberRange != nil && shard.SequenceNumberRange.EndingSequenceNumber != nil
+
+			if running[id] || done[id] {
+				continue
+			}
+
+			iteratorType := dynamodbstreams.ShardIteratorTypeTrimHorizon
+			if initial {
+				if closed && p.IteratorType == dynamodbstreams.ShardIteratorTypeLatest {
+					// Nothing new will ever be written to a closed shard
+					done[id] = true
+					continue
+				}
+				iteratorType = p.IteratorType
+			}
+
+			if parent != "" && known[parent] && !done[parent] {
+				// The parent may have been read by another worker
+				if p.Leases == nil {
+					continue
+				}
+				parentDone, err := p.Leases.Finished(ctx, parent)
+				if err != nil {
+					return err
+				}
+				if !parentDone {
+					continue
+				}
+				done[parent] = true
+			}
+
+			var checkpoint string
+			if p.Leases != nil {
+				var claimed bool
+				if checkpoint, claimed, err = p.Leases.Claim(ctx, id); err != nil {
+					return err
+				}
+				if !claimed {
+					continue
+				}
+			}
+
+			running[id] = true
+			wg.Add(1)
+			go func(id, iteratorType, checkpoint string) {
+				defer wg.Done()
+
+				err := p.read(ctx, id, iteratorType, checkpoint, handler)
+				if err == ErrShardLeaseLost {
+					select {
+					case lost <- id:
+					case <-ctx.Done():
+					}
+					return
+				}
+				if err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+					return
+				}
+
+				select {
+				case finished <- id:
+				case <-ctx.Done():
+				}
+			}(id, iteratorType, checkpoint)
+		}
+		initial = false
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			return err
+		case id := <-finished:
+			delete(running, id)
+			done[id] = true
+		case id := <-lost:
+			delete(running, id)
+		case <-time.After(p.RefreshInterval):
+		}
+	}
+}
+
+func (p *StreamPoller) shards(ctx context.Context) ([]*dynamodbstreams.Shard, error) {
+	shards := []*dynamodbstreams.Shard{}
+	input := &dynamodbstreams.DescribeStreamInput{
+		StreamArn: aws.String(p.arn),
+	}
+
+	for {
+		result, err := p.streams.DescribeStreamWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		shards = append(shards, result.StreamDescription.Shards...)
+
+		if result.StreamDescription.LastEvaluatedShardId == nil {
+			return shards, nil
+		}
+		input.ExclusiveStartShardId = result.StreamDescription.LastEvaluatedShardId
+	}
+}
+
+func (p *StreamPoller) iterator(ctx context.Context, shardID, iteratorType, sequenceNumber string) (*string, error) {
+	input := &dynamodbstreams.GetShardIteratorInput{
+		StreamArn:         aws.String(p.arn),
+		ShardId:           aws.String(shardID),
+		ShardIteratorType: aws.String(iteratorType),
+	}
+	if sequenceNumber != "" {
+		input.ShardIteratorType = aws.String(dynamodbstreams.ShardIteratorTypeAfterSequenceNumber)
+		input.SequenceNumber = aws.String(sequenceNumber)
+	}
+
+	result, err := p.streams.GetShardIteratorWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.ShardIterator, nil
+}
+
+// read reads the shard to its end, starting after the checkpoint if there is one
+func (p *StreamPoller) read(ctx context.Context, shardID, iteratorType, checkpoint string, handler StreamHandler) error {
+	last := checkpoint
+	renewed := time.Now()
+
+	iterator, err := p.iterator(ctx, shardID, iteratorType, last)
+	if err != nil {
+		return err
+	}
+
+	for iterator != nil {
+		result, err := p.streams.GetRecordsWithContext(ctx, &dynamodbstreams.GetRecordsInput{
+			ShardIterator: iterator,
+		})
+		if isAwsErrorCode(err, dynamodbstreams.ErrCodeExpiredIteratorException) {
+			if iterator, err = p.iterator(ctx, shardID, iteratorType, last); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(result.Records) > 0 {
+			if err := handler(ctx, shardID, result.Records); err != nil {
+				return err
+			}
+			last = aws.StringValue(result.Records[len(result.Records)-1].Dynamodb.SequenceNumber)
+
+			if p.Leases != nil {
+				if err := p.Leases.Checkpoint(ctx, shardID, last); err != nil {
+					return err
+				}
+				renewed = time.Now()
+			}
+		} else if p.Leases != nil && time.Since(renewed) > p.Leases.Lease/3 {
+			if err := p.Leases.Renew(ctx, shardID); err != nil {
+				return err
+			}
+			renewed = time.Now()
+		}
+
+		iterator = result.NextShardIterator
+
+		if len(result.Records) == 0 && iterator != nil {
+			if err := sleepContext(ctx, p.Interval); err != nil {
+				return err
+			}
+		}
+	}
+
+	if p.Leases != nil {
+		return p.Leases.Finish(ctx, shardID)
+	}
+	return nil
+}
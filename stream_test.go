@@ -0,0 +1,128 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsiface"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamPoller(t *testing.T) {
+	name := fmt.Sprintf("dyno-test-stream-%s", ksuid.New().String())
+
+	_, err := testClient.CreateTable(&dynamodb.CreateTableInput{
+		TableName:   aws.String(name),
+		BillingMode: aws.String("PAY_PER_REQUEST"),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("PK"), AttributeType: aws.String("S")},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("PK"), KeyType: aws.String("HASH")},
+		},
+		StreamSpecification: &dynamodb.StreamSpecification{
+			StreamEnabled:  aws.Bool(true),
+			StreamViewType: aws.String("NEW_AND_OLD_IMAGES"),
+		},
+	})
+	require.NoError(t, err)
+	defer testClient.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String(name)})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	arn, err := LatestStreamArn(ctx, testClient, name)
+	require.NoError(t, err)
+
+	_, err = testClient.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(name),
+		Item:      map[string]*dynamodb.AttributeValue{"PK": {S: aws.String("item-1")}},
+	})
+	require.NoError(t, err)
+
+	poller := NewStreamPoller(testStreams, arn)
+	poller.IteratorType = dynamodbstreams.ShardIteratorTypeTrimHorizon
+	poller.Interval = 100 * time.Millisecond
+
+	var received *dynamodbstreams.Record
+	err = poller.Run(ctx, func(ctx context.Context, shardID string, records []*dynamodbstreams.Record) error {
+		received = records[0]
+		cancel()
+		return nil
+	})
+	assert.Equal(t, context.Canceled, err)
+
+	require.NotNil(t, received)
+	assert.Equal(t, "INSERT", aws.StringValue(received.EventName))
+	assert.Equal(t, "item-1", aws.StringValue(received.Dynamodb.Keys["PK"].S))
+}
+
+// memoryStreams is a stream with open shards that each return one record and then nothing more
+type memoryStreams struct {
+	dynamodbstreamsiface.DynamoDBStreamsAPI
+
+	shards []string
+}
+
+func (m *memoryStreams) DescribeStreamWithContext(ctx aws.Context, input *dynamodbstreams.DescribeStreamInput, opts ...request.Option) (*dynamodbstreams.DescribeStreamOutput, error) {
+	shards := make([]*dynamodbstreams.Shard, len(m.shards))
+	for i, id := range m.shards {
+		shards[i] = &dynamodbstreams.Shard{ShardId: aws.String(id)}
+	}
+	return &dynamodbstreams.DescribeStreamOutput{
+		StreamDescription: &dynamodbstreams.StreamDescription{Shards: shards},
+	}, nil
+}
+
+func (m *memoryStreams) GetShardIteratorWithContext(ctx aws.Context, input *dynamodbstreams.GetShardIteratorInput, opts ...request.Option) (*dynamodbstreams.GetShardIteratorOutput, error) {
+	return &dynamodbstreams.GetShardIteratorOutput{ShardIterator: aws.String(aws.StringValue(input.ShardId) + "/0")}, nil
+}
+
+func (m *memoryStreams) GetRecordsWithContext(ctx aws.Context, input *dynamodbstreams.GetRecordsInput, opts ...request.Option) (*dynamodbstreams.GetRecordsOutput, error) {
+	iterator := aws.StringValue(input.ShardIterator)
+	shard := strings.TrimSuffix(iterator, "/0")
+	if shard == iterator {
+		return &dynamodbstreams.GetRecordsOutput{NextShardIterator: input.ShardIterator}, nil
+	}
+
+	return &dynamodbstreams.GetRecordsOutput{
+		Records: []*dynamodbstreams.Record{{
+			EventName: aws.String("INSERT"),
+			Dynamodb:  &dynamodbstreams.StreamRecord{SequenceNumber: aws.String("1"), Keys: map[string]*dynamodb.AttributeValue{"PK": Str(shard)}},
+		}},
+		NextShardIterator: aws.String(shard + "/1"),
+	}, nil
+}
+
+func TestStreamPollerHandlerError(t *testing.T) {
+	poller := NewStreamPoller(&memoryStreams{shards: []string{"shard-1", "shard-2"}}, "arn:aws:dynamodb:stream/1")
+	poller.Interval = 10 * time.Millisecond
+
+	failure := errors.New("can't handle shard-2")
+	returned := make(chan error, 1)
+	go func() {
+		returned <- poller.Run(context.Background(), func(ctx context.Context, shardID string, records []*dynamodbstreams.Record) error {
+			if shardID == "shard-2" {
+				return failure
+			}
+			return nil
+		})
+	}()
+
+	select {
+	case err := <-returned:
+		assert.Equal(t, failure, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return after the handler failed")
+	}
+}
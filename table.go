@@ -0,0 +1,216 @@
+package dyno
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// tablePollInterval is how often control plane operations are polled while waiting for them to finish
+const tablePollInterval = 5 * time.Second
+
+// TableOptions configures the tables EnsureTable creates
+type TableOptions struct {
+	// Tags are applied to the table, e.g. team, environment and cost-center for cost allocation
+	Tags map[string]string
+
+	// BillingMode is used when the table's input doesn't set one. Defaults to PAY_PER_REQUEST.
+	BillingMode string
+
+	// ReadCapacity and WriteCapacity are the provisioned throughput of PROVISIONED tables, and their global secondary
+	// indexes, when the input doesn't set it. Both default to 5.
+	ReadCapacity  int64
+	WriteCapacity int64
+
+	// Wait configures waiting for the table to become ACTIVE
+	Wait *WaitOptions
+
+	// AutoScaling registers PROVISIONED tables with Application Auto Scaling. It's ignored for PAY_PER_REQUEST tables.
+	AutoScaling *AutoScalingOptions
+
+	// PointInTimeRecovery enables point in time recovery on the table. It's never disabled, so a table that has it
+	// keeps it if the option is left off.
+	PointInTimeRecovery bool
+}
+
+// createInput returns a copy of the input with the options' billing mode and throughput
+func (o *TableOptions) createInput(input *dynamodb.CreateTableInput) *dynamodb.CreateTableInput {
+	create := *input
+	if create.BillingMode == nil {
+		create.BillingMode = aws.String(o.BillingMode)
+		if o.BillingMode == "" {
+			create.BillingMode = aws.String(dynamodb.BillingModePayPerRequest)
+		}
+	}
+	if aws.StringValue(create.BillingMode) != dynamodb.BillingModeProvisioned {
+		return &create
+	}
+
+	throughput := &dynamodb.ProvisionedThroughput{
+		ReadCapacityUnits:  aws.Int64(o.ReadCapacity),
+		WriteCapacityUnits: aws.Int64(o.WriteCapacity),
+	}
+	if o.ReadCapacity == 0 {
+		throughput.ReadCapacityUnits = aws.Int64(5)
+	}
+	if o.WriteCapacity == 0 {
+		throughput.WriteCapacityUnits = aws.Int64(5)
+	}
+
+	if create.ProvisionedThroughput == nil {
+		create.ProvisionedThroughput = throughput
+	}
+	create.GlobalSecondaryIndexes = nil
+	for _, gsi := range input.GlobalSecondaryIndexes {
+		if gsi.ProvisionedThroughput == nil {
+			index := *gsi
+			index.ProvisionedThroughput = throughput
+			gsi = &index
+		}
+		create.GlobalSecondaryIndexes = append(create.GlobalSecondaryIndexes, gsi)
+	}
+
+	return &create
+}
+
+// EnsureTable creates the table if it doesn't exist, waits for it to become ACTIVE, reconciles its tags with the
+// options', and enables point in time recovery and registers it for auto scaling if the options have them. An existing table's key schema and billing mode
+// aren't checked. opts can be nil.
+func EnsureTable(ctx context.Context, db dynamodbiface.DynamoDBAPI, input *dynamodb.CreateTableInput, opts *TableOptions) error {
+	if opts == nil {
+		opts = &TableOptions{}
+	}
+
+	_, err := db.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{TableName: input.TableName})
+	if Classify(err) == ErrorClassNotFound {
+		_, err = db.CreateTableWithContext(ctx, opts.createInput(input))
+		// Another process created it first
+		if isAwsErrorCode(err, dynamodb.ErrCodeResourceInUseException) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := WaitUntilTableActive(ctx, db, aws.StringValue(input.TableName), opts.Wait); err != nil {
+		return err
+	}
+
+	if err := ReconcileTags(ctx, db, aws.StringValue(input.TableName), opts.Tags); err != nil {
+		return err
+	}
+
+	if opts.PointInTimeRecovery {
+		if err := EnsurePointInTimeRecovery(ctx, db, aws.StringValue(input.TableName)); err != nil {
+			return err
+		}
+	}
+
+	if opts.AutoScaling == nil {
+		return nil
+	}
+	return RegisterAutoScaling(ctx, db, aws.StringValue(input.TableName), opts.AutoScaling)
+}
+
+// EnsureLockTable creates a table for locks and the other primitives, with string partition and sort keys of the given
+// names, if it doesn't exist. sortKey can be empty for a table without one. Its billing mode and throughput come from
+// the options.
+func EnsureLockTable(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey string, opts *TableOptions) error {
+	input := &dynamodb.CreateTableInput{
+		TableName: aws.String(tableName),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String(primaryKey), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String(primaryKey), KeyType: aws.String(dynamodb.KeyTypeHash)},
+		},
+	}
+	if sortKey != "" {
+		input.AttributeDefinitions = append(input.AttributeDefinitions, &dynamodb.AttributeDefinition{
+			AttributeName: aws.String(sortKey),
+			AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
+		})
+		input.KeySchema = append(input.KeySchema, &dynamodb.KeySchemaElement{
+			AttributeName: aws.String(sortKey),
+			KeyType:       aws.String(dynamodb.KeyTypeRange),
+		})
+	}
+
+	return EnsureTable(ctx, db, input, opts)
+}
+
+// TableTags returns the table's tags
+func TableTags(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName string) (map[string]string, error) {
+	arn, err := tableArn(ctx, db, tableName)
+	if err != nil {
+		return nil, err
+	}
+	return tagsOf(ctx, db, arn)
+}
+
+// ReconcileTags adds the tags the table is missing and updates the ones with different values. Tags on the table that
+// aren't in tags are left alone, so tags managed elsewhere aren't removed.
+func ReconcileTags(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName string, tags map[string]string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	arn, err := tableArn(ctx, db, tableName)
+	if err != nil {
+		return err
+	}
+	current, err := tagsOf(ctx, db, arn)
+	if err != nil {
+		return err
+	}
+
+	changed := []*dynamodb.Tag{}
+	for key, value := range tags {
+		if v, ok := current[key]; !ok || v != value {
+			changed = append(changed, &dynamodb.Tag{Key: aws.String(key), Value: aws.String(value)})
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	_, err = db.TagResourceWithContext(ctx, &dynamodb.TagResourceInput{
+		ResourceArn: aws.String(arn),
+		Tags:        changed,
+	})
+	return err
+}
+
+func tableArn(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName string) (string, error) {
+	result, err := db.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(result.Table.TableArn), nil
+}
+
+func tagsOf(ctx context.Context, db dynamodbiface.DynamoDBAPI, arn string) (map[string]string, error) {
+	tags := map[string]string{}
+	input := &dynamodb.ListTagsOfResourceInput{ResourceArn: aws.String(arn)}
+
+	for {
+		result, err := db.ListTagsOfResourceWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range result.Tags {
+			tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+
+		if result.NextToken == nil {
+			return tags, nil
+		}
+		input.NextToken = result.NextToken
+	}
+}
@@ -0,0 +1,55 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsureTable(t *testing.T) {
+	ctx := context.Background()
+	name := fmt.Sprintf("dyno-ensure-%s", ksuid.New().String())
+	defer testClient.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String(name)})
+
+	opts := &TableOptions{Tags: map[string]string{"team": "platform", "environment": "test"}}
+
+	t.Run("given a table that doesn't exist", func(t *testing.T) {
+		require.NoError(t, EnsureLockTable(ctx, testClient, name, "PK", "SK", opts))
+
+		result, err := testClient.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(name)})
+		require.NoError(t, err)
+		assert.Len(t, result.Table.KeySchema, 2)
+
+		tags, err := TableTags(ctx, testClient, name)
+		require.NoError(t, err)
+		assert.Equal(t, opts.Tags, tags)
+	})
+
+	t.Run("given a table that exists with other tags", func(t *testing.T) {
+		arn, err := tableArn(ctx, testClient, name)
+		require.NoError(t, err)
+		_, err = testClient.TagResource(&dynamodb.TagResourceInput{
+			ResourceArn: aws.String(arn),
+			Tags:        []*dynamodb.Tag{{Key: aws.String("owner"), Value: aws.String("someone")}},
+		})
+		require.NoError(t, err)
+
+		opts := &TableOptions{Tags: map[string]string{"environment": "staging", "cost-center": "1234"}}
+		require.NoError(t, EnsureLockTable(ctx, testClient, name, "PK", "SK", opts))
+
+		tags, err := TableTags(ctx, testClient, name)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"team":        "platform",
+			"environment": "staging",
+			"cost-center": "1234",
+			"owner":       "someone",
+		}, tags)
+	})
+}
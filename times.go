@@ -0,0 +1,94 @@
+package dyno
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var ErrAttributeType = errors.New("attribute value is missing or has the wrong type")
+
+// TimeFormat is how a time is stored in an attribute value
+type TimeFormat int
+
+const (
+	// TimeUnixSeconds is a number of seconds since the Unix epoch, the format DynamoDB's TTL expects
+	TimeUnixSeconds TimeFormat = iota
+
+	// TimeUnixMillis is a number of milliseconds since the Unix epoch
+	TimeUnixMillis
+
+	// TimeRFC3339 is a string in RFC 3339 format, in UTC with nanoseconds. Strings in this format sort by time as
+	// long as they have the same number of fractional digits, so it's best left to TimeUnixMillis for sort keys.
+	TimeRFC3339
+)
+
+// TimeValue returns an attribute value for the time in the format
+func TimeValue(t time.Time, format TimeFormat) *dynamodb.AttributeValue {
+	switch format {
+	case TimeUnixMillis:
+		return &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(unixMilli(t), 10))}
+	case TimeRFC3339:
+		return &dynamodb.AttributeValue{S: aws.String(t.UTC().Format(time.RFC3339Nano))}
+	default:
+		return &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(t.Unix(), 10))}
+	}
+}
+
+// ParseTime returns the time stored in an attribute value in the format. ErrAttributeType is returned if the value is
+// nil or isn't the type the format is stored as.
+func ParseTime(av *dynamodb.AttributeValue, format TimeFormat) (time.Time, error) {
+	if format == TimeRFC3339 {
+		if av == nil || av.S == nil {
+			return time.Time{}, ErrAttributeType
+		}
+		return time.Parse(time.RFC3339Nano, *av.S)
+	}
+
+	if av == nil || av.N == nil {
+		return time.Time{}, ErrAttributeType
+	}
+	n, err := strconv.ParseInt(*av.N, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if format == TimeUnixMillis {
+		return time.Unix(0, n*int64(time.Millisecond)), nil
+	}
+	return time.Unix(n, 0), nil
+}
+
+// DurationValue returns an attribute value for the duration as a whole number of units, e.g. time.Second, truncating
+// any remainder. A unit of 0 stores the duration as a string, such as "1m30s".
+func DurationValue(d time.Duration, unit time.Duration) *dynamodb.AttributeValue {
+	if unit == 0 {
+		return &dynamodb.AttributeValue{S: aws.String(d.String())}
+	}
+	return &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(int64(d/unit), 10))}
+}
+
+// ParseDuration returns the duration stored in an attribute value by DurationValue with the same unit
+func ParseDuration(av *dynamodb.AttributeValue, unit time.Duration) (time.Duration, error) {
+	if unit == 0 {
+		if av == nil || av.S == nil {
+			return 0, ErrAttributeType
+		}
+		return time.ParseDuration(*av.S)
+	}
+
+	if av == nil || av.N == nil {
+		return 0, ErrAttributeType
+	}
+	n, err := strconv.ParseInt(*av.N, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(n) * unit, nil
+}
+
+func unixMilli(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,66 @@
+package dyno
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeValue(t *testing.T) {
+	at := time.Date(2020, 11, 15, 8, 30, 15, 250*int(time.Millisecond), time.UTC)
+
+	t.Run("given each format", func(t *testing.T) {
+		assert.Equal(t, "1605429015", aws.StringValue(TimeValue(at, TimeUnixSeconds).N))
+		assert.Equal(t, "1605429015250", aws.StringValue(TimeValue(at, TimeUnixMillis).N))
+		assert.Equal(t, "2020-11-15T08:30:15.25Z", aws.StringValue(TimeValue(at, TimeRFC3339).S))
+	})
+
+	t.Run("given a round trip", func(t *testing.T) {
+		for format, want := range map[TimeFormat]time.Time{
+			TimeUnixSeconds: at.Truncate(time.Second),
+			TimeUnixMillis:  at,
+			TimeRFC3339:     at,
+		} {
+			parsed, err := ParseTime(TimeValue(at, format), format)
+			require.NoError(t, err)
+			assert.True(t, want.Equal(parsed), "format %d", format)
+		}
+	})
+
+	t.Run("given the wrong type", func(t *testing.T) {
+		_, err := ParseTime(nil, TimeUnixMillis)
+		assert.Equal(t, ErrAttributeType, err)
+
+		_, err = ParseTime(&dynamodb.AttributeValue{N: aws.String("1")}, TimeRFC3339)
+		assert.Equal(t, ErrAttributeType, err)
+	})
+}
+
+func TestDurationValue(t *testing.T) {
+	t.Run("given a unit", func(t *testing.T) {
+		av := DurationValue(90*time.Second+500*time.Millisecond, time.Second)
+		assert.Equal(t, "90", aws.StringValue(av.N))
+
+		d, err := ParseDuration(av, time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, 90*time.Second, d)
+	})
+
+	t.Run("given no unit", func(t *testing.T) {
+		av := DurationValue(90*time.Second, 0)
+		assert.Equal(t, "1m30s", aws.StringValue(av.S))
+
+		d, err := ParseDuration(av, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 90*time.Second, d)
+	})
+
+	t.Run("given the wrong type", func(t *testing.T) {
+		_, err := ParseDuration(&dynamodb.AttributeValue{S: aws.String("90")}, time.Second)
+		assert.Equal(t, ErrAttributeType, err)
+	})
+}
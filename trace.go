@@ -0,0 +1,35 @@
+package dyno
+
+import (
+	"context"
+)
+
+// Tracer instruments dyno operations, e.g. as X-Ray subsegments with the dynoxray package
+type Tracer interface {
+	// StartSpan begins a span named for the operation. The returned context carries the span to the AWS calls made
+	// by the operation.
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a single traced operation
+type Span interface {
+	// Annotate records an indexed key value pair on the span
+	Annotate(key string, value interface{})
+
+	// End finishes the span, recording the error if the operation failed
+	End(err error)
+}
+
+type nopSpan struct{}
+
+func (nopSpan) Annotate(string, interface{}) {}
+
+func (nopSpan) End(error) {}
+
+// startSpan starts a span with the tracer, if there is one
+func startSpan(ctx context.Context, tracer Tracer, name string) (context.Context, Span) {
+	if tracer == nil {
+		return ctx, nopSpan{}
+	}
+	return tracer.StartSpan(ctx, name)
+}
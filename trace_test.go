@@ -0,0 +1,85 @@
+package dyno
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedSpan struct {
+	name        string
+	annotations map[string]interface{}
+	err         error
+	ended       bool
+}
+
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (r *recordingTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	span := &recordedSpan{name: name, annotations: map[string]interface{}{}}
+	r.spans = append(r.spans, span)
+	return ctx, span
+}
+
+func (s *recordedSpan) Annotate(key string, value interface{}) {
+	s.annotations[key] = value
+}
+
+func (s *recordedSpan) End(err error) {
+	s.err = err
+	s.ended = true
+}
+
+func TestTracer(t *testing.T) {
+	t.Run("given a lock", func(t *testing.T) {
+		tracer := &recordingTracer{}
+		lock := NewLock(testClient, tableName, "PK", "SK", "test-trace-lock")
+		lock.Tracer(tracer)
+		other := NewLock(testClient, tableName, "PK", "SK", "test-trace-lock")
+
+		require.NoError(t, other.Acquire(time.Minute))
+		assert.Equal(t, ErrLockAcquireTimeout, lock.AcquireWithTimeout(time.Minute, 60*time.Millisecond))
+		require.NoError(t, other.Release())
+
+		require.Len(t, tracer.spans, 1)
+		span := tracer.spans[0]
+		assert.Equal(t, "dyno.Lock.Acquire", span.name)
+		assert.Equal(t, "test-trace-lock", span.annotations["dyno_lock"])
+		assert.True(t, span.annotations["dyno_retries"].(int) > 0)
+		assert.Equal(t, ErrLockAcquireTimeout, span.err)
+		assert.True(t, span.ended)
+	})
+
+	t.Run("given a batch writer", func(t *testing.T) {
+		ctx := context.Background()
+		tracer := &recordingTracer{}
+		writer := NewBatchWriter(testClient, tableName)
+		writer.Tracer = tracer
+
+		require.NoError(t, writer.Put(ctx, map[string]*dynamodb.AttributeValue{
+			"PK": {S: aws.String("test-trace-batch")},
+			"SK": {S: aws.String("1")},
+		}))
+		require.NoError(t, writer.Flush(ctx))
+
+		require.Len(t, tracer.spans, 1)
+		span := tracer.spans[0]
+		assert.Equal(t, "dyno.BatchWriter.Write", span.name)
+		assert.Equal(t, tableName, span.annotations["dyno_table"])
+		assert.Equal(t, 1, span.annotations["dyno_items"])
+		assert.Equal(t, 0, span.annotations["dyno_retries"])
+		assert.NoError(t, span.err)
+	})
+}
@@ -0,0 +1,341 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+var ErrInvalidLockPath = errors.New("lock path must have between 1 and 25 non-empty segments")
+
+// TreeLock is a lock on a path in a tree, e.g. "tenant/123/orders". It conflicts with locks on the same path, on its
+// ancestors ("tenant/123" and "tenant") and on its descendants ("tenant/123/orders/456"), but not with locks on its
+// siblings. A coarse lock on a tenant waits for the fine-grained locks held on its orders, and blocks new ones until
+// it's released.
+//
+// Each path has an item recording who holds it, and a marker for each lock held on one of its descendants. A lock is
+// acquired in a transaction that claims its path's item, unless the item changed since it was read, and adds its marker
+// to each ancestor's item, unless one of them is held. Leases are timed by the local clock, so hosts' clocks must
+// roughly agree. Items are kept after they're released, like Lock's.
+type TreeLock struct {
+	db    dynamodbiface.DynamoDBAPI
+	tn    string
+	pk    string
+	sk    string
+	name  string
+	path  string
+	owned string
+	lease time.Duration
+	local sync.Mutex
+
+	// PollInterval is how long to wait before retrying a contended lock. It doubles after each try, up to
+	// MaxPollInterval. Defaults to 100ms and 2s.
+	PollInterval    time.Duration
+	MaxPollInterval time.Duration
+}
+
+// NewTreeLock returns a lock on the path, with segments separated by "/", in the named tree
+func NewTreeLock(db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey, tree, path string) *TreeLock {
+	return &TreeLock{
+		db:              db,
+		tn:              tableName,
+		pk:              primaryKey,
+		sk:              sortKey,
+		name:            tree,
+		path:            path,
+		PollInterval:    100 * time.Millisecond,
+		MaxPollInterval: 2 * time.Second,
+	}
+}
+
+// Path returns the locked path
+func (l *TreeLock) Path() string {
+	return l.path
+}
+
+// paths returns the lock's ancestors, root first, and then its own path
+func (l *TreeLock) paths() ([]string, error) {
+	segments := strings.Split(l.path, "/")
+	if len(segments) > maxTransactItems {
+		return nil, ErrInvalidLockPath
+	}
+
+	paths := make([]string, len(segments))
+	for i, segment := range segments {
+		if segment == "" {
+			return nil, ErrInvalidLockPath
+		}
+		paths[i] = strings.Join(segments[:i+1], "/")
+	}
+	return paths, nil
+}
+
+func (l *TreeLock) key(path string) map[string]*dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{}
+	item[l.pk] = Str(fmt.Sprintf("Dyno_TreeLock/%s/%s", l.name, path))
+
+	if l.sk != "" {
+		item[l.sk] = Str("Dyno_TreeLockSortKeyValue")
+	}
+
+	return item
+}
+
+func (l *TreeLock) Acquire(lease time.Duration) error {
+	return l.AcquireWithTimeout(lease, time.Duration(0))
+}
+
+func (l *TreeLock) AcquireWithTimeout(lease, duration time.Duration) error {
+	return l.AcquireContext(context.Background(), lease, duration)
+}
+
+// AcquireContext acquires the lock, waiting up to duration for conflicting locks to be released or expire
+func (l *TreeLock) AcquireContext(ctx context.Context, lease, duration time.Duration) error {
+	l.local.Lock()
+	defer l.local.Unlock()
+
+	paths, err := l.paths()
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	lockID := NewKSUID()
+	poll := l.PollInterval
+
+	for {
+		items, err := l.read(ctx, paths)
+		if err != nil {
+			return err
+		}
+
+		if now := time.Now(); !treeLockConflict(items, now) {
+			err := l.claim(ctx, paths, items, lockID, now, now.Add(lease))
+			if err == nil {
+				l.owned = lockID
+				l.lease = lease
+				return nil
+			}
+			if Classify(err) != ErrorClassConditionalCheckFailed && !IsRetryable(err) {
+				return err
+			}
+		}
+
+		if start.Add(duration).Before(time.Now()) {
+			return ErrLockAcquireTimeout
+		}
+
+		// Jitter keeps waiters from retrying in step
+		if err := sleepContext(ctx, poll/2+time.Duration(rand.Int63n(int64(poll)))); err != nil {
+			return err
+		}
+		if poll *= 2; poll > l.MaxPollInterval {
+			poll = l.MaxPollInterval
+		}
+	}
+}
+
+// read returns the items of the paths, reading them together so they're consistent with each other
+func (l *TreeLock) read(ctx context.Context, paths []string) ([]map[string]*dynamodb.AttributeValue, error) {
+	gets := make([]*dynamodb.TransactGetItem, len(paths))
+	for i, path := range paths {
+		gets[i] = &dynamodb.TransactGetItem{Get: &dynamodb.Get{TableName: aws.String(l.tn), Key: l.key(path)}}
+	}
+
+	result, err := l.db.TransactGetItemsWithContext(ctx, &dynamodb.TransactGetItemsInput{TransactItems: gets})
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]map[string]*dynamodb.AttributeValue, len(paths))
+	for i, response := range result.Responses {
+		items[i] = response.Item
+	}
+	return items, nil
+}
+
+// treeLockConflict returns true if the lock's path or one of its ancestors is held, or a lock is held on one of its
+// descendants. The lock's own item is last.
+func treeLockConflict(items []map[string]*dynamodb.AttributeValue, now time.Time) bool {
+	for _, item := range items {
+		if treeLockHeld(item, now) {
+			return true
+		}
+	}
+
+	for _, expiresAt := range GetMap(items[len(items)-1], "Dyno_Descendants") {
+		if ms, err := ParseTime(expiresAt, TimeUnixMillis); err == nil && ms.After(now) {
+			return true
+		}
+	}
+	return false
+}
+
+func treeLockHeld(item map[string]*dynamodb.AttributeValue, now time.Time) bool {
+	if item["Dyno_LockID"] == nil {
+		return false
+	}
+	expiresAt, err := ParseTime(item["Dyno_ExpiresAtMs"], TimeUnixMillis)
+	return err != nil || expiresAt.After(now)
+}
+
+// claim takes the lock's path and marks its ancestors. Every write bumps the item's version, so claiming a path fails
+// if anything acquired, renewed or released it, or a lock on a descendant, since it was read.
+func (l *TreeLock) claim(ctx context.Context, paths []string, items []map[string]*dynamodb.AttributeValue, lockID string, now, expiresAt time.Time) error {
+	writes := make([]*dynamodb.TransactWriteItem, 0, len(paths))
+
+	for i, path := range paths[:len(paths)-1] {
+		update := &dynamodb.Update{
+			TableName:        aws.String(l.tn),
+			Key:              l.key(path),
+			UpdateExpression: aws.String("SET #d.#me = :x ADD #v :one"),
+			ExpressionAttributeNames: map[string]*string{
+				"#d": aws.String("Dyno_Descendants"), "#me": aws.String(lockID), "#v": aws.String("Dyno_Version"),
+				"#id": aws.String("Dyno_LockID"), "#lx": aws.String("Dyno_ExpiresAtMs"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":x": TimeValue(expiresAt, TimeUnixMillis), ":one": Int(1), ":now": TimeValue(now, TimeUnixMillis),
+			},
+			ConditionExpression: aws.String("attribute_exists(#d) AND (attribute_not_exists(#id) OR #lx <= :now)"),
+		}
+
+		// A nested attribute can't be set until its map exists, so the map is created with the marker in it, unless
+		// another lock created it first
+		if items[i]["Dyno_Descendants"] == nil {
+			update.UpdateExpression = aws.String("SET #d = :d ADD #v :one")
+			update.ConditionExpression = aws.String("attribute_not_exists(#d) AND (attribute_not_exists(#id) OR #lx <= :now)")
+			update.ExpressionAttributeValues[":d"] = Map(map[string]*dynamodb.AttributeValue{lockID: update.ExpressionAttributeValues[":x"]})
+			delete(update.ExpressionAttributeNames, "#me")
+			delete(update.ExpressionAttributeValues, ":x")
+		}
+		writes = append(writes, &dynamodb.TransactWriteItem{Update: update})
+	}
+
+	own := items[len(items)-1]
+	update := &dynamodb.Update{
+		TableName:        aws.String(l.tn),
+		Key:              l.key(paths[len(paths)-1]),
+		UpdateExpression: aws.String("SET #id = :id, #lx = :x ADD #v :one"),
+		ExpressionAttributeNames: map[string]*string{
+			"#id": aws.String("Dyno_LockID"), "#lx": aws.String("Dyno_ExpiresAtMs"), "#v": aws.String("Dyno_Version"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":id": Str(lockID), ":x": TimeValue(expiresAt, TimeUnixMillis), ":one": Int(1),
+		},
+		ConditionExpression: aws.String("attribute_not_exists(#v)"),
+	}
+	if own["Dyno_Version"] != nil {
+		update.ConditionExpression = aws.String("#v = :v")
+		update.ExpressionAttributeValues[":v"] = own["Dyno_Version"]
+	}
+
+	// Every descendant lock has expired, since there's no conflict, so their markers are cleared out
+	expired := []string{}
+	for id := range GetMap(own, "Dyno_Descendants") {
+		alias := fmt.Sprintf("#e%d", len(expired))
+		update.ExpressionAttributeNames[alias] = aws.String(id)
+		expired = append(expired, "#d."+alias)
+	}
+	if len(expired) > 0 {
+		update.UpdateExpression = aws.String(aws.StringValue(update.UpdateExpression) + " REMOVE " + strings.Join(expired, ", "))
+		update.ExpressionAttributeNames["#d"] = aws.String("Dyno_Descendants")
+	}
+	writes = append(writes, &dynamodb.TransactWriteItem{Update: update})
+
+	_, err := l.db.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: writes})
+	return err
+}
+
+// Renew extends the lock's lease, and the markers on its ancestors. ErrLockNotOwned is returned if it isn't held, or
+// its lease expired and a conflicting lock was acquired.
+func (l *TreeLock) Renew(ctx context.Context) error {
+	l.local.Lock()
+	defer l.local.Unlock()
+
+	if l.owned == "" {
+		return ErrLockNotOwned
+	}
+
+	expiresAt := TimeValue(time.Now().Add(l.lease), TimeUnixMillis)
+	err := l.write(ctx, func(own bool) *dynamodb.Update {
+		if own {
+			return &dynamodb.Update{
+				UpdateExpression:          aws.String("SET #lx = :x ADD #v :one"),
+				ConditionExpression:       aws.String("#id = :id"),
+				ExpressionAttributeNames:  map[string]*string{"#id": aws.String("Dyno_LockID"), "#lx": aws.String("Dyno_ExpiresAtMs"), "#v": aws.String("Dyno_Version")},
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":id": Str(l.owned), ":x": expiresAt, ":one": Int(1)},
+			}
+		}
+		return &dynamodb.Update{
+			UpdateExpression:          aws.String("SET #d.#me = :x ADD #v :one"),
+			ConditionExpression:       aws.String("attribute_exists(#d.#me)"),
+			ExpressionAttributeNames:  map[string]*string{"#d": aws.String("Dyno_Descendants"), "#me": aws.String(l.owned), "#v": aws.String("Dyno_Version")},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":x": expiresAt, ":one": Int(1)},
+		}
+	})
+	if Classify(err) == ErrorClassConditionalCheckFailed {
+		l.owned = ""
+		return ErrLockNotOwned
+	}
+	return err
+}
+
+// Release releases the lock and removes its markers from its ancestors
+func (l *TreeLock) Release() error {
+	l.local.Lock()
+	defer l.local.Unlock()
+
+	if l.owned == "" {
+		return ErrLockNotOwned
+	}
+
+	err := l.write(context.Background(), func(own bool) *dynamodb.Update {
+		if own {
+			return &dynamodb.Update{
+				UpdateExpression:          aws.String("REMOVE #id, #lx ADD #v :one"),
+				ConditionExpression:       aws.String("#id = :id"),
+				ExpressionAttributeNames:  map[string]*string{"#id": aws.String("Dyno_LockID"), "#lx": aws.String("Dyno_ExpiresAtMs"), "#v": aws.String("Dyno_Version")},
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":id": Str(l.owned), ":one": Int(1)},
+			}
+		}
+		return &dynamodb.Update{
+			UpdateExpression:          aws.String("REMOVE #d.#me ADD #v :one"),
+			ExpressionAttributeNames:  map[string]*string{"#d": aws.String("Dyno_Descendants"), "#me": aws.String(l.owned), "#v": aws.String("Dyno_Version")},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":one": Int(1)},
+		}
+	})
+	if Classify(err) == ErrorClassConditionalCheckFailed { // The lease expired and the lock was taken
+		err = nil
+	}
+	if err == nil {
+		l.owned = ""
+	}
+	return err
+}
+
+// write updates the items of the lock's path and its ancestors in a transaction
+func (l *TreeLock) write(ctx context.Context, fn func(own bool) *dynamodb.Update) error {
+	paths, err := l.paths()
+	if err != nil {
+		return err
+	}
+
+	writes := make([]*dynamodb.TransactWriteItem, len(paths))
+	for i, path := range paths {
+		update := fn(i == len(paths)-1)
+		update.TableName = aws.String(l.tn)
+		update.Key = l.key(path)
+		writes[i] = &dynamodb.TransactWriteItem{Update: update}
+	}
+
+	_, err = l.db.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: writes})
+	return err
+}
@@ -0,0 +1,132 @@
+package dyno
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTreeLock(t *testing.T) {
+	ctx := context.Background()
+	tree := ksuid.New().String()
+	lock := func(path string) *TreeLock {
+		return NewTreeLock(testClient, tableName, "PK", "SK", tree, path)
+	}
+
+	t.Run("given a lock held on an ancestor", func(t *testing.T) {
+		tenant := lock("tenant/1")
+		require.NoError(t, tenant.Acquire(30*time.Second))
+
+		assert.Equal(t, ErrLockAcquireTimeout, lock("tenant/1").Acquire(30*time.Second))
+		assert.Equal(t, ErrLockAcquireTimeout, lock("tenant/1/orders").Acquire(30*time.Second))
+		assert.Equal(t, ErrLockAcquireTimeout, lock("tenant/1/orders/9").Acquire(30*time.Second))
+
+		sibling := lock("tenant/2/orders")
+		require.NoError(t, sibling.Acquire(30*time.Second))
+		require.NoError(t, sibling.Release())
+
+		require.NoError(t, tenant.Release())
+		orders := lock("tenant/1/orders")
+		require.NoError(t, orders.Acquire(30*time.Second))
+		require.NoError(t, orders.Release())
+	})
+
+	t.Run("given locks held on descendants", func(t *testing.T) {
+		orders := lock("tenant/1/orders/9")
+		require.NoError(t, orders.Acquire(30*time.Second))
+		invoices := lock("tenant/1/invoices")
+		require.NoError(t, invoices.Acquire(30*time.Second))
+
+		assert.Equal(t, ErrLockAcquireTimeout, lock("tenant/1").Acquire(30*time.Second))
+		assert.Equal(t, ErrLockAcquireTimeout, lock("tenant").Acquire(30*time.Second))
+
+		require.NoError(t, orders.Release())
+		assert.Equal(t, ErrLockAcquireTimeout, lock("tenant/1").Acquire(30*time.Second), "invoices are still locked")
+		other := lock("tenant/1/orders")
+		require.NoError(t, other.Acquire(30*time.Second))
+		require.NoError(t, other.Release())
+
+		require.NoError(t, invoices.Release())
+		assert.Equal(t, ErrLockNotOwned, invoices.Release())
+	})
+
+	t.Run("given a descendant lock whose lease expired", func(t *testing.T) {
+		shipments := lock("tenant/3/shipments")
+		require.NoError(t, shipments.Acquire(200*time.Millisecond))
+
+		maintenance := lock("tenant/3")
+		require.NoError(t, maintenance.AcquireWithTimeout(30*time.Second, 5*time.Second))
+		defer maintenance.Release()
+
+		assert.Equal(t, ErrLockNotOwned, shipments.Renew(ctx))
+	})
+
+	t.Run("given a renewed lock", func(t *testing.T) {
+		refunds := lock("tenant/4/refunds")
+		require.NoError(t, refunds.Acquire(300*time.Millisecond))
+		defer refunds.Release()
+
+		time.Sleep(200 * time.Millisecond)
+		require.NoError(t, refunds.Renew(ctx))
+		time.Sleep(200 * time.Millisecond)
+
+		assert.Equal(t, ErrLockAcquireTimeout, lock("tenant/4").Acquire(30*time.Second))
+	})
+
+	t.Run("given contending locks", func(t *testing.T) {
+		var mu sync.Mutex
+		held := map[string]bool{}
+		overlapped := false
+
+		hold := func(path string) {
+			l := lock(path)
+			l.PollInterval = 5 * time.Millisecond
+			l.MaxPollInterval = 20 * time.Millisecond
+			if !assert.NoError(t, l.AcquireWithTimeout(30*time.Second, 10*time.Second)) {
+				return
+			}
+
+			mu.Lock()
+			for other := range held {
+				if strings.HasPrefix(other+"/", path+"/") || strings.HasPrefix(path+"/", other+"/") {
+					overlapped = true
+				}
+			}
+			held[path] = true
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			delete(held, path)
+			mu.Unlock()
+			assert.NoError(t, l.Release())
+		}
+
+		var wg sync.WaitGroup
+		for _, path := range []string{"tenant/6", "tenant/6/orders", "tenant/6/orders/1", "tenant/6/invoices"} {
+			wg.Add(1)
+			go func(path string) {
+				defer wg.Done()
+				for i := 0; i < 5; i++ {
+					hold(path)
+				}
+			}(path)
+		}
+		wg.Wait()
+
+		assert.False(t, overlapped)
+	})
+
+	t.Run("given invalid paths", func(t *testing.T) {
+		assert.Equal(t, ErrInvalidLockPath, lock("").Acquire(time.Second))
+		assert.Equal(t, ErrInvalidLockPath, lock("tenant//orders").Acquire(time.Second))
+		assert.Equal(t, ErrLockNotOwned, lock("tenant/5").Renew(ctx))
+	})
+}
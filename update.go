@@ -0,0 +1,111 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+var ErrUpdateConflict = errors.New("item changed during every attempt to update it")
+
+// UpdateFunc returns the new version of an item, given the current one, or nil if there isn't one. Returning a nil
+// item deletes it. It may be called several times, so it shouldn't have side effects.
+type UpdateFunc func(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error)
+
+// Updater reads, modifies, and writes items in a table, using a version attribute to detect items that changed in
+// between, and retrying with the changed item when they did
+type Updater struct {
+	db dynamodbiface.DynamoDBAPI
+	tn string
+
+	// VersionAttribute is the numeric attribute that's incremented on each write. Defaults to "Version".
+	VersionAttribute string
+
+	// MaxAttempts is the number of times an update is tried before giving up with ErrUpdateConflict. Defaults to 10.
+	MaxAttempts int
+
+	// Metrics receives an "UpdateConflict" count each time an item changed before it could be written
+	Metrics Metrics
+}
+
+func NewUpdater(db dynamodbiface.DynamoDBAPI, tableName string) *Updater {
+	return &Updater{
+		db:               db,
+		tn:               tableName,
+		VersionAttribute: "Version",
+		MaxAttempts:      10,
+	}
+}
+
+// Update reads the item with the key, passes it to fn, and writes the result if the item hasn't changed since it was
+// read. If it has, it's read again and passed to fn again, after a backoff. It returns the item that was written.
+func (u *Updater) Update(ctx context.Context, key map[string]*dynamodb.AttributeValue, fn UpdateFunc) (map[string]*dynamodb.AttributeValue, error) {
+	backoff := 10 * time.Millisecond
+
+	for attempt := 1; ; attempt++ {
+		result, err := u.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+			TableName:      aws.String(u.tn),
+			Key:            key,
+			ConsistentRead: aws.Bool(true),
+		})
+		if err != nil && !IsRetryable(err) {
+			return nil, err
+		}
+
+		if err == nil {
+			current := result.Item
+			version := GetInt(current, u.VersionAttribute, 0)
+
+			item, err := fn(copyItem(current))
+			if err != nil {
+				return nil, err
+			}
+
+			if item == nil {
+				err = DeleteIfVersion(ctx, u.db, u.tn, key, u.VersionAttribute, version)
+			} else {
+				err = PutIfVersion(ctx, u.db, u.tn, item, u.VersionAttribute, version)
+				if err == nil {
+					item = copyItem(item)
+					item[u.VersionAttribute] = Int(version + 1)
+				}
+			}
+			if err == nil {
+				return item, nil
+			}
+			if err == ErrVersionMismatch {
+				countMetric(u.Metrics, "UpdateConflict", 1, map[string]string{"Table": u.tn})
+			} else if !IsRetryable(err) {
+				return nil, err
+			}
+		}
+
+		if attempt >= u.MaxAttempts {
+			return nil, ErrUpdateConflict
+		}
+
+		// Jitter keeps writers that conflicted from retrying in lockstep
+		if err := sleepContext(ctx, backoff/2+time.Duration(rand.Int63n(int64(backoff)))); err != nil {
+			return nil, err
+		}
+		if backoff < time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+func copyItem(item map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	if item == nil {
+		return nil
+	}
+	c := make(map[string]*dynamodb.AttributeValue, len(item))
+	for k, v := range item {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,101 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdater(t *testing.T) {
+	ctx := context.Background()
+	table, drop := createTestTable(t, "dyno-test-update")
+	defer drop()
+
+	key := func() map[string]*dynamodb.AttributeValue {
+		return map[string]*dynamodb.AttributeValue{
+			"PK": {S: aws.String("counters")},
+			"SK": {S: aws.String("visits")},
+		}
+	}
+	increment := func(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+		count := 0
+		if item == nil {
+			item = key()
+		} else {
+			count, _ = strconv.Atoi(aws.StringValue(item["Count"].N))
+		}
+		item["Count"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(count + 1))}
+		return item, nil
+	}
+
+	t.Run("given concurrent updates", func(t *testing.T) {
+		updater := NewUpdater(testClient, table)
+		updater.MaxAttempts = 100
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := updater.Update(ctx, key(), increment)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		item, err := updater.Update(ctx, key(), increment)
+		require.NoError(t, err)
+		assert.Equal(t, "11", aws.StringValue(item["Count"].N))
+		assert.Equal(t, "11", aws.StringValue(item["Version"].N))
+	})
+
+	t.Run("given an item that always changes", func(t *testing.T) {
+		counter := &countingMetrics{}
+		updater := NewUpdater(testClient, table)
+		updater.MaxAttempts = 3
+		updater.Metrics = counter
+		calls := 0
+
+		_, err := updater.Update(ctx, key(), func(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+			calls++
+			_, err := NewUpdater(testClient, table).Update(ctx, key(), increment)
+			require.NoError(t, err)
+			return increment(item)
+		})
+
+		assert.Equal(t, ErrUpdateConflict, err)
+		assert.Equal(t, 3, calls)
+		assert.Equal(t, 3.0, counter.counts["UpdateConflict"])
+	})
+
+	t.Run("given a failing function", func(t *testing.T) {
+		failure := errors.New("failed")
+
+		_, err := NewUpdater(testClient, table).Update(ctx, key(), func(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+			return nil, failure
+		})
+
+		assert.Equal(t, failure, err)
+	})
+
+	t.Run("given a deleted item", func(t *testing.T) {
+		updater := NewUpdater(testClient, table)
+
+		item, err := updater.Update(ctx, key(), func(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+			return nil, nil
+		})
+		require.NoError(t, err)
+		assert.Nil(t, item)
+
+		item, err = updater.Update(ctx, key(), increment)
+		require.NoError(t, err)
+		assert.Equal(t, "1", aws.StringValue(item["Count"].N))
+	})
+}
@@ -0,0 +1,159 @@
+package dyno
+
+import (
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Str returns a string attribute value
+func Str(s string) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{S: aws.String(s)}
+}
+
+// Int returns a number attribute value
+func Int(n int64) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(n, 10))}
+}
+
+// Float returns a number attribute value, with the fewest digits that represent f exactly
+func Float(f float64) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{N: aws.String(strconv.FormatFloat(f, 'f', -1, 64))}
+}
+
+// Bool returns a boolean attribute value
+func Bool(b bool) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{BOOL: aws.Bool(b)}
+}
+
+// Bytes returns a binary attribute value
+func Bytes(b []byte) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{B: b}
+}
+
+// Null returns a null attribute value
+func Null() *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{NULL: aws.Bool(true)}
+}
+
+// StringSet returns a string set attribute value. DynamoDB rejects empty sets, so an empty set is returned as null.
+func StringSet(ss ...string) *dynamodb.AttributeValue {
+	if len(ss) == 0 {
+		return Null()
+	}
+	return &dynamodb.AttributeValue{SS: aws.StringSlice(ss)}
+}
+
+// NumberSet returns a number set attribute value. DynamoDB rejects empty sets, so an empty set is returned as null.
+func NumberSet(ns ...int64) *dynamodb.AttributeValue {
+	if len(ns) == 0 {
+		return Null()
+	}
+	av := &dynamodb.AttributeValue{NS: make([]*string, len(ns))}
+	for i, n := range ns {
+		av.NS[i] = aws.String(strconv.FormatInt(n, 10))
+	}
+	return av
+}
+
+// List returns a list attribute value
+func List(values ...*dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if values == nil {
+		values = []*dynamodb.AttributeValue{}
+	}
+	return &dynamodb.AttributeValue{L: values}
+}
+
+// Map returns a map attribute value
+func Map(m map[string]*dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if m == nil {
+		m = map[string]*dynamodb.AttributeValue{}
+	}
+	return &dynamodb.AttributeValue{M: m}
+}
+
+// GetString returns the named string attribute, or def if the item doesn't have one
+func GetString(item map[string]*dynamodb.AttributeValue, name, def string) string {
+	if av := item[name]; av != nil && av.S != nil {
+		return *av.S
+	}
+	return def
+}
+
+// GetInt returns the named number attribute, or def if the item doesn't have one that's an integer
+func GetInt(item map[string]*dynamodb.AttributeValue, name string, def int64) int64 {
+	if av := item[name]; av != nil && av.N != nil {
+		if n, err := strconv.ParseInt(*av.N, 10, 64); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+// GetFloat returns the named number attribute, or def if the item doesn't have one
+func GetFloat(item map[string]*dynamodb.AttributeValue, name string, def float64) float64 {
+	if av := item[name]; av != nil && av.N != nil {
+		if f, err := strconv.ParseFloat(*av.N, 64); err == nil {
+			return f
+		}
+	}
+	return def
+}
+
+// GetBool returns the named boolean attribute, or def if the item doesn't have one
+func GetBool(item map[string]*dynamodb.AttributeValue, name string, def bool) bool {
+	if av := item[name]; av != nil && av.BOOL != nil {
+		return *av.BOOL
+	}
+	return def
+}
+
+// GetBytes returns the named binary attribute, or def if the item doesn't have one
+func GetBytes(item map[string]*dynamodb.AttributeValue, name string, def []byte) []byte {
+	if av := item[name]; av != nil && av.B != nil {
+		return av.B
+	}
+	return def
+}
+
+// GetStringSet returns the named string set attribute, or nil if the item doesn't have one
+func GetStringSet(item map[string]*dynamodb.AttributeValue, name string) []string {
+	if av := item[name]; av != nil && av.SS != nil {
+		return aws.StringValueSlice(av.SS)
+	}
+	return nil
+}
+
+// GetNumberSet returns the named number set attribute, or nil if the item doesn't have one. Numbers that aren't
+// integers are skipped.
+func GetNumberSet(item map[string]*dynamodb.AttributeValue, name string) []int64 {
+	av := item[name]
+	if av == nil || av.NS == nil {
+		return nil
+	}
+
+	ns := make([]int64, 0, len(av.NS))
+	for _, s := range av.NS {
+		if n, err := strconv.ParseInt(aws.StringValue(s), 10, 64); err == nil {
+			ns = append(ns, n)
+		}
+	}
+	return ns
+}
+
+// GetList returns the named list attribute, or nil if the item doesn't have one
+func GetList(item map[string]*dynamodb.AttributeValue, name string) []*dynamodb.AttributeValue {
+	if av := item[name]; av != nil {
+		return av.L
+	}
+	return nil
+}
+
+// GetMap returns the named map attribute, or nil if the item doesn't have one
+func GetMap(item map[string]*dynamodb.AttributeValue, name string) map[string]*dynamodb.AttributeValue {
+	if av := item[name]; av != nil {
+		return av.M
+	}
+	return nil
+}
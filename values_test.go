@@ -0,0 +1,54 @@
+package dyno
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValues(t *testing.T) {
+	item := map[string]*dynamodb.AttributeValue{
+		"Name":    Str("dyno"),
+		"Count":   Int(42),
+		"Ratio":   Float(0.25),
+		"Active":  Bool(true),
+		"Data":    Bytes([]byte("data")),
+		"Tags":    StringSet("a", "b"),
+		"Scores":  NumberSet(1, 2, 3),
+		"Items":   List(Str("x"), Int(1)),
+		"Details": Map(map[string]*dynamodb.AttributeValue{"Nested": Str("y")}),
+		"Empty":   StringSet(),
+	}
+
+	t.Run("given attributes that are set", func(t *testing.T) {
+		assert.Equal(t, "dyno", GetString(item, "Name", ""))
+		assert.Equal(t, int64(42), GetInt(item, "Count", 0))
+		assert.Equal(t, 0.25, GetFloat(item, "Ratio", 0))
+		assert.Equal(t, 42.0, GetFloat(item, "Count", 0))
+		assert.True(t, GetBool(item, "Active", false))
+		assert.Equal(t, []byte("data"), GetBytes(item, "Data", nil))
+		assert.Equal(t, []string{"a", "b"}, GetStringSet(item, "Tags"))
+		assert.Equal(t, []int64{1, 2, 3}, GetNumberSet(item, "Scores"))
+		assert.Len(t, GetList(item, "Items"), 2)
+		assert.Equal(t, "y", GetString(GetMap(item, "Details"), "Nested", ""))
+	})
+
+	t.Run("given missing attributes or the wrong types", func(t *testing.T) {
+		assert.Equal(t, "default", GetString(item, "Missing", "default"))
+		assert.Equal(t, "default", GetString(item, "Count", "default"))
+		assert.Equal(t, int64(7), GetInt(item, "Ratio", 7), "given a number that isn't an integer")
+		assert.Equal(t, 1.5, GetFloat(item, "Name", 1.5))
+		assert.True(t, GetBool(item, "Missing", true))
+		assert.Nil(t, GetStringSet(item, "Empty"))
+		assert.Nil(t, GetMap(item, "Missing"))
+		assert.Equal(t, "", GetString(nil, "Name", ""), "given a nil item")
+	})
+
+	t.Run("given empty collections", func(t *testing.T) {
+		assert.True(t, *StringSet().NULL)
+		assert.True(t, *NumberSet().NULL)
+		assert.NotNil(t, List().L)
+		assert.NotNil(t, Map(nil).M)
+	})
+}
@@ -0,0 +1,167 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsiface"
+)
+
+var ErrViewTooManyWrites = errors.New("view function returned more than 24 writes for a change")
+
+// ViewChange is a change to an item in a view's source table
+type ViewChange struct {
+	// EventName is INSERT, MODIFY or REMOVE
+	EventName string
+
+	// SequenceNumber identifies the change in the source table's stream
+	SequenceNumber string
+
+	Keys     map[string]*dynamodb.AttributeValue
+	OldImage map[string]*dynamodb.AttributeValue
+	NewImage map[string]*dynamodb.AttributeValue
+}
+
+// ViewFunc returns the writes that bring a view up to date with a change to its source table, e.g. a Put of a
+// denormalized item, or an Update that ADDs to a count. The images are only set if the source table's stream includes
+// them. It may be called more than once for the same change, so it shouldn't have side effects.
+type ViewFunc func(change *ViewChange) ([]*dynamodb.TransactWriteItem, error)
+
+// ViewMaintainer keeps a view table derived from a source table up to date, by reading the source table's stream and
+// applying the writes its ViewFunc returns for each change.
+//
+// Each change's writes are applied in a transaction with a marker item, so a change that's read again after a worker
+// fails is only applied once, and aggregates like counts stay correct. Markers expire with the table's TTL, if it's
+// enabled on Dyno_ExpiresAt. Progress through the stream is checkpointed in the view table with ShardLeases, so Run
+// can be used on several workers.
+type ViewMaintainer struct {
+	db   dynamodbiface.DynamoDBAPI
+	tn   string
+	pk   string
+	sk   string
+	name string
+	fn   ViewFunc
+
+	// MarkerTTL is how long the markers of applied changes are kept. It must be longer than the stream keeps records,
+	// which is 24 hours. Defaults to 48 hours.
+	MarkerTTL time.Duration
+
+	// Metrics receives a "ViewChangeSkipped" count for each change that had already been applied
+	Metrics Metrics
+}
+
+// NewViewMaintainer returns a maintainer for the named view in the table with the given key names. The ViewFunc's
+// writes can go to any table, but the markers and checkpoints are kept in this one.
+func NewViewMaintainer(db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey, name string, fn ViewFunc) *ViewMaintainer {
+	return &ViewMaintainer{
+		db:        db,
+		tn:        tableName,
+		pk:        primaryKey,
+		sk:        sortKey,
+		name:      name,
+		fn:        fn,
+		MarkerTTL: 48 * time.Hour,
+	}
+}
+
+// Run reads the source table's stream from the oldest record, maintaining the view until the context is done or a
+// change can't be applied. Workers with different IDs share the stream's shards.
+func (v *ViewMaintainer) Run(ctx context.Context, streams dynamodbstreamsiface.DynamoDBStreamsAPI, streamArn, workerID string) error {
+	poller := NewStreamPoller(streams, streamArn)
+	poller.IteratorType = dynamodbstreams.ShardIteratorTypeTrimHorizon
+	poller.Leases = NewShardLeases(v.db, v.tn, v.pk, v.sk, fmt.Sprintf("Dyno_View/%s", v.name), workerID)
+
+	return poller.Run(ctx, v.Handler())
+}
+
+// Handler returns a handler that applies the records' changes to the view, for use with a StreamPoller
+func (v *ViewMaintainer) Handler() StreamHandler {
+	return func(ctx context.Context, shardID string, records []*dynamodbstreams.Record) error {
+		for _, record := range records {
+			if record.Dynamodb == nil {
+				continue
+			}
+
+			change := &ViewChange{
+				EventName:      aws.StringValue(record.EventName),
+				SequenceNumber: aws.StringValue(record.Dynamodb.SequenceNumber),
+				Keys:           record.Dynamodb.Keys,
+				OldImage:       record.Dynamodb.OldImage,
+				NewImage:       record.Dynamodb.NewImage,
+			}
+			if err := v.Apply(ctx, change); err != nil {
+				return fmt.Errorf("applying change %s to view %s: %w", change.SequenceNumber, v.name, err)
+			}
+		}
+		return nil
+	}
+}
+
+// Apply applies a change to the view, unless it has already been applied
+func (v *ViewMaintainer) Apply(ctx context.Context, change *ViewChange) error {
+	writes, err := v.fn(change)
+	if err != nil {
+		return err
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+	if len(writes) >= maxTransactItems {
+		return ErrViewTooManyWrites
+	}
+
+	marker := v.marker(change.SequenceNumber)
+	marker["Dyno_ExpiresAt"] = TimeValue(time.Now().Add(v.MarkerTTL), TimeUnixSeconds)
+	items := append([]*dynamodb.TransactWriteItem{{
+		Put: &dynamodb.Put{
+			TableName:                aws.String(v.tn),
+			Item:                     marker,
+			ConditionExpression:      aws.String("attribute_not_exists(#pk)"),
+			ExpressionAttributeNames: map[string]*string{"#pk": aws.String(v.pk)},
+		},
+	}}, writes...)
+
+	backoff := 50 * time.Millisecond
+	for {
+		_, err := v.db.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: items,
+		})
+		if err == nil {
+			return nil
+		}
+
+		var canceled *dynamodb.TransactionCanceledException
+		if errors.As(err, &canceled) && len(canceled.CancellationReasons) > 0 &&
+			aws.StringValue(canceled.CancellationReasons[0].Code) == "ConditionalCheckFailed" {
+			countMetric(v.Metrics, "ViewChangeSkipped", 1, map[string]string{"View": v.name})
+			return nil
+		}
+		if !IsRetryable(err) {
+			return err
+		}
+
+		if err := sleepContext(ctx, backoff); err != nil {
+			return err
+		}
+		if backoff < 5*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+func (v *ViewMaintainer) marker(sequenceNumber string) map[string]*dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{}
+	item[v.pk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_ViewApplied/%s/%s", v.name, sequenceNumber))}
+
+	if v.sk != "" {
+		item[v.sk] = &dynamodb.AttributeValue{S: aws.String("Dyno_ViewAppliedSortKeyValue")}
+	}
+
+	return item
+}
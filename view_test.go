@@ -0,0 +1,83 @@
+package dyno
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestViewMaintainer(t *testing.T) {
+	ctx := context.Background()
+
+	// The view counts the orders of each customer
+	count := func(change *ViewChange) ([]*dynamodb.TransactWriteItem, error) {
+		if change.EventName != "INSERT" {
+			return nil, nil
+		}
+		return []*dynamodb.TransactWriteItem{{
+			Update: &dynamodb.Update{
+				TableName: aws.String(tableName),
+				Key: map[string]*dynamodb.AttributeValue{
+					"PK": Str("view-customer/" + GetString(change.NewImage, "Customer", "")),
+					"SK": Str("orders"),
+				},
+				UpdateExpression:          aws.String("ADD #c :one"),
+				ExpressionAttributeNames:  map[string]*string{"#c": aws.String("Count")},
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":one": Int(1)},
+			},
+		}}, nil
+	}
+	metrics := &countingMetrics{}
+	view := NewViewMaintainer(testClient, tableName, "PK", "SK", "testing-view", count)
+	view.Metrics = metrics
+
+	record := func(event, sequenceNumber, customer string) *dynamodbstreams.Record {
+		return &dynamodbstreams.Record{
+			EventName: aws.String(event),
+			Dynamodb: &dynamodbstreams.StreamRecord{
+				SequenceNumber: aws.String(sequenceNumber),
+				Keys:           map[string]*dynamodb.AttributeValue{"PK": Str("order/" + sequenceNumber)},
+				NewImage:       map[string]*dynamodb.AttributeValue{"Customer": Str(customer)},
+			},
+		}
+	}
+	orders := func() int64 {
+		result, err := testClient.GetItem(&dynamodb.GetItemInput{
+			TableName: aws.String(tableName),
+			Key:       map[string]*dynamodb.AttributeValue{"PK": Str("view-customer/c1"), "SK": Str("orders")},
+		})
+		require.NoError(t, err)
+		return GetInt(result.Item, "Count", 0)
+	}
+
+	t.Run("given new records", func(t *testing.T) {
+		handler := view.Handler()
+		require.NoError(t, handler(ctx, "shard", []*dynamodbstreams.Record{
+			record("INSERT", "100", "c1"),
+			record("INSERT", "101", "c1"),
+			record("MODIFY", "102", "c1"),
+		}))
+		assert.Equal(t, int64(2), orders())
+	})
+
+	t.Run("given records that were already applied", func(t *testing.T) {
+		require.NoError(t, view.Handler()(ctx, "shard", []*dynamodbstreams.Record{
+			record("INSERT", "101", "c1"),
+			record("INSERT", "103", "c1"),
+		}))
+		assert.Equal(t, int64(3), orders())
+		assert.Equal(t, float64(1), metrics.counts["ViewChangeSkipped"])
+	})
+
+	t.Run("given too many writes", func(t *testing.T) {
+		view := NewViewMaintainer(testClient, tableName, "PK", "SK", "testing-view", func(change *ViewChange) ([]*dynamodb.TransactWriteItem, error) {
+			return make([]*dynamodb.TransactWriteItem, 25), nil
+		})
+		assert.Equal(t, ErrViewTooManyWrites, view.Apply(ctx, &ViewChange{SequenceNumber: "1"}))
+	})
+}
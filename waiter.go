@@ -0,0 +1,146 @@
+package dyno
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// TableProgress is reported after every poll while waiting for a table
+type TableProgress struct {
+	TableName string
+
+	// Status is the table's status, or empty if it doesn't exist
+	Status    string
+	ItemCount int64
+	SizeBytes int64
+
+	// Indexes are the table's global secondary indexes
+	Indexes []IndexProgress
+
+	// Polls is the number of times the table has been described
+	Polls int
+}
+
+// WaitOptions configures the table waiters
+type WaitOptions struct {
+	// PollInterval is how long to wait after the first poll. It doubles after every poll, up to MaxPollInterval.
+	// Defaults to 1 second.
+	PollInterval time.Duration
+
+	// MaxPollInterval is the longest wait between polls. Defaults to 20 seconds.
+	MaxPollInterval time.Duration
+
+	// Progress is called after every poll of the table
+	Progress func(TableProgress)
+}
+
+// WaitUntilTableActive blocks until the table is ACTIVE, and returns its description. A table that doesn't exist yet is
+// waited for, since a table that's just been created or restored can take a moment to be described.
+func WaitUntilTableActive(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName string, opts *WaitOptions) (*dynamodb.TableDescription, error) {
+	return waitForTable(ctx, db, tableName, opts, func(table *dynamodb.TableDescription) (bool, error) {
+		return table != nil && aws.StringValue(table.TableStatus) == dynamodb.TableStatusActive, nil
+	})
+}
+
+// WaitUntilTableDeleted blocks until the table doesn't exist
+func WaitUntilTableDeleted(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName string, opts *WaitOptions) error {
+	_, err := waitForTable(ctx, db, tableName, opts, func(table *dynamodb.TableDescription) (bool, error) {
+		return table == nil, nil
+	})
+	return err
+}
+
+// WaitUntilIndexActive blocks until the global secondary index is ACTIVE and has finished backfilling.
+// ErrIndexNotFound is returned if the table or index doesn't exist, and ErrIndexDeleting if the index is being deleted.
+func WaitUntilIndexActive(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName, indexName string, opts *WaitOptions) error {
+	_, err := waitForTable(ctx, db, tableName, opts, func(table *dynamodb.TableDescription) (bool, error) {
+		if table == nil {
+			return false, ErrIndexNotFound
+		}
+
+		for _, index := range table.GlobalSecondaryIndexes {
+			if aws.StringValue(index.IndexName) != indexName {
+				continue
+			}
+			switch aws.StringValue(index.IndexStatus) {
+			case dynamodb.IndexStatusActive:
+				return !aws.BoolValue(index.Backfilling), nil
+			case dynamodb.IndexStatusDeleting:
+				return false, ErrIndexDeleting
+			}
+			return false, nil
+		}
+		return false, ErrIndexNotFound
+	})
+	return err
+}
+
+// waitForTable describes the table until done returns true, with a nil description if the table doesn't exist
+func waitForTable(ctx context.Context, db dynamodbiface.DynamoDBAPI, tableName string, opts *WaitOptions, done func(*dynamodb.TableDescription) (bool, error)) (*dynamodb.TableDescription, error) {
+	if opts == nil {
+		opts = &WaitOptions{}
+	}
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	maxInterval := opts.MaxPollInterval
+	if maxInterval <= 0 {
+		maxInterval = 20 * time.Second
+	}
+
+	for polls := 1; ; polls++ {
+		var table *dynamodb.TableDescription
+		result, err := db.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(tableName),
+		})
+		if err != nil && Classify(err) != ErrorClassNotFound {
+			return nil, err
+		}
+		if err == nil {
+			table = result.Table
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(tableProgress(tableName, table, polls))
+		}
+
+		ok, err := done(table)
+		if err != nil || ok {
+			return table, err
+		}
+
+		if err := sleepContext(ctx, interval); err != nil {
+			return nil, err
+		}
+		if interval *= 2; interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+}
+
+func tableProgress(tableName string, table *dynamodb.TableDescription, polls int) TableProgress {
+	p := TableProgress{TableName: tableName, Polls: polls}
+	if table == nil {
+		return p
+	}
+
+	p.Status = aws.StringValue(table.TableStatus)
+	p.ItemCount = aws.Int64Value(table.ItemCount)
+	p.SizeBytes = aws.Int64Value(table.TableSizeBytes)
+	for _, index := range table.GlobalSecondaryIndexes {
+		p.Indexes = append(p.Indexes, IndexProgress{
+			TableName:   tableName,
+			IndexName:   aws.StringValue(index.IndexName),
+			Status:      aws.StringValue(index.IndexStatus),
+			Backfilling: aws.BoolValue(index.Backfilling),
+			ItemCount:   aws.Int64Value(index.ItemCount),
+			SizeBytes:   aws.Int64Value(index.IndexSizeBytes),
+		})
+	}
+	return p
+}
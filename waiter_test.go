@@ -0,0 +1,84 @@
+package dyno
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableWaiters(t *testing.T) {
+	ctx := context.Background()
+	name := fmt.Sprintf("dyno-test-waiter-%s", ksuid.New().String())
+	opts := &WaitOptions{PollInterval: 10 * time.Millisecond}
+
+	t.Run("given a table that doesn't exist", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		polls := 0
+		_, err := WaitUntilTableActive(ctx, testClient, name, &WaitOptions{
+			PollInterval: 10 * time.Millisecond,
+			Progress: func(p TableProgress) {
+				polls = p.Polls
+				assert.Empty(t, p.Status)
+			},
+		})
+		assert.Equal(t, ErrorClassCanceled, Classify(err))
+		assert.True(t, polls > 1)
+	})
+
+	t.Run("given an index on a table that doesn't exist", func(t *testing.T) {
+		assert.Equal(t, ErrIndexNotFound, WaitUntilIndexActive(ctx, testClient, name, "GSI1", opts))
+	})
+
+	_, err := testClient.CreateTable(&dynamodb.CreateTableInput{
+		TableName:   aws.String(name),
+		BillingMode: aws.String("PAY_PER_REQUEST"),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("PK"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("GSI1PK"), AttributeType: aws.String("S")},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("PK"), KeyType: aws.String("HASH")},
+		},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{{
+			IndexName:  aws.String("GSI1"),
+			KeySchema:  []*dynamodb.KeySchemaElement{{AttributeName: aws.String("GSI1PK"), KeyType: aws.String("HASH")}},
+			Projection: &dynamodb.Projection{ProjectionType: aws.String("KEYS_ONLY")},
+		}},
+	})
+	require.NoError(t, err)
+
+	t.Run("given a table that was created", func(t *testing.T) {
+		var last TableProgress
+		table, err := WaitUntilTableActive(ctx, testClient, name, &WaitOptions{
+			PollInterval: 10 * time.Millisecond,
+			Progress:     func(p TableProgress) { last = p },
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, name, *table.TableName)
+		assert.Equal(t, dynamodb.TableStatusActive, last.Status)
+		require.Len(t, last.Indexes, 1)
+		assert.Equal(t, "GSI1", last.Indexes[0].IndexName)
+	})
+
+	t.Run("given an index", func(t *testing.T) {
+		assert.NoError(t, WaitUntilIndexActive(ctx, testClient, name, "GSI1", opts))
+		assert.Equal(t, ErrIndexNotFound, WaitUntilIndexActive(ctx, testClient, name, "GSI2", opts))
+	})
+
+	t.Run("given a table that was deleted", func(t *testing.T) {
+		_, err := testClient.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String(name)})
+		require.NoError(t, err)
+
+		assert.NoError(t, WaitUntilTableDeleted(ctx, testClient, name, opts))
+	})
+}
@@ -0,0 +1,159 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+var (
+	ErrNotInWaitGroup         = errors.New("worker is not in the wait group")
+	ErrWaitGroupWorkerExpired = errors.New("wait group worker's lease expired")
+)
+
+// WaitGroup is a sync.WaitGroup shared between hosts. Workers are added to a named group, each with a lease they
+// renew while working, and are done when they finish. A coordinator waits until every worker is done, and finds out
+// about workers that stopped renewing their lease, e.g. because their host crashed.
+//
+// Each worker is kept in a map attribute on a single item, so a group is limited to the workers that fit in 400KB.
+type WaitGroup struct {
+	db   dynamodbiface.DynamoDBAPI
+	tn   string
+	pk   string
+	sk   string
+	name string
+
+	// Lease is how long a worker is considered to be working after it's added or renewed. Defaults to one minute.
+	Lease time.Duration
+
+	// PollInterval is how often the group is read while waiting. Defaults to one second.
+	PollInterval time.Duration
+}
+
+func NewWaitGroup(db dynamodbiface.DynamoDBAPI, tableName, primaryKey, sortKey, name string) *WaitGroup {
+	return &WaitGroup{
+		db:           db,
+		tn:           tableName,
+		pk:           primaryKey,
+		sk:           sortKey,
+		name:         name,
+		Lease:        time.Minute,
+		PollInterval: time.Second,
+	}
+}
+
+func (w *WaitGroup) key() map[string]*dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{}
+	item[w.pk] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("Dyno_WaitGroup/%s", w.name))}
+
+	if w.sk != "" {
+		item[w.sk] = &dynamodb.AttributeValue{S: aws.String("Dyno_WaitGroupSortKeyValue")}
+	}
+
+	return item
+}
+
+// Add adds the worker to the group, or renews its lease if it's already in it. The coordinator can add workers before
+// handing out their work, so Wait doesn't return before they've started.
+func (w *WaitGroup) Add(ctx context.Context, workerID string) error {
+	return setMapEntry(ctx, w.db, w.tn, w.key(), "Dyno_Workers", workerID, Int(unixMilli(time.Now().Add(w.Lease))))
+}
+
+// Renew extends the worker's lease. ErrNotInWaitGroup is returned if the worker is done, or was removed.
+func (w *WaitGroup) Renew(ctx context.Context, workerID string) error {
+	_, err := w.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(w.tn),
+		Key:                       w.key(),
+		UpdateExpression:          aws.String("SET #ws.#w = :x"),
+		ConditionExpression:       aws.String("attribute_exists(#ws.#w)"),
+		ExpressionAttributeNames:  map[string]*string{"#ws": aws.String("Dyno_Workers"), "#w": aws.String(workerID)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":x": Int(unixMilli(time.Now().Add(w.Lease)))},
+	})
+	if Classify(err) == ErrorClassConditionalCheckFailed {
+		return ErrNotInWaitGroup
+	}
+	return err
+}
+
+// Done removes the worker from the group. It's also used by the coordinator to give up on an expired worker.
+func (w *WaitGroup) Done(ctx context.Context, workerID string) error {
+	_, err := w.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                aws.String(w.tn),
+		Key:                      w.key(),
+		UpdateExpression:         aws.String("REMOVE #ws.#w"),
+		ConditionExpression:      aws.String("attribute_exists(#ws.#w)"),
+		ExpressionAttributeNames: map[string]*string{"#ws": aws.String("Dyno_Workers"), "#w": aws.String(workerID)},
+	})
+	if Classify(err) == ErrorClassConditionalCheckFailed {
+		return ErrNotInWaitGroup
+	}
+	return err
+}
+
+// Pending returns the workers that aren't done, and the ones among them whose lease has expired, sorted by ID
+func (w *WaitGroup) Pending(ctx context.Context) (pending []string, expired []string, err error) {
+	result, err := w.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:                aws.String(w.tn),
+		Key:                      w.key(),
+		ConsistentRead:           aws.Bool(true),
+		ProjectionExpression:     aws.String("#ws"),
+		ExpressionAttributeNames: map[string]*string{"#ws": aws.String("Dyno_Workers")},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := unixMilli(time.Now())
+	for id, av := range GetMap(result.Item, "Dyno_Workers") {
+		pending = append(pending, id)
+		if av.N != nil {
+			if ms, err := strconv.ParseInt(*av.N, 10, 64); err == nil && ms < now {
+				expired = append(expired, id)
+			}
+		}
+	}
+	sort.Strings(pending)
+	sort.Strings(expired)
+
+	return pending, expired, nil
+}
+
+// Wait blocks until every worker in the group is done. If a worker's lease expires first, an error wrapping
+// ErrWaitGroupWorkerExpired is returned with the expired workers' IDs; the coordinator can retry their work, then call
+// Done for them and wait again.
+func (w *WaitGroup) Wait(ctx context.Context) error {
+	for {
+		pending, expired, err := w.Pending(ctx)
+		if err != nil && !IsRetryable(err) {
+			return err
+		}
+		if err == nil {
+			if len(pending) == 0 {
+				return nil
+			}
+			if len(expired) > 0 {
+				return fmt.Errorf("%w: %v", ErrWaitGroupWorkerExpired, expired)
+			}
+		}
+
+		if err := sleepContext(ctx, w.PollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// Delete removes the group, e.g. once the coordinator has finished waiting
+func (w *WaitGroup) Delete(ctx context.Context) error {
+	_, err := w.db.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(w.tn),
+		Key:       w.key(),
+	})
+	return err
+}
@@ -0,0 +1,62 @@
+package dyno
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitGroup(t *testing.T) {
+	ctx := context.Background()
+
+	group := func() *WaitGroup {
+		w := NewWaitGroup(testClient, tableName, "PK", "SK", "testing-wait-group")
+		w.PollInterval = 10 * time.Millisecond
+		return w
+	}
+	coordinator, worker := group(), group()
+	defer coordinator.Delete(ctx)
+
+	t.Run("given an empty group", func(t *testing.T) {
+		assert.NoError(t, coordinator.Wait(ctx))
+		assert.Equal(t, ErrNotInWaitGroup, worker.Renew(ctx, "worker-1"))
+	})
+
+	t.Run("given workers that finish", func(t *testing.T) {
+		require.NoError(t, coordinator.Add(ctx, "worker-1"))
+		require.NoError(t, coordinator.Add(ctx, "worker-2"))
+
+		pending, expired, err := coordinator.Pending(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"worker-1", "worker-2"}, pending)
+		assert.Empty(t, expired)
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			worker.Renew(ctx, "worker-1")
+			worker.Done(ctx, "worker-1")
+			worker.Done(ctx, "worker-2")
+		}()
+
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		assert.NoError(t, coordinator.Wait(ctx))
+		assert.Equal(t, ErrNotInWaitGroup, worker.Done(ctx, "worker-1"))
+	})
+
+	t.Run("given a worker that stops renewing its lease", func(t *testing.T) {
+		worker.Lease = 20 * time.Millisecond
+		require.NoError(t, worker.Add(ctx, "worker-3"))
+
+		err := coordinator.Wait(ctx)
+		assert.True(t, errors.Is(err, ErrWaitGroupWorkerExpired))
+		assert.Contains(t, err.Error(), "worker-3")
+
+		require.NoError(t, coordinator.Done(ctx, "worker-3"))
+		assert.NoError(t, coordinator.Wait(ctx))
+	})
+}
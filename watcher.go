@@ -0,0 +1,89 @@
+package dyno
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsiface"
+)
+
+// LockWatcher follows the lock table's stream and wakes the locks waiting for a lock item as soon as it changes,
+// instead of leaving them to poll. One watcher can serve every lock in a process that uses the table.
+type LockWatcher struct {
+	Poller *StreamPoller
+	pk     string
+
+	mu      sync.Mutex
+	waiters map[string]map[chan struct{}]struct{}
+}
+
+// NewLockWatcher returns a watcher for a stream of the lock table. The poller reads each shard every 250ms.
+func NewLockWatcher(streams dynamodbstreamsiface.DynamoDBStreamsAPI, streamArn, primaryKey string) *LockWatcher {
+	poller := NewStreamPoller(streams, streamArn)
+	poller.Interval = 250 * time.Millisecond
+
+	return &LockWatcher{
+		Poller:  poller,
+		pk:      primaryKey,
+		waiters: map[string]map[chan struct{}]struct{}{},
+	}
+}
+
+// Run follows the stream until the context is done
+func (w *LockWatcher) Run(ctx context.Context) error {
+	return w.Poller.Run(ctx, w.handle)
+}
+
+func (w *LockWatcher) handle(ctx context.Context, shardID string, records []*dynamodbstreams.Record) error {
+	for _, record := range records {
+		if record.Dynamodb == nil {
+			continue
+		}
+		key, ok := record.Dynamodb.Keys[w.pk]
+		if !ok || !strings.HasPrefix(aws.StringValue(key.S), "Dyno_Lock/") {
+			continue
+		}
+
+		w.wake(strings.TrimPrefix(aws.StringValue(key.S), "Dyno_Lock/"))
+	}
+	return nil
+}
+
+func (w *LockWatcher) wake(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for wake := range w.waiters[name] {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// subscribe returns a channel that receives when the named lock's item changes, and a func to stop receiving
+func (w *LockWatcher) subscribe(name string) (<-chan struct{}, func()) {
+	wake := make(chan struct{}, 1)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.waiters[name] == nil {
+		w.waiters[name] = map[chan struct{}]struct{}{}
+	}
+	w.waiters[name][wake] = struct{}{}
+
+	return wake, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		delete(w.waiters[name], wake)
+		if len(w.waiters[name]) == 0 {
+			delete(w.waiters, name)
+		}
+	}
+}
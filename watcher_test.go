@@ -0,0 +1,56 @@
+package dyno
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockWatcher(t *testing.T) {
+	ctx := context.Background()
+	watcher := NewLockWatcher(testStreams, "", "PK")
+	changed := func(name string) []*dynamodbstreams.Record {
+		return []*dynamodbstreams.Record{{
+			EventName: aws.String(dynamodbstreams.OperationTypeModify),
+			Dynamodb: &dynamodbstreams.StreamRecord{
+				Keys: map[string]*dynamodb.AttributeValue{
+					"PK": {S: aws.String("Dyno_Lock/" + name)},
+					"SK": {S: aws.String("Dyno_LockSortKeyValue")},
+				},
+			},
+		}}
+	}
+
+	t.Run("given a waiter", func(t *testing.T) {
+		holder := NewLock(testClient, tableName, "PK", "SK", "testing-watched-lock")
+		require.NoError(t, holder.Acquire(30*time.Second))
+
+		waiter := NewLock(testClient, tableName, "PK", "SK", "testing-watched-lock")
+		waiter.PollInterval(time.Minute, time.Minute)
+		waiter.Watcher(watcher)
+
+		acquired := make(chan error)
+		go func() { acquired <- waiter.AcquireWithTimeout(30*time.Second, time.Minute) }()
+
+		// The first attempt to acquire finds the lock held, then waits
+		time.Sleep(50 * time.Millisecond)
+		require.NoError(t, holder.Release())
+		require.NoError(t, watcher.handle(ctx, "shard", changed("other-lock")))
+		require.NoError(t, watcher.handle(ctx, "shard", changed("testing-watched-lock")))
+
+		select {
+		case err := <-acquired:
+			require.NoError(t, err)
+			waiter.Release()
+		case <-time.After(time.Second):
+			t.Fatal("the waiter wasn't woken")
+		}
+		assert.Empty(t, watcher.waiters, "the waiter unsubscribed")
+	})
+}